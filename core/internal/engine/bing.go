@@ -0,0 +1,367 @@
+package engine
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google-dork-parser/core/internal/parser"
+	"github.com/google-dork-parser/core/internal/stealth"
+)
+
+// Bing implements the Engine interface for Bing search
+type Bing struct {
+	*BaseEngine
+	headerGen      *stealth.HeaderGenerator
+	domains        []string
+	resultsPerPage int
+	market         string
+	maxBodyBytes   int64
+	transport      http.RoundTripper
+	transports     transportCache // Built per proxy from transport
+}
+
+// BingConfig holds Bing engine configuration
+type BingConfig struct {
+	Domains        []string
+	ResultsPerPage int
+	Timeout        time.Duration
+	UserAgents     []string
+
+	// Market sets the mkt parameter (e.g. "en-US"); empty lets Bing pick
+	// from the exit IP
+	Market string
+
+	// MaxBodyBytes fails responses larger than this (0 =
+	// DefaultMaxBodyBytes)
+	MaxBodyBytes int64
+
+	// Transport replaces the built-in transport, as for GoogleConfig
+	Transport http.RoundTripper
+}
+
+// DefaultBingConfig returns default Bing configuration
+func DefaultBingConfig() BingConfig {
+	return BingConfig{
+		Domains:        []string{"www.bing.com"},
+		ResultsPerPage: 10,
+		Timeout:        30 * time.Second,
+		UserAgents:     stealth.DefaultUserAgents(),
+		Market:         "en-US",
+	}
+}
+
+// NewBing creates a new Bing search engine
+func NewBing(config BingConfig) *Bing {
+	if len(config.Domains) == 0 {
+		config.Domains = DefaultBingConfig().Domains
+	}
+	if config.ResultsPerPage == 0 {
+		config.ResultsPerPage = 10
+	}
+	// Bing serves at most 50 results per page
+	if config.ResultsPerPage > 50 {
+		config.ResultsPerPage = 50
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if len(config.UserAgents) == 0 {
+		config.UserAgents = stealth.DefaultUserAgents()
+	}
+
+	return &Bing{
+		BaseEngine:     NewBaseEngine("bing", config.Domains),
+		headerGen:      stealth.NewHeaderGenerator(config.UserAgents),
+		domains:        config.Domains,
+		resultsPerPage: config.ResultsPerPage,
+		market:         config.Market,
+		maxBodyBytes:   config.MaxBodyBytes,
+		transport:      config.Transport,
+	}
+}
+
+var (
+	// bingResultPattern matches the title link of an organic result
+	bingResultPattern = regexp.MustCompile(`(?s)<li class="b_algo"[^>]*>.*?<h2[^>]*>\s*<a[^>]+href="([^"]+)"`)
+
+	// bingNextPattern matches the pager's next link
+	bingNextPattern = regexp.MustCompile(`class="sb_pagN[^"]*"|title="Next page"`)
+
+	// bingCountPattern matches the result count, e.g. "About 1,230,000
+	// results" or "11-13 of 13 results" on later pages
+	bingCountPattern = regexp.MustCompile(`<span class="sb_count"[^>]*>(?:[^<]*?\bof\s+)?[^<0-9]*([0-9][0-9,.\s]*)`)
+)
+
+// CloseIdleConnections closes the idle connections kept for each proxy
+func (b *Bing) CloseIdleConnections() {
+	b.transports.closeAll()
+}
+
+// Search performs a Bing search
+func (b *Bing) Search(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
+	start := time.Now()
+
+	response := &SearchResponse{
+		RequestID:  request.ID,
+		Dork:       request.Dork,
+		Page:       request.Page,
+		EngineUsed: "bing",
+	}
+
+	domain := b.selectDomain()
+	searchURL := b.buildSearchURL(domain, request.Dork, request.Page)
+
+	// Create HTTP client with proxy
+	client, err := newClient(&b.transports, b.transport, request.Proxy, request.Timeout)
+	if err != nil {
+		response.Error = NewSearchError(ErrorTypeProxy, "failed to create client", err)
+		return response, err
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		response.Error = NewSearchError(ErrorTypeNetwork, "failed to create request", err)
+		return response, err
+	}
+
+	// Set headers
+	b.setHeaders(req, domain, request)
+
+	// Execute request
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			response.Error = NewSearchError(ErrorTypeTimeout, "request timed out", err)
+		} else {
+			response.Error = NewSearchError(ErrorTypeNetwork, "request failed", err)
+		}
+		return response, err
+	}
+	defer resp.Body.Close()
+
+	response.StatusCode = resp.StatusCode
+	response.Latency = time.Since(start)
+
+	if request.Proxy != nil {
+		response.ProxyUsed = request.Proxy.ID
+	}
+
+	// Check status code
+	if resp.StatusCode == 429 {
+		response.Error = NewSearchError(ErrorTypeRateLimit, "rate limited", nil)
+		response.Blocked = true
+		return response, response.Error
+	}
+
+	if resp.StatusCode == 403 || resp.StatusCode == 503 {
+		response.Error = NewSearchError(ErrorTypeBlocked, fmt.Sprintf("status %d (likely blocked)", resp.StatusCode), nil)
+		response.Blocked = true
+		return response, response.Error
+	}
+
+	if resp.StatusCode != 200 {
+		response.Error = NewSearchError(ErrorTypeNetwork, fmt.Sprintf("unexpected status: %d", resp.StatusCode), nil)
+		return response, response.Error
+	}
+
+	// Read body, capped so an endless response cannot exhaust memory
+	body, err := readBody(resp, b.maxBodyBytes)
+	if err != nil {
+		response.Error = NewSearchError(ErrorTypeNetwork, "failed to read response", err)
+		return response, err
+	}
+
+	html := string(body)
+	response.HTML = html
+
+	// Check for CAPTCHA
+	if b.IsCaptcha(html) {
+		response.Captcha = true
+		response.Error = NewSearchError(ErrorTypeCaptcha, "CAPTCHA detected", nil)
+		return response, response.Error
+	}
+
+	// Check for blocks
+	if b.IsBlocked(html) {
+		response.Blocked = true
+		response.Error = NewSearchError(ErrorTypeBlocked, "blocked by Bing", nil)
+		return response, response.Error
+	}
+
+	// Parse results
+	result := b.ParseResponse(html)
+	response.URLs = result.URLs
+	response.RawURLs = result.RawURLs
+	response.HasNextPage = result.HasNextPage
+	response.TotalResults = result.TotalResults
+
+	return response, nil
+}
+
+// BuildURL builds a Bing search URL
+func (b *Bing) BuildURL(query string, page int) string {
+	return b.buildSearchURL(b.selectDomain(), query, page)
+}
+
+// buildSearchURL builds the URL for a page. Bing paginates by the 1-based
+// offset of the first result: 1, 11, 21...
+func (b *Bing) buildSearchURL(domain, query string, page int) string {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(b.resultsPerPage))
+	params.Set("first", strconv.Itoa(page*b.resultsPerPage+1))
+	if b.market != "" {
+		params.Set("mkt", b.market)
+	}
+
+	return fmt.Sprintf("https://%s/search?%s", domain, params.Encode())
+}
+
+func (b *Bing) selectDomain() string {
+	if len(b.domains) == 0 {
+		return "www.bing.com"
+	}
+	return b.domains[rand.Intn(len(b.domains))]
+}
+
+func (b *Bing) setHeaders(req *http.Request, domain string, sr *SearchRequest) {
+	headers := b.headerGen.GenerateForSearch(domain, sr.Page > 0)
+	if sr.UserAgent != "" {
+		headers.SetUserAgent(sr.UserAgent)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range sr.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// ParseResponse extracts the organic results from a Bing results page
+func (b *Bing) ParseResponse(html string) *parser.ExtractionResult {
+	result := &parser.ExtractionResult{
+		HasNextPage:  bingNextPattern.MatchString(html),
+		TotalResults: parseBingCount(html),
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range bingResultPattern.FindAllStringSubmatch(html, -1) {
+		result.RawURLs = append(result.RawURLs, match[1])
+
+		cleaned := cleanBingURL(match[1])
+		if cleaned == "" || seen[cleaned] {
+			continue
+		}
+		seen[cleaned] = true
+		result.URLs = append(result.URLs, cleaned)
+	}
+
+	return result
+}
+
+// parseBingCount reads the result count, 0 when absent
+func parseBingCount(html string) int64 {
+	match := bingCountPattern.FindStringSubmatch(html)
+	if match == nil {
+		return 0
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, match[1])
+
+	count, _ := strconv.ParseInt(digits, 10, 64)
+	return count
+}
+
+// cleanBingURL decodes Bing's /ck/a click-tracking links and drops
+// anything that is not an external http(s) URL
+func cleanBingURL(rawURL string) string {
+	decoded := strings.ReplaceAll(rawURL, "&amp;", "&")
+
+	// /ck/a links carry the target base64-encoded in u, prefixed "a1"
+	if strings.Contains(decoded, "bing.com/ck/a") {
+		parsed, err := url.Parse(decoded)
+		if err != nil {
+			return ""
+		}
+		target := strings.TrimPrefix(parsed.Query().Get("u"), "a1")
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(target, "="))
+		if err != nil {
+			return ""
+		}
+		decoded = string(raw)
+	}
+
+	parsed, err := url.Parse(decoded)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "bing.com" || strings.HasSuffix(host, ".bing.com") ||
+		host == "microsoft.com" || strings.HasSuffix(host, ".microsoft.com") {
+		return ""
+	}
+
+	return decoded
+}
+
+// IsBlocked checks if blocked by Bing
+func (b *Bing) IsBlocked(html string) bool {
+	blockedIndicators := []string{
+		"your request has been blocked",
+		"access denied",
+		"too many requests",
+		"403 forbidden",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range blockedIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsCaptcha checks if Bing served its challenge page
+func (b *Bing) IsCaptcha(html string) bool {
+	captchaIndicators := []string{
+		"b_captcha",
+		"/turing/captcha",
+		"verify you are a human",
+		"one last step",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range captchaIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetDomains returns Bing domains
+func (b *Bing) GetDomains() []string {
+	return b.domains
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// bingPage is trimmed from a captured Bing results page
+const bingPage = `<!DOCTYPE html><html lang="en"><head><title>inurl:admin - Search</title></head>
+<body><div id="b_content"><main aria-label="Search Results">
+<div id="b_tween"><span class="sb_count">About 1,230,000 results</span></div>
+<ol id="b_results">
+<li class="b_algo" data-id="" data-bm="6"><div class="b_tpcn"><a class="tilk" href="https://a.example.com/admin" h="ID=SERP,5098.1"><div class="tpic"></div></a></div>
+<h2><a href="https://a.example.com/admin" h="ID=SERP,5111.1">Admin Login</a></h2>
+<div class="b_caption"><p>Sign in to the admin panel</p></div></li>
+<li class="b_algo" data-bm="7"><h2><a href="https://www.bing.com/ck/a?!&amp;&amp;p=4f2c&amp;ptn=3&amp;u=a1aHR0cHM6Ly90cmFja2VkLmV4YW1wbGUuY29tL2xvZ2lu&amp;ntb=1">Tracked</a></h2></li>
+<li class="b_algo" data-bm="8"><h2><a href="https://a.example.com/admin">Duplicate</a></h2></li>
+<li class="b_ans"><h2><a href="https://www.bing.com/images/search?q=admin">Images</a></h2></li>
+</ol>
+<nav role="navigation" aria-label="More results for inurl:admin"><ul class="sb_pagF">
+<li><a class="sb_pagS sb_pagS_bp b_widePag sb_bp" aria-label="Page 1">1</a></li>
+<li><a class="sb_pagN sb_pagN_bp b_widePag sb_bp" title="Next page" href="/search?q=inurl%3aadmin&amp;first=11">Next</a></li>
+</ul></nav></main></div></body></html>`
+
+func TestBingParseResponse(t *testing.T) {
+	b := NewBing(DefaultBingConfig())
+
+	tests := []struct {
+		name      string
+		html      string
+		wantURLs  []string
+		wantNext  bool
+		wantTotal int64
+	}{
+		{
+			name:      "results page",
+			html:      bingPage,
+			wantURLs:  []string{"https://a.example.com/admin", "https://tracked.example.com/login"},
+			wantNext:  true,
+			wantTotal: 1230000,
+		},
+		{
+			name: "last page",
+			html: `<span class="sb_count">11-13 of 13 results</span><ol id="b_results">
+<li class="b_algo"><h2><a href="http://b.example.org/">B</a></h2></li></ol>`,
+			wantURLs:  []string{"http://b.example.org/"},
+			wantTotal: 13,
+		},
+		{
+			name: "no results",
+			html: `<ol id="b_results"><li class="b_no"><h1>There are no results for <strong>inurl:nothing</strong></h1></li></ol>`,
+		},
+		{
+			name: "internal and broken links",
+			html: `<li class="b_algo"><h2><a href="/search?q=related">Related</a></h2></li>
+<li class="b_algo"><h2><a href="https://go.microsoft.com/fwlink">MS</a></h2></li>
+<li class="b_algo"><h2><a href="https://www.bing.com/ck/a?u=a1%%%">Broken</a></h2></li>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := b.ParseResponse(tt.html)
+			if !reflect.DeepEqual(result.URLs, tt.wantURLs) {
+				t.Errorf("URLs = %v, want %v", result.URLs, tt.wantURLs)
+			}
+			if result.HasNextPage != tt.wantNext {
+				t.Errorf("HasNextPage = %v, want %v", result.HasNextPage, tt.wantNext)
+			}
+			if result.TotalResults != tt.wantTotal {
+				t.Errorf("TotalResults = %d, want %d", result.TotalResults, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestBingBuildURL(t *testing.T) {
+	b := NewBing(BingConfig{ResultsPerPage: 10, Market: "en-US"})
+
+	tests := []struct {
+		page      int
+		wantFirst string
+	}{
+		{0, "1"},
+		{1, "11"},
+		{2, "21"},
+	}
+
+	for _, tt := range tests {
+		raw := b.BuildURL("inurl:admin", tt.page)
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("BuildURL() = %q: %v", raw, err)
+		}
+		if u.Host != "www.bing.com" || u.Path != "/search" {
+			t.Errorf("BuildURL() = %q, want www.bing.com/search", raw)
+		}
+
+		q := u.Query()
+		if q.Get("q") != "inurl:admin" || q.Get("first") != tt.wantFirst || q.Get("mkt") != "en-US" {
+			t.Errorf("page %d: query = %v, want q=inurl:admin first=%s mkt=en-US", tt.page, q, tt.wantFirst)
+		}
+	}
+}
+
+func TestBingDetection(t *testing.T) {
+	b := NewBing(DefaultBingConfig())
+
+	tests := []struct {
+		name        string
+		html        string
+		wantCaptcha bool
+		wantBlocked bool
+	}{
+		{"results", bingPage, false, false},
+		{"challenge", `<html><body><div id="b_captcha"><h1>One last step</h1>`, true, false},
+		{"turing", `<iframe src="/turing/captcha/challenge?q=">`, true, false},
+		{"blocked", `<html><body>Your request has been blocked.</body></html>`, false, true},
+		{"rate limited", `<h1>Too Many Requests</h1>`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.IsCaptcha(tt.html); got != tt.wantCaptcha {
+				t.Errorf("IsCaptcha() = %v, want %v", got, tt.wantCaptcha)
+			}
+			if got := b.IsBlocked(tt.html); got != tt.wantBlocked {
+				t.Errorf("IsBlocked() = %v, want %v", got, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestCleanBingURL(t *testing.T) {
+	target := "https://tracked.example.com/path?a=1&b=2"
+	tracked := "https://www.bing.com/ck/a?!&&p=abc&u=a1" + base64.RawURLEncoding.EncodeToString([]byte(target)) + "&ntb=1"
+
+	if got := cleanBingURL(tracked); got != target {
+		t.Errorf("cleanBingURL(tracked) = %q, want %q", got, target)
+	}
+	if got := cleanBingURL("https://example.com/a?x=1&amp;y=2"); got != "https://example.com/a?x=1&y=2" {
+		t.Errorf("cleanBingURL(entities) = %q", got)
+	}
+}
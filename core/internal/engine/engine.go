@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/google-dork-parser/core/internal/parser"
@@ -32,6 +33,14 @@ type Engine interface {
 	GetDomains() []string
 }
 
+// ProxyTransport is a custom transport that knows how to route through
+// a proxy itself (e.g. a uTLS transport dialing through SOCKS). Engines
+// call ForProxy once per client and use the returned RoundTripper.
+type ProxyTransport interface {
+	http.RoundTripper
+	ForProxy(p *proxy.Proxy) (http.RoundTripper, error)
+}
+
 // SearchRequest represents a search request
 type SearchRequest struct {
 	ID          string
@@ -42,6 +51,18 @@ type SearchRequest struct {
 	Headers     map[string]string
 	Timeout     time.Duration
 	RetryCount  int
+
+	// NoSyntheticCookies sends no fabricated consent cookies for this request
+	NoSyntheticCookies bool
+
+	// TimeRange limits results to those indexed within the past d, w, m
+	// or y; other values are ignored
+	TimeRange string
+
+	// Country and Language set Google's gl and hl as two-letter codes;
+	// empty or invalid codes keep the defaults
+	Country  string
+	Language string
 }
 
 // SearchResponse represents a search response
@@ -52,7 +73,7 @@ type SearchResponse struct {
 	URLs         []string
 	RawURLs      []string
 	HasNextPage  bool
-	TotalResults string
+	TotalResults int64
 	StatusCode   int
 	Blocked      bool
 	Captcha      bool
@@ -85,9 +106,17 @@ type EngineConfig struct {
 	Domains         []string
 	CustomHeaders   map[string]string
 	RateLimitPerMin int
+
+	// Recommended cooldowns, adopted by the timing manager unless the
+	// timing config overrides them
+	CaptchaCooldown time.Duration
+	BlockCooldown   time.Duration
 }
 
-// DefaultEngineConfigs returns default configurations for all engines
+// DefaultEngineConfigs returns default configurations for all engines.
+// Cooldowns follow how hard each engine bans: Google 2m CAPTCHA / 15m
+// block, Yandex 2m / 10m, Bing and Yahoo 1m / 5m, DuckDuckGo and Ask
+// 30s / 2m.
 func DefaultEngineConfigs() map[EngineType]EngineConfig {
 	return map[EngineType]EngineConfig{
 		EngineTypeGoogle: {
@@ -119,6 +148,8 @@ func DefaultEngineConfigs() map[EngineType]EngineConfig {
 				"www.google.com.sg",
 			},
 			RateLimitPerMin: 20,
+			CaptchaCooldown: 2 * time.Minute,
+			BlockCooldown:   15 * time.Minute,
 		},
 		EngineTypeBing: {
 			Type:           EngineTypeBing,
@@ -130,6 +161,8 @@ func DefaultEngineConfigs() map[EngineType]EngineConfig {
 				"www.bing.com",
 			},
 			RateLimitPerMin: 30,
+			CaptchaCooldown: 1 * time.Minute,
+			BlockCooldown:   5 * time.Minute,
 		},
 		EngineTypeYahoo: {
 			Type:           EngineTypeYahoo,
@@ -141,6 +174,8 @@ func DefaultEngineConfigs() map[EngineType]EngineConfig {
 				"search.yahoo.com",
 			},
 			RateLimitPerMin: 30,
+			CaptchaCooldown: 1 * time.Minute,
+			BlockCooldown:   5 * time.Minute,
 		},
 		EngineTypeDuckDuckGo: {
 			Type:           EngineTypeDuckDuckGo,
@@ -153,6 +188,8 @@ func DefaultEngineConfigs() map[EngineType]EngineConfig {
 				"html.duckduckgo.com",
 			},
 			RateLimitPerMin: 20,
+			CaptchaCooldown: 30 * time.Second,
+			BlockCooldown:   2 * time.Minute,
 		},
 		EngineTypeYandex: {
 			Type:           EngineTypeYandex,
@@ -165,6 +202,8 @@ func DefaultEngineConfigs() map[EngineType]EngineConfig {
 				"yandex.ru",
 			},
 			RateLimitPerMin: 20,
+			CaptchaCooldown: 2 * time.Minute,
+			BlockCooldown:   10 * time.Minute,
 		},
 		EngineTypeAsk: {
 			Type:           EngineTypeAsk,
@@ -176,6 +215,8 @@ func DefaultEngineConfigs() map[EngineType]EngineConfig {
 				"www.ask.com",
 			},
 			RateLimitPerMin: 30,
+			CaptchaCooldown: 30 * time.Second,
+			BlockCooldown:   2 * time.Minute,
 		},
 	}
 }
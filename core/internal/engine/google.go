@@ -2,14 +2,13 @@ package engine
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"io"
 	"math/rand"
-	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google-dork-parser/core/internal/parser"
@@ -23,15 +22,55 @@ type Google struct {
 	headerGen    *stealth.HeaderGenerator
 	domains      []string
 	resultsPerPage int
+	maxBodyBytes int64
 	httpClient   *http.Client
+	transport    http.RoundTripper
+
+	noSyntheticCookies bool
+	jars               sync.Map       // Proxy ID -> http.CookieJar when cookies are real
+	transports         transportCache // Built per proxy from transport
+
+	// Domain rotation: the domain picked last and, when sticky, the
+	// domain each proxy is pinned to
+	stickyDomain bool
+	domainMu     sync.Mutex
+	lastDomain   string
+	pinned       map[string]string // Proxy ID ("" = direct) -> domain
 }
 
 // GoogleConfig holds Google engine configuration
 type GoogleConfig struct {
+	// Domains are rotated at random, never using one twice in a row while
+	// there is another
 	Domains        []string
 	ResultsPerPage int
 	Timeout        time.Duration
 	UserAgents     []string
+
+	// MaxBodyBytes fails responses larger than this (0 =
+	// DefaultMaxBodyBytes)
+	MaxBodyBytes int64
+
+	// StickyDomain pins each proxy to the domain it was given for its
+	// first search, so its session stays on one Google domain
+	StickyDomain bool
+
+	// AcceptLanguages overrides the Accept-Language used per exit country
+	// (ISO code, e.g. "DE" -> "de-DE,de;q=0.9")
+	AcceptLanguages map[string]string
+
+	// NoSyntheticCookies stops sending fabricated CONSENT/SOCS cookies,
+	// which trigger the consent wall in some locales. Cookies Google sets
+	// are kept per proxy instead.
+	NoSyntheticCookies bool
+
+	// Transport replaces the built-in transport when set. The proxy is
+	// applied on top depending on its type:
+	//   - *http.Transport is cloned and gets the proxy like the default
+	//   - ProxyTransport is asked for a proxied transport via ForProxy
+	//   - any other RoundTripper is used as-is and cannot be combined
+	//     with a proxy; such requests fail rather than go out directly
+	Transport http.RoundTripper
 }
 
 // DefaultGoogleConfig returns default Google configuration
@@ -65,18 +104,53 @@ func NewGoogle(config GoogleConfig) *Google {
 	if config.ResultsPerPage == 0 {
 		config.ResultsPerPage = 10
 	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
 	if len(config.UserAgents) == 0 {
 		config.UserAgents = stealth.DefaultUserAgents()
 	}
 
+	headerGen := stealth.NewHeaderGenerator(config.UserAgents)
+	headerGen.SetAcceptLanguages(config.AcceptLanguages)
+
 	return &Google{
 		BaseEngine:     NewBaseEngine("google", config.Domains),
-		headerGen:      stealth.NewHeaderGenerator(config.UserAgents),
+		headerGen:      headerGen,
 		domains:        config.Domains,
 		resultsPerPage: config.ResultsPerPage,
+		maxBodyBytes:   config.MaxBodyBytes,
+		transport:      config.Transport,
+
+		noSyntheticCookies: config.NoSyntheticCookies,
+		stickyDomain:       config.StickyDomain,
 	}
 }
 
+// countryDomains maps an exit country to its local Google domain
+var countryDomains = map[string]string{
+	"US": "www.google.com",
+	"GB": "www.google.co.uk",
+	"CA": "www.google.ca",
+	"AU": "www.google.com.au",
+	"DE": "www.google.de",
+	"FR": "www.google.fr",
+	"ES": "www.google.es",
+	"IT": "www.google.it",
+	"NL": "www.google.nl",
+	"BE": "www.google.be",
+	"CH": "www.google.ch",
+	"AT": "www.google.at",
+	"PL": "www.google.pl",
+	"RU": "www.google.ru",
+	"JP": "www.google.co.jp",
+	"KR": "www.google.co.kr",
+	"BR": "www.google.com.br",
+	"MX": "www.google.com.mx",
+	"IN": "www.google.co.in",
+	"SG": "www.google.com.sg",
+}
+
 // Search performs a Google search
 func (g *Google) Search(ctx context.Context, request *SearchRequest) (*SearchResponse, error) {
 	start := time.Now()
@@ -88,11 +162,15 @@ func (g *Google) Search(ctx context.Context, request *SearchRequest) (*SearchRes
 		EngineUsed: "google",
 	}
 
-	// Select a random Google domain
-	domain := g.selectDomain()
+	// Select the exit country's Google domain, or a random one
+	var country, session string
+	if request.Proxy != nil {
+		country, session = request.Proxy.Country, request.Proxy.ID
+	}
+	domain := g.selectDomainForCountry(country, session)
 
 	// Build search URL
-	searchURL := g.buildSearchURL(domain, request.Dork, request.Page)
+	searchURL := g.buildSearchURL(domain, request)
 
 	// Create HTTP client with proxy
 	client, err := g.createClient(request.Proxy, request.Timeout)
@@ -100,6 +178,9 @@ func (g *Google) Search(ctx context.Context, request *SearchRequest) (*SearchRes
 		response.Error = NewSearchError(ErrorTypeProxy, "failed to create client", err)
 		return response, err
 	}
+	if !g.syntheticCookies(request) {
+		client.Jar = g.cookieJar(request.Proxy)
+	}
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
@@ -109,7 +190,7 @@ func (g *Google) Search(ctx context.Context, request *SearchRequest) (*SearchRes
 	}
 
 	// Set headers
-	g.setHeaders(req, domain, request)
+	g.setHeaders(req, domain, country, request)
 
 	// Execute request
 	resp, err := client.Do(req)
@@ -148,8 +229,8 @@ func (g *Google) Search(ctx context.Context, request *SearchRequest) (*SearchRes
 		return response, response.Error
 	}
 
-	// Read body
-	body, err := io.ReadAll(resp.Body)
+	// Read body, capped so an endless response cannot exhaust memory
+	body, err := readBody(resp, g.maxBodyBytes)
 	if err != nil {
 		response.Error = NewSearchError(ErrorTypeNetwork, "failed to read response", err)
 		return response, err
@@ -184,22 +265,39 @@ func (g *Google) Search(ctx context.Context, request *SearchRequest) (*SearchRes
 
 // BuildURL builds a Google search URL
 func (g *Google) BuildURL(query string, page int) string {
-	domain := g.selectDomain()
-	return g.buildSearchURL(domain, query, page)
+	domain := g.selectDomain("")
+	return g.buildSearchURL(domain, &SearchRequest{Dork: query, Page: page})
+}
+
+// timeRanges maps the accepted SearchRequest.TimeRange values to Google's
+// qdr values
+var timeRanges = map[string]string{
+	"d": "d",
+	"w": "w",
+	"m": "m",
+	"y": "y",
 }
 
-func (g *Google) buildSearchURL(domain, query string, page int) string {
+func (g *Google) buildSearchURL(domain string, request *SearchRequest) string {
+	query := request.Dork
+
 	// URL encode the query
 	encodedQuery := url.QueryEscape(query)
 
 	// Calculate start position
-	start := page * g.resultsPerPage
+	start := request.Page * g.resultsPerPage
 
 	// Build URL with parameters
 	params := url.Values{}
 	params.Set("q", query)
 	params.Set("num", fmt.Sprintf("%d", g.resultsPerPage))
 	params.Set("hl", "en")
+	if isCode(request.Language) {
+		params.Set("hl", strings.ToLower(request.Language))
+	}
+	if isCode(request.Country) {
+		params.Set("gl", strings.ToLower(request.Country))
+	}
 	params.Set("safe", "off")
 	params.Set("filter", "0") // Don't filter similar results
 
@@ -207,6 +305,11 @@ func (g *Google) buildSearchURL(domain, query string, page int) string {
 		params.Set("start", fmt.Sprintf("%d", start))
 	}
 
+	// Restrict to recently indexed pages; unknown ranges are dropped
+	if qdr, ok := timeRanges[request.TimeRange]; ok {
+		params.Set("tbs", "qdr:"+qdr)
+	}
+
 	// Randomly add some optional parameters to look more human
 	if rand.Float32() < 0.5 {
 		params.Set("pws", "0") // Disable personalized search
@@ -223,39 +326,123 @@ func (g *Google) buildSearchURL(domain, query string, page int) string {
 	_ = encodedQuery // Silence unused warning
 }
 
-func (g *Google) selectDomain() string {
+// isCode reports whether s is a two-letter country or language code
+func isCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// selectDomain picks a random domain other than the one picked last, so
+// consecutive searches do not repeat a domain. When sticky, the proxy
+// session keeps the domain picked for its first search.
+func (g *Google) selectDomain(session string) string {
+	g.domainMu.Lock()
+	defer g.domainMu.Unlock()
+
 	if len(g.domains) == 0 {
 		return "www.google.com"
 	}
-	return g.domains[rand.Intn(len(g.domains))]
+	if domain, ok := g.pinned[session]; ok && g.stickyDomain {
+		return domain
+	}
+
+	candidates := make([]string, 0, len(g.domains))
+	for _, domain := range g.domains {
+		if domain != g.lastDomain {
+			candidates = append(candidates, domain)
+		}
+	}
+	if len(candidates) == 0 {
+		// Every entry is the last domain
+		candidates = g.domains
+	}
+	g.lastDomain = candidates[rand.Intn(len(candidates))]
+
+	if g.stickyDomain {
+		if g.pinned == nil {
+			g.pinned = make(map[string]string)
+		}
+		g.pinned[session] = g.lastDomain
+	}
+	return g.lastDomain
+}
+
+// selectDomainForCountry prefers the configured Google domain local to the
+// exit country so the domain and Accept-Language tell the same story
+func (g *Google) selectDomainForCountry(country, session string) string {
+	if local, ok := countryDomains[strings.ToUpper(country)]; ok && g.hasDomain(local) {
+		return local
+	}
+	return g.selectDomain(session)
+}
+
+// hasDomain reports whether domain is among the domains in use
+func (g *Google) hasDomain(domain string) bool {
+	g.domainMu.Lock()
+	defer g.domainMu.Unlock()
+
+	for _, d := range g.domains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
 }
 
-func (g *Google) setHeaders(req *http.Request, domain string, sr *SearchRequest) {
+func (g *Google) setHeaders(req *http.Request, domain, country string, sr *SearchRequest) {
 	// Generate stealth headers
 	headers := g.headerGen.GenerateForSearch(domain, sr.Page > 0)
+	g.headerGen.ApplyCountry(headers, country)
+
+	// Override with custom user agent if provided, keeping the
+	// client hints consistent with it
+	if sr.UserAgent != "" {
+		headers.SetUserAgent(sr.UserAgent)
+	}
 
 	// Apply generated headers
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
-	// Override with custom user agent if provided
-	if sr.UserAgent != "" {
-		req.Header.Set("User-Agent", sr.UserAgent)
-	}
-
 	// Apply any custom headers from request
 	for key, value := range sr.Headers {
 		req.Header.Set(key, value)
 	}
 
-	// Ensure critical headers are set
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", stealth.RandomUserAgent())
+	// Add cookies to look more legitimate
+	if g.syntheticCookies(sr) {
+		req.Header.Set("Cookie", g.generateCookies())
 	}
+}
 
-	// Add cookies to look more legitimate
-	req.Header.Set("Cookie", g.generateCookies())
+// syntheticCookies reports whether fabricated cookies are sent for a request
+func (g *Google) syntheticCookies(sr *SearchRequest) bool {
+	return !g.noSyntheticCookies && !sr.NoSyntheticCookies
+}
+
+// cookieJar returns the jar collecting real cookies for a proxy, so
+// consent state builds up per exit IP
+func (g *Google) cookieJar(p *proxy.Proxy) http.CookieJar {
+	key := "direct"
+	if p != nil {
+		key = p.ID
+	}
+
+	if jar, ok := g.jars.Load(key); ok {
+		return jar.(http.CookieJar)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	actual, _ := g.jars.LoadOrStore(key, jar)
+	return actual.(http.CookieJar)
 }
 
 func (g *Google) generateCookies() string {
@@ -277,104 +464,33 @@ func (g *Google) generateCookies() string {
 }
 
 func (g *Google) createClient(p *proxy.Proxy, timeout time.Duration) (*http.Client, error) {
-	if timeout == 0 {
-		timeout = 30 * time.Second
-	}
-
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   timeout,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: false,
-			MinVersion:         tls.VersionTLS12,
-		},
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		DisableCompression:    false,
-	}
-
-	// Configure proxy if provided
-	if p != nil {
-		proxyURL, err := url.Parse(p.URL())
-		if err != nil {
-			return nil, fmt.Errorf("invalid proxy URL: %w", err)
-		}
-
-		switch p.Protocol {
-		case proxy.ProtocolHTTP, proxy.ProtocolHTTPS:
-			transport.Proxy = http.ProxyURL(proxyURL)
-
-		case proxy.ProtocolSOCKS4, proxy.ProtocolSOCKS5:
-			// For SOCKS, we need to use a custom dialer
-			dialer, err := g.createSOCKSDialer(p, timeout)
-			if err != nil {
-				return nil, err
-			}
-			transport.DialContext = dialer
-
-		default:
-			return nil, fmt.Errorf("unsupported proxy protocol: %s", p.Protocol)
-		}
-	}
-
-	return &http.Client{
-		Transport: transport,
-		Timeout:   timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Allow up to 5 redirects
-			if len(via) >= 5 {
-				return fmt.Errorf("too many redirects")
-			}
-			// Copy headers to redirect request
-			for key, values := range via[0].Header {
-				for _, value := range values {
-					req.Header.Add(key, value)
-				}
-			}
-			return nil
-		},
-	}, nil
+	return newClient(&g.transports, g.transport, p, timeout)
 }
 
-func (g *Google) createSOCKSDialer(p *proxy.Proxy, timeout time.Duration) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
-	proxyAddr := fmt.Sprintf("%s:%s", p.Host, p.Port)
-
-	return func(ctx context.Context, network, addr string) (net.Conn, error) {
-		// Create a basic TCP connection to the SOCKS proxy
-		dialer := &net.Dialer{
-			Timeout:   timeout,
-			KeepAlive: 30 * time.Second,
-		}
-
-		conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
-		if err != nil {
-			return nil, err
-		}
-
-		// For full SOCKS5 support, you'd implement the handshake here
-		// For now, we'll rely on the proxy package in health.go
-		// This is a simplified version
-
-		return conn, nil
-	}, nil
+// CloseIdleConnections closes the idle connections kept for each proxy
+func (g *Google) CloseIdleConnections() {
+	g.transports.closeAll()
 }
 
 // GetDomains returns Google domains
 func (g *Google) GetDomains() []string {
-	return g.domains
+	g.domainMu.Lock()
+	defer g.domainMu.Unlock()
+	return append([]string(nil), g.domains...)
 }
 
-// SetDomains sets the Google domains to use
+// SetDomains sets the Google domains to use, unpinning sticky proxies
 func (g *Google) SetDomains(domains []string) {
+	g.domainMu.Lock()
+	defer g.domainMu.Unlock()
 	g.domains = domains
+	g.pinned = nil
 }
 
 // AddDomain adds a Google domain
 func (g *Google) AddDomain(domain string) {
+	g.domainMu.Lock()
+	defer g.domainMu.Unlock()
 	g.domains = append(g.domains, domain)
 }
 
@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google-dork-parser/core/internal/proxy"
+)
+
+func TestGoogleSyntheticCookies(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     bool
+		request    bool
+		wantCookie bool
+	}{
+		{"default", false, false, true},
+		{"disabled by config", true, false, false},
+		{"disabled by request", false, true, false},
+	}
+
+	for _, tt := range tests {
+		g := NewGoogle(GoogleConfig{NoSyntheticCookies: tt.config})
+		req, _ := http.NewRequest("GET", "https://www.google.com/search?q=test", nil)
+
+		g.setHeaders(req, "www.google.com", "", &SearchRequest{Dork: "test", NoSyntheticCookies: tt.request})
+
+		if got := req.Header.Get("Cookie") != ""; got != tt.wantCookie {
+			t.Errorf("%s: Cookie set = %v, want %v", tt.name, got, tt.wantCookie)
+		}
+	}
+}
+
+func TestGoogleTimeRange(t *testing.T) {
+	g := NewGoogle(GoogleConfig{})
+
+	tests := []struct {
+		timeRange string
+		want      string
+	}{
+		{"d", "tbs=qdr%3Ad"},
+		{"y", "tbs=qdr%3Ay"},
+		{"", ""},
+		{"decade", ""},
+	}
+
+	for _, tt := range tests {
+		got := g.buildSearchURL("www.google.com", &SearchRequest{Dork: "test", TimeRange: tt.timeRange})
+		if tt.want == "" {
+			if strings.Contains(got, "tbs=") {
+				t.Errorf("time range %q: URL = %s, want no tbs", tt.timeRange, got)
+			}
+		} else if !strings.Contains(got, tt.want) {
+			t.Errorf("time range %q: URL = %s, want %s", tt.timeRange, got, tt.want)
+		}
+	}
+}
+
+func TestGoogleCountryLanguage(t *testing.T) {
+	g := NewGoogle(GoogleConfig{})
+
+	got := g.buildSearchURL("www.google.com", &SearchRequest{Dork: "test"})
+	if !strings.Contains(got, "hl=en") || strings.Contains(got, "gl=") {
+		t.Errorf("default URL = %s, want hl=en and no gl", got)
+	}
+
+	got = g.buildSearchURL("www.google.com", &SearchRequest{Dork: "test", Country: "BR", Language: "pt"})
+	if !strings.Contains(got, "hl=pt") || !strings.Contains(got, "gl=br") {
+		t.Errorf("URL = %s, want hl=pt and gl=br", got)
+	}
+
+	got = g.buildSearchURL("www.google.com", &SearchRequest{Dork: "test", Country: "bra", Language: "p"})
+	if !strings.Contains(got, "hl=en") || strings.Contains(got, "gl=") {
+		t.Errorf("invalid codes URL = %s, want the defaults", got)
+	}
+}
+
+func TestGoogleCookieJarPerProxy(t *testing.T) {
+	g := NewGoogle(GoogleConfig{NoSyntheticCookies: true})
+
+	a := g.cookieJar(&proxy.Proxy{ID: "a"})
+	if g.cookieJar(&proxy.Proxy{ID: "a"}) != a {
+		t.Error("cookieJar() returned a new jar for the same proxy")
+	}
+	if g.cookieJar(&proxy.Proxy{ID: "b"}) == a {
+		t.Error("cookieJar() shared a jar between proxies")
+	}
+	if g.cookieJar(nil) == nil {
+		t.Error("cookieJar(nil) = nil, want a direct jar")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+type proxiedTransport struct {
+	roundTripFunc
+	proxied *proxy.Proxy
+}
+
+func (t *proxiedTransport) ForProxy(p *proxy.Proxy) (http.RoundTripper, error) {
+	t.proxied = p
+	return t, nil
+}
+
+func TestGoogleCustomTransport(t *testing.T) {
+	prx := &proxy.Proxy{ID: "p", Protocol: proxy.ProtocolHTTP, Host: "10.0.0.1", Port: "8080"}
+
+	// *http.Transport is cloned and gets the proxy on top
+	base := &http.Transport{MaxIdleConnsPerHost: 7}
+	g := NewGoogle(GoogleConfig{Transport: base})
+	client, err := g.createClient(prx, 0)
+	if err != nil {
+		t.Fatalf("createClient() error = %v", err)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok || transport == base || transport.MaxIdleConnsPerHost != 7 || transport.Proxy == nil {
+		t.Errorf("transport = %+v, want a proxied clone of the custom transport", client.Transport)
+	}
+	if base.Proxy != nil {
+		t.Error("custom transport was modified")
+	}
+
+	// ProxyTransport applies the proxy itself
+	pt := &proxiedTransport{}
+	g = NewGoogle(GoogleConfig{Transport: pt})
+	if _, err := g.createClient(prx, 0); err != nil || pt.proxied != prx {
+		t.Errorf("ForProxy got %v (err %v), want %v", pt.proxied, err, prx)
+	}
+
+	// Any other RoundTripper cannot be proxied
+	rt := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, nil })
+	g = NewGoogle(GoogleConfig{Transport: rt})
+	if _, err := g.createClient(prx, 0); err == nil {
+		t.Error("createClient() with a plain RoundTripper and a proxy should fail")
+	}
+	if _, err := g.createClient(nil, 0); err != nil {
+		t.Errorf("createClient() without a proxy error = %v", err)
+	}
+}
+
+func TestGoogleTransportPerProxy(t *testing.T) {
+	g := NewGoogle(GoogleConfig{})
+	first := &proxy.Proxy{ID: "a", Protocol: proxy.ProtocolHTTP, Host: "10.0.0.1", Port: "8080"}
+	second := &proxy.Proxy{ID: "b", Protocol: proxy.ProtocolHTTP, Host: "10.0.0.2", Port: "8080"}
+
+	transportOf := func(p *proxy.Proxy) http.RoundTripper {
+		t.Helper()
+		client, err := g.createClient(p, 0)
+		if err != nil {
+			t.Fatalf("createClient() error = %v", err)
+		}
+		return client.Transport
+	}
+
+	a := transportOf(first)
+	if transportOf(first) != a {
+		t.Error("second request through a proxy built a new transport")
+	}
+	if transportOf(second) == a {
+		t.Error("two proxies share a transport")
+	}
+	if transportOf(nil) == a || transportOf(nil) != transportOf(nil) {
+		t.Error("direct requests do not have a transport of their own")
+	}
+
+	// A proxy whose address changed gets a new transport
+	first.Port = "8081"
+	if transportOf(first) == a {
+		t.Error("transport not rebuilt after the proxy's address changed")
+	}
+}
+
+func TestGoogleMaxBodyBytes(t *testing.T) {
+	page := "<html><body>" + strings.Repeat("x", 2048) + "</body></html>"
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(strings.NewReader(page)),
+			ContentLength: -1, // Streamed, so only reading can tell
+			Request:       req,
+		}, nil
+	})
+
+	g := NewGoogle(GoogleConfig{Transport: rt, MaxBodyBytes: 1024})
+	response, err := g.Search(context.Background(), &SearchRequest{Dork: "test"})
+	if !errors.Is(err, errBodyTooLarge) {
+		t.Fatalf("Search() error = %v, want %v", err, errBodyTooLarge)
+	}
+	if response.HTML != "" {
+		t.Error("oversized body was kept")
+	}
+
+	g = NewGoogle(GoogleConfig{Transport: rt, MaxBodyBytes: int64(len(page))})
+	if response, err := g.Search(context.Background(), &SearchRequest{Dork: "test"}); err != nil || response.HTML != page {
+		t.Errorf("Search() at the limit error = %v, want the page", err)
+	}
+}
+
+func TestGoogleDomainRotation(t *testing.T) {
+	hostOf := func(rawURL string) string {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) error = %v", rawURL, err)
+		}
+		return u.Host
+	}
+
+	for _, domains := range [][]string{
+		{"www.google.de", "www.google.fr"},
+		DefaultGoogleConfig().Domains,
+		{"www.google.de", "www.google.de", "www.google.fr"},
+	} {
+		g := NewGoogle(GoogleConfig{Domains: domains})
+		prev := ""
+		for i := 0; i < 200; i++ {
+			got := hostOf(g.BuildURL("test", 0))
+			if got == prev {
+				t.Fatalf("%v: search %d reused domain %q consecutively", domains, i, got)
+			}
+			prev = got
+		}
+	}
+
+	// A single domain is reused rather than failing
+	g := NewGoogle(GoogleConfig{Domains: []string{"www.google.ca"}})
+	for i := 0; i < 3; i++ {
+		if got := hostOf(g.BuildURL("test", 0)); got != "www.google.ca" {
+			t.Errorf("search %d domain = %q, want www.google.ca", i, got)
+		}
+	}
+
+	// Sticky pins each proxy to its first domain, not the whole engine
+	g = NewGoogle(GoogleConfig{StickyDomain: true})
+	pinned := make(map[string]string)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		pinned[id] = g.selectDomainForCountry("", id)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		for id, want := range pinned {
+			if got := g.selectDomainForCountry("", id); got != want {
+				t.Fatalf("proxy %s search %d domain = %q, want %q", id, i, got, want)
+			}
+			seen[want] = true
+		}
+	}
+	if len(seen) < 2 {
+		t.Errorf("every proxy pinned to %v", seen)
+	}
+}
+
+func TestGoogleDomainsConcurrent(t *testing.T) {
+	g := NewGoogle(GoogleConfig{StickyDomain: true})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			g.SetDomains([]string{"www.google.de", "www.google.fr"})
+			g.AddDomain("www.google.co.jp")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			g.selectDomainForCountry("JP", "a")
+			g.GetDomains()
+		}
+	}()
+	wg.Wait()
+
+	if got := g.selectDomainForCountry("JP", "a"); got != "www.google.co.jp" {
+		t.Errorf("selectDomainForCountry(JP) = %q, want www.google.co.jp", got)
+	}
+}
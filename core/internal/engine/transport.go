@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	netproxy "golang.org/x/net/proxy"
+
+	"github.com/google-dork-parser/core/internal/proxy"
+)
+
+// newClient creates an HTTP client routed through p, built on the custom
+// transport when one is set and reusing the transport cached for p
+func newClient(transports *transportCache, custom http.RoundTripper, p *proxy.Proxy, timeout time.Duration) (*http.Client, error) {
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	transport, err := transports.get(custom, p, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Allow up to 5 redirects
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			// Copy headers to redirect request
+			for key, values := range via[0].Header {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+			return nil
+		},
+	}, nil
+}
+
+// DefaultMaxBodyBytes caps a response body when the engine's config sets
+// no MaxBodyBytes
+const DefaultMaxBodyBytes int64 = 5 << 20
+
+// errBodyTooLarge fails a response past the engine's MaxBodyBytes
+var errBodyTooLarge = errors.New("response body too large")
+
+// readBody reads a response body, failing with errBodyTooLarge once it
+// passes limit bytes, without reading the rest, so an endless response
+// cannot exhaust memory
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", errBodyTooLarge, resp.ContentLength, limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: over %d bytes", errBodyTooLarge, limit)
+	}
+	return body, nil
+}
+
+// transportCache keeps the transport built for each proxy, so searches
+// through a proxy reuse its idle connections instead of dialing every
+// time. A transport is rebuilt when the proxy's address or the timeout
+// changes. The zero value is ready to use.
+type transportCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedTransport // Proxy ID ("direct" without one) -> transport
+}
+
+// cachedTransport is a transport and the proxy URL and timeout it was
+// built for
+type cachedTransport struct {
+	key       string
+	transport http.RoundTripper
+}
+
+// get returns the transport for requests through p, built by
+// roundTripper. Transports from a ProxyTransport or a plain custom
+// RoundTripper are not built here, so they are not cached.
+func (c *transportCache) get(custom http.RoundTripper, p *proxy.Proxy, timeout time.Duration) (http.RoundTripper, error) {
+	switch custom.(type) {
+	case nil, *http.Transport:
+	default:
+		return roundTripper(custom, p, timeout)
+	}
+
+	id, key := "direct", timeout.String()
+	if p != nil {
+		id, key = p.ID, p.URL()+"|"+key
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if ok && entry.key == key {
+		return entry.transport, nil
+	}
+
+	transport, err := roundTripper(custom, p, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		closeIdle(entry.transport)
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]cachedTransport)
+	}
+	c.entries[id] = cachedTransport{key: key, transport: transport}
+	return transport, nil
+}
+
+// closeAll closes the idle connections of every cached transport and
+// empties the cache
+func (c *transportCache) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.entries {
+		closeIdle(entry.transport)
+		delete(c.entries, id)
+	}
+}
+
+// closeIdle closes a transport's idle connections if it keeps any
+func closeIdle(rt http.RoundTripper) {
+	if t, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// roundTripper builds the transport for a client, applying the proxy to
+// either the custom transport or the built-in one
+func roundTripper(rt http.RoundTripper, p *proxy.Proxy, timeout time.Duration) (http.RoundTripper, error) {
+	switch custom := rt.(type) {
+	case nil:
+		transport := &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   timeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: false,
+				MinVersion:         tls.VersionTLS12,
+			},
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			DisableCompression:    false,
+		}
+		return transport, applyProxy(transport, p, timeout)
+
+	case *http.Transport:
+		transport := custom.Clone()
+		return transport, applyProxy(transport, p, timeout)
+
+	case ProxyTransport:
+		if p == nil {
+			return custom, nil
+		}
+		return custom.ForProxy(p)
+
+	default:
+		if p != nil {
+			return nil, fmt.Errorf("custom transport %T cannot apply proxy %s", custom, p.ID)
+		}
+		return custom, nil
+	}
+}
+
+// applyProxy routes an *http.Transport through a proxy
+func applyProxy(transport *http.Transport, p *proxy.Proxy, timeout time.Duration) error {
+	if p == nil {
+		return nil
+	}
+
+	proxyURL, err := url.Parse(p.URL())
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch p.Protocol {
+	case proxy.ProtocolHTTP, proxy.ProtocolHTTPS:
+		transport.Proxy = http.ProxyURL(proxyURL)
+
+	case proxy.ProtocolSOCKS4, proxy.ProtocolSOCKS5:
+		// For SOCKS, we need to use a custom dialer
+		dialer, err := createSOCKSDialer(p, timeout)
+		if err != nil {
+			return err
+		}
+		transport.DialContext = dialer
+
+	default:
+		return fmt.Errorf("unsupported proxy protocol: %s", p.Protocol)
+	}
+
+	return nil
+}
+
+// createSOCKSDialer returns a dial func that performs the SOCKS5
+// handshake with p, authenticating when it has credentials
+func createSOCKSDialer(p *proxy.Proxy, timeout time.Duration) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if p.Protocol != proxy.ProtocolSOCKS5 {
+		return nil, fmt.Errorf("unsupported SOCKS protocol: %s", p.Protocol)
+	}
+
+	var auth *netproxy.Auth
+	if p.Username != "" {
+		auth = &netproxy.Auth{User: p.Username, Password: p.Password}
+	}
+
+	forward := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	dialer, err := netproxy.SOCKS5("tcp", net.JoinHostPort(p.Host, p.Port), auth, forward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
+	}
+
+	contextDialer, ok := dialer.(netproxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer does not support contexts")
+	}
+	return contextDialer.DialContext, nil
+}
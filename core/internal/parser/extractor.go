@@ -2,6 +2,7 @@ package parser
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -15,7 +16,7 @@ type ExtractionResult struct {
 	URLs        []string // Cleaned URLs
 	RawURLs     []string // Original URLs before cleaning
 	HasNextPage bool     // Whether there's a next page
-	TotalResults string  // Estimated total results (if found)
+	TotalResults int64   // Estimated total results (0 if not found)
 }
 
 // NewExtractor creates a new URL extractor
@@ -28,6 +29,13 @@ func NewExtractor(cleaner *URLCleaner) *Extractor {
 	}
 }
 
+// resultCountNumber matches a count with optional thousands grouping
+const resultCountNumber = `\d{1,3}(?:[.,\x{00A0}\x{202F} ]\d{3})+|\d+`
+
+// resultCountSpace matches the space around a count, which some locales
+// render as a non-breaking space
+const resultCountSpace = `[ \x{00A0}\x{202F}]`
+
 // Google search result patterns
 var (
 	// Main result link patterns
@@ -67,8 +75,32 @@ var (
 		regexp.MustCompile(`aria-label="Page \d+"`),
 	}
 
-	// Total results pattern
-	totalResultsPattern = regexp.MustCompile(`About ([\d,]+) results`)
+	// Result count phrasings by language; the number may be grouped with
+	// commas (en), periods (de, es) or plain/non-breaking spaces (fr)
+	resultCountPatterns = map[string][]*regexp.Regexp{
+		"en": {
+			regexp.MustCompile(`About` + resultCountSpace + `(` + resultCountNumber + `)` + resultCountSpace + `results?`),
+			regexp.MustCompile(`(` + resultCountNumber + `)` + resultCountSpace + `results?`),
+		},
+		"de": {
+			regexp.MustCompile(`Ungefähr` + resultCountSpace + `(` + resultCountNumber + `)` + resultCountSpace + `Ergebnisse`),
+			regexp.MustCompile(`(` + resultCountNumber + `)` + resultCountSpace + `Ergebnis(?:se)?`),
+		},
+		"fr": {
+			regexp.MustCompile(`Environ` + resultCountSpace + `(` + resultCountNumber + `)` + resultCountSpace + `résultats?`),
+			regexp.MustCompile(`(` + resultCountNumber + `)` + resultCountSpace + `résultats?`),
+		},
+		"es": {
+			regexp.MustCompile(`(?:Aproximadamente|Cerca de)` + resultCountSpace + `(` + resultCountNumber + `)` + resultCountSpace + `resultados?`),
+			regexp.MustCompile(`(` + resultCountNumber + `)` + resultCountSpace + `resultados?`),
+		},
+	}
+
+	// Languages tried after the page's own, in order
+	resultCountLanguages = []string{"en", "de", "fr", "es"}
+
+	// Document language, used when no locale is given
+	htmlLangPattern = regexp.MustCompile(`<html[^>]+lang="([a-zA-Z]{2})`)
 
 	// Blocked/CAPTCHA detection patterns
 	captchaPatterns = []*regexp.Regexp{
@@ -136,9 +168,7 @@ func (e *Extractor) ExtractFromHTML(html string) *ExtractionResult {
 	}
 
 	// Extract total results if available
-	if matches := totalResultsPattern.FindStringSubmatch(html); len(matches) > 1 {
-		result.TotalResults = matches[1]
-	}
+	result.TotalResults = parseResultCount(html, "")
 
 	// Check for next page
 	for _, pattern := range nextPagePatterns {
@@ -377,6 +407,53 @@ func hexToByte(s string) byte {
 	return result
 }
 
+// parseResultCount extracts the "About N results" estimate. locale is a
+// language code such as "en" or "de-DE"; when empty the page's lang
+// attribute is used. Phrasings for the locale are tried first, then the
+// other known languages. Returns 0 when no count is found.
+func parseResultCount(html, locale string) int64 {
+	if locale == "" {
+		if matches := htmlLangPattern.FindStringSubmatch(html); len(matches) > 1 {
+			locale = matches[1]
+		}
+	}
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+
+	order := []string{lang}
+	for _, other := range resultCountLanguages {
+		if other != lang {
+			order = append(order, other)
+		}
+	}
+
+	for _, l := range order {
+		for _, pattern := range resultCountPatterns[l] {
+			matches := pattern.FindStringSubmatch(html)
+			if len(matches) < 2 {
+				continue
+			}
+
+			digits := strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return r
+				}
+				return -1
+			}, matches[1])
+
+			count, err := strconv.ParseInt(digits, 10, 64)
+			if err != nil {
+				return 0
+			}
+			return count
+		}
+	}
+
+	return 0
+}
+
 // ExtractWithParams extracts only URLs that have query parameters
 func (e *Extractor) ExtractWithParams(html string) *ExtractionResult {
 	fullResult := e.ExtractFromHTML(html)
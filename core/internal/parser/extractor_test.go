@@ -0,0 +1,61 @@
+package parser
+
+import "testing"
+
+func TestParseResultCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		html   string
+		locale string
+		want   int64
+	}{
+		{"US", `<div id="result-stats">About 12,300 results<nobr> (0.31 seconds)</nobr></div>`, "en", 12300},
+		{"US large", `<div id="result-stats">About 1,230,000 results</div>`, "en-US", 1230000},
+		{"US singular", `<div id="result-stats">1 result</div>`, "en", 1},
+		{"DE", `<div id="result-stats">Ungefähr 12.300 Ergebnisse (0,31 Sekunden)</div>`, "de", 12300},
+		{"DE large", `<div id="result-stats">Ungefähr 1.230.000 Ergebnisse</div>`, "de-DE", 1230000},
+		{"FR space", `<div id="result-stats">Environ 12 300 résultats (0,31 secondes)</div>`, "fr", 12300},
+		{"FR nbsp", "<div id=\"result-stats\">Environ 1\u00a0230\u00a0000 résultats</div>", "fr", 1230000},
+		{"FR narrow nbsp", "<div id=\"result-stats\">Environ 12\u202f300 résultats</div>", "fr-FR", 12300},
+		{"ES", `<div id="result-stats">Aproximadamente 12.300 resultados (0,31 segundos)</div>`, "es", 12300},
+		{"ES cerca", `<div id="result-stats">Cerca de 1.230.000 resultados</div>`, "es-419", 1230000},
+		{"ES singular", `<div id="result-stats">1 resultado</div>`, "es", 1},
+		{"locale from lang", `<html lang="de"><div>Ungefähr 4.560 Ergebnisse</div>`, "", 4560},
+		{"locale from lang ES", `<html lang="es"><div>Aproximadamente 7.890 resultados</div>`, "", 7890},
+		{"wrong locale falls back", `<div>About 789 results</div>`, "de", 789},
+		{"absent", `<div id="search">no stats here</div>`, "en", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseResultCount(tt.html, tt.locale); got != tt.want {
+				t.Errorf("parseResultCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// Result stats captured from Google result pages for several hl values
+func TestParseResultCountByHL(t *testing.T) {
+	tests := []struct {
+		hl   string
+		html string
+		want int64
+	}{
+		{"en", `<div id="result-stats">About 4,380,000,000 results<nobr> (0.42 seconds)&nbsp;</nobr></div>`, 4380000000},
+		{"en-GB", `<div id="result-stats">About 91,700 results<nobr> (0.28 seconds)&nbsp;</nobr></div>`, 91700},
+		{"de", `<div id="result-stats">Ungefähr 2.150.000 Ergebnisse<nobr> (0,37 Sekunden)&nbsp;</nobr></div>`, 2150000},
+		{"fr", "<div id=\"result-stats\">Environ 8\u00a0640\u00a0000\u00a0résultats<nobr> (0,51\u00a0secondes)&nbsp;</nobr></div>", 8640000},
+		{"fr-CA", `<div id="result-stats">Environ 5 210 résultats<nobr> (0,33 secondes)&nbsp;</nobr></div>`, 5210},
+		{"es", `<div id="result-stats">Aproximadamente 37.400 resultados<nobr> (0,29 segundos)&nbsp;</nobr></div>`, 37400},
+		{"es-419", `<div id="result-stats">Cerca de 612.000 resultados<nobr> (0,40 segundos)&nbsp;</nobr></div>`, 612000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hl, func(t *testing.T) {
+			if got := parseResultCount(tt.html, tt.hl); got != tt.want {
+				t.Errorf("parseResultCount(hl=%s) = %d, want %d", tt.hl, got, tt.want)
+			}
+		})
+	}
+}
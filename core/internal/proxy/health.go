@@ -3,10 +3,15 @@ package proxy
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,19 +22,56 @@ import (
 // HealthChecker checks proxy health
 type HealthChecker struct {
 	manager       *Manager
-	testURL       string
+	testURLs      []string
 	timeout       time.Duration
 	workers       int
 	slowThreshold time.Duration
+	probe         ProbeMode
+	resolveIP     bool
 	client        *http.Client
+
+	// Checks done per proxy ID, advancing its place in testURLs
+	rotation   map[string]int
+	rotationMu sync.Mutex
 }
 
+// ProbeMode selects how the health check reaches the test URL
+type ProbeMode string
+
+const (
+	// ProbeHTTPS tunnels TLS through the proxy (CONNECT for HTTP proxies)
+	ProbeHTTPS ProbeMode = "https"
+	// ProbeHTTP sends a plain GET through the proxy
+	ProbeHTTP ProbeMode = "http"
+	// ProbeBoth tries HTTPS first and falls back to HTTP; either passing
+	// keeps the proxy alive
+	ProbeBoth ProbeMode = "both"
+)
+
+// CheckMode selects which endpoints health checks fetch
+type CheckMode string
+
+const (
+	// CheckFull fetches TestURLs, or TestURL when the list is empty
+	CheckFull CheckMode = "full"
+	// CheckLightweight fetches a tiny endpoint instead of TestURL, so
+	// checks spend no search engine quota. TestURLs still take precedence.
+	CheckLightweight CheckMode = "lightweight"
+)
+
+// LightweightTestURL is the endpoint CheckLightweight fetches by default
+const LightweightTestURL = "http://httpbin.org/ip"
+
 // HealthCheckResult holds result of a health check
 type HealthCheckResult struct {
 	ProxyID  string
 	Status   Status
 	Latency  time.Duration
 	Error    error
+
+	// ExternalIP is the proxy's egress IP when ResolveIP is set and the
+	// endpoint reported it
+	ExternalIP string
 }
 
 // HealthCheckReport holds overall health check report
@@ -46,10 +88,27 @@ type HealthCheckReport struct {
 
 // HealthCheckerConfig holds health checker configuration
 type HealthCheckerConfig struct {
-	TestURL       string
+	TestURL string
+
+	// TestURLs are rotated per proxy, so a proxy's checks do not keep
+	// hitting the same host; a 2xx or 3xx from any of them is alive.
+	// Overrides TestURL when set.
+	TestURLs []string
+	Mode     CheckMode
+
 	Timeout       time.Duration
 	Workers       int
 	SlowThreshold time.Duration
+
+	// Probe selects HTTP, HTTPS or both; the TestURL scheme is switched
+	// to match. Defaults to HTTPS, since many HTTP proxies only allow
+	// CONNECT to 443 and reject plain GETs.
+	Probe ProbeMode
+
+	// ResolveIP reads the caller IP from a JSON {"origin": "..."} body,
+	// as returned by LightweightTestURL, and stores it on the proxy as
+	// ExternalIP. Bodies without it leave the IP empty.
+	ResolveIP bool
 }
 
 // DefaultHealthCheckerConfig returns default configuration
@@ -59,18 +118,65 @@ func DefaultHealthCheckerConfig() HealthCheckerConfig {
 		Timeout:       10 * time.Second,
 		Workers:       50,
 		SlowThreshold: 5 * time.Second,
+		Mode:          CheckFull,
+		Probe:         ProbeHTTPS,
+	}
+}
+
+// testURLs returns the URLs health checks rotate through
+func (c HealthCheckerConfig) testURLs() []string {
+	switch {
+	case len(c.TestURLs) > 0:
+		return c.TestURLs
+	case c.Mode == CheckLightweight:
+		return []string{LightweightTestURL}
+	case c.TestURL != "":
+		return []string{c.TestURL}
 	}
+	return nil
 }
 
-// NewHealthChecker creates a new health checker
-func NewHealthChecker(manager *Manager, config HealthCheckerConfig) *HealthChecker {
+// Validate checks that at least one usable test URL is configured
+func (c HealthCheckerConfig) Validate() error {
+	switch c.Mode {
+	case "", CheckFull, CheckLightweight:
+	default:
+		return fmt.Errorf("unknown health check mode: %s", c.Mode)
+	}
+
+	urls := c.testURLs()
+	if len(urls) == 0 {
+		return fmt.Errorf("no health check test URL configured")
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid health check test URL: %q", raw)
+		}
+	}
+	return nil
+}
+
+// NewHealthChecker creates a new health checker, failing when config
+// does not pass Validate
+func NewHealthChecker(manager *Manager, config HealthCheckerConfig) (*HealthChecker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Probe == "" {
+		config.Probe = ProbeHTTPS
+	}
+
 	return &HealthChecker{
 		manager:       manager,
-		testURL:       config.TestURL,
+		testURLs:      config.testURLs(),
 		timeout:       config.Timeout,
 		workers:       config.Workers,
 		slowThreshold: config.SlowThreshold,
-	}
+		probe:         config.Probe,
+		resolveIP:     config.ResolveIP,
+		rotation:      make(map[string]int),
+	}, nil
 }
 
 // CheckAll checks all proxies in the pool
@@ -158,6 +264,9 @@ func (hc *HealthChecker) checkProxies(ctx context.Context, proxies []*Proxy) *He
 		report.Results = append(report.Results, result)
 
 		// Update manager
+		if result.ExternalIP != "" {
+			hc.manager.SetExternalIP(result.ProxyID, result.ExternalIP)
+		}
 		switch result.Status {
 		case StatusAlive:
 			atomic.AddInt32(&alive, 1)
@@ -180,7 +289,72 @@ func (hc *HealthChecker) checkProxies(ctx context.Context, proxies []*Proxy) *He
 	return report
 }
 
+// checkProxy tries the test URLs in the proxy's rotation order until one
+// answers
 func (hc *HealthChecker) checkProxy(ctx context.Context, p *Proxy) *HealthCheckResult {
+	urls := hc.rotatedURLs(p.ID)
+	if len(urls) == 0 {
+		return &HealthCheckResult{
+			ProxyID: p.ID,
+			Status:  StatusDead,
+			Error:   fmt.Errorf("no health check test URL configured"),
+		}
+	}
+
+	var result *HealthCheckResult
+	for _, testURL := range urls {
+		result = hc.probeTarget(ctx, p, testURL)
+		if result.Status != StatusDead || ctx.Err() != nil {
+			break
+		}
+	}
+	return result
+}
+
+// rotatedURLs returns the test URLs starting one further along for every
+// check of the proxy. Proxies start at different points so a batch of
+// checks spreads over the list.
+func (hc *HealthChecker) rotatedURLs(proxyID string) []string {
+	n := len(hc.testURLs)
+	if n <= 1 {
+		return hc.testURLs
+	}
+
+	hc.rotationMu.Lock()
+	count := hc.rotation[proxyID]
+	hc.rotation[proxyID] = count + 1
+	hc.rotationMu.Unlock()
+
+	h := fnv.New32a()
+	h.Write([]byte(proxyID))
+	start := (int(h.Sum32()%uint32(n)) + count) % n
+
+	urls := make([]string, 0, n)
+	urls = append(urls, hc.testURLs[start:]...)
+	return append(urls, hc.testURLs[:start]...)
+}
+
+// probeTarget fetches testURL with the scheme(s) of the probe mode
+func (hc *HealthChecker) probeTarget(ctx context.Context, p *Proxy, testURL string) *HealthCheckResult {
+	switch hc.probe {
+	case ProbeHTTP:
+		return hc.probeURL(ctx, p, testURL, "http")
+	case ProbeBoth:
+		// A proxy that can tunnel TLS is alive even if plain HTTP is blocked
+		result := hc.probeURL(ctx, p, testURL, "https")
+		if result.Status == StatusDead {
+			if plain := hc.probeURL(ctx, p, testURL, "http"); plain.Status != StatusDead {
+				return plain
+			}
+		}
+		return result
+	default:
+		return hc.probeURL(ctx, p, testURL, "https")
+	}
+}
+
+// probeURL fetches rawURL through the proxy with the given scheme
+func (hc *HealthChecker) probeURL(ctx context.Context, p *Proxy, rawURL, scheme string) *HealthCheckResult {
 	result := &HealthCheckResult{
 		ProxyID: p.ID,
 	}
@@ -195,8 +369,16 @@ func (hc *HealthChecker) checkProxy(ctx context.Context, p *Proxy) *HealthCheckR
 		return result
 	}
 
+	testURL, err := url.Parse(rawURL)
+	if err != nil {
+		result.Status = StatusDead
+		result.Error = err
+		return result
+	}
+	testURL.Scheme = scheme
+
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", hc.testURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", testURL.String(), nil)
 	if err != nil {
 		result.Status = StatusDead
 		result.Error = err
@@ -226,6 +408,9 @@ func (hc *HealthChecker) checkProxy(ctx context.Context, p *Proxy) *HealthCheckR
 		} else {
 			result.Status = StatusAlive
 		}
+		if hc.resolveIP {
+			result.ExternalIP = readOrigin(resp.Body)
+		}
 	} else if resp.StatusCode == 407 {
 		result.Status = StatusDead
 		result.Error = fmt.Errorf("proxy authentication required")
@@ -240,6 +425,21 @@ func (hc *HealthChecker) checkProxy(ctx context.Context, p *Proxy) *HealthCheckR
 	return result
 }
 
+// readOrigin returns the caller IP from an httpbin-style {"origin": "..."}
+// body. Chained proxies can report several comma-separated addresses; the
+// first is the client's.
+func readOrigin(body io.Reader) string {
+	var payload struct {
+		Origin string `json:"origin"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, 4096)).Decode(&payload); err != nil {
+		return ""
+	}
+
+	origin, _, _ := strings.Cut(payload.Origin, ",")
+	return strings.TrimSpace(origin)
+}
+
 func (hc *HealthChecker) createClient(p *Proxy) (*http.Client, error) {
 	var transport *http.Transport
 
@@ -422,7 +622,7 @@ func (hc *HealthChecker) QuickCheckAll(ctx context.Context) *HealthCheckReport {
 
 // Summary returns a string summary of the report
 func (r *HealthCheckReport) Summary() string {
-	return fmt.Sprintf(
+	summary := fmt.Sprintf(
 		"Health Check: %d total, %d alive (%.1f%%), %d slow, %d dead in %s",
 		r.Total,
 		r.Alive,
@@ -431,4 +631,34 @@ func (r *HealthCheckReport) Summary() string {
 		r.Dead,
 		r.Duration.Round(time.Millisecond),
 	)
+
+	// Proxies sharing an egress IP are one exit however many are listed
+	groups := r.ByExternalIP()
+	ips := make([]string, 0, len(groups))
+	for ip, ids := range groups {
+		if len(ids) > 1 {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Strings(ips)
+
+	for _, ip := range ips {
+		summary += fmt.Sprintf("\n  %s shared by %d proxies: %s", ip, len(groups[ip]), strings.Join(groups[ip], ", "))
+	}
+	return summary
+}
+
+// ByExternalIP groups the IDs of proxies with a resolved external IP by
+// that IP, sorted
+func (r *HealthCheckReport) ByExternalIP() map[string][]string {
+	groups := make(map[string][]string)
+	for _, result := range r.Results {
+		if result.ExternalIP != "" {
+			groups[result.ExternalIP] = append(groups[result.ExternalIP], result.ProxyID)
+		}
+	}
+	for _, ids := range groups {
+		sort.Strings(ids)
+	}
+	return groups
 }
@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newConnectOnlyProxy starts an HTTP proxy that tunnels CONNECT requests
+// and rejects plain proxied GETs
+func newConnectOnlyProxy(t *testing.T) *Proxy {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusForbidden)
+			return
+		}
+
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		go func() {
+			io.Copy(target, client)
+			target.Close()
+		}()
+		io.Copy(client, target)
+		client.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	return &Proxy{ID: "connect_only", Host: host, Port: port, Protocol: ProtocolHTTP}
+}
+
+func TestHealthCheckConnectOnlyProxy(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "User-agent: *\n")
+	}))
+	defer target.Close()
+
+	p := newConnectOnlyProxy(t)
+
+	tests := []struct {
+		probe ProbeMode
+		want  Status
+	}{
+		{"", StatusAlive}, // HTTPS by default
+		{ProbeHTTPS, StatusAlive},
+		{ProbeHTTP, StatusDead},
+		{ProbeBoth, StatusAlive},
+	}
+
+	for _, tt := range tests {
+		hc, err := NewHealthChecker(nil, HealthCheckerConfig{
+			TestURL:       target.URL + "/robots.txt",
+			Timeout:       5 * time.Second,
+			Workers:       1,
+			SlowThreshold: 5 * time.Second,
+			Probe:         tt.probe,
+		})
+		if err != nil {
+			t.Fatalf("NewHealthChecker() error = %v", err)
+		}
+
+		result := hc.checkProxy(context.Background(), p)
+		if result.Status != tt.want {
+			t.Errorf("probe %q: Status = %v (err %v), want %v", tt.probe, result.Status, result.Error, tt.want)
+		}
+	}
+}
+
+func TestHealthCheckAnyTestURLAlive(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ip" {
+			http.Error(w, "blocked", http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, `{"origin": "203.0.113.7"}`)
+	}))
+	defer target.Close()
+
+	p := newConnectOnlyProxy(t)
+	hc, err := NewHealthChecker(nil, HealthCheckerConfig{
+		TestURLs:      []string{target.URL + "/robots.txt", target.URL + "/ip", target.URL + "/blocked"},
+		Timeout:       5 * time.Second,
+		Workers:       1,
+		SlowThreshold: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewHealthChecker() error = %v", err)
+	}
+
+	// Whichever URL the rotation starts at, the one answering 200 keeps
+	// the proxy alive
+	for i := 0; i < 3; i++ {
+		result := hc.checkProxy(context.Background(), p)
+		if result.Status != StatusAlive {
+			t.Errorf("check %d: Status = %v (err %v), want %v", i, result.Status, result.Error, StatusAlive)
+		}
+	}
+}
+
+func TestHealthCheckRotatesTestURLs(t *testing.T) {
+	urls := []string{"https://a.example/", "https://b.example/", "https://c.example/"}
+	hc, err := NewHealthChecker(nil, HealthCheckerConfig{TestURLs: urls})
+	if err != nil {
+		t.Fatalf("NewHealthChecker() error = %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(urls); i++ {
+		rotated := hc.rotatedURLs("p1")
+		if len(rotated) != len(urls) {
+			t.Fatalf("rotatedURLs() = %v, want all %d URLs", rotated, len(urls))
+		}
+		seen[rotated[0]] = true
+	}
+	if len(seen) != len(urls) {
+		t.Errorf("first URLs over %d checks = %v, want each URL once", len(urls), seen)
+	}
+}
+
+func TestHealthCheckerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  HealthCheckerConfig
+		wantErr bool
+	}{
+		{"default", DefaultHealthCheckerConfig(), false},
+		{"lightweight", HealthCheckerConfig{Mode: CheckLightweight}, false},
+		{"custom URLs", HealthCheckerConfig{TestURLs: []string{"http://127.0.0.1:8080/ip"}}, false},
+		{"no URL", HealthCheckerConfig{}, true},
+		{"bad URL", HealthCheckerConfig{TestURLs: []string{"not a url"}}, true},
+		{"bad scheme", HealthCheckerConfig{TestURL: "ftp://example.com/"}, true},
+		{"unknown mode", HealthCheckerConfig{TestURL: "https://example.com/", Mode: "fast"}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		// NewHealthChecker refuses the same configs
+		hc, err := NewHealthChecker(nil, tt.config)
+		if (err != nil) != tt.wantErr || (hc == nil) != tt.wantErr {
+			t.Errorf("%s: NewHealthChecker() = %v, %v, wantErr %v", tt.name, hc, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHealthCheckResolvesExternalIP(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"origin": "203.0.113.7, 10.0.0.1"}`)
+	}))
+	defer target.Close()
+
+	manager := NewManager(DefaultManagerConfig())
+	p := newConnectOnlyProxy(t)
+	manager.Add(p)
+
+	hc, err := NewHealthChecker(manager, HealthCheckerConfig{
+		TestURLs:      []string{target.URL + "/ip"},
+		Timeout:       5 * time.Second,
+		Workers:       1,
+		SlowThreshold: 5 * time.Second,
+		ResolveIP:     true,
+	})
+	if err != nil {
+		t.Fatalf("NewHealthChecker() error = %v", err)
+	}
+
+	report := hc.CheckAll(context.Background())
+	if len(report.Results) != 1 || report.Results[0].ExternalIP != "203.0.113.7" {
+		t.Fatalf("Results = %+v, want ExternalIP 203.0.113.7", report.Results)
+	}
+	if got := manager.Get(p.ID).ExternalIP; got != "203.0.113.7" {
+		t.Errorf("proxy ExternalIP = %q, want %q", got, "203.0.113.7")
+	}
+}
+
+func TestReadOrigin(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"origin": "203.0.113.7"}`, "203.0.113.7"},
+		{`{"origin": "203.0.113.7, 198.51.100.2"}`, "203.0.113.7"},
+		{`{"ip": "203.0.113.7"}`, ""},
+		{`User-agent: *`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := readOrigin(strings.NewReader(tt.body)); got != tt.want {
+			t.Errorf("readOrigin(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestHealthCheckReportGroupsExternalIPs(t *testing.T) {
+	report := &HealthCheckReport{
+		Total: 4,
+		Alive: 4,
+		Results: []HealthCheckResult{
+			{ProxyID: "p3", Status: StatusAlive, ExternalIP: "203.0.113.7"},
+			{ProxyID: "p1", Status: StatusAlive, ExternalIP: "203.0.113.7"},
+			{ProxyID: "p2", Status: StatusAlive, ExternalIP: "198.51.100.2"},
+			{ProxyID: "p4", Status: StatusAlive},
+		},
+	}
+
+	groups := report.ByExternalIP()
+	if got := strings.Join(groups["203.0.113.7"], ","); got != "p1,p3" {
+		t.Errorf("ByExternalIP()[203.0.113.7] = %q, want %q", got, "p1,p3")
+	}
+
+	summary := report.Summary()
+	if !strings.Contains(summary, "203.0.113.7 shared by 2 proxies: p1, p3") {
+		t.Errorf("Summary() = %q, want the shared IP listed", summary)
+	}
+	if strings.Contains(summary, "198.51.100.2") {
+		t.Errorf("Summary() = %q, want unshared IPs omitted", summary)
+	}
+}
@@ -3,6 +3,7 @@ package stealth
 import (
 	"fmt"
 	"math/rand"
+	"regexp"
 	"strings"
 )
 
@@ -119,6 +120,20 @@ var (
 		},
 	}
 
+	// Safari profiles
+	safariProfiles = []HeaderProfile{
+		{
+			Name:           "Safari 17 Mac",
+			AcceptLanguage: []string{"en-US,en;q=0.9"},
+			AcceptEncoding: "gzip, deflate, br",
+			Accept:         "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			SecFetchDest:   "document",
+			SecFetchMode:   "navigate",
+			SecFetchSite:   "none",
+			UpgradeInsecureRequests: "1",
+		},
+	}
+
 	// All profiles combined
 	allProfiles []HeaderProfile
 )
@@ -127,15 +142,136 @@ func init() {
 	allProfiles = append(allProfiles, chromeProfiles...)
 	allProfiles = append(allProfiles, firefoxProfiles...)
 	allProfiles = append(allProfiles, edgeProfiles...)
+	allProfiles = append(allProfiles, safariProfiles...)
+}
+
+// UserAgentInfo describes the browser a user agent claims to be
+type UserAgentInfo struct {
+	Browser  string // Chrome, Edge, Firefox, Safari
+	Version  string // Major version
+	Platform string // Windows, macOS, Linux, Android, iOS
+	Mobile   bool
+}
+
+var (
+	uaEdgePattern    = regexp.MustCompile(`Edg/(\d+)`)
+	uaChromePattern  = regexp.MustCompile(`Chrome/(\d+)`)
+	uaFirefoxPattern = regexp.MustCompile(`Firefox/(\d+)`)
+	uaSafariPattern  = regexp.MustCompile(`Version/(\d+)[.\d]* (?:Mobile/\S+ )?Safari/`)
+)
+
+// ParseUserAgent extracts browser, major version and platform from a user agent
+func ParseUserAgent(ua string) UserAgentInfo {
+	info := UserAgentInfo{}
+
+	switch {
+	case uaEdgePattern.MatchString(ua):
+		info.Browser = "Edge"
+		info.Version = uaEdgePattern.FindStringSubmatch(ua)[1]
+	case uaChromePattern.MatchString(ua):
+		info.Browser = "Chrome"
+		info.Version = uaChromePattern.FindStringSubmatch(ua)[1]
+	case uaFirefoxPattern.MatchString(ua):
+		info.Browser = "Firefox"
+		info.Version = uaFirefoxPattern.FindStringSubmatch(ua)[1]
+	case uaSafariPattern.MatchString(ua):
+		info.Browser = "Safari"
+		info.Version = uaSafariPattern.FindStringSubmatch(ua)[1]
+	}
+
+	switch {
+	case strings.Contains(ua, "Android"):
+		info.Platform = "Android"
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad"):
+		info.Platform = "iOS"
+	case strings.Contains(ua, "Windows"):
+		info.Platform = "Windows"
+	case strings.Contains(ua, "Macintosh"):
+		info.Platform = "macOS"
+	case strings.Contains(ua, "Linux"):
+		info.Platform = "Linux"
+	}
+
+	info.Mobile = strings.Contains(ua, "Mobile")
+	return info
+}
+
+// ClientHints returns the Sec-CH-UA values matching a user agent.
+// Only Chromium-based browsers send client hints, so others get empty values.
+func ClientHints(ua string) (secChUa, platform, mobile string) {
+	info := ParseUserAgent(ua)
+
+	var brand string
+	switch info.Browser {
+	case "Chrome":
+		brand = "Google Chrome"
+	case "Edge":
+		brand = "Microsoft Edge"
+	default:
+		return "", "", ""
+	}
+
+	secChUa = fmt.Sprintf(`"Not_A Brand";v="8", "Chromium";v="%s", "%s";v="%s"`, info.Version, brand, info.Version)
+	platform = fmt.Sprintf(`"%s"`, info.Platform)
+	mobile = "?0"
+	if info.Mobile {
+		mobile = "?1"
+	}
+	return secChUa, platform, mobile
 }
 
 // Headers holds the generated HTTP headers
 type Headers map[string]string
 
+// countryLanguages maps an exit country to a plausible Accept-Language
+var countryLanguages = map[string]string{
+	"US": "en-US,en;q=0.9",
+	"GB": "en-GB,en;q=0.9,en-US;q=0.8",
+	"IE": "en-IE,en;q=0.9,en-GB;q=0.8",
+	"CA": "en-CA,en;q=0.9,fr-CA;q=0.8",
+	"AU": "en-AU,en;q=0.9",
+	"NZ": "en-NZ,en;q=0.9",
+	"IN": "en-IN,en;q=0.9,hi;q=0.8",
+	"SG": "en-SG,en;q=0.9",
+	"DE": "de-DE,de;q=0.9,en;q=0.8",
+	"AT": "de-AT,de;q=0.9,en;q=0.8",
+	"CH": "de-CH,de;q=0.9,fr;q=0.8,en;q=0.7",
+	"FR": "fr-FR,fr;q=0.9,en;q=0.8",
+	"BE": "nl-BE,nl;q=0.9,fr;q=0.8,en;q=0.7",
+	"NL": "nl-NL,nl;q=0.9,en;q=0.8",
+	"ES": "es-ES,es;q=0.9,en;q=0.8",
+	"MX": "es-MX,es;q=0.9,en;q=0.8",
+	"IT": "it-IT,it;q=0.9,en;q=0.8",
+	"PT": "pt-PT,pt;q=0.9,en;q=0.8",
+	"BR": "pt-BR,pt;q=0.9,en;q=0.8",
+	"PL": "pl-PL,pl;q=0.9,en;q=0.8",
+	"SE": "sv-SE,sv;q=0.9,en;q=0.8",
+	"NO": "nb-NO,nb;q=0.9,no;q=0.8,en;q=0.7",
+	"DK": "da-DK,da;q=0.9,en;q=0.8",
+	"FI": "fi-FI,fi;q=0.9,en;q=0.8",
+	"RU": "ru-RU,ru;q=0.9,en;q=0.8",
+	"JP": "ja-JP,ja;q=0.9,en;q=0.8",
+	"KR": "ko-KR,ko;q=0.9,en;q=0.8",
+}
+
+// AcceptLanguageForCountry returns the Accept-Language for an exit country.
+// Overrides take precedence; unknown countries return an empty string.
+func AcceptLanguageForCountry(country string, overrides map[string]string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" {
+		return ""
+	}
+	if lang, ok := overrides[country]; ok {
+		return lang
+	}
+	return countryLanguages[country]
+}
+
 // HeaderGenerator generates randomized HTTP headers
 type HeaderGenerator struct {
 	userAgents []string
 	profiles   []HeaderProfile
+	languages  map[string]string // Country -> Accept-Language overrides
 }
 
 // NewHeaderGenerator creates a new header generator
@@ -149,11 +285,50 @@ func NewHeaderGenerator(userAgents []string) *HeaderGenerator {
 	}
 }
 
+// SetAcceptLanguages sets per-country Accept-Language overrides
+func (g *HeaderGenerator) SetAcceptLanguages(overrides map[string]string) {
+	g.languages = make(map[string]string, len(overrides))
+	for country, lang := range overrides {
+		g.languages[strings.ToUpper(country)] = lang
+	}
+}
+
+// ApplyCountry sets Accept-Language to match the exit country,
+// keeping the generated English value when the country is unknown
+func (g *HeaderGenerator) ApplyCountry(headers Headers, country string) {
+	if lang := AcceptLanguageForCountry(country, g.languages); lang != "" {
+		headers["Accept-Language"] = lang
+	}
+}
+
 // Generate creates a randomized set of headers
 func (g *HeaderGenerator) Generate() Headers {
-	profile := g.profiles[rand.Intn(len(g.profiles))]
 	ua := g.userAgents[rand.Intn(len(g.userAgents))]
-	
+	return g.GenerateForUserAgent(ua)
+}
+
+// profileFor picks a random profile of the browser family the user agent claims
+func (g *HeaderGenerator) profileFor(ua string) HeaderProfile {
+	browser := ParseUserAgent(ua).Browser
+
+	matching := make([]HeaderProfile, 0, len(g.profiles))
+	for _, profile := range g.profiles {
+		if browser != "" && strings.HasPrefix(profile.Name, browser) {
+			matching = append(matching, profile)
+		}
+	}
+	if len(matching) == 0 {
+		matching = g.profiles
+	}
+
+	return matching[rand.Intn(len(matching))]
+}
+
+// GenerateForUserAgent creates headers whose profile and client hints
+// describe the same browser and version as the given user agent
+func (g *HeaderGenerator) GenerateForUserAgent(ua string) Headers {
+	profile := g.profileFor(ua)
+
 	headers := Headers{
 		"User-Agent":      ua,
 		"Accept":          profile.Accept,
@@ -162,12 +337,8 @@ func (g *HeaderGenerator) Generate() Headers {
 		"Connection":      "keep-alive",
 	}
 
-	// Add Chrome/Edge specific headers
-	if profile.SecChUa != "" {
-		headers["Sec-CH-UA"] = profile.SecChUa
-		headers["Sec-CH-UA-Mobile"] = profile.SecChUaMobile
-		headers["Sec-CH-UA-Platform"] = profile.SecChUaPlatform
-	}
+	// Add Chrome/Edge specific headers, derived from the UA itself
+	headers.applyClientHints(ua)
 
 	// Add Sec-Fetch headers
 	if profile.SecFetchDest != "" {
@@ -192,6 +363,28 @@ func (g *HeaderGenerator) Generate() Headers {
 	return headers
 }
 
+// SetUserAgent replaces the user agent and re-derives the client hints
+// so they never describe a different browser than the User-Agent header
+func (h Headers) SetUserAgent(ua string) {
+	h["User-Agent"] = ua
+	h.applyClientHints(ua)
+}
+
+// applyClientHints sets or removes Sec-CH-UA headers to match the user agent
+func (h Headers) applyClientHints(ua string) {
+	secChUa, platform, mobile := ClientHints(ua)
+	if secChUa == "" {
+		delete(h, "Sec-CH-UA")
+		delete(h, "Sec-CH-UA-Mobile")
+		delete(h, "Sec-CH-UA-Platform")
+		return
+	}
+
+	h["Sec-CH-UA"] = secChUa
+	h["Sec-CH-UA-Mobile"] = mobile
+	h["Sec-CH-UA-Platform"] = platform
+}
+
 // GenerateForGoogle creates headers specifically for Google requests
 func (g *HeaderGenerator) GenerateForGoogle(googleDomain string) Headers {
 	headers := g.Generate()
@@ -0,0 +1,151 @@
+package stealth
+
+import (
+	"regexp"
+	"testing"
+)
+
+var secChUaBrandPattern = regexp.MustCompile(`"(Google Chrome|Microsoft Edge)";v="(\d+)"`)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		ua       string
+		browser  string
+		version  string
+		platform string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36", "Chrome", "119", "Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge", "120", "Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.2; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox", "121", "macOS"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15", "Safari", "17", "macOS"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36", "Chrome", "118", "Linux"},
+	}
+
+	for _, tt := range tests {
+		info := ParseUserAgent(tt.ua)
+		if info.Browser != tt.browser || info.Version != tt.version || info.Platform != tt.platform {
+			t.Errorf("ParseUserAgent(%q) = %+v, want %s %s on %s", tt.ua, info, tt.browser, tt.version, tt.platform)
+		}
+	}
+}
+
+// assertCoherent checks that client hints describe the same browser as the UA
+func assertCoherent(t *testing.T, headers Headers) {
+	t.Helper()
+
+	ua := headers["User-Agent"]
+	info := ParseUserAgent(ua)
+	secChUa, hasHints := headers["Sec-CH-UA"]
+
+	switch info.Browser {
+	case "Chrome", "Edge":
+		if !hasHints {
+			t.Fatalf("%s UA without Sec-CH-UA: %s", info.Browser, ua)
+		}
+		match := secChUaBrandPattern.FindStringSubmatch(secChUa)
+		if match == nil {
+			t.Fatalf("Sec-CH-UA has no browser brand: %s", secChUa)
+		}
+		wantBrand := "Google Chrome"
+		if info.Browser == "Edge" {
+			wantBrand = "Microsoft Edge"
+		}
+		if match[1] != wantBrand || match[2] != info.Version {
+			t.Errorf("Sec-CH-UA %q does not match UA %q", secChUa, ua)
+		}
+		if headers["Sec-CH-UA-Platform"] != `"`+info.Platform+`"` {
+			t.Errorf("Sec-CH-UA-Platform %q does not match UA %q", headers["Sec-CH-UA-Platform"], ua)
+		}
+	default:
+		if hasHints {
+			t.Errorf("%s UA should not send Sec-CH-UA (%q): %s", info.Browser, secChUa, ua)
+		}
+	}
+}
+
+func TestGenerateClientHintsMatchUserAgent(t *testing.T) {
+	g := NewHeaderGenerator(nil)
+
+	for i := 0; i < 500; i++ {
+		assertCoherent(t, g.Generate())
+	}
+}
+
+func TestGenerateForUserAgentPicksMatchingProfile(t *testing.T) {
+	g := NewHeaderGenerator(nil)
+
+	firefox := "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	for i := 0; i < 50; i++ {
+		headers := g.GenerateForUserAgent(firefox)
+		if headers["Accept-Language"] != "en-US,en;q=0.5" && headers["Accept-Language"] != "en-GB,en;q=0.5" {
+			t.Fatalf("Firefox UA got non-Firefox Accept-Language %q", headers["Accept-Language"])
+		}
+		assertCoherent(t, headers)
+	}
+}
+
+func TestHeadersSetUserAgent(t *testing.T) {
+	g := NewHeaderGenerator([]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	})
+
+	headers := g.GenerateForSearch("www.google.com", false)
+	if headers["Sec-CH-UA"] == "" {
+		t.Fatal("Chrome headers should carry Sec-CH-UA")
+	}
+
+	// Overriding with a Firefox UA must drop the Chrome client hints
+	headers.SetUserAgent("Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+	assertCoherent(t, headers)
+
+	// Overriding with a different Chrome version must update them
+	headers.SetUserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36")
+	assertCoherent(t, headers)
+}
+
+func TestAcceptLanguageForCountry(t *testing.T) {
+	overrides := map[string]string{"CH": "fr-CH,fr;q=0.9,en;q=0.8"}
+
+	tests := []struct {
+		country string
+		want    string
+	}{
+		{"DE", "de-DE,de;q=0.9,en;q=0.8"},
+		{"de", "de-DE,de;q=0.9,en;q=0.8"},
+		{"FR", "fr-FR,fr;q=0.9,en;q=0.8"},
+		{"US", "en-US,en;q=0.9"},
+		{"CH", "fr-CH,fr;q=0.9,en;q=0.8"},
+		{"ZZ", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := AcceptLanguageForCountry(tt.country, overrides); got != tt.want {
+			t.Errorf("AcceptLanguageForCountry(%q) = %q, want %q", tt.country, got, tt.want)
+		}
+	}
+}
+
+func TestHeaderGeneratorApplyCountry(t *testing.T) {
+	g := NewHeaderGenerator(nil)
+	g.SetAcceptLanguages(map[string]string{"br": "pt-BR,pt;q=0.9"})
+
+	headers := g.Generate()
+	g.ApplyCountry(headers, "DE")
+	if headers["Accept-Language"] != "de-DE,de;q=0.9,en;q=0.8" {
+		t.Errorf("DE Accept-Language = %q", headers["Accept-Language"])
+	}
+
+	g.ApplyCountry(headers, "BR")
+	if headers["Accept-Language"] != "pt-BR,pt;q=0.9" {
+		t.Errorf("BR override Accept-Language = %q", headers["Accept-Language"])
+	}
+
+	// Unknown country keeps the generated English value
+	headers = g.Generate()
+	before := headers["Accept-Language"]
+	g.ApplyCountry(headers, "")
+	if headers["Accept-Language"] != before {
+		t.Errorf("empty country changed Accept-Language to %q", headers["Accept-Language"])
+	}
+}
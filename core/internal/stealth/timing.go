@@ -96,6 +96,7 @@ var DefaultTimingConfigs = map[TimingProfile]TimingConfig{
 // TimingManager manages request timing for stealth
 type TimingManager struct {
 	config       TimingConfig
+	custom       bool // Config was set explicitly; keep its cooldowns
 	mu           sync.RWMutex
 	sessions     map[string]*Session
 	rng          *rand.Rand
@@ -132,6 +133,7 @@ func NewTimingManager(profile TimingProfile) *TimingManager {
 func NewTimingManagerWithConfig(config TimingConfig) *TimingManager {
 	return &TimingManager{
 		config:   config,
+		custom:   true,
 		sessions: make(map[string]*Session),
 		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
@@ -219,6 +221,24 @@ func (tm *TimingManager) RecordBlock(proxyID string) {
 	session.BurstCount = 0
 }
 
+// ApplyEngineCooldowns adopts an engine's recommended CAPTCHA and block
+// cooldowns. Zero values and managers built from a custom config keep
+// their current cooldowns.
+func (tm *TimingManager) ApplyEngineCooldowns(captcha, block time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.custom {
+		return
+	}
+	if captcha > 0 {
+		tm.config.CaptchaCooldown = captcha
+	}
+	if block > 0 {
+		tm.config.BlockCooldown = block
+	}
+}
+
 // IsInCooldown checks if a proxy is in cooldown
 func (tm *TimingManager) IsInCooldown(proxyID string) bool {
 	tm.mu.RLock()
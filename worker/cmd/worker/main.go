@@ -1,14 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"dorker/worker/internal/engine"
+	"dorker/worker/internal/output"
 	"dorker/worker/internal/protocol"
 	"dorker/worker/internal/proxy"
 	"dorker/worker/internal/stealth"
@@ -20,14 +33,35 @@ var (
 	BuildTime = "unknown"
 )
 
+// shutdownDrainTimeout bounds how long a shutdown waits for running tasks
+const shutdownDrainTimeout = 30 * time.Second
+
 func main() {
 	// Parse flags
 	showVersion := flag.Bool("version", false, "Show version")
 	standalone := flag.Bool("standalone", false, "Run in standalone mode")
-	dorkFile := flag.String("dorks", "", "Path to dorks file (standalone mode)")
-	proxyFile := flag.String("proxies", "", "Path to proxies file (standalone mode)")
-	outputDir := flag.String("output", "./output", "Output directory (standalone mode)")
+	dorkFile := flag.String("dorks", "", "Path to dorks file, or - for stdin (standalone mode)")
+	dork := flag.String("dork", "", "Run a single dork instead of a dorks file (standalone mode)")
+	proxyFile := flag.String("proxies", "", "Path to proxies file, or - for stdin (standalone mode)")
+	outputDir := flag.String("output", "./output", "Output directory, or sqlite:<file> for a database (standalone mode)")
 	workers := flag.Int("workers", 10, "Number of workers (standalone mode)")
+	configFile := flag.String("config", "", "Path to JSON init config file")
+	maxRuntime := flag.Duration("max-runtime", 0, "Stop after this wall-clock duration, e.g. 45m (0 = no limit)")
+	maxFileSize := flag.String("max-file-size", "", "Roll over to a new output file past this size, e.g. 100MB (standalone mode)")
+	format := flag.String("format", "txt", "Output file format: txt, jsonl or csv (standalone mode)")
+	timeRange := flag.String("time-range", "", "Only results indexed within the past d, w, m or y (standalone mode)")
+	checkpoint := flag.String("checkpoint", "", "Save progress to this file and resume from it if it exists (standalone mode)")
+	checkpointInterval := flag.Duration("checkpoint-interval", 30*time.Second, "How often to save the checkpoint (standalone mode)")
+	dryRun := flag.Bool("dry-run", false, "Output the search URLs each dork would request instead of requesting them")
+	strictDorks := flag.Bool("strict-dorks", false, "Fail dorks that cannot match anything instead of searching them")
+	skip := flag.Int("skip", 0, "Skip the first N dorks (standalone mode)")
+	limit := flag.Int("limit", 0, "Process at most N dorks, after --skip (standalone mode, 0 = all)")
+	seed := flag.Int64("seed", 0, "Seed every random choice to make runs reproducible (0 = random)")
+	varsFile := flag.String("vars", "", "JSON file mapping dork template variables to wordlist files (standalone mode)")
+	maxExpansions := flag.Int("max-expansions", 10000, "Expand dork templates into at most N dorks in total (standalone mode, 0 = no limit)")
+	testProxies := flag.Bool("test-proxies", false, "Health check the proxies, report on each and exit")
+	quick := flag.Bool("quick", false, "With --test-proxies, only check that each proxy accepts TCP connections")
+	aliveOut := flag.String("alive-out", "", "With --test-proxies, write the proxies that pass to this file")
 	flag.Parse()
 
 	if *showVersion {
@@ -35,84 +69,470 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Check if running in IPC mode or standalone
+	// Load init config file if provided
+	initData := make(map[string]any)
+	if *configFile != "" {
+		data, err := protocol.ReadInitConfigFile(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(1)
+		}
+		initData = data
+	}
+
+	// Explicitly set flags override file values
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "workers":
+			initData["workers"] = float64(*workers)
+		case "proxies":
+			initData["proxy_file"] = *proxyFile
+		case "max-runtime":
+			initData["max_runtime"] = float64(maxRuntime.Milliseconds())
+		case "time-range":
+			initData["time_range"] = *timeRange
+		case "dry-run":
+			initData["dry_run"] = *dryRun
+		case "strict-dorks":
+			initData["strict_dorks"] = *strictDorks
+		case "seed":
+			initData["seed"] = float64(*seed)
+		}
+	})
+
+	if *testProxies {
+		runProxyTest(protocol.ParseInitConfig(&protocol.Message{Type: protocol.MsgTypeInit, Data: initData}), *quick, *aliveOut)
+		return
+	}
+
+	// Check if running in IPC mode or standalone. Reading dorks or
+	// proxies from stdin, or an inline dork, implies standalone.
 	stat, _ := os.Stdin.Stat()
-	isIPCMode := (stat.Mode()&os.ModeCharDevice) == 0 && !*standalone
+	implied := *dorkFile == "-" || *proxyFile == "-" || *dork != ""
+	isIPCMode := (stat.Mode()&os.ModeCharDevice) == 0 && !*standalone && !implied
 
 	if isIPCMode {
-		runIPCMode()
+		runIPCMode(initData)
 	} else {
-		runStandaloneMode(*dorkFile, *proxyFile, *outputDir, *workers)
+		initConfig := protocol.ParseInitConfig(&protocol.Message{Type: protocol.MsgTypeInit, Data: initData})
+		maxSize, err := output.ParseSize(*maxFileSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ --max-file-size: %v\n", err)
+			os.Exit(1)
+		}
+		outputFormat, err := output.ParseFormat(*format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ --format: %v\n", err)
+			os.Exit(1)
+		}
+		if initConfig.TimeRange != "" && !engine.ValidTimeRange(initConfig.TimeRange) {
+			fmt.Fprintf(os.Stderr, "✗ --time-range: unknown time range %q (want d, w, m or y)\n", initConfig.TimeRange)
+			os.Exit(1)
+		}
+		if initConfig.TimingProfile != "" && !stealth.ValidTimingProfile(initConfig.TimingProfile) {
+			fmt.Fprintf(os.Stderr, "✗ timing_profile: unknown profile %q (want aggressive, normal, cautious or stealth)\n", initConfig.TimingProfile)
+			os.Exit(1)
+		}
+		if initConfig.OverflowPolicy != "" && !worker.ValidOverflowPolicy(initConfig.OverflowPolicy) {
+			fmt.Fprintf(os.Stderr, "✗ overflow_policy: unknown policy %q (want block, drop_newest or drop_oldest)\n", initConfig.OverflowPolicy)
+			os.Exit(1)
+		}
+		if *skip < 0 || *limit < 0 {
+			fmt.Fprintf(os.Stderr, "✗ --skip and --limit cannot be negative\n")
+			os.Exit(1)
+		}
+		if *maxExpansions < 0 {
+			fmt.Fprintf(os.Stderr, "✗ --max-expansions cannot be negative\n")
+			os.Exit(1)
+		}
+		if *checkpointInterval <= 0 {
+			fmt.Fprintf(os.Stderr, "✗ --checkpoint-interval: must be positive\n")
+			os.Exit(1)
+		}
+		if *dork != "" && *dorkFile != "" {
+			fmt.Fprintf(os.Stderr, "✗ --dork and --dorks cannot be used together\n")
+			os.Exit(1)
+		}
+		if *dorkFile == "-" && initConfig.ProxyFile == "-" {
+			fmt.Fprintf(os.Stderr, "✗ --dorks and --proxies cannot both read stdin\n")
+			os.Exit(1)
+		}
+		if initConfig.ProxyFile == "-" {
+			lines, err := readLines(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✗ --proxies: %v\n", err)
+				os.Exit(1)
+			}
+			initConfig.Proxies = append(initConfig.Proxies, lines...)
+			initConfig.ProxyFile = ""
+		}
+		var vars map[string][]string
+		if *varsFile != "" {
+			vars, err = loadVars(*varsFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✗ --vars: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		runStandaloneMode(*dorkFile, *dork, *outputDir, outputFormat, maxSize, *checkpoint, *checkpointInterval, *skip, *limit, vars, *maxExpansions, initConfig)
 	}
 }
 
-func runIPCMode() {
+// loadFingerprints returns a stealth manager with the fingerprints in path
+// loaded over the built-in ones, and how many the file held. A non-zero
+// seed makes its rotation reproducible.
+func loadFingerprints(path string, seed int64) (*stealth.Manager, int, error) {
+	m := stealth.NewManager()
+	if seed != 0 {
+		m = stealth.NewManagerWithSeed(seed)
+	}
+	n, err := m.LoadFingerprintsFromFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return m, n, nil
+}
+
+// checkTimeRange returns r if it is a known time range, otherwise it warns
+// and returns "" so the search runs without one
+func checkTimeRange(handler *protocol.Handler, r string) string {
+	if r == "" || engine.ValidTimeRange(r) {
+		return r
+	}
+	handler.SendLog("warn", fmt.Sprintf("Ignoring unknown time range %q (want d, w, m or y)", r))
+	return ""
+}
+
+// checkTimingProfile returns p if it is a known timing profile, otherwise
+// it warns and returns "" so the worker keeps its plain delays
+func checkTimingProfile(handler *protocol.Handler, p string) string {
+	if p == "" || stealth.ValidTimingProfile(p) {
+		return p
+	}
+	handler.SendLog("warn", fmt.Sprintf("Ignoring unknown timing profile %q (want aggressive, normal, cautious or stealth)", p))
+	return ""
+}
+
+// checkOverflowPolicy returns p if it is a known overflow policy,
+// otherwise it warns and returns "" so full buffers drop new results
+func checkOverflowPolicy(handler *protocol.Handler, p string) string {
+	if p == "" || worker.ValidOverflowPolicy(p) {
+		return p
+	}
+	handler.SendLog("warn", fmt.Sprintf("Ignoring unknown overflow policy %q (want block, drop_newest or drop_oldest)", p))
+	return ""
+}
+
+// checkCode returns code if it is a two-letter code, otherwise it warns
+// and returns "" so the engine's default is used
+func checkCode(handler *protocol.Handler, kind, code string) string {
+	if code == "" || engine.ValidCode(code) {
+		return code
+	}
+	handler.SendLog("warn", fmt.Sprintf("Ignoring invalid %s code %q (want two letters)", kind, code))
+	return ""
+}
+
+// newEngines creates the search engines selected in the init config:
+// every entry of engines in multi-engine mode, otherwise engine alone
+func newEngines(config *protocol.InitConfig) ([]engine.SearchEngine, error) {
+	names := config.Engines
+	if len(names) == 0 {
+		names = []string{config.Engine}
+	}
+
+	engines := make([]engine.SearchEngine, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		e, err := newEngine(name, config)
+		if err != nil {
+			return nil, err
+		}
+		engines = append(engines, e)
+	}
+	return engines, nil
+}
+
+// newEngine creates a search engine by name
+func newEngine(name string, config *protocol.InitConfig) (engine.SearchEngine, error) {
+	switch name {
+	case "", "google":
+		g := engine.NewGoogle()
+		if config.Seed != 0 {
+			g = engine.NewGoogleWithSeed(config.Seed)
+		}
+		if len(config.SoftBlockMarkers) > 0 {
+			g.SoftBlockMarkers = make([]string, len(config.SoftBlockMarkers))
+			for i, marker := range config.SoftBlockMarkers {
+				g.SoftBlockMarkers[i] = strings.ToLower(marker)
+			}
+		}
+		if len(config.GoogleDomains) > 0 {
+			domains := make([]string, 0, len(config.GoogleDomains))
+			for _, domain := range config.GoogleDomains {
+				domain = strings.ToLower(strings.TrimSpace(domain))
+				if !engine.ValidGoogleDomain(domain) {
+					return nil, fmt.Errorf("google_domains: %q is not a Google domain", domain)
+				}
+				domains = append(domains, domain)
+			}
+			g.SetDomains(domains, config.StickyDomain)
+		}
+		return g, nil
+	case "bing":
+		return engine.NewBing(), nil
+	case "duckduckgo", "ddg":
+		return engine.NewDuckDuckGo(), nil
+	case "yandex":
+		return engine.NewYandex(), nil
+	default:
+		return nil, fmt.Errorf("unknown engine: %s", name)
+	}
+}
+
+// workerConfigFromInit builds a worker config from init config, failing
+// on invalid URL filter patterns
+func workerConfigFromInit(config *protocol.InitConfig) (worker.Config, error) {
+	workerConfig := worker.DefaultConfig()
+	workerConfig.Workers = config.Workers
+	workerConfig.RequestTimeout = config.Timeout
+	workerConfig.BaseDelay = config.BaseDelay
+	workerConfig.MinDelay = config.MinDelay
+	workerConfig.MaxDelay = config.MaxDelay
+	workerConfig.MaxRetries = config.MaxRetries
+	workerConfig.GlobalRPM = config.GlobalRPM
+	workerConfig.ResultsPerPage = config.ResultsPerPage
+	workerConfig.MaxPages = config.MaxPages
+	workerConfig.MaxResultsPerDork = config.MaxResultsPerDork
+	workerConfig.UniqueDomains = config.UniqueDomains
+	workerConfig.Dedup = config.Dedup
+	workerConfig.AdaptiveConcurrency = config.AdaptiveConcurrency
+	workerConfig.AdaptiveWindow = config.AdaptiveWindow
+	workerConfig.AdaptiveThreshold = config.AdaptiveThreshold
+	workerConfig.CircuitBreaker = config.CircuitBreaker
+	workerConfig.BreakerWindow = config.BreakerWindow
+	workerConfig.BreakerThreshold = config.BreakerThreshold
+	workerConfig.BreakerCooldown = config.BreakerCooldown
+	workerConfig.BreakerProbes = config.BreakerProbes
+	workerConfig.TimingProfile = config.TimingProfile
+	workerConfig.OverflowPolicy = worker.OverflowPolicy(config.OverflowPolicy)
+	workerConfig.TLSFingerprint = config.TLSFingerprint
+	workerConfig.ForceHTTP1 = config.ForceHTTP1
+	workerConfig.WarmUp = config.WarmUp
+	workerConfig.FingerprintRotation = config.FingerprintRotation
+	workerConfig.DryRun = config.DryRun
+	workerConfig.StrictDorks = config.StrictDorks
+	workerConfig.Seed = config.Seed
+	workerConfig.MaxRuntime = config.MaxRuntime
+	workerConfig.DNSCacheSize = config.DNSCacheSize
+	workerConfig.CaptchaCooldown = config.CaptchaCooldown
+	workerConfig.BlockCooldown = config.BlockCooldown
+	workerConfig.RecordDir = config.RecordDir
+	workerConfig.RecordHTML = config.RecordHTML
+	if config.RecordMaxBytes > 0 {
+		workerConfig.RecordMaxBytes = config.RecordMaxBytes
+	}
+	if config.DNSCacheTTL > 0 {
+		workerConfig.DNSCacheTTL = config.DNSCacheTTL
+	}
+	if config.MaxBodyBytes > 0 {
+		workerConfig.MaxBodyBytes = config.MaxBodyBytes
+	}
+
+	var err error
+	if workerConfig.IncludePatterns, err = compilePatterns("include_patterns", config.IncludePatterns); err != nil {
+		return workerConfig, err
+	}
+	if workerConfig.ExcludePatterns, err = compilePatterns("exclude_patterns", config.ExcludePatterns); err != nil {
+		return workerConfig, err
+	}
+	workerConfig.DenyDomains = config.DenyDomains
+	return workerConfig, nil
+}
+
+// compilePatterns compiles the regular expressions of a filter option
+func compilePatterns(key string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func runIPCMode(initDefaults map[string]any) {
 	// Create protocol handler
 	handler := protocol.NewHandler()
+	handler.SetInitDefaults(initDefaults)
 
 	// Worker instance (created on init)
 	var w *worker.Worker
 	var proxyPool *proxy.Pool
+	var stickyProxy bool
+	var timeRange string
+	var proxyStatePath string
+	var resultsDone chan struct{} // Closed once every result is sent
+	var current worker.Config     // Config applied by the last init or update
 
 	// Handle init
 	handler.OnInit(func(config *protocol.InitConfig) {
+		engines, err := newEngines(config)
+		if err != nil {
+			handler.SendError("invalid_config", err.Error())
+			return
+		}
+		config.TimingProfile = checkTimingProfile(handler, config.TimingProfile)
+		config.OverflowPolicy = checkOverflowPolicy(handler, config.OverflowPolicy)
+		workerConfig, err := workerConfigFromInit(config)
+		if err != nil {
+			handler.SendError("invalid_config", err.Error())
+			return
+		}
+
 		// Create proxy pool
-		poolConfig := proxy.DefaultPoolConfig()
+		poolConfig, err := newPoolConfig(config)
+		if err != nil {
+			handler.SendError("invalid_config", err.Error())
+			return
+		}
 		proxyPool = proxy.NewPool(poolConfig)
 
-		// Load proxies from file if provided
-		if config.ProxyFile != "" {
-			added, errs := proxyPool.LoadFromFile(config.ProxyFile)
-			handler.SendLog("info", fmt.Sprintf("Loaded %d proxies from file", added))
+		// A dry run sends no requests, so proxies are neither loaded nor
+		// checked
+		if config.DryRun {
+			handler.SendLog("info", "Dry run: tasks return their search URLs, no proxies loaded")
+		} else {
+			// Load proxies from the configured sources
+			added, errs := loadProxies(proxyPool, config)
+			handler.SendLog("info", fmt.Sprintf("Loaded %d proxies", added))
 			for _, err := range errs {
 				handler.SendLog("warn", fmt.Sprintf("Proxy load error: %v", err))
 			}
-		}
 
-		// Load proxies from list if provided
-		if len(config.Proxies) > 0 {
-			parser := proxy.NewParser()
-			for _, p := range config.Proxies {
-				prx, err := parser.ParseLine(p)
-				if err != nil {
-					handler.SendLog("warn", fmt.Sprintf("Invalid proxy: %s", p))
-					continue
-				}
-				if prx != nil {
-					proxyPool.AddProxy(prx)
-				}
+			// Restore what earlier runs learned about these proxies
+			proxyStatePath = config.ProxyState
+			if err := loadProxyState(proxyPool, proxyStatePath); err != nil {
+				handler.SendLog("warn", fmt.Sprintf("Proxy state not restored: %v", err))
+			}
+
+			// Lazily check only as many proxies as needed
+			if config.TargetAlive > 0 {
+				checked := proxyPool.WarmUp(context.Background())
+				handler.SendLog("info", fmt.Sprintf("Checked %d proxies to reach %d alive", checked, proxyPool.Stats().Alive))
 			}
 		}
 
 		// Send proxy info
 		stats := proxyPool.Stats()
-		handler.SendProxyInfo(stats.Alive, stats.Dead, stats.Quarantined)
-
-		// Create worker config
-		workerConfig := worker.DefaultConfig()
-		workerConfig.Workers = config.Workers
-		workerConfig.RequestTimeout = config.Timeout
-		workerConfig.BaseDelay = config.BaseDelay
-		workerConfig.MinDelay = config.MinDelay
-		workerConfig.MaxDelay = config.MaxDelay
-		workerConfig.MaxRetries = config.MaxRetries
-		workerConfig.ResultsPerPage = config.ResultsPerPage
+		handler.SendProxyInfo(stats.Alive, stats.Dead, stats.Quarantined, stats.Evicted)
 
 		// Create worker
+		stickyProxy = config.StickyProxy
+		timeRange = checkTimeRange(handler, config.TimeRange)
 		w = worker.New(workerConfig, proxyPool)
+		current = workerConfig
+		w.SetEngines(engines...)
+		if config.FingerprintsFile != "" {
+			if fingerprints, n, err := loadFingerprints(config.FingerprintsFile, config.Seed); err != nil {
+				handler.SendLog("warn", fmt.Sprintf("Keeping built-in fingerprints: %v", err))
+			} else {
+				w.SetStealthManager(fingerprints)
+				handler.SendLog("info", fmt.Sprintf("Loaded %d fingerprints", n))
+			}
+		}
+		w.OnPoolCooldown(func(until time.Time) {
+			handler.SendLog("warn", fmt.Sprintf("All proxies cooling down, pausing for %s", time.Until(until).Round(time.Second)))
+		})
+		w.OnBreaker(func(state worker.BreakerState, until time.Time) {
+			switch state {
+			case worker.BreakerOpen:
+				handler.SendLog("warn", fmt.Sprintf("Block rate spiked, circuit breaker halting requests for %s", time.Until(until).Round(time.Second)))
+			case worker.BreakerHalfOpen:
+				handler.SendLog("info", "Circuit breaker probing with a few requests")
+			case worker.BreakerClosed:
+				handler.SendLog("info", "Circuit breaker closed, resuming requests")
+			}
+		})
 
 		// Start result processor
-		go processResults(handler, w)
+		var batcher *protocol.ResultBatcher
+		if config.BatchResults {
+			batcher = protocol.NewResultBatcher(handler, config.BatchSize, config.BatchFlushInterval)
+		}
+		resultsDone = make(chan struct{})
+		go processResults(handler, w, proxyPool, batcher, config.DorkProgress, resultsDone)
 
 		// Start worker
 		w.Start()
 
 		// Start proxy pool health check
-		proxyPool.StartHealthCheck()
+		if !config.DryRun {
+			proxyPool.StartHealthCheck()
+		}
 
 		handler.SendStatus("initialized", fmt.Sprintf("Worker initialized with %d workers", config.Workers))
 	})
 
+	// Handle config update
+	handler.OnUpdateConfig(func(config *protocol.InitConfig) {
+		if w == nil {
+			handler.SendError("not_initialized", "Worker not initialized")
+			return
+		}
+
+		workerConfig, err := workerConfigFromInit(config)
+		if err != nil {
+			handler.SendError("invalid_config", err.Error())
+			return
+		}
+
+		// The timing profile set at init paces requests instead of the
+		// delays, so new delays are kept but have no effect
+		if current.TimingProfile != "" && (workerConfig.BaseDelay != current.BaseDelay ||
+			workerConfig.MinDelay != current.MinDelay || workerConfig.MaxDelay != current.MaxDelay) {
+			handler.SendLog("warn", fmt.Sprintf("base_delay, min_delay and max_delay have no effect with timing profile %q", current.TimingProfile))
+		}
+		workerConfig.TimingProfile = current.TimingProfile
+		w.UpdateConfig(workerConfig)
+		current = workerConfig
+		handler.SendStatus("config_updated", fmt.Sprintf("Worker running with %d workers", config.Workers))
+	})
+
+	// newTask builds a worker task, applying init defaults the task does
+	// not override
+	newTask := func(task *protocol.TaskData) *worker.Task {
+		sticky := stickyProxy
+		if task.StickyProxy != nil {
+			sticky = *task.StickyProxy
+		}
+		taskTimeRange := timeRange
+		if task.TimeRange != "" {
+			taskTimeRange = checkTimeRange(handler, task.TimeRange)
+		}
+
+		return &worker.Task{
+			ID:         task.ID,
+			Dork:       task.Dork,
+			Page:       task.Page,
+			Priority:   task.Priority,
+			MaxPages:   task.MaxPages,
+			Sticky:     sticky,
+			SessionKey: task.SessionKey,
+			TimeRange:  taskTimeRange,
+			Country:    checkCode(handler, "country", task.Country),
+			Language:   checkCode(handler, "language", task.Language),
+		}
+	}
+
 	// Handle task
 	handler.OnTask(func(task *protocol.TaskData) {
 		if w == nil {
@@ -120,28 +540,58 @@ func runIPCMode() {
 			return
 		}
 
-		err := w.Submit(&worker.Task{
-			ID:   task.ID,
-			Dork: task.Dork,
-			Page: task.Page,
-		})
+		err := w.Submit(newTask(task))
+		if err != nil {
+			handler.SendError("submit_failed", err.Error())
+		}
+	})
 
+	// Handle task batch, acknowledging which tasks were queued
+	handler.OnTaskBatch(func(batch []*protocol.TaskData) {
+		if w == nil {
+			handler.SendError("not_initialized", "Worker not initialized")
+			return
+		}
+
+		tasks := make([]*worker.Task, len(batch))
+		for i, task := range batch {
+			tasks[i] = newTask(task)
+		}
+
+		rejected, err := w.SubmitBatch(tasks)
 		if err != nil {
 			handler.SendError("submit_failed", err.Error())
+			return
+		}
+		handler.SendBatchAck(len(tasks)-len(rejected), rejected)
+	})
+
+	// Handle cancel task
+	handler.OnCancelTask(func(taskID string) {
+		if w == nil {
+			handler.SendError("not_initialized", "Worker not initialized")
+			return
+		}
+
+		if !w.Cancel(taskID) {
+			handler.SendLogFields("warn", fmt.Sprintf("Task %s is not queued or running", taskID), map[string]any{
+				"task_id": taskID,
+			})
 		}
 	})
 
-	// Handle pause
+	// Handle pause; workers stay up and pick up where they left off on
+	// resume
 	handler.OnPause(func() {
 		if w != nil {
-			w.Stop()
+			w.Pause()
 		}
 	})
 
 	// Handle resume
 	handler.OnResume(func() {
 		if w != nil {
-			w.Start()
+			w.Resume()
 		}
 	})
 
@@ -152,39 +602,80 @@ func runIPCMode() {
 			return
 		}
 
-		workerStats := w.Stats()
-		proxyStats := proxyPool.Stats()
-
-		// Calculate ETA
-		var etaMs int64
-		if workerStats.RequestsPerSec > 0 {
-			remaining := workerStats.TasksTotal - workerStats.TasksCompleted - workerStats.TasksFailed
-			etaMs = int64(float64(remaining) / workerStats.RequestsPerSec * 1000)
-		}
-
-		handler.SendStats(&protocol.StatsData{
-			TasksTotal:     workerStats.TasksTotal,
-			TasksCompleted: workerStats.TasksCompleted,
-			TasksFailed:    workerStats.TasksFailed,
-			TasksPending:   int64(w.TaskQueueLength()),
-			URLsFound:      workerStats.URLsFound,
-			CaptchaCount:   workerStats.CaptchaCount,
-			BlockCount:     workerStats.BlockCount,
-			ProxiesAlive:   proxyStats.Alive,
-			ProxiesDead:    proxyStats.Dead,
-			RequestsPerSec: workerStats.RequestsPerSec,
-			ElapsedMs:      workerStats.TotalDuration.Milliseconds(),
-			ETAMs:          etaMs,
+		handler.SendStats(buildStats(w, proxyPool))
+	})
+
+	handler.OnGetHealth(func() {
+		if w == nil {
+			handler.SendHealth(&protocol.HealthData{Goroutines: runtime.NumGoroutine()})
+			return
+		}
+
+		health := w.Health()
+		handler.SendHealth(&protocol.HealthData{
+			Goroutines:     health.Goroutines,
+			TaskQueueLen:   health.TaskQueueLen,
+			ResultQueueLen: health.ResultQueueLen,
+			HeapAllocBytes: health.HeapAllocBytes,
+			ActiveWorkers:  health.ActiveWorkers,
+			Workers:        health.Workers,
 		})
 	})
 
+	handler.OnGetProxies(func(status string) {
+		if proxyPool == nil {
+			handler.SendProxies([]protocol.ProxyData{})
+			return
+		}
+
+		snapshots := proxyPool.Snapshot(proxy.ProxyStatus(status))
+		proxies := make([]protocol.ProxyData, len(snapshots))
+		for i, s := range snapshots {
+			proxies[i] = protocol.ProxyData{
+				ID:            s.ID,
+				Host:          s.Host,
+				Port:          s.Port,
+				Type:          string(s.Type),
+				Status:        string(s.Status),
+				SuccessRate:   s.SuccessRate,
+				Score:         s.Score,
+				AvgLatency:    s.AvgLatency.Milliseconds(),
+				TotalRequests: s.TotalRequests,
+				Captchas:      s.CaptchaCount,
+			}
+		}
+		handler.SendProxies(proxies)
+	})
+
+	handler.OnExportProxyStats(func(path, format string) {
+		if proxyPool == nil {
+			handler.SendError("export_error", "no proxies loaded")
+			return
+		}
+		if err := exportProxyStats(proxyPool, path, format); err != nil {
+			handler.SendError("export_error", err.Error())
+			return
+		}
+		handler.SendStatus("proxy_stats_exported", path)
+	})
+
 	// Handle shutdown
 	handler.OnShutdown(func() {
 		if w != nil {
-			w.Stop()
+			// Running tasks finish and their results go out before the
+			// shutdown status
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownDrainTimeout)
+			if err := w.Drain(ctx); err != nil {
+				handler.SendLog("warn", fmt.Sprintf("Shutdown cancelled running tasks: %v", err))
+			}
+			cancel()
+			<-resultsDone
 		}
 		if proxyPool != nil {
 			proxyPool.StopHealthCheck()
+			if err := saveProxyState(proxyPool, proxyStatePath); err != nil {
+				handler.SendLog("warn", err.Error())
+			}
 		}
 	})
 
@@ -197,6 +688,12 @@ func runIPCMode() {
 		if w != nil {
 			w.Stop()
 		}
+		if proxyPool != nil {
+			if err := saveProxyState(proxyPool, proxyStatePath); err != nil {
+				handler.SendLog("warn", err.Error())
+			}
+		}
+		handler.Seal()
 		os.Exit(0)
 	}()
 
@@ -204,116 +701,337 @@ func runIPCMode() {
 	handler.Start()
 }
 
-func processResults(handler *protocol.Handler, w *worker.Worker) {
-	for result := range w.Results() {
-		// Convert URLs to string slice
-		urls := make([]string, len(result.URLs))
-		for i, u := range result.URLs {
-			urls[i] = u.URL
+// processResults forwards results until the worker stops, then closes done
+func processResults(handler *protocol.Handler, w *worker.Worker, proxyPool *proxy.Pool, batcher *protocol.ResultBatcher, dorkProgress bool, done chan struct{}) {
+	defer close(done)
+	results := w.Results()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				batcher.Flush()
+				if w.HitDeadline() {
+					stats := w.Stats()
+					handler.SendStatus("deadline_reached", fmt.Sprintf("Max runtime reached: %d completed, %d failed, %d pending",
+						stats.TasksCompleted, stats.TasksFailed, stats.TasksTotal-stats.TasksCompleted-stats.TasksFailed))
+				}
+				return
+			}
+			sendResult(handler, w, batcher, result, dorkProgress)
+
+		case <-w.Drained():
+			// Results of the finished tasks are already queued; flush them
+			// so complete is the last message of the run
+			for flushed := false; !flushed; {
+				select {
+				case result, ok := <-results:
+					if !ok {
+						batcher.Flush()
+						return
+					}
+					sendResult(handler, w, batcher, result, dorkProgress)
+				default:
+					flushed = true
+				}
+			}
+
+			batcher.Flush()
+			if w.IsDrained() {
+				handler.SendComplete(buildStats(w, proxyPool))
+			}
+		}
+	}
+}
+
+// sendResult forwards a worker result, through batcher when set, and the
+// resulting progress, naming the finished dork in the progress when
+// dorkProgress is set
+func sendResult(handler *protocol.Handler, w *worker.Worker, batcher *protocol.ResultBatcher, result *worker.Result, dorkProgress bool) {
+	// Convert URLs to string slice
+	urls := make([]string, len(result.URLs))
+	var engines map[string][]string
+	for i, u := range result.URLs {
+		urls[i] = u.URL
+		if len(u.Engines) > 0 {
+			if engines == nil {
+				engines = make(map[string][]string, len(result.URLs))
+			}
+			engines[u.URL] = u.Engines
 		}
+	}
+
+	data := &protocol.ResultData{
+		TaskID:    result.TaskID,
+		Dork:      result.Dork,
+		URLs:      urls,
+		Status:    string(result.Status),
+		Error:     result.Error,
+		ProxyID:   result.ProxyID,
+		Duration:  result.Duration.Milliseconds(),
+		Pages:     result.Pages,
+		Engines:   engines,
+		Warnings:  result.Warnings,
+		Truncated: result.Truncated,
+
+		StatusCode: result.StatusCode,
+		Blocked:    result.Status == worker.StatusBlocked,
+		Captcha:    result.Status == worker.StatusCaptcha,
+		Latency:    result.Latency.Milliseconds(),
+	}
+	if batcher != nil {
+		batcher.Add(data)
+	} else {
+		handler.SendResult(data)
+	}
 
-		handler.SendResult(&protocol.ResultData{
-			TaskID:   result.TaskID,
-			Dork:     result.Dork,
-			URLs:     urls,
-			Status:   string(result.Status),
-			Error:    result.Error,
-			ProxyID:  result.ProxyID,
-			Duration: result.Duration.Milliseconds(),
+	// Log request errors with enough context to trace the proxy and dork;
+	// CAPTCHAs and blocks are reported through the result status alone
+	if result.Status == worker.StatusError {
+		handler.SendLogFields("warn", fmt.Sprintf("Task %s failed: %s", result.TaskID, result.Error), map[string]any{
+			"task_id":  result.TaskID,
+			"dork":     result.Dork,
+			"proxy_id": result.ProxyID,
+			"status":   string(result.Status),
 		})
+	}
 
-		// Send progress update every result
-		stats := w.Stats()
-		if stats.TasksTotal > 0 {
-			percentage := float64(stats.TasksCompleted+stats.TasksFailed) / float64(stats.TasksTotal) * 100
-			handler.SendProgress(&protocol.ProgressData{
-				Current:    stats.TasksCompleted + stats.TasksFailed,
-				Total:      stats.TasksTotal,
-				Percentage: percentage,
-			})
+	// Send progress update every result
+	stats := w.Stats()
+	if stats.TasksTotal > 0 {
+		percentage := float64(stats.TasksCompleted+stats.TasksFailed) / float64(stats.TasksTotal) * 100
+		progress := &protocol.ProgressData{
+			Current:    stats.TasksCompleted + stats.TasksFailed,
+			Total:      stats.TasksTotal,
+			Percentage: percentage,
+		}
+		if dorkProgress {
+			progress.Dork = result.Dork
+			progress.DorkURLs = len(urls)
 		}
+		handler.SendProgress(progress)
+	}
+}
+
+// buildStats collects worker and proxy pool statistics for the controller
+func buildStats(w *worker.Worker, proxyPool *proxy.Pool) *protocol.StatsData {
+	workerStats := w.Stats()
+	proxyStats := proxyPool.Stats()
+
+	// Calculate ETA from the recent rate, which follows slowdowns, or the
+	// lifetime rate while nothing finished lately
+	var etaMs int64
+	rate := workerStats.RequestsPerSecRecent
+	if rate <= 0 {
+		rate = workerStats.RequestsPerSec
+	}
+	if rate > 0 {
+		remaining := workerStats.TasksTotal - workerStats.TasksCompleted - workerStats.TasksFailed
+		etaMs = int64(float64(remaining) / rate * 1000)
+	}
+
+	// The run ends at the deadline even if the queue is not empty
+	remainingMs := workerStats.RemainingRuntime.Milliseconds()
+	if remainingMs > 0 && (etaMs == 0 || etaMs > remainingMs) {
+		etaMs = remainingMs
+	}
+
+	return &protocol.StatsData{
+		TasksTotal:         workerStats.TasksTotal,
+		TasksCompleted:     workerStats.TasksCompleted,
+		TasksFailed:        workerStats.TasksFailed,
+		TasksPending:       int64(w.TaskQueueLength()),
+		RetryQueued:        workerStats.RetryQueued,
+		URLsFound:          workerStats.URLsFound,
+		URLsFiltered:       workerStats.URLsFiltered,
+		CaptchaCount:       workerStats.CaptchaCount,
+		BlockCount:         workerStats.BlockCount,
+		ResultsDropped:     workerStats.ResultsDropped,
+		UniqueDomains:      workerStats.UniqueDomains,
+		WireBytes:          workerStats.WireBytes,
+		DecodedBytes:       workerStats.DecodedBytes,
+		ProxiesAlive:       proxyStats.Alive,
+		ProxiesDead:        proxyStats.Dead,
+		ProxiesQuarantined: proxyStats.Quarantined,
+		ProxiesEvicted:     proxyStats.Evicted,
+		RequestsPerSec:     workerStats.RequestsPerSec,
+		ElapsedMs:          workerStats.TotalDuration.Milliseconds(),
+		ETAMs:              etaMs,
+		RemainingMs:        remainingMs,
+
+		RequestsPerSecRecent: workerStats.RequestsPerSecRecent,
+
+		EffectiveConcurrency: workerStats.EffectiveConcurrency,
+
+		BreakerState: string(workerStats.BreakerState),
+		BreakerTrips: workerStats.BreakerTrips,
 	}
 }
 
-func runStandaloneMode(dorkFile, proxyFile, outputDir string, numWorkers int) {
+func runStandaloneMode(dorkFile, dork, outputTarget string, format output.Format, maxFileSize int64, checkpoint string, checkpointInterval time.Duration, skip, limit int, vars map[string][]string, maxExpansions int, config *protocol.InitConfig) {
 	printBanner()
 
-	if dorkFile == "" || proxyFile == "" {
+	if (dorkFile == "" && dork == "") || (config.ProxyFile == "" && config.ProxyURL == "" && len(config.Proxies) == 0 && !config.DryRun) {
 		fmt.Println("Usage: dorker-worker --standalone --dorks <file> --proxies <file> [options]")
 		fmt.Println()
 		fmt.Println("Options:")
-		fmt.Println("  --dorks     Path to dorks file (required)")
-		fmt.Println("  --proxies   Path to proxies file (required)")
-		fmt.Println("  --output    Output directory (default: ./output)")
+		fmt.Println("  --dorks     Path to dorks file, or - to read stdin (required unless --dork)")
+		fmt.Println("  --dork      Run this one dork instead of a dorks file")
+		fmt.Println("  --proxies   Path to proxies file, or - to read stdin (required unless set in --config)")
+		fmt.Println("  --output    Output directory, or sqlite:<file> (default: ./output)")
 		fmt.Println("  --workers   Number of workers (default: 10)")
+		fmt.Println("  --config    JSON init config file (flags override its values)")
+		fmt.Println("  --max-runtime  Stop after this duration, e.g. 45m (default: no limit)")
+		fmt.Println("  --max-file-size  Roll over output files past this size, e.g. 100MB (default: no limit)")
+		fmt.Println("  --format    Output file format: txt, jsonl or csv (default: txt)")
+		fmt.Println("  --time-range  Only results indexed within the past d, w, m or y (default: any time)")
+		fmt.Println("  --checkpoint  Save progress to this file and resume from it if it exists")
+		fmt.Println("  --checkpoint-interval  How often to save the checkpoint (default: 30s)")
+		fmt.Println("  --dry-run   Output the search URLs each dork would request; no proxies needed")
+		fmt.Println("  --strict-dorks  Fail dorks that cannot match anything, e.g. only exclusions")
+		fmt.Println("  --skip      Skip the first N dorks (default: 0)")
+		fmt.Println("  --limit     Process at most N dorks after --skip (default: all)")
+		fmt.Println("  --seed      Seed random choices to make runs reproducible (default: random)")
+		fmt.Println("  --vars      JSON file mapping {name} template variables to wordlist files")
+		fmt.Println("  --max-expansions  Expand dork templates into at most N dorks in total (default: 10000)")
+		fmt.Println("  --test-proxies  Health check the proxies and exit, with --quick for TCP only")
+		fmt.Println("  --alive-out  With --test-proxies, write the proxies that pass to this file")
 		fmt.Println("  --version   Show version")
 		fmt.Println()
 		fmt.Println("Example:")
 		fmt.Println("  dorker-worker --standalone --dorks dorks.txt --proxies proxies.txt --workers 20")
+		fmt.Println("  cat dorks.txt | dorker-worker --dorks - --proxies proxies.txt")
 		fmt.Println()
 		os.Exit(1)
 	}
 
-	// Create proxy pool
-	fmt.Println("Loading proxies...")
-	poolConfig := proxy.DefaultPoolConfig()
+	// Create proxy pool; a dry run needs no proxies
+	poolConfig, err := newPoolConfig(config)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
 	proxyPool := proxy.NewPool(poolConfig)
+	if config.DryRun {
+		fmt.Println("Dry run: writing search URLs to the output, no requests are sent")
+		config.ProxyState = "" // Leave saved state alone, the pool is empty
+	} else {
+		loadStandaloneProxies(proxyPool, config)
+	}
 
-	added, errs := proxyPool.LoadFromFile(proxyFile)
-	fmt.Printf("✓ Loaded %d proxies\n", added)
-	if len(errs) > 0 {
-		fmt.Printf("⚠ %d proxy errors\n", len(errs))
+	// Load dorks
+	dorks := []string{dork}
+	if dork == "" {
+		fmt.Println("Loading dorks...")
+		var err error
+		dorks, err = loadDorks(dorkFile)
+		if err != nil {
+			fmt.Printf("✗ Failed to load dorks: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Loaded %d dorks\n", len(dorks))
 	}
 
-	if added == 0 {
-		fmt.Println("✗ No valid proxies found")
-		os.Exit(1)
+	// Expand {name} templates into a dork per combination of their words
+	if len(vars) > 0 {
+		templates := len(dorks)
+		var truncated []string
+		dorks, truncated = engine.ExpandDorks(dorks, vars, maxExpansions)
+		fmt.Printf("✓ Expanded %d templates into %d dorks\n", templates, len(dorks))
+		if len(truncated) > 0 {
+			fmt.Printf("⚠ Template expansion capped at %d dorks, %d templates truncated\n", maxExpansions, len(truncated))
+		}
+		if len(dorks) == 0 {
+			fmt.Println("✗ No dorks left after expanding templates")
+			os.Exit(1)
+		}
 	}
 
-	// Load dorks
-	fmt.Println("Loading dorks...")
-	dorks, err := loadDorks(dorkFile)
-	if err != nil {
-		fmt.Printf("✗ Failed to load dorks: %v\n", err)
-		os.Exit(1)
+	// Run only a slice of the dorks; task IDs keep the dorks' positions
+	// in the file so checkpoints of different slices do not collide
+	loaded := len(dorks)
+	dorks = sliceDorks(dorks, skip, limit)
+	if skip > 0 || limit > 0 {
+		if len(dorks) == 0 {
+			fmt.Printf("✗ --skip %d leaves none of the %d dorks\n", skip, loaded)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Running dorks %d-%d of %d\n", skip+1, skip+len(dorks), loaded)
 	}
-	fmt.Printf("✓ Loaded %d dorks\n", len(dorks))
 
-	// Create output directory
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		fmt.Printf("✗ Failed to create output directory: %v\n", err)
+	// Create output writer
+	outputWriter, err := newOutputWriter(outputTarget, format, maxFileSize)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
 		os.Exit(1)
 	}
 
 	// Create worker
-	workerConfig := worker.DefaultConfig()
-	workerConfig.Workers = numWorkers
+	engines, err := newEngines(config)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
+	workerConfig, err := workerConfigFromInit(config)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
 	w := worker.New(workerConfig, proxyPool)
+	w.SetEngines(engines...)
+	if config.FingerprintsFile != "" {
+		fingerprints, n, err := loadFingerprints(config.FingerprintsFile, config.Seed)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			os.Exit(1)
+		}
+		w.SetStealthManager(fingerprints)
+		fmt.Printf("✓ Loaded %d fingerprints\n", n)
+	}
+	w.OnPoolCooldown(func(until time.Time) {
+		fmt.Printf("\n⚠ All proxies cooling down, pausing for %s\n", time.Until(until).Round(time.Second))
+	})
+	w.OnBreaker(func(state worker.BreakerState, until time.Time) {
+		switch state {
+		case worker.BreakerOpen:
+			fmt.Printf("\n⚠ Block rate spiked, halting requests for %s\n", time.Until(until).Round(time.Second))
+		case worker.BreakerClosed:
+			fmt.Printf("\n✓ Block rate recovered, resuming requests\n")
+		}
+	})
+
+	// Resume a previous run; dorks it finished are not submitted again
+	var resumed int64
+	if checkpoint != "" {
+		if _, err := w.LoadCheckpoint(checkpoint); err == nil {
+			stats := w.Stats()
+			resumed = stats.TasksCompleted + stats.TasksFailed
+			fmt.Printf("✓ Resuming from %s: %d dorks already done\n", checkpoint, resumed)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("✗ %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	// Start worker
 	fmt.Println()
-	fmt.Printf("Starting %d workers...\n", numWorkers)
+	fmt.Printf("Starting %d workers...\n", config.Workers)
 	w.Start()
-	proxyPool.StartHealthCheck()
-
-	// Create output file
-	outputFile, err := os.Create(fmt.Sprintf("%s/results_%d.txt", outputDir, time.Now().Unix()))
-	if err != nil {
-		fmt.Printf("✗ Failed to create output file: %v\n", err)
-		os.Exit(1)
+	if !config.DryRun {
+		proxyPool.StartHealthCheck()
 	}
-	defer outputFile.Close()
 
 	// Process results in background
 	done := make(chan struct{})
-	var urlCount int64
 	go func() {
 		for result := range w.Results() {
-			for _, u := range result.URLs {
-				outputFile.WriteString(u.URL + "\n")
-				urlCount++
+			if err := outputWriter.Write(result); err != nil {
+				fmt.Printf("\n⚠ Failed to write result: %v\n", err)
 			}
 		}
+		if err := outputWriter.Close(); err != nil {
+			fmt.Printf("\n⚠ Failed to close output file: %v\n", err)
+		}
 		close(done)
 	}()
 
@@ -321,13 +1039,31 @@ func runStandaloneMode(dorkFile, proxyFile, outputDir string, numWorkers int) {
 	fmt.Println("Processing dorks...")
 	fmt.Println()
 
+	var pending []*worker.Task
 	for i, dork := range dorks {
-		w.Submit(&worker.Task{
-			ID:   fmt.Sprintf("task_%d", i),
-			Dork: dork,
+		id := fmt.Sprintf("task_%d", skip+i)
+		if w.Finished(id) {
+			continue
+		}
+		pending = append(pending, &worker.Task{
+			ID:        id,
+			Dork:      dork,
+			Sticky:    config.StickyProxy,
+			TimeRange: config.TimeRange,
 		})
 	}
 
+	// The task buffer holds fewer dorks than a large file, so feed it in
+	// the background and wait for room as the workers drain it
+	go func() {
+		for i, task := range pending {
+			if err := w.SubmitWait(task); err != nil {
+				fmt.Printf("\n⚠ %d dorks were not submitted: %v\n", len(pending)-i, err)
+				return
+			}
+		}
+	}()
+
 	// Wait for completion
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
@@ -335,76 +1071,424 @@ func runStandaloneMode(dorkFile, proxyFile, outputDir string, numWorkers int) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// Without a checkpoint file the channel stays nil and never fires
+	var checkpointC <-chan time.Time
+	if checkpoint != "" {
+		checkpointTicker := time.NewTicker(checkpointInterval)
+		defer checkpointTicker.Stop()
+		checkpointC = checkpointTicker.C
+	}
+
 	for {
 		select {
 		case <-sigCh:
 			fmt.Println("\n\nInterrupted. Shutting down...")
 			w.Stop()
 			proxyPool.StopHealthCheck()
+			if err := saveProxyState(proxyPool, config.ProxyState); err != nil {
+				fmt.Printf("⚠ %v\n", err)
+			}
 			<-done
-			printFinalStats(w, urlCount, outputDir)
+			saveCheckpoint(w, checkpoint)
+			printFinalStats(w, outputLabel(outputTarget, format), outputWriter.Files())
+			writeProxyReport(proxyPool, outputTarget)
 			os.Exit(0)
 
+		case <-w.DeadlineReached():
+			fmt.Printf("\n\nMax runtime of %v reached. Shutting down...\n", config.MaxRuntime)
+			proxyPool.StopHealthCheck()
+			if err := saveProxyState(proxyPool, config.ProxyState); err != nil {
+				fmt.Printf("⚠ %v\n", err)
+			}
+			<-done
+			saveCheckpoint(w, checkpoint)
+			printFinalStats(w, outputLabel(outputTarget, format), outputWriter.Files())
+			writeProxyReport(proxyPool, outputTarget)
+			return
+
+		case <-checkpointC:
+			saveCheckpoint(w, checkpoint)
+
 		case <-ticker.C:
 			stats := w.Stats()
 			proxyStats := proxyPool.Stats()
 
-			completed := stats.TasksCompleted + stats.TasksFailed
-			total := stats.TasksTotal
-			percentage := float64(completed) / float64(total) * 100
+			// Progress covers the dorks this run submits, not the whole
+			// file or those a resumed checkpoint already finished
+			completed := stats.TasksCompleted + stats.TasksFailed - resumed
+			total := int64(len(pending))
+			percentage := 100.0
+			if total > 0 {
+				percentage = float64(completed) / float64(total) * 100
+			}
 
 			fmt.Printf("\r[%.1f%%] %d/%d dorks | %d URLs | %.1f req/s | Proxies: %d alive",
-				percentage, completed, total, urlCount, stats.RequestsPerSec, proxyStats.Alive)
+				percentage, completed, total, outputWriter.Count(), stats.RequestsPerSecRecent, proxyStats.Alive)
 
 			if completed >= total {
 				fmt.Println()
 				w.Stop()
 				proxyPool.StopHealthCheck()
+				if err := saveProxyState(proxyPool, config.ProxyState); err != nil {
+					fmt.Printf("⚠ %v\n", err)
+				}
 				<-done
-				printFinalStats(w, urlCount, outputDir)
+				saveCheckpoint(w, checkpoint)
+				printFinalStats(w, outputLabel(outputTarget, format), outputWriter.Files())
+				writeProxyReport(proxyPool, outputTarget)
 				return
 			}
 		}
 	}
 }
 
-func loadDorks(filepath string) ([]string, error) {
-	file, err := os.Open(filepath)
-	if err != nil {
-		return nil, err
+// newOutputWriter opens the standalone output: a SQLite database for a
+// sqlite:<file> target, otherwise files of the given format in the
+// target directory
+func newOutputWriter(target string, format output.Format, maxFileSize int64) (output.Writer, error) {
+	if path, ok := strings.CutPrefix(target, "sqlite:"); ok {
+		return output.NewSQLiteWriter(path, output.DefaultSQLiteBatchSize)
 	}
-	defer file.Close()
 
-	var dorks []string
-	var buf [4096]byte
-	var line []byte
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return output.NewFormatWriter(target, format, maxFileSize)
+}
 
-	for {
-		n, err := file.Read(buf[:])
-		if n == 0 {
-			if len(line) > 0 {
-				dorks = append(dorks, string(line))
-			}
-			break
+// outputLabel names the output format for the final stats
+func outputLabel(target string, format output.Format) string {
+	if strings.HasPrefix(target, "sqlite:") {
+		return "sqlite"
+	}
+	return string(format)
+}
+
+// loadStandaloneProxies loads and optionally warms up the proxies for a
+// standalone run, exiting when none are usable
+func loadStandaloneProxies(proxyPool *proxy.Pool, config *protocol.InitConfig) {
+	fmt.Println("Loading proxies...")
+	added, errs := loadProxies(proxyPool, config)
+	fmt.Printf("✓ Loaded %d proxies\n", added)
+	if len(errs) > 0 {
+		fmt.Printf("⚠ %d proxy errors\n", len(errs))
+	}
+
+	if err := loadProxyState(proxyPool, config.ProxyState); err != nil {
+		fmt.Printf("⚠ Proxy state not restored: %v\n", err)
+	}
+
+	if added == 0 {
+		fmt.Println("✗ No valid proxies found")
+		os.Exit(1)
+	}
+
+	// Lazily check only as many proxies as needed
+	if config.TargetAlive > 0 {
+		fmt.Printf("Checking proxies until %d are alive...\n", config.TargetAlive)
+		checked := proxyPool.WarmUp(context.Background())
+		fmt.Printf("✓ %d alive after checking %d proxies\n", proxyPool.Stats().Alive, checked)
+	}
+
+	// Checks, or a saved state, can leave nothing to search through;
+	// requests never go out directly, so stop here
+	if stats := proxyPool.Stats(); stats.Alive == 0 {
+		fmt.Printf("✗ No alive proxies found (%d dead, %d quarantined)\n", stats.Dead, stats.Quarantined)
+		if config.ProxyState != "" && !config.ProxyStateRecheck {
+			fmt.Println("  Set proxy_state_recheck to health check proxies saved as dead")
 		}
+		os.Exit(1)
+	}
+}
 
-		for i := 0; i < n; i++ {
-			if buf[i] == '\n' {
-				if len(line) > 0 && line[0] != '#' {
-					dorks = append(dorks, string(line))
-				}
-				line = line[:0]
-			} else if buf[i] != '\r' {
-				line = append(line, buf[i])
-			}
+// runProxyTest health checks the configured proxies, prints a report on
+// each and exits, with status 1 when none pass
+func runProxyTest(config *protocol.InitConfig, quick bool, aliveOut string) {
+	printBanner()
+
+	if config.ProxyFile == "-" {
+		lines, err := readLines(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ --proxies: %v\n", err)
+			os.Exit(1)
 		}
+		config.Proxies = append(config.Proxies, lines...)
+		config.ProxyFile = ""
+	}
+	if config.ProxyFile == "" && config.ProxyURL == "" && len(config.Proxies) == 0 {
+		fmt.Println("Usage: dorker-worker --test-proxies --proxies <file> [--quick] [--alive-out <file>]")
+		os.Exit(1)
+	}
 
+	// Proxies join an unchecked pool as alive, in list order
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+	added, errs := loadProxies(pool, config)
+	fmt.Printf("✓ Loaded %d proxies\n", added)
+	if len(errs) > 0 {
+		fmt.Printf("⚠ %d proxy errors\n", len(errs))
+	}
+	if added == 0 {
+		fmt.Println("✗ No valid proxies found")
+		os.Exit(1)
+	}
+	proxies := pool.GetAllAlive()
+
+	checker := proxy.NewHealthChecker()
+	var results proxy.CheckResults
+	if quick {
+		fmt.Printf("Checking %d proxies accept connections...\n", len(proxies))
+		results = checker.QuickCheckAll(context.Background(), proxies)
+	} else {
+		fmt.Printf("Checking %d proxies against %s...\n", len(proxies), checker.CheckURL)
+		results = checker.CheckAll(context.Background(), proxies)
+	}
+
+	fmt.Println()
+	printCheckResults(results)
+	fmt.Println()
+	fmt.Printf("✓ %s\n", results.Summary())
+
+	alive := results.Alive()
+	if aliveOut != "" {
+		if err := writeProxyList(aliveOut, alive); err != nil {
+			fmt.Printf("✗ Failed to write alive proxies: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ %d alive proxies written to %s\n", len(alive), aliveOut)
+	}
+	if len(alive) == 0 {
+		os.Exit(1)
+	}
+}
+
+// printCheckResults prints a table of proxy health check results
+func printCheckResults(results proxy.CheckResults) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tADDRESS\tSTATUS\tLATENCY\tEXTERNAL IP\tERROR")
+	for _, result := range results {
+		prx := result.Proxy
+		status, latency := "dead", "-"
+		if result.Alive {
+			status, latency = "alive", result.Latency.Round(time.Millisecond).String()
+		}
+		externalIP := result.ExternalIP
+		if externalIP == "" {
+			externalIP = "-"
+		}
+		var errText string
+		if result.Err != nil {
+			errText = result.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", prx.ID, prx.Type, net.JoinHostPort(prx.Host, prx.Port),
+			status, latency, externalIP, errText)
+	}
+	tw.Flush()
+}
+
+// writeProxyList writes proxies to path one URL per line, in the format
+// --proxies reads
+func writeProxyList(path string, proxies []*proxy.Proxy) error {
+	var buf bytes.Buffer
+	for _, prx := range proxies {
+		buf.WriteString(prx.URL() + "\n")
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// loadProxies registers the configured proxy sources and loads them
+func loadProxies(pool *proxy.Pool, config *protocol.InitConfig) (int, []error) {
+	if config.ProxyFile != "" {
+		pool.AddSource(proxy.NewFileSource(config.ProxyFile))
+	}
+	if config.ProxyURL != "" {
+		pool.AddSource(proxy.NewHTTPSource(config.ProxyURL))
+	}
+	if len(config.Proxies) > 0 {
+		pool.AddSource(proxy.NewStaticSource(config.Proxies))
+	}
+
+	return pool.LoadSources(context.Background())
+}
+
+// newPoolConfig builds the proxy pool configuration from init config,
+// failing on an unknown proxy_selection
+func newPoolConfig(config *protocol.InitConfig) (proxy.PoolConfig, error) {
+	poolConfig := proxy.DefaultPoolConfig()
+	poolConfig.TargetAlive = config.TargetAlive
+	poolConfig.SourceRefreshInterval = config.ProxyRefresh
+	poolConfig.RecheckDead = config.ProxyStateRecheck
+	poolConfig.QuarantineThreshold = config.QuarantineThreshold
+	poolConfig.MaxConcurrentPerProxy = config.MaxConcurrentPerProxy
+	poolConfig.FailureCooldown = config.FailureCooldown
+	poolConfig.MaxRetryAfter = config.MaxRetryAfter
+	poolConfig.EvictAfterDeadDuration = config.EvictAfterDeadDuration
+	poolConfig.Seed = config.Seed
+	poolConfig.RotateEveryRequest = config.RotateEveryRequest
+	if config.QuarantineDuration > 0 {
+		poolConfig.QuarantineDuration = config.QuarantineDuration
+	}
+	selection, err := proxy.ParseSelectionStrategy(config.ProxySelection)
+	if err != nil {
+		return poolConfig, err
+	}
+	poolConfig.Selection = selection
+	return poolConfig, nil
+}
+
+// loadProxyState restores saved proxy stats when a state file is set. A
+// missing file is just a first run.
+func loadProxyState(pool *proxy.Pool, path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := pool.LoadState(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// saveCheckpoint saves the run's progress to path, if set, warning on
+// failure
+func saveCheckpoint(w *worker.Worker, path string) {
+	if path == "" {
+		return
+	}
+	if err := w.SaveCheckpoint(path); err != nil {
+		fmt.Printf("\n⚠ %v\n", err)
+	}
+}
+
+// saveProxyState saves proxy stats for the next run when a state file is set
+func saveProxyState(pool *proxy.Pool, path string) error {
+	if path == "" {
+		return nil
+	}
+	return pool.SaveState(path)
+}
+
+// exportFormat returns the proxy stats export format, going by the file
+// extension when none is given
+func exportFormat(path, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return proxy.ExportJSON
+	}
+	return proxy.ExportCSV
+}
+
+// exportProxyStats writes the per-proxy stats to path. The file is only
+// created once the format is known to be valid.
+func exportProxyStats(pool *proxy.Pool, path, format string) error {
+	var buf bytes.Buffer
+	if err := pool.ExportStats(&buf, exportFormat(path, format)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write proxy stats: %w", err)
+	}
+	return nil
+}
+
+// proxyStatsPath places the standalone proxy stats export alongside the
+// results: in the output directory, or next to a SQLite database
+func proxyStatsPath(target string) string {
+	if path, ok := strings.CutPrefix(target, "sqlite:"); ok {
+		return filepath.Join(filepath.Dir(path), "proxy_stats.csv")
+	}
+	return filepath.Join(target, "proxy_stats.csv")
+}
+
+// writeProxyReport exports the per-proxy stats at the end of a standalone
+// run
+func writeProxyReport(pool *proxy.Pool, target string) {
+	path := proxyStatsPath(target)
+	if err := exportProxyStats(pool, path, proxy.ExportCSV); err != nil {
+		fmt.Printf("⚠ %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Proxy stats saved to %s\n", path)
+}
+
+// sliceDorks drops the first skip dorks and keeps at most limit of the
+// rest (0 = all)
+func sliceDorks(dorks []string, skip, limit int) []string {
+	if skip >= len(dorks) {
+		return nil
+	}
+	dorks = dorks[skip:]
+	if limit > 0 && limit < len(dorks) {
+		dorks = dorks[:limit]
+	}
+	return dorks
+}
+
+// loadDorks reads dorks from a file, or from stdin when filepath is -
+func loadDorks(filepath string) ([]string, error) {
+	if filepath == "-" {
+		return readLines(os.Stdin)
+	}
+	return readLinesFile(filepath)
+}
+
+// loadVars reads a JSON object mapping template variable names to
+// wordlist files, relative paths being taken from the vars file's
+// directory, and returns each variable's words
+func loadVars(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var files map[string]string
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	vars := make(map[string][]string, len(files))
+	for name, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		words, err := readLinesFile(file)
 		if err != nil {
-			break
+			return nil, fmt.Errorf("variable %s: %w", name, err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("variable %s: %s has no words", name, file)
+		}
+		vars[name] = words
+	}
+	return vars, nil
+}
+
+// readLinesFile reads the lines of a file as readLines does
+func readLinesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readLines(file)
+}
+
+// readLines reads non-empty lines from r, trimmed of surrounding space
+// and skipping # comments the way the proxy parser does
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
+		lines = append(lines, line)
 	}
 
-	return dorks, nil
+	return lines, scanner.Err()
 }
 
 func printBanner() {
@@ -423,7 +1507,7 @@ func printBanner() {
 	fmt.Println()
 }
 
-func printFinalStats(w *worker.Worker, urlCount int64, outputDir string) {
+func printFinalStats(w *worker.Worker, format string, files []string) {
 	stats := w.Stats()
 
 	fmt.Println()
@@ -434,18 +1518,32 @@ func printFinalStats(w *worker.Worker, urlCount int64, outputDir string) {
 	fmt.Printf("  Total Dorks:      %d\n", stats.TasksTotal)
 	fmt.Printf("  Completed:        %d\n", stats.TasksCompleted)
 	fmt.Printf("  Failed:           %d\n", stats.TasksFailed)
-	fmt.Printf("  URLs Found:       %d\n", urlCount)
+	fmt.Printf("  URLs Found:       %d\n", stats.URLsFound)
+	if stats.URLsFiltered > 0 {
+		fmt.Printf("  URLs Filtered:    %d\n", stats.URLsFiltered)
+	}
+	if stats.UniqueDomains > 0 {
+		fmt.Printf("  Unique Domains:   %d\n", stats.UniqueDomains)
+	}
 	fmt.Printf("  CAPTCHAs:         %d\n", stats.CaptchaCount)
 	fmt.Printf("  Blocks:           %d\n", stats.BlockCount)
+	if stats.ResultsDropped > 0 {
+		fmt.Printf("  Results Dropped:  %d\n", stats.ResultsDropped)
+	}
+	if stats.BreakerTrips > 0 {
+		fmt.Printf("  Breaker Trips:    %d\n", stats.BreakerTrips)
+	}
 	fmt.Printf("  Duration:         %s\n", stats.TotalDuration.Round(time.Second))
 	fmt.Printf("  Avg Speed:        %.1f req/s\n", stats.RequestsPerSec)
 	fmt.Println()
-	fmt.Printf("  Results saved to: %s/\n", outputDir)
+	fmt.Printf("  Results saved to (%s):\n", format)
+	for _, file := range files {
+		fmt.Printf("    %s\n", file)
+	}
 	fmt.Println()
 }
 
 // Blank imports to ensure packages are included
 var (
-	_ = engine.NewGoogle
 	_ = stealth.NewManager
 )
@@ -1,50 +1,34 @@
 module dorker/worker
 
-go 1.22
+// Go 1.24 and the x/crypto, x/net and x/sys versions below are the
+// minimums of github.com/refraction-networking/utls v1.8, which
+// tls_fingerprint uses to send browser ClientHellos.
+go 1.24
 
 require (
-	github.com/Danny-Dasilva/CycleTLS/cycletls v1.0.26
-	github.com/PuerkitoBio/goquery v1.8.1
-	github.com/bits-and-blooms/bloom/v3 v3.6.0
-	github.com/corpix/uarand v0.2.0
-	github.com/goccy/go-json v0.10.2
-	github.com/panjf2000/ants/v2 v2.9.0
-	github.com/rs/zerolog v1.31.0
-	github.com/spf13/viper v1.18.2
-	golang.org/x/net v0.19.0
+	github.com/andybalholm/brotli v1.1.0
+	github.com/refraction-networking/utls v1.8.2
+	golang.org/x/net v0.38.0
 	golang.org/x/time v0.5.0
+	modernc.org/sqlite v1.29.5
 )
 
 require (
-	github.com/Danny-Dasilva/fhttp v0.0.0-20240217042913-eeeb0b347ce1 // indirect
-	github.com/Danny-Dasilva/utls v0.0.0-20240310150143-7c2a2e5f86cd // indirect
-	github.com/andybalholm/brotli v1.1.0 // indirect
-	github.com/andybalholm/cascadia v1.3.2 // indirect
-	github.com/bits-and-blooms/bitset v1.13.0 // indirect
-	github.com/cloudflare/circl v1.3.7 // indirect
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
-	github.com/gorilla/websocket v1.5.1 // indirect
-	github.com/hashicorp/hcl v1.0.0 // indirect
-	github.com/klauspost/compress v1.17.7 // indirect
-	github.com/magiconair/properties v1.8.7 // indirect
-	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/mitchellh/mapstructure v1.5.0 // indirect
-	github.com/pelletier/go-toml/v2 v2.1.1 // indirect
-	github.com/quic-go/quic-go v0.41.0 // indirect
-	github.com/refraction-networking/utls v1.6.3 // indirect
-	github.com/sagikazarmark/locafero v0.4.0 // indirect
-	github.com/sagikazarmark/slog-shim v0.1.0 // indirect
-	github.com/sourcegraph/conc v0.3.0 // indirect
-	github.com/spf13/afero v1.11.0 // indirect
-	github.com/spf13/cast v1.6.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	github.com/subosito/gotenv v1.6.0 // indirect
-	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/crypto v0.21.0 // indirect
-	golang.org/x/exp v0.0.0-20240222234643-814bf88cf225 // indirect
-	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/ini.v1 v1.67.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sync v0.12.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
+	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
+	modernc.org/libc v1.41.0 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.7.2 // indirect
+	modernc.org/strutil v1.2.0 // indirect
+	modernc.org/token v1.1.0 // indirect
 )
@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Bing implements SearchEngine for Bing
+type Bing struct {
+	Domain         string   // www.bing.com
+	Market         string   // mkt parameter, e.g. en-US
+	ExcludeDomains []string // Domains to exclude from results
+}
+
+// NewBing creates a new Bing search engine
+func NewBing() *Bing {
+	return &Bing{
+		Domain: "www.bing.com",
+		Market: "en-US",
+	}
+}
+
+// bingResultPattern matches the title link of an organic result
+var bingResultPattern = regexp.MustCompile(`(?s)<li class="b_algo"[^>]*>.*?<h2[^>]*>\s*<a[^>]+href="([^"]+)"`)
+
+// Name returns the engine name
+func (b *Bing) Name() string {
+	return "bing"
+}
+
+// Timing returns Bing's recommended cooldowns. Bing forgives flagged
+// exits much sooner than Google.
+func (b *Bing) Timing() Timing {
+	return Timing{
+		CaptchaCooldown: time.Minute,
+		BlockCooldown:   5 * time.Minute,
+	}
+}
+
+// BuildSearchURL constructs the Bing search URL
+func (b *Bing) BuildSearchURL(query string, page int, resultsPerPage int) string {
+	// Bing serves at most 50 results per page
+	if resultsPerPage > 50 {
+		resultsPerPage = 50
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", fmt.Sprintf("%d", resultsPerPage))
+	if b.Market != "" {
+		params.Set("mkt", b.Market)
+	}
+
+	// Pagination (1-based first result)
+	if page > 0 {
+		params.Set("first", fmt.Sprintf("%d", page*resultsPerPage+1))
+	}
+
+	return fmt.Sprintf("https://%s/search?%s", b.Domain, params.Encode())
+}
+
+// ParseResults extracts URLs from Bing search results HTML
+func (b *Bing) ParseResults(html string) []SearchResult {
+	var results []SearchResult
+	seen := make(map[string]bool)
+
+	for _, match := range bingResultPattern.FindAllStringSubmatch(html, -1) {
+		cleanURL := b.cleanURL(match[1])
+		if cleanURL == "" || seen[cleanURL] || b.isExcludedDomain(cleanURL) {
+			continue
+		}
+
+		seen[cleanURL] = true
+		results = append(results, SearchResult{
+			URL:      cleanURL,
+			Position: len(results) + 1,
+		})
+	}
+
+	return results
+}
+
+// cleanURL decodes Bing click-tracking links and validates the target
+func (b *Bing) cleanURL(rawURL string) string {
+	decoded := strings.ReplaceAll(rawURL, "&amp;", "&")
+
+	// /ck/a links carry the target base64-encoded in u, prefixed "a1"
+	if strings.Contains(decoded, "bing.com/ck/a") {
+		parsed, err := url.Parse(decoded)
+		if err != nil {
+			return ""
+		}
+		target := strings.TrimPrefix(parsed.Query().Get("u"), "a1")
+		raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(target, "="))
+		if err != nil {
+			return ""
+		}
+		decoded = string(raw)
+	}
+
+	parsed, err := url.Parse(decoded)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Host)
+	if host == "bing.com" || strings.HasSuffix(host, ".bing.com") {
+		return ""
+	}
+
+	return decoded
+}
+
+// isExcludedDomain checks if URL matches excluded domains
+func (b *Bing) isExcludedDomain(urlStr string) bool {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Host)
+	for _, domain := range b.ExcludeDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectCaptcha checks if the response contains a CAPTCHA
+func (b *Bing) DetectCaptcha(html string) bool {
+	captchaIndicators := []string{
+		"b_captcha",
+		"/turing/captcha",
+		"captcha",
+		"verify you are a human",
+		"one last step",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range captchaIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectBlock checks if the response indicates a block/ban
+func (b *Bing) DetectBlock(html string) bool {
+	blockIndicators := []string{
+		"403 forbidden",
+		"access denied",
+		"too many requests",
+		"your request has been blocked",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range blockIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	// Very short responses are error pages, not result pages
+	return len(html) < 1000 && !strings.Contains(htmlLower, "<html")
+}
+
+// DetectNoResults checks if there are no search results
+func (b *Bing) DetectNoResults(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, `class="b_no"`) ||
+		strings.Contains(htmlLower, "there are no results for")
+}
+
+// HasNextPage checks whether the pager links to a next page
+func (b *Bing) HasNextPage(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, `class="sb_pagn`) ||
+		strings.Contains(htmlLower, `title="next page"`)
+}
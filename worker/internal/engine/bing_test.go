@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestBingBuildSearchURL(t *testing.T) {
+	b := NewBing()
+
+	first := b.BuildSearchURL("inurl:admin", 0, 50)
+	if !strings.HasPrefix(first, "https://www.bing.com/search?") || !strings.Contains(first, "q=inurl%3Aadmin") {
+		t.Errorf("BuildSearchURL() = %q", first)
+	}
+	if strings.Contains(first, "first=") {
+		t.Errorf("page 0 URL = %q, want no first parameter", first)
+	}
+
+	second := b.BuildSearchURL("inurl:admin", 1, 100)
+	if !strings.Contains(second, "count=50") || !strings.Contains(second, "first=51") {
+		t.Errorf("page 1 URL = %q, want count=50 and first=51", second)
+	}
+}
+
+func TestBingParseResults(t *testing.T) {
+	tracked := "https://www.bing.com/ck/a?!&&p=abc&u=a1" +
+		base64.RawURLEncoding.EncodeToString([]byte("https://tracked.example.com/login")) + "&ntb=1"
+
+	html := `<html><body><ol id="b_results">
+<li class="b_algo" data-id="1"><div class="b_title">
+<h2><a href="https://a.example.com/admin" h="ID=SERP">A</a></h2></div></li>
+<li class="b_algo"><h2><a href="` + tracked + `">B</a></h2></li>
+<li class="b_algo"><h2><a href="https://a.example.com/admin">dup</a></h2></li>
+<li class="b_algo"><h2><a href="https://www.bing.com/images">internal</a></h2></li>
+</ol></body></html>`
+
+	results := NewBing().ParseResults(html)
+
+	want := []string{"https://a.example.com/admin", "https://tracked.example.com/login"}
+	if len(results) != len(want) {
+		t.Fatalf("ParseResults() = %+v, want %v", results, want)
+	}
+	for i, r := range results {
+		if r.URL != want[i] || r.Position != i+1 {
+			t.Errorf("result %d = %+v, want %s at %d", i, r, want[i], i+1)
+		}
+	}
+}
+
+func TestBingDetect(t *testing.T) {
+	b := NewBing()
+	page := "<html>" + strings.Repeat(" ", 1000) + "</html>"
+
+	if !b.DetectCaptcha(`<html><div id="b_captcha">`) {
+		t.Error("DetectCaptcha() missed the challenge page")
+	}
+	if b.DetectBlock(page) {
+		t.Error("DetectBlock() flagged a normal page")
+	}
+	if !b.DetectBlock("Too Many Requests") {
+		t.Error("DetectBlock() missed a rate limit")
+	}
+	if !b.DetectNoResults(`<li class="b_no"><h1>There are no results for x</h1></li>`) {
+		t.Error("DetectNoResults() missed the empty page")
+	}
+	if !b.HasNextPage(`<a class="sb_pagN sb_pagN_bp b_widePag sb_bp" title="Next page" href="/search?q=x&amp;first=11">`) {
+		t.Error("HasNextPage() missed the pager link")
+	}
+	if b.HasNextPage(`<a class="sb_pagP" title="Previous page" href="/search?q=x&amp;first=1">`) {
+		t.Error("HasNextPage() = true on the last page")
+	}
+	if timing := b.Timing(); timing.BlockCooldown >= NewGoogle().Timing().BlockCooldown {
+		t.Errorf("Timing() = %+v, want shorter cooldowns than Google", timing)
+	}
+}
@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidDork is wrapped by the errors ValidateDork returns for dorks
+// that cannot match anything
+var ErrInvalidDork = errors.New("invalid dork")
+
+const (
+	maxDorkLength = 2048 // Longer queries are rejected outright
+	maxDorkWords  = 32   // Words past this are ignored by Google
+)
+
+// dorkOperators are the operators engines still honour
+var dorkOperators = map[string]bool{
+	"site": true, "inurl": true, "allinurl": true,
+	"intitle": true, "allintitle": true, "intext": true, "allintext": true,
+	"filetype": true, "ext": true, "before": true, "after": true,
+	"related": true, "define": true,
+}
+
+// retiredOperators were dropped by Google and are searched as plain text
+var retiredOperators = map[string]bool{
+	"link": true, "info": true, "cache": true, "inanchor": true,
+	"allinanchor": true, "daterange": true, "phonebook": true,
+}
+
+// typographicQuotes are quotes word processors and chat apps substitute
+// for the plain double quote
+var typographicQuotes = strings.NewReplacer(
+	"“", `"`, "”", `"`, "„", `"`, "«", `"`, "»", `"`,
+)
+
+// ValidateDork cleans up a pasted dork before it is searched. It turns
+// control characters, tabs and newlines into spaces, drops invalid UTF-8,
+// collapses whitespace, straightens typographic quotes and closes an
+// unbalanced quote or parenthesis. Known operator names are lowercased, an
+// operator separated from its value by a space is joined to it, and
+// operators with no value and dangling ORs are dropped. Other words ending
+// in a colon are left alone. warnings describe each change and point out
+// retired or unknown operators and queries too long for Google to read in
+// full.
+//
+// err wraps ErrInvalidDork when the dork cannot match anything: it is
+// empty, too long, or only excludes terms. normalized is then the best
+// effort so far, possibly empty.
+func ValidateDork(dork string) (normalized string, warnings []string, err error) {
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	s := strings.ToValidUTF8(dork, "")
+	if s != dork {
+		warn("dropped invalid UTF-8")
+	}
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, s)
+
+	if straight := typographicQuotes.Replace(s); straight != s {
+		warn("replaced typographic quotes with plain ones")
+		s = straight
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", warnings, fmt.Errorf("%w: empty", ErrInvalidDork)
+	}
+
+	if strings.Count(s, `"`)%2 != 0 {
+		warn("closed an unbalanced quote")
+		s += `"`
+	}
+	s = balanceParens(s, warn)
+
+	tokens := cleanTokens(splitDork(s), warn)
+	normalized = strings.Join(tokens, " ")
+
+	if len(tokens) == 0 {
+		return normalized, warnings, fmt.Errorf("%w: nothing left to search for", ErrInvalidDork)
+	}
+	if len(normalized) > maxDorkLength {
+		return normalized, warnings, fmt.Errorf("%w: longer than %d characters", ErrInvalidDork, maxDorkLength)
+	}
+	if onlyExclusions(tokens) {
+		return normalized, warnings, fmt.Errorf("%w: every term is excluded", ErrInvalidDork)
+	}
+	if len(tokens) > maxDorkWords {
+		warn("%d words, Google ignores those past the %dth", len(tokens), maxDorkWords)
+	}
+
+	return normalized, warnings, nil
+}
+
+// balanceParens drops closing parentheses with no opening one and closes
+// those left open, ignoring parentheses inside quotes
+func balanceParens(s string, warn func(string, ...any)) string {
+	var b strings.Builder
+	depth := 0
+	stray, quoted := false, false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')' && depth == 0:
+			stray = true
+			continue
+		case r == ')':
+			depth--
+		}
+		b.WriteRune(r)
+	}
+
+	if stray {
+		warn("dropped unmatched closing parentheses")
+	}
+	if depth > 0 {
+		warn("closed unbalanced parentheses")
+		b.WriteString(strings.Repeat(")", depth))
+	}
+	return b.String()
+}
+
+// splitDork splits a dork into words at spaces outside quotes
+func splitDork(s string) []string {
+	var tokens []string
+	var b strings.Builder
+	quoted := false
+
+	for _, r := range s {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ' ' && !quoted {
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens
+}
+
+// cleanTokens fixes up operators and drops dangling ORs
+func cleanTokens(tokens []string, warn func(string, ...any)) []string {
+	var cleaned []string
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		prefix, name, value, ok := splitOperator(token)
+		if !ok {
+			if isOr(token) && (len(cleaned) == 0 || isOr(cleaned[len(cleaned)-1]) || i == len(tokens)-1) {
+				warn("dropped a dangling %s", token)
+				continue
+			}
+			cleaned = append(cleaned, token)
+			continue
+		}
+
+		// Anything else followed by a colon is text, like "Warning:"
+		lower := strings.ToLower(name)
+		if !dorkOperators[lower] && !retiredOperators[lower] {
+			if value != "" {
+				warn("unknown operator %s: is searched as text", name)
+			}
+			cleaned = append(cleaned, token)
+			continue
+		}
+		if lower != name {
+			warn("lowercased %s:", name)
+			name = lower
+		}
+
+		if value == "" {
+			if i+1 >= len(tokens) || isOr(tokens[i+1]) {
+				warn("dropped %s: with no value", name)
+				continue
+			}
+			if _, _, _, next := splitOperator(tokens[i+1]); next {
+				warn("dropped %s: with no value", name)
+				continue
+			}
+			i++
+			value = tokens[i]
+			warn("joined %s: to the word after it", name)
+		}
+
+		if retiredOperators[name] {
+			warn("%s: is no longer supported and is searched as text", name)
+		}
+
+		cleaned = append(cleaned, prefix+name+":"+value)
+	}
+
+	// Dropping an operator can leave an OR at the end
+	for len(cleaned) > 0 && isOr(cleaned[len(cleaned)-1]) {
+		warn("dropped a dangling %s", cleaned[len(cleaned)-1])
+		cleaned = cleaned[:len(cleaned)-1]
+	}
+	return cleaned
+}
+
+// splitOperator splits a word like -inurl:admin into its prefix ("-", "+"
+// or opening parentheses), operator name and value. URLs, times and
+// quoted text are not operators.
+func splitOperator(token string) (prefix, name, value string, ok bool) {
+	rest := strings.TrimLeft(token, "-+(")
+	prefix = token[:len(token)-len(rest)]
+
+	name, value, found := strings.Cut(rest, ":")
+	if !found || name == "" || strings.HasPrefix(value, "//") {
+		return "", "", "", false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return "", "", "", false
+		}
+	}
+	return prefix, name, value, true
+}
+
+// isOr reports whether a word is the OR operator
+func isOr(token string) bool {
+	return token == "OR" || token == "|"
+}
+
+// onlyExclusions reports whether every word of a dork excludes results,
+// which leaves nothing to match
+func onlyExclusions(tokens []string) bool {
+	for _, token := range tokens {
+		if !strings.HasPrefix(strings.TrimLeft(token, "("), "-") && !isOr(token) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDork(t *testing.T) {
+	tests := []struct {
+		name     string
+		dork     string
+		want     string
+		warnings []string // Substrings of the expected warnings, in order
+	}{
+		{"plain", `inurl:admin intitle:"login page"`, `inurl:admin intitle:"login page"`, nil},
+		{"site and filetype", `site:example.com filetype:pdf "confidential"`, `site:example.com filetype:pdf "confidential"`, nil},
+		{"grouped or", `(inurl:admin | inurl:login) -site:github.com`, `(inurl:admin | inurl:login) -site:github.com`, nil},
+		{"url is not an operator", `"http://example.com" login`, `"http://example.com" login`, nil},
+		{"text colon left alone", `"Warning: mysql_connect()" Warning: error`, `"Warning: mysql_connect()" Warning: error`, nil},
+		{"whitespace", "  inurl:admin \t\n  login  ", "inurl:admin login", nil},
+		{"quoted spaces kept", `intitle:"index  of"`, `intitle:"index  of"`, nil},
+		{"control characters", "inurl:admin\x00login", "inurl:admin login", nil},
+		{"invalid utf-8", "inurl:admin\xff", "inurl:admin", []string{"invalid UTF-8"}},
+		{"typographic quotes", `intitle:“index of”`, `intitle:"index of"`, []string{"typographic quotes"}},
+		{"unbalanced quote", `intitle:"index of`, `intitle:"index of"`, []string{"unbalanced quote"}},
+		{"open parenthesis", `(inurl:admin | inurl:login`, `(inurl:admin | inurl:login)`, []string{"closed unbalanced parentheses"}},
+		{"stray parenthesis", `inurl:admin) login`, `inurl:admin login`, []string{"unmatched closing"}},
+		{"parenthesis in quotes", `intitle:"(beta" admin`, `intitle:"(beta" admin`, nil},
+		{"uppercase operator", `INURL:admin Site:example.com`, `inurl:admin site:example.com`, []string{"lowercased INURL:", "lowercased Site:"}},
+		{"space after operator", `inurl: admin login`, `inurl:admin login`, []string{"joined inurl:"}},
+		{"empty operator", `admin site:`, `admin`, []string{"dropped site: with no value"}},
+		{"operator before operator", `intitle: inurl:admin`, `inurl:admin`, []string{"dropped intitle:"}},
+		{"leading or", `OR inurl:admin`, `inurl:admin`, []string{"dangling OR"}},
+		{"trailing or", `inurl:admin |`, `inurl:admin`, []string{"dangling |"}},
+		{"double or", `inurl:admin OR OR inurl:login`, `inurl:admin OR inurl:login`, []string{"dangling OR"}},
+		{"or left by dropped operator", `inurl:admin OR site:`, `inurl:admin`, []string{"dropped site:", "dangling OR"}},
+		{"retired operator", `link:example.com`, `link:example.com`, []string{"link: is no longer supported"}},
+		{"unknown operator", `foo:bar admin`, `foo:bar admin`, []string{"unknown operator foo:"}},
+		{"too many words", strings.Repeat("word ", 40), strings.TrimSpace(strings.Repeat("word ", 40)), []string{"40 words"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ValidateDork(tt.dork)
+			if err != nil {
+				t.Fatalf("ValidateDork(%q) error = %v", tt.dork, err)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateDork(%q) = %q, want %q", tt.dork, got, tt.want)
+			}
+			if len(warnings) != len(tt.warnings) {
+				t.Fatalf("warnings = %q, want %d matching %q", warnings, len(tt.warnings), tt.warnings)
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(warnings[i], want) {
+					t.Errorf("warning %d = %q, want it to mention %q", i, warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDorkHopeless(t *testing.T) {
+	tests := []struct {
+		name string
+		dork string
+	}{
+		{"empty", ""},
+		{"blank", " \t\n "},
+		{"only empty operators", "inurl: site:"},
+		{"only or", "OR |"},
+		{"only exclusions", "-site:example.com -inurl:admin"},
+		{"too long", strings.Repeat("a", maxDorkLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ValidateDork(tt.dork)
+			if !errors.Is(err, ErrInvalidDork) {
+				t.Errorf("ValidateDork(%q) error = %v, want ErrInvalidDork", tt.dork, err)
+			}
+		})
+	}
+}
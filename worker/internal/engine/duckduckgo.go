@@ -0,0 +1,199 @@
+package engine
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DuckDuckGo implements SearchEngine for DuckDuckGo's JavaScript-free
+// HTML endpoint
+type DuckDuckGo struct {
+	Domain         string   // html.duckduckgo.com
+	Region         string   // kl parameter, e.g. us-en (empty = all regions)
+	ExcludeDomains []string // Domains to exclude from results
+}
+
+// NewDuckDuckGo creates a new DuckDuckGo search engine
+func NewDuckDuckGo() *DuckDuckGo {
+	return &DuckDuckGo{
+		Domain: "html.duckduckgo.com",
+	}
+}
+
+// ddgResultsPerPage is the fixed page size of the HTML endpoint
+const ddgResultsPerPage = 30
+
+// ddgResultPattern matches the title link of an organic result
+var ddgResultPattern = regexp.MustCompile(`<a[^>]+class="result__a"[^>]*href="([^"]+)"|<a[^>]+href="([^"]+)"[^>]*class="result__a"`)
+
+// Name returns the engine name
+func (d *DuckDuckGo) Name() string {
+	return "duckduckgo"
+}
+
+// Timing returns DuckDuckGo's recommended cooldowns. Its anomaly checks
+// clear quickly, so flagged exits come back sooner than on Google.
+func (d *DuckDuckGo) Timing() Timing {
+	return Timing{
+		CaptchaCooldown: time.Minute,
+		BlockCooldown:   5 * time.Minute,
+	}
+}
+
+// BuildSearchURL constructs the DuckDuckGo search URL. The HTML endpoint
+// pages with the s (offset) and dc (1-based first result) fields its
+// "Next" form posts; it accepts them as query parameters too, so pages
+// are plain GETs. The page size is fixed, so resultsPerPage is ignored.
+func (d *DuckDuckGo) BuildSearchURL(query string, page int, resultsPerPage int) string {
+	params := url.Values{}
+	params.Set("q", query)
+	if d.Region != "" {
+		params.Set("kl", d.Region)
+	}
+
+	if page > 0 {
+		offset := page * ddgResultsPerPage
+		params.Set("s", fmt.Sprintf("%d", offset))
+		params.Set("dc", fmt.Sprintf("%d", offset+1))
+	}
+
+	return fmt.Sprintf("https://%s/html/?%s", d.Domain, params.Encode())
+}
+
+// ParseResults extracts URLs from DuckDuckGo search results HTML
+func (d *DuckDuckGo) ParseResults(html string) []SearchResult {
+	var results []SearchResult
+	seen := make(map[string]bool)
+
+	for _, match := range ddgResultPattern.FindAllStringSubmatch(html, -1) {
+		rawURL := match[1]
+		if rawURL == "" {
+			rawURL = match[2]
+		}
+
+		cleanURL := d.cleanURL(rawURL)
+		if cleanURL == "" || seen[cleanURL] || d.isExcludedDomain(cleanURL) {
+			continue
+		}
+
+		seen[cleanURL] = true
+		results = append(results, SearchResult{
+			URL:      cleanURL,
+			Position: len(results) + 1,
+		})
+	}
+
+	return results
+}
+
+// cleanURL unwraps DuckDuckGo's /l/?uddg= redirects and validates the
+// target
+func (d *DuckDuckGo) cleanURL(rawURL string) string {
+	decoded := strings.ReplaceAll(rawURL, "&amp;", "&")
+
+	// Redirect links are protocol-relative: //duckduckgo.com/l/?uddg=...
+	if strings.HasPrefix(decoded, "//") {
+		decoded = "https:" + decoded
+	}
+
+	parsed, err := url.Parse(decoded)
+	if err != nil {
+		return ""
+	}
+	if isDDGHost(parsed.Host) && parsed.Path == "/l/" {
+		decoded = parsed.Query().Get("uddg")
+		if parsed, err = url.Parse(decoded); err != nil {
+			return ""
+		}
+	}
+
+	if parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+	if isDDGHost(parsed.Host) {
+		return ""
+	}
+
+	return decoded
+}
+
+// isDDGHost reports whether host belongs to DuckDuckGo
+func isDDGHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "duckduckgo.com" || strings.HasSuffix(host, ".duckduckgo.com")
+}
+
+// isExcludedDomain checks if URL matches excluded domains
+func (d *DuckDuckGo) isExcludedDomain(urlStr string) bool {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Host)
+	for _, domain := range d.ExcludeDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectCaptcha checks for DuckDuckGo's anomaly challenge, which asks
+// the visitor to pick images before showing results
+func (d *DuckDuckGo) DetectCaptcha(html string) bool {
+	captchaIndicators := []string{
+		"anomaly-modal",
+		"/anomaly.js",
+		"bots use duckduckgo too",
+		"please complete the following challenge",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range captchaIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectBlock checks if the response indicates a block/ban. DuckDuckGo
+// answers blocked exits with a bare error page rather than a challenge.
+func (d *DuckDuckGo) DetectBlock(html string) bool {
+	blockIndicators := []string{
+		"403 forbidden",
+		"access denied",
+		"too many requests",
+		"if this error persists, please let us know",
+		"error-lite@duckduckgo.com",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range blockIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	// Very short responses are error pages, not result pages
+	return len(html) < 1000 && !strings.Contains(htmlLower, "<html")
+}
+
+// DetectNoResults checks if there are no search results
+func (d *DuckDuckGo) DetectNoResults(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, `class="no-results"`) ||
+		strings.Contains(htmlLower, "no results found for")
+}
+
+// HasNextPage checks whether the page ends with the "Next" form
+func (d *DuckDuckGo) HasNextPage(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, `value="next"`)
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDuckDuckGoBuildSearchURL(t *testing.T) {
+	d := NewDuckDuckGo()
+
+	first := d.BuildSearchURL("inurl:admin", 0, 100)
+	if !strings.HasPrefix(first, "https://html.duckduckgo.com/html/?") || !strings.Contains(first, "q=inurl%3Aadmin") {
+		t.Errorf("BuildSearchURL() = %q", first)
+	}
+	if strings.Contains(first, "s=") || strings.Contains(first, "dc=") {
+		t.Errorf("page 0 URL = %q, want no offset", first)
+	}
+
+	second := d.BuildSearchURL("inurl:admin", 2, 100)
+	if !strings.Contains(second, "s=60") || !strings.Contains(second, "dc=61") {
+		t.Errorf("page 2 URL = %q, want s=60 and dc=61", second)
+	}
+}
+
+func TestDuckDuckGoParseResults(t *testing.T) {
+	redirect := "//duckduckgo.com/l/?uddg=" + url.QueryEscape("https://tracked.example.com/login?id=1") + "&amp;rut=abc"
+
+	html := `<html><body><div class="results">
+<div class="result results_links"><h2 class="result__title">
+<a rel="nofollow" class="result__a" href="https://a.example.com/admin">A</a></h2>
+<a class="result__url" href="https://a.example.com/admin">a.example.com</a></div>
+<div class="result"><h2><a rel="nofollow" class="result__a" href="` + redirect + `">B</a></h2></div>
+<div class="result"><h2><a rel="nofollow" href="https://a.example.com/admin" class="result__a">dup</a></h2></div>
+<div class="result"><h2><a class="result__a" href="https://duckduckgo.com/y.js?ad=1">ad</a></h2></div>
+</div></body></html>`
+
+	results := NewDuckDuckGo().ParseResults(html)
+
+	want := []string{"https://a.example.com/admin", "https://tracked.example.com/login?id=1"}
+	if len(results) != len(want) {
+		t.Fatalf("ParseResults() = %+v, want %v", results, want)
+	}
+	for i, r := range results {
+		if r.URL != want[i] || r.Position != i+1 {
+			t.Errorf("result %d = %+v, want %s at %d", i, r, want[i], i+1)
+		}
+	}
+}
+
+func TestDuckDuckGoDetect(t *testing.T) {
+	d := NewDuckDuckGo()
+	page := "<html>" + strings.Repeat(" ", 1000) + "</html>"
+
+	if !d.DetectCaptcha(`<html><div class="anomaly-modal__title">Unfortunately, bots use DuckDuckGo too.</div>`) {
+		t.Error("DetectCaptcha() missed the anomaly challenge")
+	}
+	if d.DetectCaptcha(page) || d.DetectBlock(page) {
+		t.Error("normal page flagged as CAPTCHA or block")
+	}
+	if !d.DetectBlock("<html>If this error persists, please let us know: error-lite@duckduckgo.com</html>") {
+		t.Error("DetectBlock() missed the error page")
+	}
+	if !d.DetectNoResults(`<div class="no-results">No results.</div>`) {
+		t.Error("DetectNoResults() missed the empty page")
+	}
+	if !d.HasNextPage(`<form action="/html/" method="post"><input type="submit" class="btn btn--alt" value="Next" /></form>`) {
+		t.Error("HasNextPage() missed the Next form")
+	}
+	if d.HasNextPage(`<form action="/html/" method="post"><input type="submit" class="btn btn--alt" value="Previous" /></form>`) {
+		t.Error("HasNextPage() = true on the last page")
+	}
+}
+
+// DuckDuckGo must drop into Worker.SetEngine like the other engines
+var (
+	_ SearchEngine = (*DuckDuckGo)(nil)
+	_ Paginator    = (*DuckDuckGo)(nil)
+)
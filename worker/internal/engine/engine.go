@@ -2,9 +2,16 @@ package engine
 
 import (
 	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 // SearchEngine defines the interface for search engines
@@ -14,6 +21,106 @@ type SearchEngine interface {
 	ParseResults(html string) []SearchResult
 	DetectCaptcha(html string) bool
 	DetectBlock(html string) bool
+	DetectNoResults(html string) bool
+	Timing() Timing
+}
+
+// SoftBlockDetector is implemented by engines that can serve a block as
+// an ordinary 200 page with no results. DetectSoftBlock is given the
+// query searched and how many results the page held.
+type SoftBlockDetector interface {
+	DetectSoftBlock(html string, query string, resultCount int) bool
+}
+
+// Compile-time checks of the interfaces each engine implements
+var (
+	_ SearchEngine      = (*Google)(nil)
+	_ SoftBlockDetector = (*Google)(nil)
+	_ Paginator         = (*Google)(nil)
+	_ CookieSeeder      = (*Google)(nil)
+	_ OptionsBuilder    = (*Google)(nil)
+
+	_ SearchEngine = (*Bing)(nil)
+	_ Paginator    = (*Bing)(nil)
+
+	_ SearchEngine = (*DuckDuckGo)(nil)
+	_ Paginator    = (*DuckDuckGo)(nil)
+
+	_ SearchEngine = (*Yandex)(nil)
+	_ Paginator    = (*Yandex)(nil)
+)
+
+// Paginator is implemented by engines that can tell whether a results
+// page links to a next page. Engines without it are crawled until a page
+// comes back empty.
+type Paginator interface {
+	HasNextPage(html string) bool
+}
+
+// CookieSeeder is implemented by engines that expect cookies a browser
+// would already hold on its first visit. They are set once per proxy;
+// after that the engine's own Set-Cookie headers take over.
+type CookieSeeder interface {
+	SeedCookies() []*http.Cookie
+}
+
+// SearchOptions are per-task search parameters. Empty fields keep the
+// engine's defaults.
+type SearchOptions struct {
+	TimeRange string // Only results indexed within: d, w, m or y
+	Country   string // Two-letter country code (gl)
+	Language  string // Two-letter language code (hl)
+
+	// Session identifies who is searching, e.g. the proxy ID, for engines
+	// that keep per-session state such as Google's sticky domain
+	Session string
+}
+
+// OptionsBuilder is implemented by engines that honour SearchOptions.
+// Engines without it search with their defaults.
+type OptionsBuilder interface {
+	BuildSearchURLWithOptions(query string, page int, resultsPerPage int, opts SearchOptions) string
+}
+
+// timeRanges maps the accepted time ranges to Google's qdr values
+var timeRanges = map[string]string{
+	"d": "d", // Past day
+	"w": "w", // Past week
+	"m": "m", // Past month
+	"y": "y", // Past year
+}
+
+// ValidTimeRange reports whether r is an accepted SearchOptions.TimeRange
+func ValidTimeRange(r string) bool {
+	_, ok := timeRanges[r]
+	return ok
+}
+
+// ValidCode reports whether code is a two-letter country or language code
+func ValidCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, c := range code {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Timing holds an engine's recommended pacing. Zero fields leave the
+// pool's configured values in place.
+//
+// Engine defaults:
+//
+//	google:     2m CAPTCHA cooldown, 15m block cooldown
+//	bing:       1m CAPTCHA cooldown, 5m block cooldown
+//	duckduckgo: 1m CAPTCHA cooldown, 5m block cooldown
+//	yandex:     5m CAPTCHA cooldown, 10m block cooldown
+type Timing struct {
+	CaptchaCooldown time.Duration // Proxy cooldown after a CAPTCHA
+	BlockCooldown   time.Duration // Proxy cooldown after a block
 }
 
 // SearchResult represents a single search result
@@ -22,6 +129,10 @@ type SearchResult struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Position    int    `json:"position"`
+
+	// Engines lists the engines that returned this URL when a task runs
+	// on several engines at once
+	Engines []string `json:"engines,omitempty"`
 }
 
 // Google implements SearchEngine for Google
@@ -32,15 +143,54 @@ type Google struct {
 	Country        string   // gl parameter
 	SafeSearch     bool     // safe parameter
 	ExcludeDomains []string // Domains to exclude from results
+
+	// SoftBlockMarkers are lowercase fragments Google leaves on 200 pages
+	// that withhold results instead of showing a CAPTCHA
+	SoftBlockMarkers []string
+
+	// Domains rotates searches across these domains at random, never
+	// using one twice in a row while there is another (empty = Domain
+	// only). StickyDomain instead pins each SearchOptions.Session to one
+	// of them, picked on its first search.
+	Domains      []string
+	StickyDomain bool
+
+	// Randomizes seeded cookies and domain rotation
+	rng        *rand.Rand
+	rngMu      sync.Mutex
+	lastDomain string            // Guarded by rngMu
+	pinned     map[string]string // Session -> sticky domain, guarded by rngMu
 }
 
 // NewGoogle creates a new Google search engine
 func NewGoogle() *Google {
+	return NewGoogleWithSeed(time.Now().UnixNano())
+}
+
+// NewGoogleWithSeed creates a Google search engine whose randomized
+// cookies are reproducible: engines with the same seed seed the same
+// cookies
+func NewGoogleWithSeed(seed int64) *Google {
 	return &Google{
 		Domain:     "www.google.com",
 		Language:   "en",
 		Country:    "us",
 		SafeSearch: false,
+
+		SoftBlockMarkers: DefaultSoftBlockMarkers(),
+
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// DefaultSoftBlockMarkers returns the markers of known soft-block pages
+func DefaultSoftBlockMarkers() []string {
+	return []string{
+		"/httpservice/retry/enablejs",
+		"having trouble accessing google search",
+		"please click here if you are not redirected",
+		"why did this happen",
+		`id="infodiv"`,
 	}
 }
 
@@ -49,16 +199,104 @@ func (g *Google) Name() string {
 	return "google"
 }
 
+// Timing returns Google's recommended cooldowns. Google bans proxies for
+// far longer than other engines, so retrying a flagged exit early only
+// burns it.
+func (g *Google) Timing() Timing {
+	return Timing{
+		CaptchaCooldown: 2 * time.Minute,
+		BlockCooldown:   15 * time.Minute,
+	}
+}
+
+// SeedCookies returns the CONSENT cookie a browser holds after accepting
+// Google's consent dialog, so the first request skips the consent wall
+func (g *Google) SeedCookies() []*http.Cookie {
+	return []*http.Cookie{{
+		Name:  "CONSENT",
+		Value: fmt.Sprintf("YES+%d", g.intn(999)),
+		Path:  "/",
+	}}
+}
+
+// intn returns a random number in [0, n), from the global source for a
+// Google not made by a constructor
+func (g *Google) intn(n int) int {
+	g.rngMu.Lock()
+	defer g.rngMu.Unlock()
+	return g.intnLocked(n)
+}
+
+// intnLocked is intn for callers holding rngMu
+func (g *Google) intnLocked(n int) int {
+	if g.rng == nil {
+		return rand.Intn(n)
+	}
+	return g.rng.Intn(n)
+}
+
+// selectDomain returns the domain for session's next search: Domain when
+// no rotation is configured, otherwise a random one of Domains other than
+// the last one picked. When sticky, a session keeps the domain picked for
+// its first search.
+func (g *Google) selectDomain(session string) string {
+	if len(g.Domains) == 0 {
+		return g.Domain
+	}
+
+	g.rngMu.Lock()
+	defer g.rngMu.Unlock()
+
+	if domain, ok := g.pinned[session]; ok && g.StickyDomain {
+		return domain
+	}
+
+	candidates := make([]string, 0, len(g.Domains))
+	for _, domain := range g.Domains {
+		if domain != g.lastDomain {
+			candidates = append(candidates, domain)
+		}
+	}
+	if len(candidates) == 0 {
+		// Every entry is the last domain
+		candidates = g.Domains
+	}
+	g.lastDomain = candidates[g.intnLocked(len(candidates))]
+
+	if g.StickyDomain {
+		if g.pinned == nil {
+			g.pinned = make(map[string]string)
+		}
+		g.pinned[session] = g.lastDomain
+	}
+	return g.lastDomain
+}
+
 // BuildSearchURL constructs the Google search URL
 func (g *Google) BuildSearchURL(query string, page int, resultsPerPage int) string {
+	return g.BuildSearchURLWithOptions(query, page, resultsPerPage, SearchOptions{})
+}
+
+// BuildSearchURLWithOptions constructs the Google search URL for a task's
+// options. Unknown time ranges and invalid codes fall back to the
+// engine's defaults.
+func (g *Google) BuildSearchURLWithOptions(query string, page int, resultsPerPage int, opts SearchOptions) string {
 	// Base URL
-	baseURL := fmt.Sprintf("https://%s/search", g.Domain)
+	baseURL := fmt.Sprintf("https://%s/search", g.selectDomain(opts.Session))
+
+	language, country := g.Language, g.Country
+	if ValidCode(opts.Language) {
+		language = strings.ToLower(opts.Language)
+	}
+	if ValidCode(opts.Country) {
+		country = strings.ToLower(opts.Country)
+	}
 
 	// Build query parameters
 	params := url.Values{}
 	params.Set("q", query)
-	params.Set("hl", g.Language)
-	params.Set("gl", g.Country)
+	params.Set("hl", language)
+	params.Set("gl", country)
 	params.Set("num", fmt.Sprintf("%d", resultsPerPage))
 
 	// Pagination (start parameter)
@@ -72,6 +310,11 @@ func (g *Google) BuildSearchURL(query string, page int, resultsPerPage int) stri
 		params.Set("safe", "active")
 	}
 
+	// Time range
+	if qdr, ok := timeRanges[opts.TimeRange]; ok {
+		params.Set("tbs", "qdr:"+qdr)
+	}
+
 	// Additional params to look more legitimate
 	params.Set("ie", "UTF-8")
 	params.Set("oe", "UTF-8")
@@ -156,27 +399,25 @@ func (g *Google) ParseResults(html string) []SearchResult {
 	return results
 }
 
-// cleanURL decodes and cleans a URL
+// cleanURL decodes and cleans a URL, unwrapping Google's /url?q=
+// redirects and dropping their tracking parameters. Returns "" for
+// anything that is not an absolute http(s) URL.
 func (g *Google) cleanURL(rawURL string) string {
-	// URL decode
-	decoded, err := url.QueryUnescape(rawURL)
-	if err != nil {
-		decoded = rawURL
-	}
-
 	// Handle HTML entities
-	decoded = strings.ReplaceAll(decoded, "&amp;", "&")
+	decoded := strings.ReplaceAll(rawURL, "&amp;", "&")
 	decoded = strings.ReplaceAll(decoded, "&#39;", "'")
 	decoded = strings.ReplaceAll(decoded, "&quot;", "\"")
 
-	// Remove tracking parameters from Google redirect URLs
-	if strings.Contains(decoded, "/url?") {
-		if u, err := url.Parse(decoded); err == nil {
-			if q := u.Query().Get("q"); q != "" {
-				decoded = q
-			} else if q := u.Query().Get("url"); q != "" {
-				decoded = q
-			}
+	if target, ok := unwrapRedirect(decoded); ok {
+		// A wrapper without a target leads nowhere useful
+		if target == "" {
+			return ""
+		}
+		decoded = target
+	} else if !strings.HasPrefix(decoded, "http://") && !strings.HasPrefix(decoded, "https://") {
+		// Bare targets captured from a wrapper are still encoded
+		if unescaped, err := url.QueryUnescape(decoded); err == nil {
+			decoded = unescaped
 		}
 	}
 
@@ -199,6 +440,40 @@ func (g *Google) cleanURL(rawURL string) string {
 	return decoded
 }
 
+// unwrapRedirect returns the target of a Google /url? redirect, taken from
+// its q or url parameter. ok is false when rawURL is not a redirect.
+func unwrapRedirect(rawURL string) (target string, ok bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Path != "/url" {
+		return "", false
+	}
+	if u.Host != "" && !isGoogleHost(u.Host) {
+		return "", false
+	}
+
+	query := u.Query()
+	if q := query.Get("q"); q != "" {
+		return q, true
+	}
+	return query.Get("url"), true
+}
+
+// isGoogleHost reports whether host is a Google search domain
+func isGoogleHost(host string) bool {
+	host = strings.ToLower(host)
+	return strings.HasPrefix(host, "www.google.") || strings.HasPrefix(host, "google.")
+}
+
+// ValidGoogleDomain reports whether domain is a bare Google search host,
+// e.g. www.google.de, as Google.Domain and Google.Domains hold
+func ValidGoogleDomain(domain string) bool {
+	if !isGoogleHost(domain) || strings.ContainsAny(domain, "/:?#@ ") {
+		return false
+	}
+	// A TLD must follow "google."
+	return !strings.HasSuffix(domain, ".") && !strings.HasSuffix(strings.ToLower(domain), "google.")
+}
+
 // isGoogleURL checks if URL is a Google internal URL
 func (g *Google) isGoogleURL(urlStr string) bool {
 	googleDomains := []string{
@@ -355,6 +630,63 @@ func (g *Google) DetectNoResults(html string) bool {
 	return false
 }
 
+// HasNextPage checks whether the page links to more results, via the
+// desktop pager or the mobile "More results" button
+func (g *Google) HasNextPage(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, `id="pnnext"`) ||
+		strings.Contains(htmlLower, `aria-label="next page"`) ||
+		strings.Contains(htmlLower, `aria-label="more results"`)
+}
+
+// searchOperators narrow a query; several together can legitimately
+// return nothing
+var searchOperators = []string{
+	"site:", "inurl:", "allinurl:", "intitle:", "allintitle:",
+	"intext:", "allintext:", "filetype:", "ext:",
+}
+
+// DetectSoftBlock checks whether an empty result page is a soft block:
+// Google answered 200 with no results, the page carries a soft-block
+// marker, and the query is broad enough that it should have had hits
+func (g *Google) DetectSoftBlock(html string, query string, resultCount int) bool {
+	if resultCount > 0 || g.DetectNoResults(html) {
+		return false
+	}
+
+	htmlLower := strings.ToLower(html)
+	marked := false
+	for _, marker := range g.SoftBlockMarkers {
+		if strings.Contains(htmlLower, marker) {
+			marked = true
+			break
+		}
+	}
+	if !marked {
+		return false
+	}
+
+	return expectsResults(query)
+}
+
+// expectsResults reports whether a query is broad enough that zero hits
+// is suspicious: at most two operators or quoted phrases
+func expectsResults(query string) bool {
+	queryLower := strings.ToLower(query)
+
+	constraints := strings.Count(queryLower, `"`) / 2
+	for _, op := range searchOperators {
+		constraints += strings.Count(queryLower, op)
+	}
+
+	// "allinurl:" also matches "inurl:", don't count it twice
+	for _, op := range []string{"allinurl:", "allintitle:", "allintext:"} {
+		constraints -= strings.Count(queryLower, op)
+	}
+
+	return constraints <= 2
+}
+
 // GoogleDomains returns a list of Google domains for rotation
 func GoogleDomains() []string {
 	return []string{
@@ -386,6 +718,17 @@ func (g *Google) SetDomain(domain string) {
 	g.Domain = domain
 }
 
+// SetDomains rotates searches across domains, pinning each session to
+// one of them if sticky. Call it before searching.
+func (g *Google) SetDomains(domains []string, sticky bool) {
+	g.rngMu.Lock()
+	defer g.rngMu.Unlock()
+	g.Domains = domains
+	g.StickyDomain = sticky
+	g.lastDomain = ""
+	g.pinned = nil
+}
+
 // SetLanguage sets the search language
 func (g *Google) SetLanguage(lang string) {
 	g.Language = lang
@@ -400,3 +743,50 @@ func (g *Google) SetCountry(country string) {
 func (g *Google) AddExcludedDomain(domain string) {
 	g.ExcludeDomains = append(g.ExcludeDomains, strings.ToLower(domain))
 }
+
+// NormalizeURL returns the form of a URL used to spot duplicates: scheme
+// and host lowercased, fragment dropped and trailing slash stripped, so
+// "HTTPS://Example.com/a/#top" becomes "https://example.com/a". URLs that
+// do not parse are returned unchanged.
+func NormalizeURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+
+	return parsed.String()
+}
+
+// RegistrableDomain returns the registrable domain (eTLD+1) of a URL,
+// so "https://a.example.co.uk/x" becomes "example.co.uk"
+func RegistrableDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "" {
+		return ""
+	}
+
+	// IP addresses have no public suffix
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		// Host is itself a public suffix (e.g. "co.uk")
+		return host
+	}
+
+	return domain
+}
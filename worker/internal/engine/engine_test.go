@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -33,6 +34,32 @@ func TestGoogleName(t *testing.T) {
 	}
 }
 
+func TestGoogleWithSeedCookies(t *testing.T) {
+	first, second := NewGoogleWithSeed(42), NewGoogleWithSeed(42)
+	for i := 0; i < 5; i++ {
+		a, b := first.SeedCookies()[0].Value, second.SeedCookies()[0].Value
+		if a != b {
+			t.Fatalf("cookie %d = %s and %s, want the same for the same seed", i, a, b)
+		}
+	}
+
+	// A Google built without a constructor still seeds cookies
+	if cookies := (&Google{}).SeedCookies(); len(cookies) != 1 || cookies[0].Name != "CONSENT" {
+		t.Errorf("SeedCookies() = %v, want a CONSENT cookie", cookies)
+	}
+}
+
+func TestGoogleTiming(t *testing.T) {
+	timing := NewGoogle().Timing()
+
+	if timing.CaptchaCooldown <= 0 || timing.BlockCooldown <= 0 {
+		t.Errorf("Timing() = %+v, want positive cooldowns", timing)
+	}
+	if timing.BlockCooldown < timing.CaptchaCooldown {
+		t.Errorf("BlockCooldown %v shorter than CaptchaCooldown %v", timing.BlockCooldown, timing.CaptchaCooldown)
+	}
+}
+
 func TestGoogleBuildSearchURL(t *testing.T) {
 	g := NewGoogle()
 
@@ -105,6 +132,79 @@ func TestGoogleBuildSearchURLWithSafeSearch(t *testing.T) {
 	}
 }
 
+func TestGoogleBuildSearchURLWithTimeRange(t *testing.T) {
+	g := NewGoogle()
+
+	tests := []struct {
+		timeRange string
+		want      string
+	}{
+		{"d", "tbs=qdr%3Ad"},
+		{"w", "tbs=qdr%3Aw"},
+		{"m", "tbs=qdr%3Am"},
+		{"y", "tbs=qdr%3Ay"},
+		{"", ""},
+		{"fortnight", ""},
+		{"d&x=1", ""},
+	}
+
+	for _, tt := range tests {
+		url := g.BuildSearchURLWithOptions("test", 0, 10, SearchOptions{TimeRange: tt.timeRange})
+		if tt.want == "" {
+			if strings.Contains(url, "tbs=") {
+				t.Errorf("time range %q: URL should not contain tbs, got: %s", tt.timeRange, url)
+			}
+			continue
+		}
+		if !strings.Contains(url, tt.want) {
+			t.Errorf("time range %q: URL should contain %q, got: %s", tt.timeRange, tt.want, url)
+		}
+	}
+}
+
+func TestGoogleBuildSearchURLWithLocale(t *testing.T) {
+	g := NewGoogle()
+
+	tests := []struct {
+		name   string
+		opts   SearchOptions
+		wantHL string
+		wantGL string
+	}{
+		{"defaults", SearchOptions{}, "hl=en", "gl=us"},
+		{"override", SearchOptions{Country: "DE", Language: "de"}, "hl=de", "gl=de"},
+		{"invalid falls back", SearchOptions{Country: "deu", Language: "e1"}, "hl=en", "gl=us"},
+	}
+
+	for _, tt := range tests {
+		url := g.BuildSearchURLWithOptions("test", 0, 10, tt.opts)
+		if !strings.Contains(url, tt.wantHL) || !strings.Contains(url, tt.wantGL) {
+			t.Errorf("%s: URL should contain %s and %s, got: %s", tt.name, tt.wantHL, tt.wantGL, url)
+		}
+	}
+}
+
+func TestValidCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"us", true},
+		{"GB", true},
+		{"", false},
+		{"u", false},
+		{"usa", false},
+		{"u1", false},
+		{"é", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidCode(tt.code); got != tt.want {
+			t.Errorf("ValidCode(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
 func TestGoogleBuildSearchURLWithDifferentDomain(t *testing.T) {
 	g := NewGoogle()
 	g.SetDomain("www.google.co.uk")
@@ -232,6 +332,24 @@ func TestGoogleParseResultsWithJSONLD(t *testing.T) {
 	}
 }
 
+func TestGoogleParseResultsSkipsInternal(t *testing.T) {
+	g := NewGoogle()
+
+	html := `
+		<a href="/url?q=https://example.com/admin&amp;sa=U&amp;ved=2ahUKEwi&amp;usg=AOvVaw">Admin</a>
+		<a href="/url?q=https://webcache.googleusercontent.com/search%3Fq%3Dcache:example.com&amp;sa=U">Cached</a>
+		<a href="https://webcache.googleusercontent.com/search?q=cache:x" data-ved="1">Cached</a>
+		<a href="/url?q=/search%3Fq%3Drelated&amp;sa=U">Related</a>
+		<a href="/url?q=/preferences%3Fhl%3Den&amp;sa=U">Settings</a>
+		<a href="/url?sa=U&amp;ved=2ahUKEwi">No target</a>
+	`
+
+	results := g.ParseResults(html)
+	if len(results) != 1 || results[0].URL != "https://example.com/admin" {
+		t.Errorf("ParseResults() = %+v, want only https://example.com/admin", results)
+	}
+}
+
 func TestGoogleCleanURL(t *testing.T) {
 	g := NewGoogle()
 
@@ -260,6 +378,46 @@ func TestGoogleCleanURL(t *testing.T) {
 			input: "/url?q=https://example.com/page&sa=U",
 			want:  "https://example.com/page",
 		},
+		{
+			name:  "google redirect with tracking",
+			input: "/url?q=https://example.com/admin&amp;sa=U&amp;ved=2ahUKEwi&amp;usg=AOvVaw",
+			want:  "https://example.com/admin",
+		},
+		{
+			name:  "google redirect with encoded query",
+			input: "/url?q=https://example.com/page%3Fa%3D1%26b%3D2&sa=U",
+			want:  "https://example.com/page?a=1&b=2",
+		},
+		{
+			name:  "absolute google redirect",
+			input: "https://www.google.com/url?q=https://example.com/page&sa=U",
+			want:  "https://example.com/page",
+		},
+		{
+			name:  "google redirect url param",
+			input: "/url?esrc=s&source=web&rct=j&url=https://example.com/page&ved=2ahUKEwi",
+			want:  "https://example.com/page",
+		},
+		{
+			name:  "google redirect without target",
+			input: "/url?sa=U&ved=2ahUKEwi",
+			want:  "",
+		},
+		{
+			name:  "absolute url keeps encoding",
+			input: "https://example.com/a%20b?x=1",
+			want:  "https://example.com/a%20b?x=1",
+		},
+		{
+			name:  "search link",
+			input: "/search?q=related&tbm=isch",
+			want:  "",
+		},
+		{
+			name:  "preferences link",
+			input: "/preferences?hl=en",
+			want:  "",
+		},
 		{
 			name:  "no scheme",
 			input: "example.com/page",
@@ -456,6 +614,114 @@ func TestGoogleDetectNoResults(t *testing.T) {
 	}
 }
 
+func TestGoogleHasNextPage(t *testing.T) {
+	g := NewGoogle()
+
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{
+			name: "desktop pager",
+			html: `<td class="d6cvqb BBwThe"><a id="pnnext" href="/search?q=x&amp;start=10"><span>Next</span></a></td>`,
+			want: true,
+		},
+		{
+			name: "aria label",
+			html: `<a class="nBDE1b G5eFlf" aria-label="Next page" href="/search?q=x&amp;start=10">`,
+			want: true,
+		},
+		{
+			name: "mobile more results",
+			html: `<a class="T7sFge" aria-label="More results" href="/search?q=x&amp;start=10">`,
+			want: true,
+		},
+		{
+			name: "last page",
+			html: `<td class="d6cvqb BBwThe"><a id="pnprev" href="/search?q=x&amp;start=10"><span>Previous</span></a></td>`,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.HasNextPage(tt.html); got != tt.want {
+				t.Errorf("HasNextPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Fixtures for a genuine empty result page and a soft-blocked one
+const (
+	genuineNoResultsHTML = `<!doctype html><html lang="en"><head><title>inurl:zzqxv - Google Search</title></head>
+<body><div id="search"><div id="topstuff"><p>Your search - <em>inurl:zzqxv</em> - did not match any documents.</p>
+<p>Suggestions:</p><ul><li>Make sure that all words are spelled correctly.</li></ul></div></div></body></html>`
+
+	softBlockedHTML = `<!doctype html><html lang="en"><head><title>Google Search</title></head>
+<body><noscript><meta content="0;url=/httpservice/retry/enablejs?sei=abc" http-equiv="refresh"></noscript>
+<div id="search"></div><div style="font-size:13px">If you're having trouble accessing Google Search, please
+<a href="/search?q=inurl:admin&amp;emsg=SG_REL">click here</a>, or send feedback.</div></body></html>`
+)
+
+func TestGoogleDetectSoftBlock(t *testing.T) {
+	g := NewGoogle()
+
+	tests := []struct {
+		name  string
+		html  string
+		query string
+		count int
+		want  bool
+	}{
+		{"soft blocked broad dork", softBlockedHTML, "inurl:admin", 0, true},
+		{"genuine no results", genuineNoResultsHTML, "inurl:zzqxv", 0, false},
+		{"soft marker but results parsed", softBlockedHTML, "inurl:admin", 3, false},
+		{"soft marker on narrow dork", softBlockedHTML, `site:example.com inurl:admin intitle:"index of" filetype:sql`, 0, false},
+		{"empty page without markers", `<html><body><div id="search"></div></body></html>`, "inurl:admin", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.DetectSoftBlock(tt.html, tt.query, tt.count); got != tt.want {
+				t.Errorf("DetectSoftBlock() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoogleDetectSoftBlockCustomMarkers(t *testing.T) {
+	g := NewGoogle()
+	g.SoftBlockMarkers = []string{"custom-wall"}
+
+	if g.DetectSoftBlock(softBlockedHTML, "inurl:admin", 0) {
+		t.Error("default markers should be replaced")
+	}
+	if !g.DetectSoftBlock(`<html><div class="custom-wall"></div></html>`, "inurl:admin", 0) {
+		t.Error("custom marker should be detected")
+	}
+}
+
+func TestExpectsResults(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"inurl:admin", true},
+		{`intitle:"index of" backup`, true},
+		{"allinurl:admin login", true},
+		{`site:example.com inurl:admin filetype:php`, false},
+		{`"exact one" "exact two" "exact three"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := expectsResults(tt.query); got != tt.want {
+			t.Errorf("expectsResults(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
 func TestGoogleDomains(t *testing.T) {
 	domains := GoogleDomains()
 
@@ -484,6 +750,117 @@ func TestGoogleDomains(t *testing.T) {
 	}
 }
 
+func TestGoogleDomainRotation(t *testing.T) {
+	domainOf := func(g *Google) string {
+		u, err := url.Parse(g.BuildSearchURL("test", 0, 10))
+		if err != nil {
+			t.Fatalf("BuildSearchURL() error = %v", err)
+		}
+		return u.Host
+	}
+
+	// Without Domains every search uses Domain
+	g := NewGoogleWithSeed(1)
+	for i := 0; i < 3; i++ {
+		if got := domainOf(g); got != "www.google.com" {
+			t.Errorf("search %d domain = %q, want www.google.com", i, got)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		domains []string
+	}{
+		{"two domains", []string{"www.google.de", "www.google.fr"}},
+		{"all domains", GoogleDomains()},
+		{"duplicates", []string{"www.google.de", "www.google.de", "www.google.fr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGoogleWithSeed(42)
+			g.SetDomains(tt.domains, false)
+
+			allowed := make(map[string]bool)
+			for _, d := range tt.domains {
+				allowed[d] = true
+			}
+			seen := make(map[string]bool)
+			prev := ""
+			for i := 0; i < 200; i++ {
+				got := domainOf(g)
+				if !allowed[got] {
+					t.Fatalf("search %d domain = %q, not one of %v", i, got, tt.domains)
+				}
+				if got == prev {
+					t.Fatalf("search %d reused domain %q consecutively", i, got)
+				}
+				seen[got] = true
+				prev = got
+			}
+			if len(seen) < 2 {
+				t.Errorf("rotation only used %v", seen)
+			}
+		})
+	}
+
+	// A single domain is reused rather than failing
+	g = NewGoogleWithSeed(42)
+	g.SetDomains([]string{"www.google.ca"}, false)
+	for i := 0; i < 3; i++ {
+		if got := domainOf(g); got != "www.google.ca" {
+			t.Errorf("search %d domain = %q, want www.google.ca", i, got)
+		}
+	}
+
+	// Sticky pins each session to the first domain it was given, and
+	// sessions are not all pinned to the same one
+	g = NewGoogleWithSeed(42)
+	g.SetDomains(GoogleDomains(), true)
+	sessionDomain := func(session string) string {
+		u, _ := url.Parse(g.BuildSearchURLWithOptions("test", 0, 10, SearchOptions{Session: session}))
+		return u.Host
+	}
+	pinned := make(map[string]string)
+	for _, session := range []string{"a", "b", "c", "d"} {
+		pinned[session] = sessionDomain(session)
+	}
+	domains := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		for session, want := range pinned {
+			if got := sessionDomain(session); got != want {
+				t.Fatalf("sticky session %s search %d domain = %q, want %q", session, i, got, want)
+			}
+			domains[want] = true
+		}
+	}
+	if len(domains) < 2 {
+		t.Errorf("every session pinned to %v", domains)
+	}
+}
+
+func TestValidGoogleDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"www.google.com", true},
+		{"www.google.co.uk", true},
+		{"google.de", true},
+		{"", false},
+		{"www.google.", false},
+		{"www.bing.com", false},
+		{"https://www.google.com", false},
+		{"www.google.com/search", false},
+		{"www.google.com:443", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidGoogleDomain(tt.domain); got != tt.want {
+			t.Errorf("ValidGoogleDomain(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
+
 func TestGoogleSetters(t *testing.T) {
 	g := NewGoogle()
 
@@ -536,3 +913,53 @@ func TestGoogleResultPositions(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://example.com/admin", "https://example.com/admin"},
+		{"HTTPS://WWW.Example.COM/Admin/", "https://www.example.com/Admin"},
+		{"https://example.com/", "https://example.com"},
+		{"https://example.com/a/#top", "https://example.com/a"},
+		{"https://example.com/a?id=1#frag", "https://example.com/a?id=1"},
+		{"http://Example.com:8080//", "http://example.com:8080"},
+		{"https://example.com/a%2Fb/", "https://example.com/a%2Fb"},
+		{"not a url", "not a url"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.input); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://example.com/admin", "example.com"},
+		{"https://a.example.com/x", "example.com"},
+		{"http://deep.sub.example.com:8080/path?q=1", "example.com"},
+		{"https://WWW.Example.COM/", "example.com"},
+		{"https://shop.example.co.uk/login", "example.co.uk"},
+		{"https://example.co.uk", "example.co.uk"},
+		{"https://foo.bar.com.au/", "bar.com.au"},
+		{"https://user.github.io/repo", "user.github.io"},
+		{"https://co.uk/", "co.uk"},
+		{"http://192.168.1.1/admin", "192.168.1.1"},
+		{"not a url", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := RegistrableDomain(tt.input)
+		if got != tt.want {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+)
+
+// placeholderRe matches a template variable like {domain}
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// ExpandDork expands the {name} placeholders of a dork template into
+// every combination of the words in vars, the first variable varying
+// slowest. A variable used twice takes the same word in both places.
+// Placeholders without a variable in vars are left as written, so a dork
+// with none passes through untouched; one using a variable with no words
+// expands to nothing. At most limit dorks are returned (0 = no limit);
+// truncated reports that more combinations were left out.
+func ExpandDork(dork string, vars map[string][]string, limit int) (expanded []string, truncated bool) {
+	names := templateVars(dork, vars)
+	if len(names) == 0 {
+		return []string{dork}, false
+	}
+	for _, name := range names {
+		if len(vars[name]) == 0 {
+			return nil, false
+		}
+	}
+
+	// Count through the combinations like an odometer, last digit fastest
+	digits := make([]int, len(names))
+	pairs := make([]string, 2*len(names))
+	for {
+		if limit > 0 && len(expanded) == limit {
+			return expanded, true
+		}
+
+		for i, name := range names {
+			pairs[2*i] = "{" + name + "}"
+			pairs[2*i+1] = vars[name][digits[i]]
+		}
+		expanded = append(expanded, strings.NewReplacer(pairs...).Replace(dork))
+
+		i := len(digits) - 1
+		for ; i >= 0; i-- {
+			digits[i]++
+			if digits[i] < len(vars[names[i]]) {
+				break
+			}
+			digits[i] = 0
+		}
+		if i < 0 {
+			return expanded, false
+		}
+	}
+}
+
+// templateVars returns the variables in vars that dork uses, in order of
+// first use
+func templateVars(dork string, vars map[string][]string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range placeholderRe.FindAllStringSubmatch(dork, -1) {
+		name := match[1]
+		if _, ok := vars[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ExpandDorks expands every dork template as ExpandDork does, keeping the
+// order of the templates. limit caps the dorks expanded from all the
+// templates together (0 = no limit): once it is used up, later templates
+// are cut short or dropped, while plain dorks still pass through.
+// truncated lists the templates that lost combinations to the cap.
+func ExpandDorks(dorks []string, vars map[string][]string, limit int) (expanded []string, truncated []string) {
+	remaining := limit
+	for _, dork := range dorks {
+		if len(templateVars(dork, vars)) == 0 {
+			expanded = append(expanded, dork)
+			continue
+		}
+
+		if limit > 0 && remaining == 0 {
+			// Only a template that would expand to something loses out
+			if list, _ := ExpandDork(dork, vars, 1); len(list) > 0 {
+				truncated = append(truncated, dork)
+			}
+			continue
+		}
+
+		list, cut := ExpandDork(dork, vars, remaining)
+		expanded = append(expanded, list...)
+		remaining -= len(list)
+		if cut {
+			truncated = append(truncated, dork)
+		}
+	}
+	return expanded, truncated
+}
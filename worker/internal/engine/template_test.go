@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandDork(t *testing.T) {
+	vars := map[string][]string{
+		"domain": {"a.com", "b.org"},
+		"path":   {"admin", "login", "panel"},
+		"ext":    {"php"},
+		"none":   {},
+	}
+
+	tests := []struct {
+		name      string
+		dork      string
+		limit     int
+		want      []string
+		truncated bool
+	}{
+		{"plain", `inurl:admin intitle:"index of"`, 0, []string{`inurl:admin intitle:"index of"`}, false},
+		{"unknown placeholder", "site:{tld} inurl:admin", 0, []string{"site:{tld} inurl:admin"}, false},
+		{"one variable", "site:{domain}", 0, []string{"site:a.com", "site:b.org"}, false},
+		{"two variables", "site:{domain} inurl:{path}", 0, []string{
+			"site:a.com inurl:admin", "site:a.com inurl:login", "site:a.com inurl:panel",
+			"site:b.org inurl:admin", "site:b.org inurl:login", "site:b.org inurl:panel",
+		}, false},
+		{"three variables", "site:{domain} inurl:{path}.{ext}", 0, []string{
+			"site:a.com inurl:admin.php", "site:a.com inurl:login.php", "site:a.com inurl:panel.php",
+			"site:b.org inurl:admin.php", "site:b.org inurl:login.php", "site:b.org inurl:panel.php",
+		}, false},
+		{"repeated variable", "site:{domain} -inurl:{domain}", 0, []string{"site:a.com -inurl:a.com", "site:b.org -inurl:b.org"}, false},
+		{"mixed known and unknown", "site:{domain} {tld}", 0, []string{"site:a.com {tld}", "site:b.org {tld}"}, false},
+		{"empty wordlist", "site:{domain} inurl:{none}", 0, nil, false},
+		{"truncated", "site:{domain} inurl:{path}", 4, []string{
+			"site:a.com inurl:admin", "site:a.com inurl:login", "site:a.com inurl:panel", "site:b.org inurl:admin",
+		}, true},
+		{"exactly at limit", "site:{domain}", 2, []string{"site:a.com", "site:b.org"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := ExpandDork(tt.dork, vars, tt.limit)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("ExpandDork(%q) = %q, want %q", tt.dork, got, tt.want)
+			}
+			if truncated != tt.truncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestExpandDorkWordsAreNotReexpanded(t *testing.T) {
+	vars := map[string][]string{
+		"a": {"{b}"},
+		"b": {"x"},
+	}
+	got, _ := ExpandDork("{a} {b}", vars, 0)
+	if len(got) != 1 || got[0] != "{b} x" {
+		t.Errorf("ExpandDork() = %q, want [\"{b} x\"]", got)
+	}
+}
+
+func TestExpandDorks(t *testing.T) {
+	vars := map[string][]string{"domain": {"a.com", "b.org", "c.net"}, "none": {}}
+	dorks := []string{"inurl:admin", "site:{domain}", "site:{domain} login", "site:{domain} panel", "inurl:{none}", "inurl:login"}
+
+	// The cap is shared: the first template uses 3, the second gets the
+	// last 1 and the third none, while plain dorks still pass through
+	got, truncated := ExpandDorks(dorks, vars, 4)
+	want := "inurl:admin|site:a.com|site:b.org|site:c.net|site:a.com login|inurl:login"
+	if strings.Join(got, "|") != want {
+		t.Errorf("ExpandDorks() = %q, want %s", got, want)
+	}
+	if strings.Join(truncated, "|") != "site:{domain} login|site:{domain} panel" {
+		t.Errorf("truncated = %q, want the second and third templates", truncated)
+	}
+
+	got, truncated = ExpandDorks(dorks, vars, 0)
+	if len(got) != 11 || len(truncated) != 0 {
+		t.Errorf("ExpandDorks() without a limit = %d dorks, %q truncated, want 11 and none", len(got), truncated)
+	}
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Yandex implements SearchEngine for Yandex's HTML results page
+type Yandex struct {
+	Domain         string   // yandex.com
+	Region         int      // lr parameter, a Yandex region ID (0 = by IP)
+	ExcludeDomains []string // Domains to exclude from results
+}
+
+// NewYandex creates a new Yandex search engine
+func NewYandex() *Yandex {
+	return &Yandex{
+		Domain: "yandex.com",
+	}
+}
+
+// yandexMaxResultsPerPage is the largest numdoc Yandex honours
+const yandexMaxResultsPerPage = 50
+
+// yandexResultPattern matches the title link of an organic result, which
+// carries the organic__url class (not organic__url-sitelink and the like)
+// before or after its href
+var yandexResultPattern = regexp.MustCompile(`<a[^>]+class="(?:[^"]*\s)?organic__url(?:\s[^"]*)?"[^>]*href="([^"]+)"|<a[^>]+href="([^"]+)"[^>]*class="(?:[^"]*\s)?organic__url[\s"]`)
+
+// Name returns the engine name
+func (y *Yandex) Name() string {
+	return "yandex"
+}
+
+// Timing returns Yandex's recommended cooldowns. Yandex challenges and
+// bans readily, so these follow the cautious timing profile.
+func (y *Yandex) Timing() Timing {
+	return Timing{
+		CaptchaCooldown: 5 * time.Minute,
+		BlockCooldown:   10 * time.Minute,
+	}
+}
+
+// BuildSearchURL constructs the Yandex search URL. Pages are 0-based in p.
+func (y *Yandex) BuildSearchURL(query string, page int, resultsPerPage int) string {
+	if resultsPerPage > yandexMaxResultsPerPage {
+		resultsPerPage = yandexMaxResultsPerPage
+	}
+
+	params := url.Values{}
+	params.Set("text", query)
+	if resultsPerPage > 0 {
+		params.Set("numdoc", fmt.Sprintf("%d", resultsPerPage))
+	}
+	if y.Region > 0 {
+		params.Set("lr", fmt.Sprintf("%d", y.Region))
+	}
+	if page > 0 {
+		params.Set("p", fmt.Sprintf("%d", page))
+	}
+
+	return fmt.Sprintf("https://%s/search/?%s", y.Domain, params.Encode())
+}
+
+// ParseResults extracts URLs from Yandex search results HTML
+func (y *Yandex) ParseResults(html string) []SearchResult {
+	var results []SearchResult
+	seen := make(map[string]bool)
+
+	for _, match := range yandexResultPattern.FindAllStringSubmatch(html, -1) {
+		rawURL := match[1]
+		if rawURL == "" {
+			rawURL = match[2]
+		}
+
+		cleanURL := y.cleanURL(rawURL)
+		if cleanURL == "" || seen[cleanURL] || y.isExcludedDomain(cleanURL) {
+			continue
+		}
+
+		seen[cleanURL] = true
+		results = append(results, SearchResult{
+			URL:      cleanURL,
+			Position: len(results) + 1,
+		})
+	}
+
+	return results
+}
+
+// cleanURL unwraps Yandex click redirects and validates the target.
+// Links to Yandex's own services (ads, Turbo pages, images) are dropped.
+func (y *Yandex) cleanURL(rawURL string) string {
+	decoded := strings.ReplaceAll(rawURL, "&amp;", "&")
+
+	// Some links are protocol-relative: //yandex.com/clck/jsredir?...
+	if strings.HasPrefix(decoded, "//") {
+		decoded = "https:" + decoded
+	}
+
+	parsed, err := url.Parse(decoded)
+	if err != nil {
+		return ""
+	}
+	if isYandexHost(parsed.Host) && strings.HasPrefix(parsed.Path, "/clck/") {
+		decoded = parsed.Query().Get("url")
+		if parsed, err = url.Parse(decoded); err != nil {
+			return ""
+		}
+	}
+
+	if parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+	if isYandexHost(parsed.Host) {
+		return ""
+	}
+
+	return decoded
+}
+
+// isYandexHost reports whether host belongs to Yandex
+func isYandexHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range []string{"yandex.com", "yandex.ru", "ya.ru", "yandex.net"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcludedDomain checks if URL matches excluded domains
+func (y *Yandex) isExcludedDomain(urlStr string) bool {
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Host)
+	for _, domain := range y.ExcludeDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectCaptcha checks if the response is Yandex's SmartCaptcha or the
+// older showcaptcha challenge
+func (y *Yandex) DetectCaptcha(html string) bool {
+	captchaIndicators := []string{
+		"smartcaptcha",
+		"showcaptcha",
+		"checkcaptcha",
+		"captcha__image",
+		"are you not a robot",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range captchaIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DetectBlock checks if the response indicates a block/ban
+func (y *Yandex) DetectBlock(html string) bool {
+	blockIndicators := []string{
+		"403 forbidden",
+		"access denied",
+		"too many requests",
+		"доступ ограничен",
+	}
+
+	htmlLower := strings.ToLower(html)
+	for _, indicator := range blockIndicators {
+		if strings.Contains(htmlLower, indicator) {
+			return true
+		}
+	}
+
+	// Very short responses are error pages, not result pages
+	return len(html) < 1000 && !strings.Contains(htmlLower, "<html")
+}
+
+// DetectNoResults checks if there are no search results
+func (y *Yandex) DetectNoResults(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, "emptysearchresults") ||
+		strings.Contains(htmlLower, "no results found") ||
+		strings.Contains(htmlLower, "ничего не нашли")
+}
+
+// HasNextPage checks whether the pager links to a next page
+func (y *Yandex) HasNextPage(html string) bool {
+	htmlLower := strings.ToLower(html)
+	return strings.Contains(htmlLower, "pager__item_kind_next") ||
+		strings.Contains(htmlLower, "pager-item_type_next")
+}
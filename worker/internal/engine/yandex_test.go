@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", name, err)
+	}
+	return string(data)
+}
+
+func TestYandexBuildSearchURL(t *testing.T) {
+	y := NewYandex()
+
+	first := y.BuildSearchURL("inurl:admin", 0, 10)
+	if !strings.HasPrefix(first, "https://yandex.com/search/?") || !strings.Contains(first, "text=inurl%3Aadmin") {
+		t.Errorf("BuildSearchURL() = %q", first)
+	}
+	if strings.Contains(first, "p=") {
+		t.Errorf("page 0 URL = %q, want no p parameter", first)
+	}
+
+	y.Region = 84
+	second := y.BuildSearchURL("inurl:admin", 2, 100)
+	if !strings.Contains(second, "p=2") || !strings.Contains(second, "numdoc=50") || !strings.Contains(second, "lr=84") {
+		t.Errorf("page 2 URL = %q, want p=2, numdoc=50 and lr=84", second)
+	}
+}
+
+func TestYandexParseResults(t *testing.T) {
+	results := NewYandex().ParseResults(readTestdata(t, "yandex_serp.html"))
+
+	want := []string{
+		"https://a.example.com/admin/login.php",
+		"https://b.example.org/wp-admin/",
+		"http://c.example.net/cgi-bin/admin.cgi?mode=1&lang=en",
+	}
+	if len(results) != len(want) {
+		t.Fatalf("ParseResults() = %+v, want %v", results, want)
+	}
+	for i, r := range results {
+		if r.URL != want[i] || r.Position != i+1 {
+			t.Errorf("result %d = %+v, want %s at %d", i, r, want[i], i+1)
+		}
+	}
+}
+
+func TestYandexExcludeDomains(t *testing.T) {
+	y := NewYandex()
+	y.ExcludeDomains = []string{"example.org"}
+
+	for _, r := range y.ParseResults(readTestdata(t, "yandex_serp.html")) {
+		if strings.Contains(r.URL, "example.org") {
+			t.Errorf("ParseResults() kept excluded %s", r.URL)
+		}
+	}
+}
+
+func TestYandexDetect(t *testing.T) {
+	y := NewYandex()
+	serp := readTestdata(t, "yandex_serp.html")
+	captcha := readTestdata(t, "yandex_captcha.html")
+
+	if !y.DetectCaptcha(captcha) {
+		t.Error("DetectCaptcha() missed the SmartCaptcha page")
+	}
+	if !y.DetectCaptcha(`<html><form action="/showcaptcha?cc=1&amp;retpath=x">`) {
+		t.Error("DetectCaptcha() missed the showcaptcha redirect")
+	}
+	if y.DetectCaptcha(serp) || y.DetectBlock(serp) {
+		t.Error("results page flagged as CAPTCHA or block")
+	}
+	if !y.DetectBlock("Too Many Requests") {
+		t.Error("DetectBlock() missed a rate limit")
+	}
+	if !y.DetectNoResults(`<div class="EmptySearchResults"><div class="EmptySearchResults-Title">No results found</div></div>`) {
+		t.Error("DetectNoResults() missed the empty page")
+	}
+	if !y.HasNextPage(serp) {
+		t.Error("HasNextPage() missed the pager link")
+	}
+	if y.HasNextPage(`<div class="pager"><span class="pager__item pager__item_current_yes">5</span></div>`) {
+		t.Error("HasNextPage() = true on the last page")
+	}
+	if timing := y.Timing(); timing.CaptchaCooldown <= NewGoogle().Timing().CaptchaCooldown {
+		t.Errorf("Timing() = %+v, want longer CAPTCHA cooldowns than Google", timing)
+	}
+}
+
+// Yandex must drop into Worker.SetEngine like the other engines
+var (
+	_ SearchEngine = (*Yandex)(nil)
+	_ Paginator    = (*Yandex)(nil)
+)
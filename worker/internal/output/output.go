@@ -0,0 +1,369 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dorker/worker/internal/worker"
+)
+
+// Writer persists worker results
+type Writer interface {
+	Write(result *worker.Result) error
+	Count() int64    // URLs written
+	Files() []string // Files produced
+	Close() error
+}
+
+// Format selects how a TextWriter encodes results
+type Format string
+
+const (
+	FormatTXT   Format = "txt"   // One URL per line
+	FormatJSONL Format = "jsonl" // One JSON object per result
+	FormatCSV   Format = "csv"   // One row per URL, with a header
+)
+
+// ParseFormat validates a format name, defaulting to txt when empty
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return FormatTXT, nil
+	case FormatTXT, FormatJSONL, FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (want txt, jsonl or csv)", value)
+	}
+}
+
+// csvHeader starts every CSV file
+var csvHeader = []string{"dork", "url", "status", "proxy_id", "duration_ms"}
+
+// DefaultFlushInterval bounds how long written results may sit in the
+// buffer before reaching the file
+const DefaultFlushInterval = time.Second
+
+// jsonlRecord is one JSON Lines result, shaped like protocol.ResultData
+type jsonlRecord struct {
+	TaskID   string              `json:"task_id"`
+	Dork     string              `json:"dork"`
+	URLs     []string            `json:"urls"`
+	Status   string              `json:"status"`
+	Error    string              `json:"error,omitempty"`
+	ProxyID  string              `json:"proxy_id"`
+	Duration int64               `json:"duration_ms"`
+	Pages    int                 `json:"pages"`
+	Engines  map[string][]string `json:"engines,omitempty"`
+}
+
+// TextWriter writes results to line-based files in one of the formats.
+// When MaxSize is set it rolls over to a new file before a line would
+// push the current file past the limit, so no line is ever split across
+// two files. Buffered lines are flushed within DefaultFlushInterval.
+type TextWriter struct {
+	mu      sync.Mutex
+	dir     string
+	format  Format
+	stamp   int64
+	maxSize int64 // 0 = no rotation
+
+	file   *os.File
+	buf    *bufio.Writer
+	size   int64 // Bytes written to the current file
+	header int64 // Bytes of the current file taken by the CSV header
+	index  int   // Number of the current file, starting at 1
+	files  []string
+	count  int64
+
+	flushTimer *time.Timer // Pending flush, nil when the buffer is flushed
+}
+
+// NewTextWriter creates the first txt output file in dir. Without
+// rotation the file is named results_<ts>.txt; with rotation files are
+// numbered results_<ts>_<n>.txt.
+func NewTextWriter(dir string, maxSize int64) (*TextWriter, error) {
+	return NewFormatWriter(dir, FormatTXT, maxSize)
+}
+
+// NewFormatWriter creates the first output file in dir for format, named
+// as for NewTextWriter with the format as extension
+func NewFormatWriter(dir string, format Format, maxSize int64) (*TextWriter, error) {
+	t := &TextWriter{
+		dir:     dir,
+		format:  format,
+		stamp:   time.Now().Unix(),
+		maxSize: maxSize,
+	}
+
+	if err := t.open(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// open creates the next output file (must hold lock)
+func (t *TextWriter) open() error {
+	t.index++
+
+	name := fmt.Sprintf("results_%d.%s", t.stamp, t.format)
+	if t.maxSize > 0 {
+		name = fmt.Sprintf("results_%d_%d.%s", t.stamp, t.index, t.format)
+	}
+	path := filepath.Join(t.dir, name)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	t.file = file
+	t.buf = bufio.NewWriter(file)
+	t.size = 0
+	t.header = 0
+	t.files = append(t.files, path)
+
+	if t.format == FormatCSV {
+		line, err := csvLine(csvHeader)
+		if err != nil {
+			return err
+		}
+		n, err := t.buf.WriteString(line)
+		t.size += int64(n)
+		t.header = t.size
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// closeFile flushes and closes the current file (must hold lock)
+func (t *TextWriter) closeFile() error {
+	if t.file == nil {
+		return nil
+	}
+
+	if t.flushTimer != nil {
+		t.flushTimer.Stop()
+		t.flushTimer = nil
+	}
+
+	err := t.buf.Flush()
+	if closeErr := t.file.Close(); err == nil {
+		err = closeErr
+	}
+	t.file = nil
+
+	return err
+}
+
+// Write writes the result in the writer's format: a line per URL for txt
+// and csv, a single line for jsonl. Results without URLs still produce a
+// jsonl line and a csv row with an empty url, so failures are recorded.
+func (t *TextWriter) Write(result *worker.Result) error {
+	switch t.format {
+	case FormatJSONL:
+		return t.writeJSONL(result)
+	case FormatCSV:
+		return t.writeCSV(result)
+	}
+
+	for _, u := range result.URLs {
+		if err := t.WriteURL(u.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSONL writes the result as one JSON object
+func (t *TextWriter) writeJSONL(result *worker.Result) error {
+	record := jsonlRecord{
+		TaskID:   result.TaskID,
+		Dork:     result.Dork,
+		URLs:     make([]string, len(result.URLs)),
+		Status:   string(result.Status),
+		Error:    result.Error,
+		ProxyID:  result.ProxyID,
+		Duration: result.Duration.Milliseconds(),
+		Pages:    result.Pages,
+	}
+	for i, u := range result.URLs {
+		record.URLs[i] = u.URL
+		if len(u.Engines) > 0 {
+			if record.Engines == nil {
+				record.Engines = make(map[string][]string, len(result.URLs))
+			}
+			record.Engines[u.URL] = u.Engines
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	return t.writeLine(string(data)+"\n", len(result.URLs))
+}
+
+// writeCSV writes a row per URL of the result
+func (t *TextWriter) writeCSV(result *worker.Result) error {
+	urls := make([]string, len(result.URLs))
+	for i, u := range result.URLs {
+		urls[i] = u.URL
+	}
+	if len(urls) == 0 {
+		urls = []string{""}
+	}
+
+	duration := strconv.FormatInt(result.Duration.Milliseconds(), 10)
+	for _, u := range urls {
+		line, err := csvLine([]string{result.Dork, u, string(result.Status), result.ProxyID, duration})
+		if err != nil {
+			return err
+		}
+
+		written := 0
+		if u != "" {
+			written = 1
+		}
+		if err := t.writeLine(line, written); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvLine encodes one CSV record, quoting fields as needed
+func csvLine(fields []string) (string, error) {
+	var sb strings.Builder
+	cw := csv.NewWriter(&sb)
+	if err := cw.Write(fields); err != nil {
+		return "", err
+	}
+	cw.Flush()
+	return sb.String(), cw.Error()
+}
+
+// WriteURL writes a single URL line, rotating first if needed
+func (t *TextWriter) WriteURL(url string) error {
+	return t.writeLine(url+"\n", 1)
+}
+
+// writeLine writes one encoded line holding urls URLs, rotating first if
+// needed
+func (t *TextWriter) writeLine(line string, urls int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return fmt.Errorf("output writer closed")
+	}
+
+	// An empty file always takes the line, even one longer than the limit
+	if t.maxSize > 0 && t.size > t.header && t.size+int64(len(line)) > t.maxSize {
+		if err := t.closeFile(); err != nil {
+			return err
+		}
+		if err := t.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := t.buf.WriteString(line)
+	t.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	t.count += int64(urls)
+	t.scheduleFlush()
+	return nil
+}
+
+// scheduleFlush arranges for the buffer to be flushed soon, so a crash
+// loses at most DefaultFlushInterval of results (must hold lock)
+func (t *TextWriter) scheduleFlush() {
+	if t.flushTimer != nil {
+		return
+	}
+
+	t.flushTimer = time.AfterFunc(DefaultFlushInterval, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		t.flushTimer = nil
+		if t.file != nil {
+			t.buf.Flush()
+		}
+	})
+}
+
+// Count returns the number of URLs written
+func (t *TextWriter) Count() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.count
+}
+
+// Files returns the paths of all files produced so far
+func (t *TextWriter) Files() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	files := make([]string, len(t.files))
+	copy(files, t.files)
+	return files
+}
+
+// Close flushes and closes the current file
+func (t *TextWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closeFile()
+}
+
+// ParseSize parses a byte size such as "500", "64KB", "10MB" or "1GB".
+// Units are powers of 1024.
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", value)
+	}
+
+	return n * multiplier, nil
+}
@@ -0,0 +1,307 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dorker/worker/internal/engine"
+	"dorker/worker/internal/worker"
+)
+
+func TestTextWriterNoRotation(t *testing.T) {
+	tw, err := NewTextWriter(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewTextWriter() error = %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		tw.WriteURL("https://example.com/page")
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	files := tw.Files()
+	if len(files) != 1 {
+		t.Fatalf("Files() = %d, want 1", len(files))
+	}
+	if strings.Count(filepath.Base(files[0]), "_") != 1 {
+		t.Errorf("file name = %s, want results_<ts>.txt", files[0])
+	}
+	if tw.Count() != 100 {
+		t.Errorf("Count() = %d, want 100", tw.Count())
+	}
+}
+
+func TestTextWriterRotation(t *testing.T) {
+	tw, err := NewTextWriter(t.TempDir(), 50)
+	if err != nil {
+		t.Fatalf("NewTextWriter() error = %v", err)
+	}
+
+	// 20 bytes per line, so two lines fit per file
+	urls := []string{
+		"https://a.com/00001",
+		"https://a.com/00002",
+		"https://a.com/00003",
+		"https://a.com/00004",
+		"https://a.com/00005",
+	}
+	for _, u := range urls {
+		if err := tw.WriteURL(u); err != nil {
+			t.Fatalf("WriteURL() error = %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	files := tw.Files()
+	if len(files) != 3 {
+		t.Fatalf("Files() = %d, want 3", len(files))
+	}
+
+	var lines []string
+	for i, path := range files {
+		if !strings.HasSuffix(path, fmt.Sprintf("_%d.txt", i+1)) {
+			t.Errorf("files[%d] = %s, want suffix _%d.txt", i, path, i+1)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if len(data) > 50 {
+			t.Errorf("%s is %d bytes, want <= 50", path, len(data))
+		}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			t.Errorf("%s does not end on a line boundary", path)
+		}
+		lines = append(lines, strings.Fields(string(data))...)
+	}
+
+	if len(lines) != len(urls) {
+		t.Errorf("read back %d URLs, want %d", len(lines), len(urls))
+	}
+}
+
+func TestTextWriterOversizedLine(t *testing.T) {
+	tw, err := NewTextWriter(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("NewTextWriter() error = %v", err)
+	}
+	defer tw.Close()
+
+	// A line larger than the limit still goes into a fresh file
+	tw.WriteURL("https://example.com/long")
+	tw.WriteURL("https://example.com/long")
+
+	if len(tw.Files()) != 2 {
+		t.Errorf("Files() = %d, want 2", len(tw.Files()))
+	}
+}
+
+func TestTextWriterClosed(t *testing.T) {
+	tw, err := NewTextWriter(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewTextWriter() error = %v", err)
+	}
+	tw.Close()
+
+	if err := tw.WriteURL("https://example.com"); err == nil {
+		t.Error("WriteURL() should fail after Close()")
+	}
+}
+
+// formatResults are a success with two URLs and a failure without any
+var formatResults = []*worker.Result{
+	{
+		TaskID:   "task_1",
+		Dork:     `inurl:"admin"`,
+		URLs:     []engine.SearchResult{{URL: "https://a.example.com/admin"}, {URL: "https://b.example.com/a,b"}},
+		Status:   worker.StatusSuccess,
+		ProxyID:  "http_1.2.3.4_8080",
+		Duration: 1500 * time.Millisecond,
+		Pages:    1,
+	},
+	{
+		TaskID:   "task_2",
+		Dork:     "inurl:login",
+		Status:   worker.StatusCaptcha,
+		ProxyID:  "http_1.2.3.4_8080",
+		Duration: 200 * time.Millisecond,
+	},
+}
+
+func TestFormatWriterJSONL(t *testing.T) {
+	fw, err := NewFormatWriter(t.TempDir(), FormatJSONL, 0)
+	if err != nil {
+		t.Fatalf("NewFormatWriter() error = %v", err)
+	}
+	for _, r := range formatResults {
+		if err := fw.Write(r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if !strings.HasSuffix(fw.Files()[0], ".jsonl") {
+		t.Errorf("file = %s, want .jsonl", fw.Files()[0])
+	}
+	if fw.Count() != 2 {
+		t.Errorf("Count() = %d, want 2", fw.Count())
+	}
+
+	data, err := os.ReadFile(fw.Files()[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2", len(lines))
+	}
+
+	var first map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("line 1 is not JSON: %v", err)
+	}
+	if first["dork"] != `inurl:"admin"` || first["status"] != "success" || first["duration_ms"] != float64(1500) {
+		t.Errorf("line 1 = %v", first)
+	}
+	if urls, _ := first["urls"].([]any); len(urls) != 2 {
+		t.Errorf("line 1 urls = %v, want 2", first["urls"])
+	}
+	if !strings.Contains(lines[1], `"status":"captcha"`) || !strings.Contains(lines[1], `"urls":[]`) {
+		t.Errorf("line 2 = %s, want the captcha result with no URLs", lines[1])
+	}
+}
+
+func TestFormatWriterCSV(t *testing.T) {
+	fw, err := NewFormatWriter(t.TempDir(), FormatCSV, 0)
+	if err != nil {
+		t.Fatalf("NewFormatWriter() error = %v", err)
+	}
+	for _, r := range formatResults {
+		fw.Write(r)
+	}
+	fw.Close()
+
+	file, err := os.Open(fw.Files()[0])
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	want := [][]string{
+		{"dork", "url", "status", "proxy_id", "duration_ms"},
+		{`inurl:"admin"`, "https://a.example.com/admin", "success", "http_1.2.3.4_8080", "1500"},
+		{`inurl:"admin"`, "https://b.example.com/a,b", "success", "http_1.2.3.4_8080", "1500"},
+		{"inurl:login", "", "captcha", "http_1.2.3.4_8080", "200"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+func TestFormatWriterCSVRotation(t *testing.T) {
+	fw, err := NewFormatWriter(t.TempDir(), FormatCSV, 100)
+	if err != nil {
+		t.Fatalf("NewFormatWriter() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		fw.Write(formatResults[0])
+	}
+	fw.Close()
+
+	// Every file starts with the header and holds at least one row
+	for _, path := range fw.Files() {
+		data, _ := os.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if lines[0] != "dork,url,status,proxy_id,duration_ms" || len(lines) < 2 {
+			t.Errorf("%s = %q, want header and rows", path, data)
+		}
+	}
+	if fw.Count() != 6 {
+		t.Errorf("Count() = %d, want 6", fw.Count())
+	}
+}
+
+func TestFormatWriterFlushes(t *testing.T) {
+	fw, err := NewFormatWriter(t.TempDir(), FormatJSONL, 0)
+	if err != nil {
+		t.Fatalf("NewFormatWriter() error = %v", err)
+	}
+	defer fw.Close()
+
+	fw.Write(formatResults[1])
+	time.Sleep(DefaultFlushInterval + 200*time.Millisecond)
+
+	// Reaches the file without Close
+	data, _ := os.ReadFile(fw.Files()[0])
+	if !strings.Contains(string(data), "task_2") {
+		t.Errorf("file = %q, want the result flushed", data)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatTXT, false},
+		{"txt", FormatTXT, false},
+		{"JSONL", FormatJSONL, false},
+		{" csv ", FormatCSV, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+		ok    bool
+	}{
+		{"", 0, true},
+		{"500", 500, true},
+		{"64KB", 64 << 10, true},
+		{"10mb", 10 << 20, true},
+		{"1G", 1 << 30, true},
+		{"2 MB", 2 << 20, true},
+		{"abc", 0, false},
+		{"-5MB", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseSize(%q) error = %v, want ok %v", tt.input, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
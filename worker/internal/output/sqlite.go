@@ -0,0 +1,187 @@
+package output
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"dorker/worker/internal/worker"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS results (
+	dork     TEXT NOT NULL,
+	url      TEXT NOT NULL,
+	proxy_id TEXT,
+	status   TEXT,
+	ts       TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS results_dork_url ON results (dork, url);
+`
+
+// DefaultSQLiteBatchSize is the number of rows inserted per transaction
+const DefaultSQLiteBatchSize = 500
+
+// SQLiteWriter stores result URLs in a SQLite database. Rows are inserted
+// in batched transactions, and the unique (dork, url) index drops repeats.
+type SQLiteWriter struct {
+	mu        sync.Mutex
+	path      string
+	db        *sql.DB
+	tx        *sql.Tx
+	stmt      *sql.Stmt
+	pending   int
+	batchSize int
+	count     int64
+}
+
+// NewSQLiteWriter opens or creates the database at path
+func NewSQLiteWriter(path string, batchSize int) (*SQLiteWriter, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultSQLiteBatchSize
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// A single connection keeps transactions and the schema on one handle
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure database: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	return &SQLiteWriter{
+		path:      path,
+		db:        db,
+		batchSize: batchSize,
+	}, nil
+}
+
+// begin starts a new batch (must hold lock)
+func (s *SQLiteWriter) begin() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO results (dork, url, proxy_id, status, ts) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+
+	s.tx = tx
+	s.stmt = stmt
+	return nil
+}
+
+// commit ends the current batch (must hold lock)
+func (s *SQLiteWriter) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	s.stmt.Close()
+	err := s.tx.Commit()
+	s.tx = nil
+	s.stmt = nil
+	s.pending = 0
+
+	if err != nil {
+		return fmt.Errorf("failed to commit results: %w", err)
+	}
+	return nil
+}
+
+// Write inserts a row for each URL in the result
+func (s *SQLiteWriter) Write(result *worker.Result) error {
+	if len(result.URLs) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return fmt.Errorf("output writer closed")
+	}
+
+	ts := result.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	timestamp := ts.UTC().Format(time.RFC3339)
+
+	for _, u := range result.URLs {
+		if s.tx == nil {
+			if err := s.begin(); err != nil {
+				return err
+			}
+		}
+
+		res, err := s.stmt.Exec(result.Dork, u.URL, result.ProxyID, string(result.Status), timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to insert result: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			s.count += n
+		}
+
+		s.pending++
+		if s.pending >= s.batchSize {
+			if err := s.commit(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush commits any pending rows
+func (s *SQLiteWriter) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit()
+}
+
+// Count returns the number of rows inserted, excluding duplicates
+func (s *SQLiteWriter) Count() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Files returns the database path
+func (s *SQLiteWriter) Files() []string {
+	return []string{s.path}
+}
+
+// Close commits pending rows and closes the database
+func (s *SQLiteWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	err := s.commit()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	s.db = nil
+
+	return err
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dorker/worker/internal/engine"
+	"dorker/worker/internal/worker"
+)
+
+func testResult(dork string, urls ...string) *worker.Result {
+	result := &worker.Result{
+		Dork:      dork,
+		Status:    worker.StatusSuccess,
+		ProxyID:   "proxy_1",
+		Timestamp: time.Now(),
+	}
+	for _, u := range urls {
+		result.URLs = append(result.URLs, engine.SearchResult{URL: u})
+	}
+	return result
+}
+
+func TestSQLiteWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+
+	sw, err := NewSQLiteWriter(path, 2)
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter() error = %v", err)
+	}
+
+	sw.Write(testResult("inurl:admin", "https://a.com", "https://b.com", "https://c.com"))
+	// Same dork and URL is dropped; same URL under another dork is kept
+	sw.Write(testResult("inurl:admin", "https://a.com"))
+	sw.Write(testResult("inurl:login", "https://a.com"))
+
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if sw.Count() != 4 {
+		t.Errorf("Count() = %d, want 4", sw.Count())
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	var rows int
+	if err := db.QueryRow("SELECT COUNT(*) FROM results").Scan(&rows); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if rows != 4 {
+		t.Errorf("rows = %d, want 4", rows)
+	}
+
+	var proxyID, status string
+	db.QueryRow("SELECT proxy_id, status FROM results WHERE url = ?", "https://b.com").Scan(&proxyID, &status)
+	if proxyID != "proxy_1" || status != "success" {
+		t.Errorf("row = (%q, %q), want (proxy_1, success)", proxyID, status)
+	}
+}
+
+func TestSQLiteWriterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+
+	sw, err := NewSQLiteWriter(path, 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter() error = %v", err)
+	}
+	sw.Write(testResult("inurl:admin", "https://a.com"))
+	sw.Close()
+
+	// An existing database is appended to and still deduplicated
+	sw, err = NewSQLiteWriter(path, 0)
+	if err != nil {
+		t.Fatalf("NewSQLiteWriter() error = %v", err)
+	}
+	sw.Write(testResult("inurl:admin", "https://a.com", "https://b.com"))
+	sw.Close()
+
+	if sw.Count() != 1 {
+		t.Errorf("Count() = %d, want 1", sw.Count())
+	}
+	if err := sw.Write(testResult("inurl:admin", "https://c.com")); err == nil {
+		t.Error("Write() should fail after Close()")
+	}
+}
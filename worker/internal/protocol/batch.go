@@ -0,0 +1,82 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+// Result batching defaults, used when the batcher is given zero values
+const (
+	DefaultBatchSize          = 50
+	DefaultBatchFlushInterval = 500 * time.Millisecond
+)
+
+// ResultBatcher coalesces results into result_batch messages. A batch is
+// sent once it holds size results or interval after its first result,
+// whichever comes first. Flush before exiting so no result is lost.
+type ResultBatcher struct {
+	handler  *Handler
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []*ResultData
+	timer   *time.Timer
+}
+
+// NewResultBatcher creates a batcher sending through h
+func NewResultBatcher(h *Handler, size int, interval time.Duration) *ResultBatcher {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if interval <= 0 {
+		interval = DefaultBatchFlushInterval
+	}
+
+	return &ResultBatcher{
+		handler:  h,
+		size:     size,
+		interval: interval,
+	}
+}
+
+// Add queues a result, sending the batch if it is full
+func (b *ResultBatcher) Add(result *ResultData) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, result)
+	if len(b.pending) >= b.size {
+		return b.flush()
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, func() { b.Flush() })
+	}
+	return nil
+}
+
+// Flush sends the queued results now. A nil batcher has nothing to send.
+func (b *ResultBatcher) Flush() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush()
+}
+
+// flush sends the pending batch (must hold lock)
+func (b *ResultBatcher) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	batch := b.pending
+	b.pending = nil
+	return b.handler.SendResultBatch(batch)
+}
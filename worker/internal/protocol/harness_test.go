@@ -0,0 +1,162 @@
+package protocol
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// harnessTimeout bounds every wait on the handler
+const harnessTimeout = 2 * time.Second
+
+// pipeHarness runs a Handler's Start loop over in-memory pipes, so tests
+// can talk to it the way a controller does over stdin/stdout
+type pipeHarness struct {
+	t       *testing.T
+	handler *Handler
+
+	in   *io.PipeWriter // Commands to the handler
+	out  chan *Message  // Messages from the handler, closed when output ends
+	done chan struct{}  // Closed when Start returns
+}
+
+// newPipeHarness wires a handler to pipes, lets setup register callbacks
+// and starts it. The handler is stopped when the test ends.
+func newPipeHarness(t *testing.T, setup func(h *Handler)) *pipeHarness {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	p := &pipeHarness{
+		t:       t,
+		handler: NewHandlerWithIO(inR, outW),
+		in:      inW,
+		out:     make(chan *Message, 100),
+		done:    make(chan struct{}),
+	}
+	if setup != nil {
+		setup(p.handler)
+	}
+
+	// Drain output continuously; pipe writes block until read
+	go func() {
+		defer close(p.out)
+		scanner := bufio.NewScanner(outR)
+		scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+		for scanner.Scan() {
+			var msg Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				t.Errorf("handler wrote invalid JSON %q: %v", scanner.Text(), err)
+				continue
+			}
+			p.out <- &msg
+		}
+	}()
+
+	go func() {
+		p.handler.Start()
+		// Fail further sends instead of blocking, and end the output
+		inR.Close()
+		outW.Close()
+		close(p.done)
+	}()
+
+	t.Cleanup(p.stop)
+	return p
+}
+
+// send writes a command to the handler
+func (p *pipeHarness) send(msgType MessageType, data map[string]any) {
+	p.t.Helper()
+
+	msg := NewMessage(msgType)
+	msg.Data = data
+	line, err := json.Marshal(msg)
+	if err != nil {
+		p.t.Fatalf("Marshal() error = %v", err)
+	}
+	if _, err := p.in.Write(append(line, '\n')); err != nil {
+		p.t.Fatalf("send %s: %v", msgType, err)
+	}
+}
+
+// next returns the next message from the handler
+func (p *pipeHarness) next() *Message {
+	p.t.Helper()
+
+	select {
+	case msg, ok := <-p.out:
+		if !ok {
+			p.t.Fatal("handler output closed")
+		}
+		return msg
+	case <-time.After(harnessTimeout):
+		p.t.Fatal("timed out waiting for a message")
+	}
+	return nil
+}
+
+// expect skips messages until one of the given type arrives
+func (p *pipeHarness) expect(msgType MessageType) *Message {
+	p.t.Helper()
+
+	deadline := time.After(harnessTimeout)
+	for {
+		select {
+		case msg, ok := <-p.out:
+			if !ok {
+				p.t.Fatalf("handler output closed before a %s message", msgType)
+			}
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-deadline:
+			p.t.Fatalf("timed out waiting for a %s message", msgType)
+		}
+	}
+}
+
+// expectStatus skips messages until a status message with the given
+// status arrives
+func (p *pipeHarness) expectStatus(status string) *Message {
+	p.t.Helper()
+
+	for {
+		msg := p.expect(MsgTypeStatus)
+		if msg.GetString("status") == status {
+			return msg
+		}
+	}
+}
+
+// closeInput closes the handler's input, as a controller exiting would
+func (p *pipeHarness) closeInput() {
+	p.in.Close()
+}
+
+// wait blocks until Start returns
+func (p *pipeHarness) wait() {
+	p.t.Helper()
+
+	select {
+	case <-p.done:
+	case <-time.After(harnessTimeout):
+		p.t.Fatal("Start() did not return")
+	}
+}
+
+// stop stops the handler and waits for Start to return. The blocked read
+// is released by closing the input.
+func (p *pipeHarness) stop() {
+	p.handler.Stop()
+	p.in.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(harnessTimeout):
+		p.t.Error("Start() did not return after Stop()")
+	}
+}
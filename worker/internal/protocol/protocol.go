@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,22 +18,33 @@ type MessageType string
 
 const (
 	// Commands from CLI to Worker
-	MsgTypeInit      MessageType = "init"
-	MsgTypeTask      MessageType = "task"
-	MsgTypeTaskBatch MessageType = "task_batch"
-	MsgTypePause     MessageType = "pause"
-	MsgTypeResume    MessageType = "resume"
-	MsgTypeShutdown  MessageType = "shutdown"
-	MsgTypeGetStats  MessageType = "get_stats"
+	MsgTypeInit             MessageType = "init"
+	MsgTypeUpdateConfig     MessageType = "update_config"
+	MsgTypeTask             MessageType = "task"
+	MsgTypeTaskBatch        MessageType = "task_batch"
+	MsgTypeCancelTask       MessageType = "cancel_task"
+	MsgTypePause            MessageType = "pause"
+	MsgTypeResume           MessageType = "resume"
+	MsgTypeShutdown         MessageType = "shutdown"
+	MsgTypeGetStats         MessageType = "get_stats"
+	MsgTypeGetHealth        MessageType = "get_health"
+	MsgTypeGetProxies       MessageType = "get_proxies"
+	MsgTypeGetProxyList     MessageType = "get_proxy_list" // Alias of get_proxies
+	MsgTypeExportProxyStats MessageType = "export_proxy_stats"
 
 	// Responses from Worker to CLI
-	MsgTypeStatus    MessageType = "status"
-	MsgTypeResult    MessageType = "result"
-	MsgTypeStats     MessageType = "stats"
-	MsgTypeError     MessageType = "error"
-	MsgTypeLog       MessageType = "log"
-	MsgTypeProgress  MessageType = "progress"
-	MsgTypeProxyInfo MessageType = "proxy_info"
+	MsgTypeStatus      MessageType = "status"
+	MsgTypeResult      MessageType = "result"
+	MsgTypeResultBatch MessageType = "result_batch"
+	MsgTypeStats       MessageType = "stats"
+	MsgTypeHealth      MessageType = "health"
+	MsgTypeError       MessageType = "error"
+	MsgTypeLog         MessageType = "log"
+	MsgTypeProgress    MessageType = "progress"
+	MsgTypeProxyInfo   MessageType = "proxy_info"
+	MsgTypeProxies     MessageType = "proxies"
+	MsgTypeComplete    MessageType = "complete"
+	MsgTypeBatchAck    MessageType = "batch_ack"
 )
 
 // Message is the base IPC message structure
@@ -138,9 +152,235 @@ type InitConfig struct {
 	MinDelay       time.Duration `json:"min_delay"`
 	MaxDelay       time.Duration `json:"max_delay"`
 	MaxRetries     int           `json:"max_retries"`
+	GlobalRPM      int           `json:"global_rpm"` // Requests per minute across all workers (0 = unlimited)
 	ResultsPerPage int           `json:"results_per_page"`
+	MaxPages       int           `json:"max_pages"`
 	Proxies        []string      `json:"proxies"`
 	ProxyFile      string        `json:"proxy_file"`
+	ProxyURL       string        `json:"proxy_url"`
+	ProxyRefresh   time.Duration `json:"proxy_refresh"`
+	Engine         string        `json:"engine"`
+	Engines        []string      `json:"engines"` // Run every task on all of these (opt-in)
+	UniqueDomains  bool          `json:"unique_domains"`
+	Dedup          bool          `json:"dedup"` // Drop URLs already emitted this run
+	MaxRuntime     time.Duration `json:"max_runtime"`
+	DNSCacheSize   int           `json:"dns_cache_size"` // Caches HTTP proxy hostnames
+	DNSCacheTTL    time.Duration `json:"dns_cache_ttl"`
+	TargetAlive    int           `json:"target_alive"`
+	StickyProxy    bool          `json:"sticky_proxy"`
+	TimeRange      string        `json:"time_range"` // Default task time range: d, w, m or y
+
+	// ProxySelection picks the pool's selection strategy: weighted
+	// (default), round_robin, least_used or random
+	ProxySelection string `json:"proxy_selection"`
+
+	// RotateEveryRequest hands every request the proxy idle longest,
+	// overriding ProxySelection
+	RotateEveryRequest bool `json:"rotate_every_request"`
+
+	// MaxResultsPerDork stops paging once a dork has this many URLs,
+	// counted after dedup (0 = unlimited)
+	MaxResultsPerDork int `json:"max_results_per_dork"`
+
+	// MaxBodyBytes fails responses larger than this once decoded (0 = the
+	// worker's 5MB default)
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+
+	// ProxyState is a file holding learned proxy stats, loaded at init
+	// and saved on shutdown (empty = disabled)
+	ProxyState        string `json:"proxy_state"`
+	ProxyStateRecheck bool   `json:"proxy_state_recheck"` // Health check proxies saved as dead
+
+	// Failures in a row that quarantine a proxy, and how long it stays
+	// out before being rechecked (zero = pool defaults)
+	QuarantineThreshold int           `json:"quarantine_threshold"`
+	QuarantineDuration  time.Duration `json:"quarantine_duration"`
+
+	// Remove proxies dead this long (0 = keep them)
+	EvictAfterDeadDuration time.Duration `json:"evict_after_dead_duration"`
+
+	// Requests in flight through one proxy at a time (0 = no limit)
+	MaxConcurrentPerProxy int `json:"max_concurrent_per_proxy"`
+
+	// Park workers while the CAPTCHA/block rate over the last window
+	// responses reaches threshold (zero = worker defaults)
+	AdaptiveConcurrency bool    `json:"adaptive_concurrency"`
+	AdaptiveWindow      int     `json:"adaptive_window"`
+	AdaptiveThreshold   float64 `json:"adaptive_threshold"`
+
+	// Halt every worker while the CAPTCHA/block rate over the last window
+	// responses reaches threshold, then probe before resuming (zero =
+	// worker defaults)
+	CircuitBreaker   bool          `json:"circuit_breaker"`
+	BreakerWindow    int           `json:"breaker_window"`
+	BreakerThreshold float64       `json:"breaker_threshold"`
+	BreakerCooldown  time.Duration `json:"breaker_cooldown"`
+	BreakerProbes    int           `json:"breaker_probes"`
+
+	// Per-proxy burst and session pacing: aggressive, normal, cautious
+	// or stealth (empty = base/min/max delay)
+	TimingProfile string `json:"timing_profile"`
+
+	// What to do with results while the results buffer is full: block,
+	// drop_newest or drop_oldest (empty = drop_newest)
+	OverflowPolicy string `json:"overflow_policy"`
+
+	// Send each fingerprint's browser ClientHello via uTLS
+	TLSFingerprint bool `json:"tls_fingerprint"`
+
+	// Keep requests on HTTP/1.1 instead of negotiating HTTP/2
+	ForceHTTP1 bool `json:"force_http1"`
+
+	// Load the engine's homepage through each proxy before its first search
+	WarmUp bool `json:"warm_up"`
+
+	// JSON file of browser fingerprints added to, or replacing by ID, the
+	// built-in ones
+	FingerprintsFile string `json:"fingerprints_file"`
+
+	// Reassign every proxy's fingerprint after this many requests (0 =
+	// kept for the session)
+	FingerprintRotation int `json:"fingerprint_rotation"`
+
+	// Zero uses the engine's recommended cooldowns
+	CaptchaCooldown time.Duration `json:"captcha_cooldown"`
+	BlockCooldown   time.Duration `json:"block_cooldown"`
+
+	// Cooldown after a failed request (0 = none)
+	FailureCooldown time.Duration `json:"failure_cooldown"`
+
+	// Cap on the cooldown a 429's Retry-After asks for (0 = pool default)
+	MaxRetryAfter time.Duration `json:"max_retry_after"`
+
+	// Send results in result_batch messages of up to BatchSize, flushed
+	// after BatchFlushInterval (zero = batcher defaults)
+	BatchResults       bool          `json:"batch_results"`
+	BatchSize          int           `json:"batch_size"`
+	BatchFlushInterval time.Duration `json:"batch_flush_interval"`
+
+	// Debug recording of requests and responses (empty dir = disabled)
+	RecordDir      string `json:"record_dir"`
+	RecordMaxBytes int64  `json:"record_max_bytes"`
+	RecordHTML     bool   `json:"record_html"`
+
+	SoftBlockMarkers []string `json:"soft_block_markers"`
+
+	// Google domains to rotate searches across, never the same one twice
+	// in a row, or to pin each proxy to one of at random if sticky (empty
+	// = www.google.com only)
+	GoogleDomains []string `json:"google_domains"`
+	StickyDomain  bool     `json:"sticky_domain"`
+
+	// Result URL filters: regular expressions a URL must match one of
+	// (include) or none of (exclude), and domains whose URLs are dropped
+	IncludePatterns []string `json:"include_patterns"`
+	ExcludePatterns []string `json:"exclude_patterns"`
+	DenyDomains     []string `json:"deny_domains"`
+
+	// DryRun returns each task's search URLs instead of requesting them
+	DryRun bool `json:"dry_run"`
+
+	// StrictDorks fails tasks whose dork cannot match anything instead of
+	// searching them as written
+	StrictDorks bool `json:"strict_dorks"`
+
+	// DorkProgress adds the finished dork and its URL count to every
+	// progress message
+	DorkProgress bool `json:"dork_progress"`
+
+	// Seed for every random source, making runs reproducible (0 = seeded
+	// from the clock)
+	Seed int64 `json:"seed"`
+}
+
+// initConfigKeys lists the keys accepted in init data and their JSON kinds
+var initConfigKeys = map[string]string{
+	"workers":          "number",
+	"timeout":          "number",
+	"base_delay":       "number",
+	"min_delay":        "number",
+	"max_delay":        "number",
+	"max_retries":      "number",
+	"global_rpm":       "number",
+	"results_per_page": "number",
+	"max_pages":        "number",
+	"proxies":          "array",
+	"proxy_file":       "string",
+	"proxy_url":        "string",
+	"proxy_refresh":    "number",
+	"engine":           "string",
+	"engines":          "array",
+	"unique_domains":   "bool",
+	"dedup":            "bool",
+	"max_runtime":      "number",
+	"dns_cache_size":   "number",
+	"dns_cache_ttl":    "number",
+	"target_alive":     "number",
+	"sticky_proxy":     "bool",
+	"time_range":       "string",
+	"proxy_selection":  "string",
+
+	"max_results_per_dork": "number",
+	"rotate_every_request": "bool",
+	"max_body_bytes":       "number",
+
+	"proxy_state":         "string",
+	"proxy_state_recheck": "bool",
+
+	"quarantine_threshold": "number",
+	"quarantine_duration":  "number",
+
+	"evict_after_dead_duration": "number",
+
+	"max_concurrent_per_proxy": "number",
+
+	"adaptive_concurrency": "bool",
+	"adaptive_window":      "number",
+	"adaptive_threshold":   "number",
+
+	"circuit_breaker":   "bool",
+	"breaker_window":    "number",
+	"breaker_threshold": "number",
+	"breaker_cooldown":  "number",
+	"breaker_probes":    "number",
+
+	"timing_profile":  "string",
+	"overflow_policy": "string",
+
+	"tls_fingerprint": "bool",
+	"force_http1":     "bool",
+	"warm_up":         "bool",
+
+	"fingerprints_file":    "string",
+	"fingerprint_rotation": "number",
+
+	"captcha_cooldown": "number",
+	"block_cooldown":   "number",
+	"failure_cooldown": "number",
+	"max_retry_after":  "number",
+
+	"batch_results":        "bool",
+	"batch_size":           "number",
+	"batch_flush_interval": "number",
+
+	"record_dir":       "string",
+	"record_max_bytes": "number",
+	"record_html":      "bool",
+
+	"soft_block_markers": "array",
+	"include_patterns":   "array",
+	"exclude_patterns":   "array",
+	"deny_domains":       "array",
+
+	"google_domains": "array",
+	"sticky_domain":  "bool",
+
+	"dry_run":      "bool",
+	"strict_dorks": "bool",
+
+	"dork_progress": "bool",
+
+	"seed": "number",
 }
 
 // ParseInitConfig parses init config from message data
@@ -152,9 +392,87 @@ func ParseInitConfig(m *Message) *InitConfig {
 		MinDelay:       time.Duration(m.GetInt("min_delay")) * time.Millisecond,
 		MaxDelay:       time.Duration(m.GetInt("max_delay")) * time.Millisecond,
 		MaxRetries:     m.GetInt("max_retries"),
+		GlobalRPM:      m.GetInt("global_rpm"),
 		ResultsPerPage: m.GetInt("results_per_page"),
+		MaxPages:       m.GetInt("max_pages"),
 		Proxies:        m.GetStringSlice("proxies"),
 		ProxyFile:      m.GetString("proxy_file"),
+		ProxyURL:       m.GetString("proxy_url"),
+		ProxyRefresh:   time.Duration(m.GetInt("proxy_refresh")) * time.Millisecond,
+		Engine:         m.GetString("engine"),
+		Engines:        m.GetStringSlice("engines"),
+		UniqueDomains:  m.GetBool("unique_domains"),
+		Dedup:          m.GetBool("dedup"),
+		MaxRuntime:     time.Duration(m.GetInt("max_runtime")) * time.Millisecond,
+		DNSCacheSize:   m.GetInt("dns_cache_size"),
+		DNSCacheTTL:    time.Duration(m.GetInt("dns_cache_ttl")) * time.Millisecond,
+		TargetAlive:    m.GetInt("target_alive"),
+		StickyProxy:    m.GetBool("sticky_proxy"),
+		TimeRange:      m.GetString("time_range"),
+		ProxySelection: m.GetString("proxy_selection"),
+
+		MaxResultsPerDork:  m.GetInt("max_results_per_dork"),
+		RotateEveryRequest: m.GetBool("rotate_every_request"),
+		MaxBodyBytes:       int64(m.GetInt("max_body_bytes")),
+
+		ProxyState:        m.GetString("proxy_state"),
+		ProxyStateRecheck: m.GetBool("proxy_state_recheck"),
+
+		QuarantineThreshold: m.GetInt("quarantine_threshold"),
+		QuarantineDuration:  time.Duration(m.GetInt("quarantine_duration")) * time.Millisecond,
+
+		EvictAfterDeadDuration: time.Duration(m.GetInt("evict_after_dead_duration")) * time.Millisecond,
+
+		MaxConcurrentPerProxy: m.GetInt("max_concurrent_per_proxy"),
+
+		AdaptiveConcurrency: m.GetBool("adaptive_concurrency"),
+		AdaptiveWindow:      m.GetInt("adaptive_window"),
+		AdaptiveThreshold:   m.GetFloat("adaptive_threshold"),
+
+		CircuitBreaker:   m.GetBool("circuit_breaker"),
+		BreakerWindow:    m.GetInt("breaker_window"),
+		BreakerThreshold: m.GetFloat("breaker_threshold"),
+		BreakerCooldown:  time.Duration(m.GetInt("breaker_cooldown")) * time.Millisecond,
+		BreakerProbes:    m.GetInt("breaker_probes"),
+
+		TimingProfile: m.GetString("timing_profile"),
+
+		OverflowPolicy: m.GetString("overflow_policy"),
+
+		TLSFingerprint: m.GetBool("tls_fingerprint"),
+		ForceHTTP1:     m.GetBool("force_http1"),
+		WarmUp:         m.GetBool("warm_up"),
+
+		FingerprintsFile:    m.GetString("fingerprints_file"),
+		FingerprintRotation: m.GetInt("fingerprint_rotation"),
+
+		CaptchaCooldown: time.Duration(m.GetInt("captcha_cooldown")) * time.Millisecond,
+		BlockCooldown:   time.Duration(m.GetInt("block_cooldown")) * time.Millisecond,
+		FailureCooldown: time.Duration(m.GetInt("failure_cooldown")) * time.Millisecond,
+		MaxRetryAfter:   time.Duration(m.GetInt("max_retry_after")) * time.Millisecond,
+
+		BatchResults:       m.GetBool("batch_results"),
+		BatchSize:          m.GetInt("batch_size"),
+		BatchFlushInterval: time.Duration(m.GetInt("batch_flush_interval")) * time.Millisecond,
+
+		RecordDir:      m.GetString("record_dir"),
+		RecordMaxBytes: int64(m.GetInt("record_max_bytes")),
+		RecordHTML:     m.GetBool("record_html"),
+
+		SoftBlockMarkers: m.GetStringSlice("soft_block_markers"),
+		IncludePatterns:  m.GetStringSlice("include_patterns"),
+		ExcludePatterns:  m.GetStringSlice("exclude_patterns"),
+		DenyDomains:      m.GetStringSlice("deny_domains"),
+
+		GoogleDomains: m.GetStringSlice("google_domains"),
+		StickyDomain:  m.GetBool("sticky_domain"),
+
+		DryRun:      m.GetBool("dry_run"),
+		StrictDorks: m.GetBool("strict_dorks"),
+
+		DorkProgress: m.GetBool("dork_progress"),
+
+		Seed: int64(m.GetInt("seed")),
 	}
 
 	// Apply defaults
@@ -179,24 +497,181 @@ func ParseInitConfig(m *Message) *InitConfig {
 	if config.ResultsPerPage == 0 {
 		config.ResultsPerPage = 100
 	}
+	if config.MaxPages == 0 {
+		config.MaxPages = 1
+	}
+	if config.Engine == "" {
+		config.Engine = "google"
+	}
 
 	return config
 }
 
+// ReadInitConfigFile reads a JSON file holding init data.
+// The file uses the same keys and units (milliseconds) as the init message.
+func ReadInitConfigFile(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+
+	if err := ValidateInitData(data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ValidateInitData checks init data for unknown keys and mistyped values
+func ValidateInitData(data map[string]any) error {
+	var unknown []string
+	var problems []string
+
+	for key, value := range data {
+		kind, ok := initConfigKeys[key]
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		switch kind {
+		case "number":
+			v, ok := value.(float64)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s must be a number", key))
+			} else if v < 0 {
+				problems = append(problems, fmt.Sprintf("%s must not be negative", key))
+			}
+		case "string":
+			if _, ok := value.(string); !ok {
+				problems = append(problems, fmt.Sprintf("%s must be a string", key))
+			}
+		case "array":
+			if _, ok := value.([]any); !ok {
+				problems = append(problems, fmt.Sprintf("%s must be an array", key))
+			}
+		case "bool":
+			if _, ok := value.(bool); !ok {
+				problems = append(problems, fmt.Sprintf("%s must be a boolean", key))
+			}
+		}
+	}
+
+	if min, ok := data["min_delay"].(float64); ok {
+		if max, ok := data["max_delay"].(float64); ok && min > max {
+			problems = append(problems, "min_delay must not exceed max_delay")
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		problems = append(problems, fmt.Sprintf("unknown keys: %s", strings.Join(unknown, ", ")))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid init config: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
 // TaskData represents a single task
 type TaskData struct {
-	ID   string `json:"id"`
-	Dork string `json:"dork"`
-	Page int    `json:"page"`
+	ID       string `json:"id"`
+	Dork     string `json:"dork"`
+	Page     int    `json:"page"`
+	Priority int    `json:"priority"`
+	MaxPages int    `json:"max_pages"` // Overrides init max_pages when > 0
+
+	// StickyProxy overrides the init sticky_proxy setting when set
+	StickyProxy *bool `json:"sticky_proxy,omitempty"`
+
+	// SessionKey pins tasks sharing it to one proxy, moving to another
+	// once that proxy fails
+	SessionKey string `json:"session_key,omitempty"`
+
+	// TimeRange overrides the init time_range setting when not empty
+	TimeRange string `json:"time_range,omitempty"`
+
+	// Two-letter codes for regional results (empty = engine defaults)
+	Country  string `json:"country,omitempty"`
+	Language string `json:"language,omitempty"`
 }
 
 // ParseTaskData parses task data from message
 func ParseTaskData(m *Message) *TaskData {
-	return &TaskData{
-		ID:   m.GetString("task_id"),
-		Dork: m.GetString("dork"),
-		Page: m.GetInt("page"),
+	task := &TaskData{
+		ID:       m.GetString("task_id"),
+		Dork:     m.GetString("dork"),
+		Page:     m.GetInt("page"),
+		Priority: m.GetInt("priority"),
+		MaxPages: m.GetInt("max_pages"),
+
+		SessionKey: m.GetString("session_key"),
+		TimeRange:  m.GetString("time_range"),
+		Country:    m.GetString("country"),
+		Language:   m.GetString("language"),
 	}
+	if sticky, ok := m.Data["sticky_proxy"].(bool); ok {
+		task.StickyProxy = &sticky
+	}
+	return task
+}
+
+// ParseTaskBatch parses the tasks of a task_batch message, skipping
+// entries that are not objects
+func ParseTaskBatch(m *Message) []*TaskData {
+	items, _ := m.Data["tasks"].([]any)
+
+	tasks := make([]*TaskData, 0, len(items))
+	for _, t := range items {
+		taskMap, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		task := &TaskData{
+			ID:   fmt.Sprintf("%v", taskMap["id"]),
+			Dork: fmt.Sprintf("%v", taskMap["dork"]),
+		}
+		if page, ok := taskMap["page"].(float64); ok {
+			task.Page = int(page)
+		}
+		if priority, ok := taskMap["priority"].(float64); ok {
+			task.Priority = int(priority)
+		}
+		if maxPages, ok := taskMap["max_pages"].(float64); ok {
+			task.MaxPages = int(maxPages)
+		}
+		if sticky, ok := taskMap["sticky_proxy"].(bool); ok {
+			task.StickyProxy = &sticky
+		}
+		if sessionKey, ok := taskMap["session_key"].(string); ok {
+			task.SessionKey = sessionKey
+		}
+		if timeRange, ok := taskMap["time_range"].(string); ok {
+			task.TimeRange = timeRange
+		}
+		if country, ok := taskMap["country"].(string); ok {
+			task.Country = country
+		}
+		if language, ok := taskMap["language"].(string); ok {
+			task.Language = language
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// ParseCancelTask returns the ID of the task a cancel message targets
+func ParseCancelTask(m *Message) string {
+	return m.GetString("task_id")
 }
 
 // ResultData represents task result
@@ -208,6 +683,22 @@ type ResultData struct {
 	Error    string   `json:"error,omitempty"`
 	ProxyID  string   `json:"proxy_id"`
 	Duration int64    `json:"duration_ms"`
+	Pages    int      `json:"pages"` // Result pages fetched
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Truncated reports that max_results_per_dork cut the result short
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Why a task ended as it did: the last HTTP status received (0 =
+	// none, e.g. a connection error), whether it ended on a block or
+	// CAPTCHA, and the time spent on requests out of Duration
+	StatusCode int   `json:"status_code,omitempty"`
+	Blocked    bool  `json:"blocked,omitempty"`
+	Captcha    bool  `json:"captcha,omitempty"`
+	Latency    int64 `json:"latency_ms"`
+
+	// Engines maps each URL to the engines that found it (multi-engine mode)
+	Engines map[string][]string `json:"engines,omitempty"`
 }
 
 // ToMessage converts result data to a message
@@ -219,26 +710,66 @@ func (r *ResultData) ToMessage() *Message {
 	msg.SetData("status", r.Status)
 	msg.SetData("proxy_id", r.ProxyID)
 	msg.SetData("duration_ms", r.Duration)
+	msg.SetData("pages", r.Pages)
+	msg.SetData("latency_ms", r.Latency)
+	if r.StatusCode != 0 {
+		msg.SetData("status_code", r.StatusCode)
+	}
+	if r.Blocked {
+		msg.SetData("blocked", true)
+	}
+	if r.Captcha {
+		msg.SetData("captcha", true)
+	}
+	if len(r.Engines) > 0 {
+		msg.SetData("engines", r.Engines)
+	}
 	if r.Error != "" {
 		msg.SetData("error", r.Error)
 	}
+	if len(r.Warnings) > 0 {
+		msg.SetData("warnings", r.Warnings)
+	}
+	if r.Truncated {
+		msg.SetData("truncated", true)
+	}
 	return msg
 }
 
 // StatsData represents worker statistics
 type StatsData struct {
-	TasksTotal     int64   `json:"tasks_total"`
-	TasksCompleted int64   `json:"tasks_completed"`
-	TasksFailed    int64   `json:"tasks_failed"`
-	TasksPending   int64   `json:"tasks_pending"`
-	URLsFound      int64   `json:"urls_found"`
-	CaptchaCount   int64   `json:"captcha_count"`
-	BlockCount     int64   `json:"block_count"`
-	ProxiesAlive   int     `json:"proxies_alive"`
-	ProxiesDead    int     `json:"proxies_dead"`
-	RequestsPerSec float64 `json:"requests_per_sec"`
-	ElapsedMs      int64   `json:"elapsed_ms"`
-	ETAMs          int64   `json:"eta_ms"`
+	TasksTotal         int64   `json:"tasks_total"`
+	TasksCompleted     int64   `json:"tasks_completed"`
+	TasksFailed        int64   `json:"tasks_failed"`
+	TasksPending       int64   `json:"tasks_pending"`
+	RetryQueued        int64   `json:"retry_queued"`
+	URLsFound          int64   `json:"urls_found"`
+	URLsFiltered       int64   `json:"urls_filtered"`
+	CaptchaCount       int64   `json:"captcha_count"`
+	BlockCount         int64   `json:"block_count"`
+	ResultsDropped     int64   `json:"results_dropped"`
+	UniqueDomains      int64   `json:"unique_domains"`
+	WireBytes          int64   `json:"wire_bytes"`
+	DecodedBytes       int64   `json:"decoded_bytes"`
+	ProxiesAlive       int     `json:"proxies_alive"`
+	ProxiesDead        int     `json:"proxies_dead"`
+	ProxiesQuarantined int     `json:"proxies_quarantined"`
+	ProxiesEvicted     int64   `json:"proxies_evicted"`
+	RequestsPerSec     float64 `json:"requests_per_sec"`
+	ElapsedMs          int64   `json:"elapsed_ms"`
+	ETAMs              int64   `json:"eta_ms"`
+	RemainingMs        int64   `json:"remaining_runtime_ms"`
+
+	// Completion rate over roughly the last 30 seconds, where
+	// requests_per_sec averages the whole run
+	RequestsPerSecRecent float64 `json:"requests_per_sec_recent"`
+
+	// Workers allowed to run at once under adaptive concurrency
+	EffectiveConcurrency int `json:"effective_concurrency"`
+
+	// Circuit breaker state (empty = disabled) and how often it tripped
+	BreakerState string `json:"breaker_state,omitempty"`
+	BreakerTrips int64  `json:"breaker_trips"`
 }
 
 // ToMessage converts stats data to a message
@@ -248,14 +779,52 @@ func (s *StatsData) ToMessage() *Message {
 	msg.SetData("tasks_completed", s.TasksCompleted)
 	msg.SetData("tasks_failed", s.TasksFailed)
 	msg.SetData("tasks_pending", s.TasksPending)
+	msg.SetData("retry_queued", s.RetryQueued)
 	msg.SetData("urls_found", s.URLsFound)
+	msg.SetData("urls_filtered", s.URLsFiltered)
 	msg.SetData("captcha_count", s.CaptchaCount)
 	msg.SetData("block_count", s.BlockCount)
+	msg.SetData("results_dropped", s.ResultsDropped)
+	msg.SetData("unique_domains", s.UniqueDomains)
+	msg.SetData("wire_bytes", s.WireBytes)
+	msg.SetData("decoded_bytes", s.DecodedBytes)
 	msg.SetData("proxies_alive", s.ProxiesAlive)
 	msg.SetData("proxies_dead", s.ProxiesDead)
+	msg.SetData("proxies_quarantined", s.ProxiesQuarantined)
+	msg.SetData("proxies_evicted", s.ProxiesEvicted)
 	msg.SetData("requests_per_sec", s.RequestsPerSec)
+	msg.SetData("requests_per_sec_recent", s.RequestsPerSecRecent)
 	msg.SetData("elapsed_ms", s.ElapsedMs)
 	msg.SetData("eta_ms", s.ETAMs)
+	msg.SetData("remaining_runtime_ms", s.RemainingMs)
+	msg.SetData("effective_concurrency", s.EffectiveConcurrency)
+	if s.BreakerState != "" {
+		msg.SetData("breaker_state", s.BreakerState)
+		msg.SetData("breaker_trips", s.BreakerTrips)
+	}
+	return msg
+}
+
+// HealthData is a cheap snapshot of the worker process, answering
+// get_health, for spotting a stuck or backed-up worker
+type HealthData struct {
+	Goroutines     int    `json:"goroutines"`
+	TaskQueueLen   int    `json:"task_queue_len"`
+	ResultQueueLen int    `json:"result_queue_len"`
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	ActiveWorkers  int    `json:"active_workers"`
+	Workers        int    `json:"workers"` // Workers allowed to run at once
+}
+
+// ToMessage converts health data to a message
+func (d *HealthData) ToMessage() *Message {
+	msg := NewMessage(MsgTypeHealth)
+	msg.SetData("goroutines", d.Goroutines)
+	msg.SetData("task_queue_len", d.TaskQueueLen)
+	msg.SetData("result_queue_len", d.ResultQueueLen)
+	msg.SetData("heap_alloc_bytes", d.HeapAllocBytes)
+	msg.SetData("active_workers", d.ActiveWorkers)
+	msg.SetData("workers", d.Workers)
 	return msg
 }
 
@@ -264,6 +833,11 @@ type ProgressData struct {
 	Current    int64   `json:"current"`
 	Total      int64   `json:"total"`
 	Percentage float64 `json:"percentage"`
+
+	// Set only with dork progress enabled: the dork that just finished
+	// and how many URLs it yielded
+	Dork     string `json:"dork,omitempty"`
+	DorkURLs int    `json:"dork_urls,omitempty"`
 }
 
 // ToMessage converts progress data to a message
@@ -272,9 +846,28 @@ func (p *ProgressData) ToMessage() *Message {
 	msg.SetData("current", p.Current)
 	msg.SetData("total", p.Total)
 	msg.SetData("percentage", p.Percentage)
+	if p.Dork != "" {
+		msg.SetData("dork", p.Dork)
+		msg.SetData("dork_urls", p.DorkURLs)
+	}
 	return msg
 }
 
+// ProxyData is one proxy in a proxies message. Credentials are never
+// included and the host is masked.
+type ProxyData struct {
+	ID            string  `json:"id"`
+	Host          string  `json:"host"`
+	Port          string  `json:"port"`
+	Type          string  `json:"type"`
+	Status        string  `json:"status"`
+	SuccessRate   float64 `json:"success_rate"`
+	Score         float64 `json:"score"` // Recency-weighted success rate
+	AvgLatency    int64   `json:"avg_latency_ms"`
+	TotalRequests int64   `json:"total_requests"`
+	Captchas      int64   `json:"captcha_count"`
+}
+
 // Handler handles IPC communication
 type Handler struct {
 	reader  *bufio.Reader
@@ -282,16 +875,30 @@ type Handler struct {
 	writeMu sync.Mutex
 
 	// Callbacks
-	onInit     func(*InitConfig)
-	onTask     func(*TaskData)
-	onPause    func()
-	onResume   func()
-	onShutdown func()
-	onGetStats func()
+	onInit         func(*InitConfig)
+	onUpdateConfig func(*InitConfig)
+	onTask         func(*TaskData)
+	onTaskBatch    func([]*TaskData)
+	onCancelTask   func(taskID string)
+	onPause        func()
+	onResume       func()
+	onShutdown     func()
+	onGetStats     func()
+	onGetHealth    func()
+	onGetProxies   func(status string)
+	onExportStats  func(path, format string)
+
+	// Init data applied underneath every init message
+	initDefaults map[string]any
+
+	// Effective data of the last init, updated by update_config
+	initData map[string]any
 
 	// State
-	running bool
-	stopCh  chan struct{}
+	running      atomic.Bool
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	shutdownOnce sync.Once
 }
 
 // NewHandler creates a new IPC handler
@@ -317,11 +924,29 @@ func (h *Handler) OnInit(fn func(*InitConfig)) {
 	h.onInit = fn
 }
 
-// OnTask sets the task callback
+// OnUpdateConfig sets the update config callback. It receives the full
+// config: the last init data with the update's keys applied on top.
+func (h *Handler) OnUpdateConfig(fn func(*InitConfig)) {
+	h.onUpdateConfig = fn
+}
+
+// OnTask sets the task callback. Without an OnTaskBatch callback it is
+// also called for every task of a batch.
 func (h *Handler) OnTask(fn func(*TaskData)) {
 	h.onTask = fn
 }
 
+// OnTaskBatch sets the task batch callback, called once with all tasks
+// of a task_batch message so they can be acknowledged together
+func (h *Handler) OnTaskBatch(fn func([]*TaskData)) {
+	h.onTaskBatch = fn
+}
+
+// OnCancelTask sets the cancel task callback
+func (h *Handler) OnCancelTask(fn func(taskID string)) {
+	h.onCancelTask = fn
+}
+
 // OnPause sets the pause callback
 func (h *Handler) OnPause(fn func()) {
 	h.onPause = fn
@@ -342,50 +967,91 @@ func (h *Handler) OnGetStats(fn func()) {
 	h.onGetStats = fn
 }
 
+// OnGetHealth sets the get health callback
+func (h *Handler) OnGetHealth(fn func()) {
+	h.onGetHealth = fn
+}
+
+// OnGetProxies sets the get proxies callback, called with the requested
+// status filter (empty for all proxies). It also answers get_proxy_list.
+func (h *Handler) OnGetProxies(fn func(status string)) {
+	h.onGetProxies = fn
+}
+
+// OnExportProxyStats sets the export proxy stats callback, called with
+// the file to write and the requested format ("csv", "json" or empty to
+// go by the file extension)
+func (h *Handler) OnExportProxyStats(fn func(path, format string)) {
+	h.onExportStats = fn
+}
+
+// SetInitDefaults sets init data that init messages override key by key
+func (h *Handler) SetInitDefaults(data map[string]any) {
+	h.initDefaults = data
+}
+
 // Start starts listening for messages
 func (h *Handler) Start() {
-	h.running = true
+	h.running.Store(true)
 
 	// Send ready message
 	h.SendStatus("ready", "")
 
-	for h.running {
+	for h.running.Load() {
 		select {
 		case <-h.stopCh:
 			return
 		default:
-			h.readMessage()
+			if !h.readMessage() {
+				return
+			}
 		}
 	}
 }
 
 // Stop stops the handler
 func (h *Handler) Stop() {
-	h.running = false
-	close(h.stopCh)
+	h.stopOnce.Do(func() {
+		h.running.Store(false)
+		close(h.stopCh)
+	})
+}
+
+// shutdown runs the shutdown callback and stops the handler, once
+func (h *Handler) shutdown(message string) {
+	h.shutdownOnce.Do(func() {
+		if h.onShutdown != nil {
+			h.onShutdown()
+		}
+		h.SendStatus("shutdown", message)
+		h.Stop()
+	})
 }
 
-// readMessage reads and processes a single message
-func (h *Handler) readMessage() {
+// readMessage reads and processes a single message. It returns false once
+// input is exhausted; a closed or failing reader is an implicit shutdown.
+func (h *Handler) readMessage() bool {
 	line, err := h.reader.ReadString('\n')
+
+	// A final line may arrive without a trailing newline
+	if strings.TrimSpace(line) != "" {
+		var msg Message
+		if jsonErr := json.Unmarshal([]byte(line), &msg); jsonErr != nil {
+			h.SendError("parse_error", jsonErr.Error())
+		} else {
+			h.handleMessage(&msg)
+		}
+	}
+
 	if err != nil {
 		if err != io.EOF {
 			h.SendError("read_error", err.Error())
 		}
-		return
-	}
-
-	if line == "" || line == "\n" {
-		return
-	}
-
-	var msg Message
-	if err := json.Unmarshal([]byte(line), &msg); err != nil {
-		h.SendError("parse_error", err.Error())
-		return
+		h.shutdown("input closed")
+		return false
 	}
 
-	h.handleMessage(&msg)
+	return true
 }
 
 // handleMessage handles a parsed message
@@ -393,10 +1059,22 @@ func (h *Handler) handleMessage(msg *Message) {
 	switch msg.Type {
 	case MsgTypeInit:
 		if h.onInit != nil {
+			if len(h.initDefaults) > 0 {
+				msg.Data = mergeData(h.initDefaults, msg.Data)
+			}
+			h.initData = msg.Data
 			config := ParseInitConfig(msg)
 			h.onInit(config)
 		}
 
+	case MsgTypeUpdateConfig:
+		if h.onUpdateConfig != nil {
+			msg.Data = mergeData(h.initData, msg.Data)
+			h.initData = msg.Data
+			config := ParseInitConfig(msg)
+			h.onUpdateConfig(config)
+		}
+
 	case MsgTypeTask:
 		if h.onTask != nil {
 			task := ParseTaskData(msg)
@@ -404,24 +1082,19 @@ func (h *Handler) handleMessage(msg *Message) {
 		}
 
 	case MsgTypeTaskBatch:
-		if h.onTask != nil {
-			// Handle batch of tasks
-			if tasks, ok := msg.Data["tasks"].([]any); ok {
-				for _, t := range tasks {
-					if taskMap, ok := t.(map[string]any); ok {
-						task := &TaskData{
-							ID:   fmt.Sprintf("%v", taskMap["id"]),
-							Dork: fmt.Sprintf("%v", taskMap["dork"]),
-						}
-						if page, ok := taskMap["page"].(float64); ok {
-							task.Page = int(page)
-						}
-						h.onTask(task)
-					}
-				}
+		if h.onTaskBatch != nil {
+			h.onTaskBatch(ParseTaskBatch(msg))
+		} else if h.onTask != nil {
+			for _, task := range ParseTaskBatch(msg) {
+				h.onTask(task)
 			}
 		}
 
+	case MsgTypeCancelTask:
+		if h.onCancelTask != nil {
+			h.onCancelTask(ParseCancelTask(msg))
+		}
+
 	case MsgTypePause:
 		if h.onPause != nil {
 			h.onPause()
@@ -435,36 +1108,75 @@ func (h *Handler) handleMessage(msg *Message) {
 		h.SendStatus("resumed", "")
 
 	case MsgTypeShutdown:
-		if h.onShutdown != nil {
-			h.onShutdown()
-		}
-		h.SendStatus("shutdown", "")
-		h.Stop()
+		h.shutdown("")
 
 	case MsgTypeGetStats:
 		if h.onGetStats != nil {
 			h.onGetStats()
 		}
 
+	case MsgTypeGetHealth:
+		if h.onGetHealth != nil {
+			h.onGetHealth()
+		}
+
+	case MsgTypeGetProxies, MsgTypeGetProxyList:
+		if h.onGetProxies != nil {
+			h.onGetProxies(msg.GetString("status"))
+		}
+
+	case MsgTypeExportProxyStats:
+		if h.onExportStats != nil {
+			path := msg.GetString("path")
+			if path == "" {
+				h.SendError("invalid_message", "export_proxy_stats requires a path")
+				return
+			}
+			h.onExportStats(path, msg.GetString("format"))
+		}
+
 	default:
 		h.SendError("unknown_type", fmt.Sprintf("unknown message type: %s", msg.Type))
 	}
 }
 
+// mergeData returns base with over's keys applied on top
+func mergeData(base, over map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range over {
+		merged[k] = v
+	}
+	return merged
+}
+
 // Send sends a message
 func (h *Handler) Send(msg *Message) error {
-	h.writeMu.Lock()
-	defer h.writeMu.Unlock()
-
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
-	_, err = fmt.Fprintln(h.writer, string(data))
+	// One Write per line: a line split across writes could interleave
+	// with another writer of the same pipe
+	data = append(data, '\n')
+
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+
+	_, err = h.writer.Write(data)
 	return err
 }
 
+// Seal waits for an in-flight Send to finish and blocks every later one,
+// so the process can exit without cutting a line short. It does not
+// return the lock; sends after Seal block forever.
+func (h *Handler) Seal() {
+	h.writeMu.Lock()
+}
+
 // SendStatus sends a status message
 func (h *Handler) SendStatus(status string, message string) error {
 	msg := NewMessage(MsgTypeStatus)
@@ -488,11 +1200,32 @@ func (h *Handler) SendResult(result *ResultData) error {
 	return h.Send(result.ToMessage())
 }
 
+// SendResultBatch sends several results in one result_batch message
+func (h *Handler) SendResultBatch(results []*ResultData) error {
+	msg := NewMessage(MsgTypeResultBatch)
+	msg.SetData("results", results)
+	msg.SetData("count", len(results))
+	return h.Send(msg)
+}
+
+// SendHealth sends a health message
+func (h *Handler) SendHealth(health *HealthData) error {
+	return h.Send(health.ToMessage())
+}
+
 // SendStats sends a stats message
 func (h *Handler) SendStats(stats *StatsData) error {
 	return h.Send(stats.ToMessage())
 }
 
+// SendComplete signals that all submitted tasks have finished,
+// carrying the final statistics
+func (h *Handler) SendComplete(stats *StatsData) error {
+	msg := stats.ToMessage()
+	msg.Type = MsgTypeComplete
+	return h.Send(msg)
+}
+
 // SendProgress sends a progress message
 func (h *Handler) SendProgress(progress *ProgressData) error {
 	return h.Send(progress.ToMessage())
@@ -500,18 +1233,53 @@ func (h *Handler) SendProgress(progress *ProgressData) error {
 
 // SendLog sends a log message
 func (h *Handler) SendLog(level string, message string) error {
+	return h.SendLogFields(level, message, nil)
+}
+
+// SendLogFields sends a log message with fields such as proxy_id or
+// task_id alongside level and message in the message data. Fields named
+// level or message are dropped.
+func (h *Handler) SendLogFields(level string, message string, fields map[string]any) error {
 	msg := NewMessage(MsgTypeLog)
+	for k, v := range fields {
+		msg.SetData(k, v)
+	}
 	msg.SetData("level", level)
 	msg.SetData("message", message)
 	return h.Send(msg)
 }
 
-// SendProxyInfo sends proxy information
-func (h *Handler) SendProxyInfo(alive, dead, quarantined int) error {
+// SendBatchAck reports how many tasks of a batch were queued and which
+// were rejected, so the CLI can resubmit them
+func (h *Handler) SendBatchAck(accepted int, rejected []string) error {
+	if rejected == nil {
+		rejected = []string{}
+	}
+
+	msg := NewMessage(MsgTypeBatchAck)
+	msg.SetData("accepted", accepted)
+	msg.SetData("rejected", len(rejected))
+	msg.SetData("task_ids_rejected", rejected)
+	return h.Send(msg)
+}
+
+// SendProxies sends the proxy inventory in reply to get_proxies or
+// get_proxy_list
+func (h *Handler) SendProxies(proxies []ProxyData) error {
+	msg := NewMessage(MsgTypeProxies)
+	msg.SetData("proxies", proxies)
+	msg.SetData("count", len(proxies))
+	return h.Send(msg)
+}
+
+// SendProxyInfo sends proxy information. evicted counts the proxies
+// dropped for staying dead, which are no longer part of the total.
+func (h *Handler) SendProxyInfo(alive, dead, quarantined int, evicted int64) error {
 	msg := NewMessage(MsgTypeProxyInfo)
 	msg.SetData("alive", alive)
 	msg.SetData("dead", dead)
 	msg.SetData("quarantined", quarantined)
 	msg.SetData("total", alive+dead+quarantined)
+	msg.SetData("proxies_evicted", evicted)
 	return h.Send(msg)
 }
@@ -3,7 +3,11 @@ package protocol
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -120,6 +124,11 @@ func TestParseInitConfig(t *testing.T) {
 	msg.SetData("max_retries", 5)
 	msg.SetData("results_per_page", 50)
 	msg.SetData("proxy_file", "/path/to/proxies.txt")
+	msg.SetData("max_runtime", 2700000)
+	msg.SetData("block_cooldown", 600000)
+	msg.SetData("fingerprint_rotation", 50)
+	msg.Data["exclude_patterns"] = []any{`\.pdf$`}
+	msg.Data["deny_domains"] = []any{"facebook.com", "cloudfront.net"}
 
 	config := ParseInitConfig(msg)
 
@@ -138,6 +147,30 @@ func TestParseInitConfig(t *testing.T) {
 	if config.ProxyFile != "/path/to/proxies.txt" {
 		t.Errorf("ProxyFile = %q", config.ProxyFile)
 	}
+
+	if config.MaxRuntime != 45*time.Minute {
+		t.Errorf("MaxRuntime = %v, want 45m", config.MaxRuntime)
+	}
+
+	if config.BlockCooldown != 10*time.Minute {
+		t.Errorf("BlockCooldown = %v, want 10m", config.BlockCooldown)
+	}
+
+	if config.CaptchaCooldown != 0 {
+		t.Errorf("CaptchaCooldown = %v, want 0 (engine default)", config.CaptchaCooldown)
+	}
+
+	if config.FingerprintRotation != 50 {
+		t.Errorf("FingerprintRotation = %d, want 50", config.FingerprintRotation)
+	}
+
+	if len(config.ExcludePatterns) != 1 || config.ExcludePatterns[0] != `\.pdf$` {
+		t.Errorf("ExcludePatterns = %q, want [\\.pdf$]", config.ExcludePatterns)
+	}
+
+	if len(config.DenyDomains) != 2 || config.DenyDomains[1] != "cloudfront.net" {
+		t.Errorf("DenyDomains = %q, want [facebook.com cloudfront.net]", config.DenyDomains)
+	}
 }
 
 func TestParseInitConfigDefaults(t *testing.T) {
@@ -162,11 +195,116 @@ func TestParseInitConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestReadInitConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"workers": 25, "base_delay": 5000, "proxy_file": "proxies.txt", "engine": "google"}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	data, err := ReadInitConfigFile(path)
+	if err != nil {
+		t.Fatalf("ReadInitConfigFile failed: %v", err)
+	}
+
+	config := ParseInitConfig(&Message{Type: MsgTypeInit, Data: data})
+
+	if config.Workers != 25 {
+		t.Errorf("Workers = %d, want 25", config.Workers)
+	}
+
+	if config.BaseDelay != 5*time.Second {
+		t.Errorf("BaseDelay = %v, want 5s", config.BaseDelay)
+	}
+
+	if config.ProxyFile != "proxies.txt" {
+		t.Errorf("ProxyFile = %q", config.ProxyFile)
+	}
+
+	// Unset values fall back to defaults
+	if config.MaxRetries != 3 {
+		t.Errorf("MaxRetries = %d, want 3", config.MaxRetries)
+	}
+}
+
+func TestReadInitConfigFileInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown keys", `{"workers": 5, "wrokers": 5, "colour": "red"}`, "unknown keys: colour, wrokers"},
+		{"wrong type", `{"workers": "ten"}`, "workers must be a number"},
+		{"negative", `{"timeout": -1}`, "timeout must not be negative"},
+		{"delay range", `{"min_delay": 9000, "max_delay": 1000}`, "min_delay must not exceed max_delay"},
+		{"not json", `workers=5`, "invalid config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+
+			_, err := ReadInitConfigFile(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := ReadInitConfigFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file should return error")
+	}
+}
+
+func TestHandlerInitDefaults(t *testing.T) {
+	input := `{"type":"init","ts":1234567890,"data":{"workers":4}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+	h.SetInitDefaults(map[string]any{
+		"workers":    float64(20),
+		"base_delay": float64(2000),
+	})
+
+	var got *InitConfig
+	h.OnInit(func(config *InitConfig) {
+		got = config
+	})
+
+	h.readMessage()
+
+	if got == nil {
+		t.Fatal("init callback not called")
+	}
+
+	// Message values win over defaults
+	if got.Workers != 4 {
+		t.Errorf("Workers = %d, want 4", got.Workers)
+	}
+
+	if got.BaseDelay != 2*time.Second {
+		t.Errorf("BaseDelay = %v, want 2s", got.BaseDelay)
+	}
+}
+
 func TestParseTaskData(t *testing.T) {
 	msg := NewMessage(MsgTypeTask)
 	msg.SetData("task_id", "task_001")
 	msg.SetData("dork", "inurl:admin")
 	msg.SetData("page", 0)
+	msg.SetData("priority", 5)
+	msg.SetData("max_pages", 3)
+	msg.SetData("time_range", "d")
+	msg.SetData("country", "de")
+	msg.SetData("language", "fr")
+	msg.SetData("session_key", "site-a")
 
 	task := ParseTaskData(msg)
 
@@ -181,6 +319,41 @@ func TestParseTaskData(t *testing.T) {
 	if task.Page != 0 {
 		t.Errorf("Page = %d, want 0", task.Page)
 	}
+
+	if task.Priority != 5 {
+		t.Errorf("Priority = %d, want 5", task.Priority)
+	}
+
+	if task.MaxPages != 3 {
+		t.Errorf("MaxPages = %d, want 3", task.MaxPages)
+	}
+
+	if task.TimeRange != "d" {
+		t.Errorf("TimeRange = %q, want d", task.TimeRange)
+	}
+
+	if task.Country != "de" || task.Language != "fr" {
+		t.Errorf("Country, Language = %q, %q, want de, fr", task.Country, task.Language)
+	}
+
+	if task.SessionKey != "site-a" {
+		t.Errorf("SessionKey = %q, want site-a", task.SessionKey)
+	}
+}
+
+func TestParseTaskDataStickyOverride(t *testing.T) {
+	msg := NewMessage(MsgTypeTask)
+	msg.SetData("dork", "inurl:admin")
+
+	if task := ParseTaskData(msg); task.StickyProxy != nil {
+		t.Errorf("StickyProxy = %v, want nil when not set", *task.StickyProxy)
+	}
+
+	msg.SetData("sticky_proxy", false)
+	task := ParseTaskData(msg)
+	if task.StickyProxy == nil || *task.StickyProxy {
+		t.Errorf("StickyProxy = %v, want explicit false", task.StickyProxy)
+	}
 }
 
 func TestResultDataToMessage(t *testing.T) {
@@ -191,6 +364,7 @@ func TestResultDataToMessage(t *testing.T) {
 		Status:   "success",
 		ProxyID:  "proxy_001",
 		Duration: 1500,
+		Pages:    2,
 	}
 
 	msg := result.ToMessage()
@@ -206,6 +380,10 @@ func TestResultDataToMessage(t *testing.T) {
 	if msg.GetString("status") != "success" {
 		t.Errorf("status = %q", msg.GetString("status"))
 	}
+
+	if msg.GetInt("pages") != 2 {
+		t.Errorf("pages = %d, want 2", msg.GetInt("pages"))
+	}
 }
 
 func TestResultDataWithError(t *testing.T) {
@@ -223,6 +401,53 @@ func TestResultDataWithError(t *testing.T) {
 	}
 }
 
+func TestResultDataTruncated(t *testing.T) {
+	msg := (&ResultData{TaskID: "task_001", Status: "success"}).ToMessage()
+	if _, ok := msg.Data["truncated"]; ok {
+		t.Error("truncated should be omitted when false")
+	}
+
+	msg = (&ResultData{TaskID: "task_001", Status: "success", Truncated: true}).ToMessage()
+	if !msg.GetBool("truncated") {
+		t.Errorf("truncated = %v, want true", msg.Data["truncated"])
+	}
+}
+
+func TestResultDataResponse(t *testing.T) {
+	msg := (&ResultData{TaskID: "task_001", Status: "error", Error: "dial tcp: timeout", Latency: 30000}).ToMessage()
+	for _, key := range []string{"status_code", "blocked", "captcha"} {
+		if _, ok := msg.Data[key]; ok {
+			t.Errorf("%s should be omitted for a task without a response", key)
+		}
+	}
+	if msg.GetInt("latency_ms") != 30000 {
+		t.Errorf("latency_ms = %d, want 30000", msg.GetInt("latency_ms"))
+	}
+
+	msg = (&ResultData{TaskID: "task_001", Status: "blocked", StatusCode: 503, Blocked: true, Latency: 420}).ToMessage()
+	if msg.GetInt("status_code") != 503 || !msg.GetBool("blocked") || msg.GetBool("captcha") {
+		t.Errorf("data = %v, want status_code 503 and blocked", msg.Data)
+	}
+
+	msg = (&ResultData{TaskID: "task_001", Status: "captcha", StatusCode: 200, Captcha: true}).ToMessage()
+	if msg.GetInt("status_code") != 200 || !msg.GetBool("captcha") || msg.GetBool("blocked") {
+		t.Errorf("data = %v, want status_code 200 and captcha", msg.Data)
+	}
+
+	// Survives the JSON round trip consumers see
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.GetInt("status_code") != 200 || !decoded.GetBool("captcha") {
+		t.Errorf("decoded data = %v, want status_code 200 and captcha", decoded.Data)
+	}
+}
+
 func TestStatsDataToMessage(t *testing.T) {
 	stats := &StatsData{
 		TasksTotal:     1000,
@@ -237,6 +462,8 @@ func TestStatsDataToMessage(t *testing.T) {
 		RequestsPerSec: 25.5,
 		ElapsedMs:      120000,
 		ETAMs:          120000,
+
+		RequestsPerSecRecent: 4.5,
 	}
 
 	msg := stats.ToMessage()
@@ -252,6 +479,10 @@ func TestStatsDataToMessage(t *testing.T) {
 	if msg.GetFloat("requests_per_sec") < 25.4 || msg.GetFloat("requests_per_sec") > 25.6 {
 		t.Errorf("requests_per_sec = %v", msg.GetFloat("requests_per_sec"))
 	}
+
+	if msg.GetFloat("requests_per_sec_recent") != 4.5 {
+		t.Errorf("requests_per_sec_recent = %v, want 4.5", msg.GetFloat("requests_per_sec_recent"))
+	}
 }
 
 func TestProgressDataToMessage(t *testing.T) {
@@ -274,6 +505,30 @@ func TestProgressDataToMessage(t *testing.T) {
 	if msg.GetFloat("percentage") != 50.0 {
 		t.Errorf("percentage = %v", msg.GetFloat("percentage"))
 	}
+
+	if _, ok := msg.Data["dork"]; ok {
+		t.Error("dork should be omitted without dork progress")
+	}
+}
+
+func TestProgressDataDork(t *testing.T) {
+	progress := &ProgressData{
+		Current:  1,
+		Total:    2,
+		Dork:     "inurl:admin",
+		DorkURLs: 7,
+	}
+
+	msg := progress.ToMessage()
+
+	if msg.GetString("dork") != "inurl:admin" || msg.GetInt("dork_urls") != 7 {
+		t.Errorf("dork = %q, dork_urls = %d, want inurl:admin and 7", msg.GetString("dork"), msg.GetInt("dork_urls"))
+	}
+
+	init := &Message{Type: MsgTypeInit, Data: map[string]any{"dork_progress": true}}
+	if !ParseInitConfig(init).DorkProgress {
+		t.Error("DorkProgress = false, want true")
+	}
 }
 
 func TestHandlerSend(t *testing.T) {
@@ -298,6 +553,57 @@ func TestHandlerSend(t *testing.T) {
 	}
 }
 
+// writeRecorder records each Write call separately
+type writeRecorder struct {
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (r *writeRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes = append(r.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestHandlerSendConcurrent(t *testing.T) {
+	rec := &writeRecorder{}
+	h := NewHandlerWithIO(strings.NewReader(""), rec)
+
+	const goroutines, perGoroutine = 20, 50
+	payload := strings.Repeat("x", 8*1024) // Past PIPE_BUF, so not atomic on its own
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				h.SendLog("info", fmt.Sprintf("%d-%d %s", g, i, payload))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if len(rec.writes) != goroutines*perGoroutine {
+		t.Fatalf("got %d writes, want one per message (%d)", len(rec.writes), goroutines*perGoroutine)
+	}
+
+	for i, write := range rec.writes {
+		if bytes.Count(write, []byte("\n")) != 1 || write[len(write)-1] != '\n' {
+			t.Fatalf("write %d is not exactly one newline-terminated line", i)
+		}
+
+		var msg Message
+		if err := json.Unmarshal(write, &msg); err != nil {
+			t.Fatalf("write %d is not standalone JSON: %v", i, err)
+		}
+		if msg.Type != MsgTypeLog {
+			t.Errorf("write %d type = %q, want log", i, msg.Type)
+		}
+	}
+}
+
 func TestHandlerSendStatus(t *testing.T) {
 	var buf bytes.Buffer
 	h := NewHandlerWithIO(strings.NewReader(""), &buf)
@@ -375,6 +681,29 @@ func TestHandlerSendStats(t *testing.T) {
 	}
 }
 
+func TestHandlerSendComplete(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(""), &buf)
+
+	stats := &StatsData{
+		TasksTotal:     100,
+		TasksCompleted: 98,
+		TasksFailed:    2,
+	}
+
+	if err := h.SendComplete(stats); err != nil {
+		t.Fatalf("SendComplete failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, `"type":"complete"`) {
+		t.Errorf("output missing type:complete, got: %s", output)
+	}
+	if !strings.Contains(output, `"tasks_completed":98`) {
+		t.Errorf("output missing final stats, got: %s", output)
+	}
+}
+
 func TestHandlerSendProgress(t *testing.T) {
 	var buf bytes.Buffer
 	h := NewHandlerWithIO(strings.NewReader(""), &buf)
@@ -415,11 +744,42 @@ func TestHandlerSendLog(t *testing.T) {
 	}
 }
 
+func TestHandlerSendLogFields(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(""), &buf)
+
+	err := h.SendLogFields("warn", "Request failed", map[string]any{
+		"proxy_id": "p1",
+		"task_id":  "t1",
+		"level":    "debug",
+	})
+	if err != nil {
+		t.Fatalf("SendLogFields failed: %v", err)
+	}
+
+	var msg Message
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if got := msg.GetString("proxy_id"); got != "p1" {
+		t.Errorf("proxy_id = %q, want %q", got, "p1")
+	}
+	if got := msg.GetString("task_id"); got != "t1" {
+		t.Errorf("task_id = %q, want %q", got, "t1")
+	}
+	if got := msg.GetString("level"); got != "warn" {
+		t.Errorf("level = %q, want %q (fields must not override it)", got, "warn")
+	}
+	if got := msg.GetString("message"); got != "Request failed" {
+		t.Errorf("message = %q, want %q", got, "Request failed")
+	}
+}
+
 func TestHandlerSendProxyInfo(t *testing.T) {
 	var buf bytes.Buffer
 	h := NewHandlerWithIO(strings.NewReader(""), &buf)
 
-	err := h.SendProxyInfo(100, 10, 5)
+	err := h.SendProxyInfo(100, 10, 5, 3)
 	if err != nil {
 		t.Fatalf("SendProxyInfo failed: %v", err)
 	}
@@ -432,6 +792,9 @@ func TestHandlerSendProxyInfo(t *testing.T) {
 	if !strings.Contains(output, `"total":115`) {
 		t.Errorf("output missing total, got: %s", output)
 	}
+	if !strings.Contains(output, `"proxies_evicted":3`) {
+		t.Errorf("output missing proxies_evicted, got: %s", output)
+	}
 }
 
 func TestHandlerCallbacks(t *testing.T) {
@@ -496,7 +859,7 @@ func TestHandlerCallbacks(t *testing.T) {
 func TestHandlerTaskBatch(t *testing.T) {
 	tasksReceived := 0
 
-	input := `{"type":"task_batch","ts":1234567890,"data":{"tasks":[{"id":"1","dork":"test1"},{"id":"2","dork":"test2"},{"id":"3","dork":"test3"}]}}
+	input := `{"type":"task_batch","ts":1234567890,"data":{"tasks":[{"id":"1","dork":"test1"},{"id":"2","dork":"test2","priority":3,"max_pages":4,"time_range":"w","country":"gb","language":"en","session_key":"s2"},{"id":"3","dork":"test3"}]}}
 `
 
 	var buf bytes.Buffer
@@ -504,6 +867,21 @@ func TestHandlerTaskBatch(t *testing.T) {
 
 	h.OnTask(func(task *TaskData) {
 		tasksReceived++
+		if task.ID == "2" && task.Priority != 3 {
+			t.Errorf("task 2 Priority = %d, want 3", task.Priority)
+		}
+		if task.ID == "2" && task.MaxPages != 4 {
+			t.Errorf("task 2 MaxPages = %d, want 4", task.MaxPages)
+		}
+		if task.ID == "2" && task.TimeRange != "w" {
+			t.Errorf("task 2 TimeRange = %q, want w", task.TimeRange)
+		}
+		if task.ID == "2" && (task.Country != "gb" || task.Language != "en") {
+			t.Errorf("task 2 Country, Language = %q, %q, want gb, en", task.Country, task.Language)
+		}
+		if task.ID == "2" && task.SessionKey != "s2" {
+			t.Errorf("task 2 SessionKey = %q, want s2", task.SessionKey)
+		}
 	})
 
 	h.readMessage()
@@ -513,6 +891,37 @@ func TestHandlerTaskBatch(t *testing.T) {
 	}
 }
 
+func TestHandlerTaskBatchAck(t *testing.T) {
+	input := `{"type":"task_batch","ts":1234567890,"data":{"tasks":[{"id":"1","dork":"test1"},"bogus",{"id":"2","dork":"test2","page":1},{"id":"3","dork":"test3"}]}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	h.OnTask(func(task *TaskData) {
+		t.Errorf("OnTask called for %s, want the batch callback only", task.ID)
+	})
+
+	var got []*TaskData
+	h.OnTaskBatch(func(tasks []*TaskData) {
+		got = tasks
+		h.SendBatchAck(1, []string{"2", "3"})
+	})
+
+	h.readMessage()
+
+	if len(got) != 3 || got[1].ID != "2" || got[1].Page != 1 {
+		t.Fatalf("batch = %+v, want tasks 1, 2 and 3", got)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`"type":"batch_ack"`, `"accepted":1`, `"rejected":2`, `"task_ids_rejected":["2","3"]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s, got: %s", want, out)
+		}
+	}
+}
+
 func TestHandlerShutdown(t *testing.T) {
 	shutdownCalled := false
 
@@ -538,6 +947,214 @@ func TestHandlerShutdown(t *testing.T) {
 	}
 }
 
+func TestHandlerStartEOF(t *testing.T) {
+	shutdownCount := 0
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(""), &buf)
+
+	h.OnShutdown(func() {
+		shutdownCount++
+	})
+
+	done := make(chan struct{})
+	go func() {
+		h.Start()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start() did not return on EOF")
+	}
+
+	if shutdownCount != 1 {
+		t.Errorf("shutdown callback called %d times, want 1", shutdownCount)
+	}
+
+	if !strings.Contains(buf.String(), `"status":"shutdown"`) {
+		t.Errorf("output should contain shutdown status, got: %s", buf.String())
+	}
+}
+
+func TestHandlerShutdownThenEOF(t *testing.T) {
+	shutdownCount := 0
+
+	// Final line has no trailing newline and is followed by EOF
+	input := `{"type":"get_stats","ts":1234567890}
+{"type":"shutdown","ts":1234567890}`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	statsCalled := false
+	h.OnGetStats(func() {
+		statsCalled = true
+	})
+	h.OnShutdown(func() {
+		shutdownCount++
+	})
+
+	h.Start()
+
+	if !statsCalled {
+		t.Error("get_stats callback not called")
+	}
+
+	if shutdownCount != 1 {
+		t.Errorf("shutdown callback called %d times, want 1", shutdownCount)
+	}
+}
+
+func TestHandlerGetHealth(t *testing.T) {
+	input := `{"type":"get_health","ts":1234567890}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	h.OnGetHealth(func() {
+		h.SendHealth(&HealthData{Goroutines: 12, TaskQueueLen: 3, ResultQueueLen: 1, HeapAllocBytes: 4096, ActiveWorkers: 2, Workers: 4})
+	})
+
+	h.Start()
+
+	out := buf.String()
+	for _, want := range []string{`"type":"health"`, `"goroutines":12`, `"task_queue_len":3`, `"result_queue_len":1`,
+		`"heap_alloc_bytes":4096`, `"active_workers":2`, `"workers":4`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s, got: %s", want, out)
+		}
+	}
+}
+
+func TestHandlerGetProxies(t *testing.T) {
+	input := `{"type":"get_proxies","ts":1234567890,"data":{"status":"alive"}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	var gotStatus string
+	h.OnGetProxies(func(status string) {
+		gotStatus = status
+		h.SendProxies([]ProxyData{{ID: "p1", Host: "10.0.x.x", Port: "8080", Type: "http", Status: "alive"}})
+	})
+
+	h.Start()
+
+	if gotStatus != "alive" {
+		t.Errorf("status filter = %q, want alive", gotStatus)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"type":"proxies"`) || !strings.Contains(out, `"id":"p1"`) || !strings.Contains(out, `"count":1`) {
+		t.Errorf("output should contain the proxies reply, got: %s", out)
+	}
+}
+
+func TestHandlerGetProxyList(t *testing.T) {
+	input := `{"type":"get_proxy_list","ts":1234567890,"data":{}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	called := false
+	h.OnGetProxies(func(status string) {
+		called = true
+		if status != "" {
+			t.Errorf("status filter = %q, want empty", status)
+		}
+		h.SendProxies([]ProxyData{{ID: "p1", AvgLatency: 250, TotalRequests: 12, Captchas: 3}})
+	})
+
+	h.Start()
+
+	if !called {
+		t.Fatal("get_proxy_list should call the get proxies callback")
+	}
+	out := buf.String()
+	for _, want := range []string{`"type":"proxies"`, `"avg_latency_ms":250`, `"total_requests":12`, `"captcha_count":3`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s, got: %s", want, out)
+		}
+	}
+}
+
+func TestHandlerExportProxyStats(t *testing.T) {
+	input := `{"type":"export_proxy_stats","ts":1234567890,"data":{"path":"/tmp/proxies.json","format":"json"}}
+{"type":"export_proxy_stats","ts":1234567890,"data":{}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	var calls []string
+	h.OnExportProxyStats(func(path, format string) {
+		calls = append(calls, path+" "+format)
+	})
+
+	h.Start()
+
+	if len(calls) != 1 || calls[0] != "/tmp/proxies.json json" {
+		t.Errorf("export calls = %q, want [\"/tmp/proxies.json json\"]", calls)
+	}
+	if out := buf.String(); !strings.Contains(out, `"code":"invalid_message"`) {
+		t.Errorf("export without a path should be rejected, got: %s", out)
+	}
+}
+
+func TestHandlerUpdateConfig(t *testing.T) {
+	input := `{"type":"init","ts":1234567890,"data":{"workers":4,"base_delay":8000}}
+{"type":"update_config","ts":1234567891,"data":{"base_delay":20000}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+	h.OnInit(func(config *InitConfig) {})
+
+	var got *InitConfig
+	h.OnUpdateConfig(func(config *InitConfig) {
+		got = config
+	})
+
+	h.Start()
+
+	if got == nil {
+		t.Fatal("update config callback was not called")
+	}
+	if got.BaseDelay != 20*time.Second {
+		t.Errorf("BaseDelay = %v, want 20s", got.BaseDelay)
+	}
+	// Keys missing from the update keep their init values
+	if got.Workers != 4 {
+		t.Errorf("Workers = %d, want 4 from init", got.Workers)
+	}
+}
+
+func TestHandlerCancelTask(t *testing.T) {
+	input := `{"type":"cancel_task","ts":1234567890,"data":{"task_id":"task_7"}}
+`
+
+	var buf bytes.Buffer
+	h := NewHandlerWithIO(strings.NewReader(input), &buf)
+
+	var gotID string
+	h.OnCancelTask(func(taskID string) {
+		gotID = taskID
+	})
+
+	h.Start()
+
+	if gotID != "task_7" {
+		t.Errorf("cancelled task ID = %q, want task_7", gotID)
+	}
+	if strings.Contains(buf.String(), "unknown_type") {
+		t.Errorf("cancel_task should be a known type, got: %s", buf.String())
+	}
+}
+
 func TestHandlerUnknownType(t *testing.T) {
 	input := `{"type":"unknown_type","ts":1234567890}
 `
@@ -623,13 +1240,16 @@ func TestMessageTypes(t *testing.T) {
 		MsgTypeResume,
 		MsgTypeShutdown,
 		MsgTypeGetStats,
+		MsgTypeGetHealth,
 		MsgTypeStatus,
 		MsgTypeResult,
 		MsgTypeStats,
+		MsgTypeHealth,
 		MsgTypeError,
 		MsgTypeLog,
 		MsgTypeProgress,
 		MsgTypeProxyInfo,
+		MsgTypeComplete,
 	}
 
 	seen := make(map[MessageType]bool)
@@ -644,3 +1264,189 @@ func TestMessageTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestHandlerPipeHandshake(t *testing.T) {
+	inits := make(chan *InitConfig, 1)
+	p := newPipeHarness(t, func(h *Handler) {
+		h.OnInit(func(config *InitConfig) {
+			inits <- config
+			h.SendStatus("initialized", "")
+		})
+	})
+
+	// ready comes first, before any command
+	if msg := p.next(); msg.Type != MsgTypeStatus || msg.GetString("status") != "ready" {
+		t.Fatalf("first message = %+v, want ready status", msg)
+	}
+
+	p.send(MsgTypeInit, map[string]any{"workers": 4})
+	p.expectStatus("initialized")
+
+	if config := <-inits; config.Workers != 4 {
+		t.Errorf("Workers = %d, want 4", config.Workers)
+	}
+}
+
+func TestHandlerPipeOrdering(t *testing.T) {
+	p := newPipeHarness(t, func(h *Handler) {
+		h.OnTask(func(task *TaskData) {
+			h.SendResult(&ResultData{TaskID: task.ID, Dork: task.Dork})
+		})
+	})
+	p.expectStatus("ready")
+
+	// Replies come back in command order
+	for _, id := range []string{"t1", "t2", "t3"} {
+		p.send(MsgTypeTask, map[string]any{"task_id": id, "dork": "inurl:" + id})
+	}
+	for _, id := range []string{"t1", "t2", "t3"} {
+		if msg := p.expect(MsgTypeResult); msg.GetString("task_id") != id {
+			t.Errorf("result task_id = %q, want %q", msg.GetString("task_id"), id)
+		}
+	}
+
+	p.send(MsgTypePause, nil)
+	p.expectStatus("paused")
+	p.send(MsgTypeResume, nil)
+	p.expectStatus("resumed")
+}
+
+func TestHandlerPipeShutdownOnEOF(t *testing.T) {
+	shutdowns := make(chan struct{}, 2)
+	p := newPipeHarness(t, func(h *Handler) {
+		h.OnShutdown(func() { shutdowns <- struct{}{} })
+	})
+	p.expectStatus("ready")
+
+	p.closeInput()
+
+	if msg := p.expectStatus("shutdown"); msg.GetString("message") != "input closed" {
+		t.Errorf("shutdown message = %q, want input closed", msg.GetString("message"))
+	}
+	p.wait()
+
+	if len(shutdowns) != 1 {
+		t.Errorf("shutdown callback called %d times, want 1", len(shutdowns))
+	}
+}
+
+func TestHandlerPipeShutdownCommand(t *testing.T) {
+	p := newPipeHarness(t, nil)
+	p.expectStatus("ready")
+
+	p.send(MsgTypeShutdown, nil)
+	p.expectStatus("shutdown")
+	p.wait()
+}
+
+// snapshot returns the writes recorded so far
+func (r *writeRecorder) snapshot() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte(nil), r.writes...)
+}
+
+// batchSizes decodes result_batch writes into their result counts
+func batchSizes(t *testing.T, writes [][]byte) []int {
+	t.Helper()
+
+	var sizes []int
+	for _, write := range writes {
+		var batch struct {
+			Type MessageType `json:"type"`
+			Data struct {
+				Results []ResultData `json:"results"`
+				Count   int          `json:"count"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(write, &batch); err != nil {
+			t.Fatalf("output is not JSON: %v", err)
+		}
+		if batch.Type != MsgTypeResultBatch {
+			t.Fatalf("type = %q, want %q", batch.Type, MsgTypeResultBatch)
+		}
+		if batch.Data.Count != len(batch.Data.Results) {
+			t.Errorf("count = %d, want %d", batch.Data.Count, len(batch.Data.Results))
+		}
+		sizes = append(sizes, len(batch.Data.Results))
+	}
+	return sizes
+}
+
+func TestResultBatcherSize(t *testing.T) {
+	rec := &writeRecorder{}
+	b := NewResultBatcher(NewHandlerWithIO(strings.NewReader(""), rec), 3, time.Hour)
+
+	for i := 0; i < 7; i++ {
+		b.Add(&ResultData{TaskID: fmt.Sprintf("t%d", i), Status: "success"})
+	}
+	if got := batchSizes(t, rec.snapshot()); fmt.Sprint(got) != "[3 3]" {
+		t.Errorf("batches = %v, want [3 3]", got)
+	}
+
+	b.Flush()
+	if got := batchSizes(t, rec.snapshot()); fmt.Sprint(got) != "[3 3 1]" {
+		t.Errorf("batches after Flush = %v, want [3 3 1]", got)
+	}
+
+	b.Flush()
+	if got := len(rec.snapshot()); got != 3 {
+		t.Errorf("writes after empty Flush = %d, want 3", got)
+	}
+}
+
+func TestResultBatcherInterval(t *testing.T) {
+	rec := &writeRecorder{}
+	b := NewResultBatcher(NewHandlerWithIO(strings.NewReader(""), rec), 100, 20*time.Millisecond)
+
+	b.Add(&ResultData{TaskID: "t1"})
+	b.Add(&ResultData{TaskID: "t2"})
+	if got := len(rec.snapshot()); got != 0 {
+		t.Fatalf("writes before interval = %d, want 0", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(rec.snapshot()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := batchSizes(t, rec.snapshot()); fmt.Sprint(got) != "[2]" {
+		t.Errorf("batches = %v, want [2]", got)
+	}
+}
+
+func TestResultBatcherNilFlush(t *testing.T) {
+	var b *ResultBatcher
+	if err := b.Flush(); err != nil {
+		t.Errorf("nil Flush() error = %v", err)
+	}
+}
+
+func TestParseInitConfigBatchResults(t *testing.T) {
+	msg := &Message{Type: MsgTypeInit, Data: map[string]any{
+		"batch_results":        true,
+		"batch_size":           float64(25),
+		"batch_flush_interval": float64(250),
+	}}
+
+	config := ParseInitConfig(msg)
+	if !config.BatchResults || config.BatchSize != 25 || config.BatchFlushInterval != 250*time.Millisecond {
+		t.Errorf("batch config = %v/%d/%v, want true/25/250ms", config.BatchResults, config.BatchSize, config.BatchFlushInterval)
+	}
+}
+
+func TestParseInitConfigCircuitBreaker(t *testing.T) {
+	msg := &Message{Type: MsgTypeInit, Data: map[string]any{
+		"circuit_breaker":   true,
+		"breaker_window":    float64(30),
+		"breaker_threshold": 0.9,
+		"breaker_cooldown":  float64(60000),
+		"breaker_probes":    float64(2),
+	}}
+
+	config := ParseInitConfig(msg)
+	if !config.CircuitBreaker || config.BreakerWindow != 30 || config.BreakerThreshold != 0.9 ||
+		config.BreakerCooldown != time.Minute || config.BreakerProbes != 2 {
+		t.Errorf("breaker config = %v/%d/%v/%v/%d, want true/30/0.9/1m/2", config.CircuitBreaker,
+			config.BreakerWindow, config.BreakerThreshold, config.BreakerCooldown, config.BreakerProbes)
+	}
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Export formats supported by ExportStats
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// exportColumns is the CSV header, matching the JSON keys of ProxyStatsRow
+var exportColumns = []string{
+	"id", "host", "port", "type", "status",
+	"total", "success", "fail", "captcha",
+	"success_rate", "avg_latency_ms", "external_ip",
+}
+
+// ProxyStatsRow is one row of a stats export. Unlike ProxySnapshot the host
+// is not masked: the export is a local report of the user's own proxies.
+type ProxyStatsRow struct {
+	ID           string      `json:"id"`
+	Host         string      `json:"host"`
+	Port         string      `json:"port"`
+	Type         ProxyType   `json:"type"`
+	Status       ProxyStatus `json:"status"`
+	Total        int64       `json:"total"`
+	Success      int64       `json:"success"`
+	Fail         int64       `json:"fail"`
+	Captcha      int64       `json:"captcha"`
+	SuccessRate  float64     `json:"success_rate"`
+	AvgLatencyMs int64       `json:"avg_latency_ms"`
+	ExternalIP   string      `json:"external_ip"`
+}
+
+// statsRows returns the live stats of every proxy, best success rate first.
+// Ties go to the proxy with more requests, then by ID.
+func (p *Pool) statsRows() []ProxyStatsRow {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := make([]ProxyStatsRow, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		proxy.mu.RLock()
+		row := ProxyStatsRow{
+			ID:         proxy.ID,
+			Host:       proxy.Host,
+			Port:       proxy.Port,
+			Type:       proxy.Type,
+			Status:     proxy.Status,
+			Total:      proxy.TotalRequests,
+			Success:    proxy.SuccessCount,
+			Fail:       proxy.FailCount,
+			Captcha:    proxy.CaptchaCount,
+			ExternalIP: proxy.ExternalIP,
+		}
+		proxy.mu.RUnlock()
+
+		row.SuccessRate = proxy.SuccessRate()
+		row.AvgLatencyMs = proxy.AvgLatency().Milliseconds()
+		stats = append(stats, row)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.SuccessRate != b.SuccessRate {
+			return a.SuccessRate > b.SuccessRate
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.ID < b.ID
+	})
+	return stats
+}
+
+// ExportStats writes the live stats of every proxy to w as CSV or JSON,
+// best success rate first
+func (p *Pool) ExportStats(w io.Writer, format string) error {
+	stats := p.statsRows()
+
+	switch format {
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+		for _, s := range stats {
+			err := cw.Write([]string{
+				s.ID, s.Host, s.Port, string(s.Type), string(s.Status),
+				strconv.FormatInt(s.Total, 10),
+				strconv.FormatInt(s.Success, 10),
+				strconv.FormatInt(s.Fail, 10),
+				strconv.FormatInt(s.Captcha, 10),
+				strconv.FormatFloat(s.SuccessRate, 'f', 2, 64),
+				strconv.FormatInt(s.AvgLatencyMs, 10),
+				s.ExternalIP,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+
+	default:
+		return fmt.Errorf("unknown export format %q (want %s or %s)", format, ExportCSV, ExportJSON)
+	}
+}
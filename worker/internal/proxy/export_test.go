@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportPool() *Pool {
+	pool := newStatePool(DefaultPoolConfig(), "a", "b", "c")
+	pool.ReportSuccess("a", 100*time.Millisecond)
+	pool.ReportFailure("a")
+	pool.ReportSuccess("b", 200*time.Millisecond)
+	pool.ReportSuccess("b", 400*time.Millisecond)
+	pool.ReportFailure("c")
+
+	b, _ := pool.GetByID("b")
+	b.SetExternalIP("203.0.113.7")
+	return pool
+}
+
+func TestPoolExportStatsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportPool().ExportStats(&buf, ExportCSV); err != nil {
+		t.Fatalf("ExportStats() error = %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("rows = %d, want header + 3", len(rows))
+	}
+	if got := strings.Join(rows[0], ","); got != strings.Join(exportColumns, ",") {
+		t.Errorf("header = %q", got)
+	}
+
+	want := [][]string{
+		{"b", "10.0.0.1", "8080", "http", "alive", "2", "2", "0", "0", "100.00", "300", "203.0.113.7"},
+		{"a", "10.0.0.1", "8080", "http", "alive", "2", "1", "1", "0", "50.00", "100", ""},
+		{"c", "10.0.0.1", "8080", "http", "alive", "1", "0", "1", "0", "0.00", "0", ""},
+	}
+	for i, w := range want {
+		if got := strings.Join(rows[i+1], ","); got != strings.Join(w, ",") {
+			t.Errorf("row %d = %q, want %q", i+1, got, strings.Join(w, ","))
+		}
+	}
+}
+
+func TestPoolExportStatsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportPool().ExportStats(&buf, ExportJSON); err != nil {
+		t.Fatalf("ExportStats() error = %v", err)
+	}
+
+	var stats []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &stats); err != nil {
+		t.Fatalf("decoding JSON: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("entries = %d, want 3", len(stats))
+	}
+	for _, key := range exportColumns {
+		if _, ok := stats[0][key]; !ok {
+			t.Errorf("entry is missing %q", key)
+		}
+	}
+
+	var ids []string
+	for _, s := range stats {
+		ids = append(ids, s["id"].(string))
+	}
+	if got := strings.Join(ids, ","); got != "b,a,c" {
+		t.Errorf("order = %s, want b,a,c", got)
+	}
+	if stats[0]["avg_latency_ms"] != float64(300) || stats[0]["external_ip"] != "203.0.113.7" {
+		t.Errorf("b = %v", stats[0])
+	}
+}
+
+func TestPoolExportStatsUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newExportPool().ExportStats(&buf, "xml"); err == nil {
+		t.Error("ExportStats(xml) error = nil, want error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("ExportStats(xml) wrote %d bytes", buf.Len())
+	}
+}
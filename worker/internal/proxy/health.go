@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCheckURL answers with the caller's IP address, so a full check
+// also learns where each proxy exits
+const DefaultCheckURL = "https://api.ipify.org"
+
+// CheckResult is the outcome of health checking one proxy
+type CheckResult struct {
+	Proxy      *Proxy
+	Alive      bool
+	Latency    time.Duration
+	ExternalIP string // Full checks only, when the check URL echoes it
+	Err        error
+}
+
+// CheckResults are the outcomes of checking a proxy list, in list order
+type CheckResults []CheckResult
+
+// Alive returns the proxies that passed, in list order
+func (r CheckResults) Alive() []*Proxy {
+	var alive []*Proxy
+	for _, result := range r {
+		if result.Alive {
+			alive = append(alive, result.Proxy)
+		}
+	}
+	return alive
+}
+
+// Summary describes the results in a line, e.g. "12/20 proxies alive,
+// 8 dead, average latency 340ms"
+func (r CheckResults) Summary() string {
+	alive := 0
+	var latency time.Duration
+	for _, result := range r {
+		if result.Alive {
+			alive++
+			latency += result.Latency
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d proxies alive, %d dead", alive, len(r), len(r)-alive)
+	if alive > 0 {
+		summary += fmt.Sprintf(", average latency %s", (latency / time.Duration(alive)).Round(time.Millisecond))
+	}
+	return summary
+}
+
+// HealthChecker checks a proxy list outside any pool, for validating it
+// before a run
+type HealthChecker struct {
+	Timeout     time.Duration // Per proxy
+	Concurrency int           // Proxies checked at once
+	CheckURL    string        // Fetched through each proxy by CheckAll
+}
+
+// NewHealthChecker returns a checker with a 10s timeout checking 50
+// proxies at once against DefaultCheckURL
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		Timeout:     10 * time.Second,
+		Concurrency: 50,
+		CheckURL:    DefaultCheckURL,
+	}
+}
+
+// QuickCheckAll only opens a TCP connection to each proxy, as the pool
+// does when topping up. It is fast but cannot tell a working proxy from
+// any open port.
+func (h *HealthChecker) QuickCheckAll(ctx context.Context, proxies []*Proxy) CheckResults {
+	return h.checkAll(ctx, proxies, func(ctx context.Context, proxy *Proxy) (string, error) {
+		return "", dialCheck(ctx, proxy)
+	})
+}
+
+// CheckAll fetches CheckURL through each proxy, which passes on a 200
+// response. A response body holding just an IP address is recorded as
+// the proxy's external IP.
+func (h *HealthChecker) CheckAll(ctx context.Context, proxies []*Proxy) CheckResults {
+	return h.checkAll(ctx, proxies, h.fetch)
+}
+
+// checkAll runs check on every proxy, Concurrency at a time
+func (h *HealthChecker) checkAll(ctx context.Context, proxies []*Proxy, check func(context.Context, *Proxy) (string, error)) CheckResults {
+	concurrency := h.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make(CheckResults, len(proxies))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, proxy := range proxies {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, proxy *Proxy) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			checkCtx := ctx
+			if h.Timeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, h.Timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			ip, err := check(checkCtx, proxy)
+			results[i] = CheckResult{Proxy: proxy, Alive: err == nil, Err: err}
+			if err == nil {
+				results[i].Latency = time.Since(start)
+			}
+			if ip != "" {
+				results[i].ExternalIP = ip
+				proxy.SetExternalIP(ip)
+			}
+		}(i, proxy)
+	}
+	wg.Wait()
+	return results
+}
+
+// fetch requests CheckURL through a proxy, returning the external IP the
+// response holds, if any
+func (h *HealthChecker) fetch(ctx context.Context, proxy *Proxy) (string, error) {
+	if proxy.Type == ProxyTypeSOCKS4 {
+		return "", fmt.Errorf("%s proxies cannot be checked", proxy.Type)
+	}
+	proxyURL, err := url.Parse(proxy.URL())
+	if err != nil {
+		return "", err
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyURL(proxyURL), DisableKeepAlives: true}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", h.CheckURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		ip = ""
+	}
+	return ip, nil
+}
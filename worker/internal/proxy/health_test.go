@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// closedPort returns a local address nothing listens on
+func closedPort(t *testing.T) (string, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	host, port, _ := net.SplitHostPort(addr)
+	return host, port
+}
+
+func TestHealthChecker(t *testing.T) {
+	// Acts as an HTTP proxy in front of an IP echo service; proxy "bad"
+	// gets an error page instead
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Proxy-Authorization"), "Basic") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintln(w, "203.0.113.7")
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	deadHost, deadPort := closedPort(t)
+	proxies := []*Proxy{
+		{ID: "good", Host: host, Port: port, Type: ProxyTypeHTTP},
+		{ID: "dead", Host: deadHost, Port: deadPort, Type: ProxyTypeHTTP},
+		{ID: "bad", Host: host, Port: port, Username: "u", Password: "p", Type: ProxyTypeHTTP},
+		{ID: "socks4", Host: host, Port: port, Type: ProxyTypeSOCKS4},
+	}
+
+	checker := NewHealthChecker()
+	checker.CheckURL = "http://ip.test/"
+	checker.Concurrency = 2
+
+	results := checker.CheckAll(context.Background(), proxies)
+	var got []string
+	for i, result := range results {
+		if result.Proxy != proxies[i] {
+			t.Errorf("results[%d] is %s, want list order", i, result.Proxy.ID)
+		}
+		got = append(got, fmt.Sprintf("%s:%v", result.Proxy.ID, result.Alive))
+		if result.Alive != (result.Err == nil) {
+			t.Errorf("%s: Alive = %v with Err = %v", result.Proxy.ID, result.Alive, result.Err)
+		}
+	}
+	if strings.Join(got, " ") != "good:true dead:false bad:false socks4:false" {
+		t.Errorf("CheckAll() = %s", strings.Join(got, " "))
+	}
+	if results[0].ExternalIP != "203.0.113.7" || proxies[0].ExternalIP != "203.0.113.7" {
+		t.Errorf("ExternalIP = %q, proxy's = %q, want 203.0.113.7", results[0].ExternalIP, proxies[0].ExternalIP)
+	}
+	if alive := results.Alive(); len(alive) != 1 || alive[0].ID != "good" {
+		t.Errorf("Alive() = %v, want good", alive)
+	}
+	if summary := results.Summary(); !strings.HasPrefix(summary, "1/4 proxies alive, 3 dead, average latency ") {
+		t.Errorf("Summary() = %q", summary)
+	}
+
+	// A TCP check only notices the proxy nothing listens on
+	results = checker.QuickCheckAll(context.Background(), proxies)
+	got = got[:0]
+	for _, result := range results {
+		got = append(got, fmt.Sprintf("%s:%v", result.Proxy.ID, result.Alive))
+	}
+	if strings.Join(got, " ") != "good:true dead:false bad:true socks4:true" {
+		t.Errorf("QuickCheckAll() = %s", strings.Join(got, " "))
+	}
+	if summary := results.Summary(); !strings.HasPrefix(summary, "3/4 proxies alive, 1 dead") {
+		t.Errorf("Summary() = %q", summary)
+	}
+}
+
+func TestCheckResultsSummaryNoneAlive(t *testing.T) {
+	results := CheckResults{{Proxy: &Proxy{ID: "a"}}, {Proxy: &Proxy{ID: "b"}}}
+	if got := results.Summary(); got != "0/2 proxies alive, 2 dead" {
+		t.Errorf("Summary() = %q", got)
+	}
+	if alive := results.Alive(); alive != nil {
+		t.Errorf("Alive() = %v, want none", alive)
+	}
+}
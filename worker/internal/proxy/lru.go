@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// lruQueue is a min-heap of alive proxies ordered by when the pool last
+// handed them out, so the proxy idle longest is found in O(log n) instead
+// of by scanning the alive list. Proxies enter it when they become alive
+// and leave lazily: one found out of rotation when popped is dropped. It
+// is guarded by the pool lock, as are the lru fields of Proxy.
+type lruQueue []*Proxy
+
+func (q lruQueue) Len() int { return len(q) }
+
+func (q lruQueue) Less(i, j int) bool {
+	if !q[i].lruAt.Equal(q[j].lruAt) {
+		return q[i].lruAt.Before(q[j].lruAt)
+	}
+	return q[i].ID < q[j].ID
+}
+
+func (q lruQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].lruIndex = i
+	q[j].lruIndex = j
+}
+
+func (q *lruQueue) Push(x any) {
+	proxy := x.(*Proxy)
+	proxy.lruIndex = len(*q)
+	proxy.inLRU = true
+	*q = append(*q, proxy)
+}
+
+func (q *lruQueue) Pop() any {
+	old := *q
+	proxy := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	proxy.inLRU = false
+	return proxy
+}
+
+// queueLRU adds a proxy that became alive to the LRU queue, ordered by
+// the later of its last hand-out and LastUsed, which a restored state
+// may carry (must hold lock)
+func (p *Pool) queueLRU(proxy *Proxy) {
+	proxy.mu.RLock()
+	lastUsed := proxy.LastUsed
+	proxy.mu.RUnlock()
+
+	if lastUsed.After(proxy.lruAt) {
+		proxy.lruAt = lastUsed
+	}
+	if proxy.inLRU {
+		heap.Fix(&p.lru, proxy.lruIndex)
+		return
+	}
+	heap.Push(&p.lru, proxy)
+}
+
+// touchLRU records that a proxy was just handed out, moving it to the
+// back of the LRU queue (must hold lock)
+func (p *Pool) touchLRU(proxy *Proxy) {
+	proxy.lruAt = time.Now()
+	if proxy.inLRU {
+		heap.Fix(&p.lru, proxy.lruIndex)
+	}
+}
+
+// takeLRU leases the proxy idle longest that can be handed out now, or
+// returns nil when none can (must hold lock). Proxies no longer alive
+// leave the queue; those cooling down or at their lease limit are put
+// back unchanged.
+func (p *Pool) takeLRU() *Proxy {
+	var skipped []*Proxy
+	defer func() {
+		for _, proxy := range skipped {
+			heap.Push(&p.lru, proxy)
+		}
+	}()
+
+	for p.lru.Len() > 0 {
+		proxy := heap.Pop(&p.lru).(*Proxy)
+		if proxy.Status != ProxyStatusAlive || !p.contains(proxy) {
+			continue
+		}
+		if !proxy.IsAvailable() || !proxy.lease(p.config.MaxConcurrentPerProxy, p.leaseTimeout()) {
+			skipped = append(skipped, proxy)
+			continue
+		}
+
+		proxy.lruAt = time.Now()
+		heap.Push(&p.lru, proxy)
+		return proxy
+	}
+	return nil
+}
+
+// GetLeastRecentlyUsed returns the available proxy the pool handed out
+// longest ago, or never, whatever the selection strategy. Like Get it
+// leases the proxy.
+func (p *Pool) GetLeastRecentlyUsed() (*Proxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.totalRotations++
+
+	proxy := p.takeLRU()
+	if proxy == nil {
+		return nil, fmt.Errorf("no available proxies")
+	}
+	return proxy, nil
+}
+
+// takeLRUN leases up to n distinct proxies in LRU order for GetN (must
+// hold lock)
+func (p *Pool) takeLRUN(n int) ([]*Proxy, error) {
+	selected := make([]*Proxy, 0, n)
+	taken := make(map[*Proxy]bool, n)
+	for len(selected) < n {
+		proxy := p.takeLRU()
+		if proxy == nil {
+			break
+		}
+		if taken[proxy] {
+			// Came back around: fewer than n are available
+			proxy.unlease()
+			break
+		}
+		taken[proxy] = true
+		selected = append(selected, proxy)
+	}
+
+	if len(selected) == 0 {
+		p.totalRotations++
+		return nil, fmt.Errorf("no available proxies")
+	}
+	p.totalRotations += int64(len(selected))
+	return selected, nil
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLRUPool(ids ...string) *Pool {
+	config := DefaultPoolConfig()
+	config.RotateEveryRequest = true
+	return newStatePool(config, ids...)
+}
+
+// picks calls Get n times, reporting each proxy back, and returns the IDs
+func picks(t *testing.T, pool *Pool, n int) string {
+	t.Helper()
+	var got []string
+	for i := 0; i < n; i++ {
+		p, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		pool.ReportSuccess(p.ID, time.Millisecond)
+		got = append(got, p.ID)
+	}
+	return strings.Join(got, " ")
+}
+
+func TestPoolRotateEveryRequest(t *testing.T) {
+	pool := newLRUPool("c", "a", "b")
+	if got := picks(t, pool, 6); got != "a b c a b c" {
+		t.Errorf("picks = %s, want a b c a b c", got)
+	}
+
+	// A proxy cooling down is passed over without losing its place
+	pool.proxies["a"].SetCooldown(time.Hour)
+	if got := picks(t, pool, 3); got != "b c b" {
+		t.Errorf("picks with a cooling down = %s, want b c b", got)
+	}
+	pool.proxies["a"].SetCooldown(0)
+	if got := picks(t, pool, 1); got != "a" {
+		t.Errorf("pick after cooldown = %s, want a", got)
+	}
+
+	// Dead proxies leave the rotation and rejoin when revived
+	pool.mu.Lock()
+	pool.markDead(pool.proxies["b"])
+	pool.mu.Unlock()
+	if got := picks(t, pool, 3); got != "c a c" {
+		t.Errorf("picks with b dead = %s, want c a c", got)
+	}
+	pool.mu.Lock()
+	pool.place(pool.proxies["b"], ProxyStatusAlive)
+	pool.mu.Unlock()
+	if got := picks(t, pool, 1); got != "b" {
+		t.Errorf("pick after revival = %s, want b", got)
+	}
+
+	// Removed proxies leave the queue
+	pool.Remove("c")
+	if got := picks(t, pool, 2); got != "a b" {
+		t.Errorf("picks after removing c = %s, want a b", got)
+	}
+	if pool.lru.Len() != 2 {
+		t.Errorf("queued = %d, want 2", pool.lru.Len())
+	}
+}
+
+func TestPoolRotateEveryRequestLastUsed(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.RotateEveryRequest = true
+	pool := NewPool(config)
+
+	// Never used first, then the one idle longest
+	pool.AddProxy(&Proxy{ID: "recent", Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP, LastUsed: time.Now()})
+	pool.AddProxy(&Proxy{ID: "old", Host: "10.0.0.2", Port: "8080", Type: ProxyTypeHTTP, LastUsed: time.Now().Add(-time.Hour)})
+	pool.AddProxy(&Proxy{ID: "never", Host: "10.0.0.3", Port: "8080", Type: ProxyTypeHTTP})
+
+	if got := picks(t, pool, 3); got != "never old recent" {
+		t.Errorf("picks = %s, want never old recent", got)
+	}
+}
+
+func TestPoolRotateEveryRequestLeases(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.RotateEveryRequest = true
+	config.MaxConcurrentPerProxy = 1
+	pool := newStatePool(config, "a", "b", "c")
+
+	// GetN hands out distinct proxies, fewer when fewer are free
+	got, err := pool.GetN(2)
+	if err != nil || len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("GetN(2) = %v, %v, want a and b", got, err)
+	}
+	got, err = pool.GetN(2)
+	if err != nil || len(got) != 1 || got[0].ID != "c" {
+		t.Fatalf("GetN(2) with one free = %v, %v, want c", got, err)
+	}
+	if _, err := pool.Get(); err == nil {
+		t.Error("Get() with every proxy leased error = nil, want error")
+	}
+
+	pool.ReportSuccess("b", time.Millisecond)
+	if p, err := pool.Get(); err != nil || p.ID != "b" {
+		t.Errorf("Get() after b is reported = %v, %v, want b", p, err)
+	}
+}
+
+func TestPoolGetLeastRecentlyUsed(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.Selection = SelectionRoundRobin
+	pool := newStatePool(config, "a", "b", "c")
+
+	// Hand-outs by any path count as use
+	pool.Get()
+	pool.Acquire("c")
+	if p, err := pool.GetLeastRecentlyUsed(); err != nil || p.ID != "b" {
+		t.Errorf("GetLeastRecentlyUsed() = %v, %v, want b", p, err)
+	}
+	if p, err := pool.GetLeastRecentlyUsed(); err != nil || p.ID != "a" {
+		t.Errorf("GetLeastRecentlyUsed() = %v, %v, want a", p, err)
+	}
+}
+
+func BenchmarkPoolGet(b *testing.B) {
+	const size = 10000
+
+	for _, bc := range []struct {
+		name   string
+		config func(*PoolConfig)
+	}{
+		{"weighted", func(c *PoolConfig) {}},
+		{"least_used", func(c *PoolConfig) { c.Selection = SelectionLeastUsed }},
+		{"rotate_every_request", func(c *PoolConfig) { c.RotateEveryRequest = true }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			config := DefaultPoolConfig()
+			bc.config(&config)
+			pool := NewPool(config)
+			for i := 0; i < size; i++ {
+				pool.AddProxy(&Proxy{ID: fmt.Sprintf("p%05d", i), Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p, err := pool.Get()
+				if err != nil {
+					b.Fatalf("Get() error = %v", err)
+				}
+				pool.ReportSuccess(p.ID, time.Millisecond)
+			}
+		})
+	}
+}
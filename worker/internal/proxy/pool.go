@@ -1,21 +1,115 @@
 package proxy
 
 import (
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// SelectionStrategy decides which available proxy Get hands out
+type SelectionStrategy string
+
+const (
+	// SelectionWeighted draws at random, favouring proxies with a better
+	// recent success score and lower latency
+	SelectionWeighted   SelectionStrategy = "weighted"
+	SelectionRoundRobin SelectionStrategy = "round_robin"
+	SelectionLeastUsed  SelectionStrategy = "least_used" // Fewest requests so far
+	SelectionRandom     SelectionStrategy = "random"
+)
+
+// ParseSelectionStrategy validates a selection strategy name, defaulting
+// to weighted when empty
+func ParseSelectionStrategy(value string) (SelectionStrategy, error) {
+	switch s := SelectionStrategy(value); s {
+	case "":
+		return SelectionWeighted, nil
+	case SelectionWeighted, SelectionRoundRobin, SelectionLeastUsed, SelectionRandom:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown proxy selection %q (want weighted, round_robin, least_used or random)", value)
+	}
+}
+
 // PoolConfig holds configuration for the proxy pool
 type PoolConfig struct {
-	MaxFailures       int           `json:"max_failures"`        // Max failures before quarantine
+	MaxFailures       int           `json:"max_failures"`        // Consecutive failures before quarantine when QuarantineThreshold is unset
 	CooldownDuration  time.Duration `json:"cooldown_duration"`   // Cooldown after CAPTCHA/rate limit
 	QuarantineDuration time.Duration `json:"quarantine_duration"` // How long to quarantine bad proxies
+	BlockCooldown     time.Duration `json:"block_cooldown"`      // Quarantine after a block (0 = QuarantineDuration)
+	FailureCooldown   time.Duration `json:"failure_cooldown"`    // Cooldown after a failed request (0 = none)
+	MaxRetryAfter     time.Duration `json:"max_retry_after"`     // Cap on a 429's Retry-After cooldown (0 = DefaultMaxRetryAfter)
+
+	// EvictAfterDeadDuration removes proxies from the pool once they have
+	// been dead this long, at the next health check (0 = keep them)
+	EvictAfterDeadDuration time.Duration `json:"evict_after_dead_duration"`
 	HealthCheckInterval time.Duration `json:"health_check_interval"` // Interval between health checks
 	MinSuccessRate    float64       `json:"min_success_rate"`    // Minimum success rate to stay active
+
+	// QuarantineThreshold is how many failures in a row quarantine a
+	// proxy (0 = MaxFailures). Once QuarantineDuration has passed the
+	// proxy is released as unknown and the next health check revives it
+	// if it still connects.
+	QuarantineThreshold int `json:"quarantine_threshold"`
+
+	// Selection is how Get picks among available proxies (default weighted)
+	Selection SelectionStrategy `json:"selection"`
+
+	// ScoreHalfLife is how long until an outcome counts half as much in
+	// the selection score, so degrading proxies lose weight quickly
+	// (0 = weigh the whole run equally)
+	ScoreHalfLife time.Duration `json:"score_half_life"`
+
+	// Lazy checking: when TargetAlive > 0, added proxies stay unchecked
+	// until WarmUp or the background topper needs them
+	TargetAlive      int           `json:"target_alive"`      // Alive proxies to reach before starting
+	LowWater         int           `json:"low_water"`         // Top up below this many alive (default TargetAlive/2)
+	CheckTimeout     time.Duration `json:"check_timeout"`     // Per-proxy connect timeout
+	CheckConcurrency int           `json:"check_concurrency"` // Parallel checks while topping up
+
+	// How often sources are re-fetched for new proxies (0 = load once)
+	SourceRefreshInterval time.Duration `json:"source_refresh_interval"`
+
+	// RecheckDead makes LoadState health check proxies saved as dead
+	// instead of keeping them out of rotation
+	RecheckDead bool `json:"recheck_dead"`
+
+	// MaxConcurrentPerProxy caps the requests in flight through one proxy
+	// (0 = no limit). Get and Acquire hand out a lease that the next
+	// Report call returns; leases not returned within LeaseTimeout
+	// (0 = DefaultLeaseTimeout) expire.
+	MaxConcurrentPerProxy int           `json:"max_concurrent_per_proxy"`
+	LeaseTimeout          time.Duration `json:"lease_timeout"`
+
+	// Seed makes random and weighted selection reproducible (0 = seeded
+	// from the clock)
+	Seed int64 `json:"seed"`
+
+	// RotateEveryRequest makes Get and GetN ignore Selection and hand out
+	// the available proxy idle longest, so consecutive requests leave
+	// through different proxies and load spreads evenly
+	RotateEveryRequest bool `json:"rotate_every_request"`
 }
 
+// DefaultLeaseTimeout is how long a lease lasts when it is never reported
+const DefaultLeaseTimeout = 2 * time.Minute
+
+// DefaultMaxRetryAfter caps the cooldown a 429's Retry-After can ask for
+const DefaultMaxRetryAfter = 15 * time.Minute
+
+// ErrProxyBusy is returned by Acquire for a proxy already at
+// MaxConcurrentPerProxy
+var ErrProxyBusy = errors.New("proxy at its concurrency limit")
+
 // DefaultPoolConfig returns sensible defaults
 func DefaultPoolConfig() PoolConfig {
 	return PoolConfig{
@@ -24,6 +118,10 @@ func DefaultPoolConfig() PoolConfig {
 		QuarantineDuration: 5 * time.Minute,
 		HealthCheckInterval: 1 * time.Minute,
 		MinSuccessRate:     50.0,
+		Selection:          SelectionWeighted,
+		ScoreHalfLife:      10 * time.Minute,
+		CheckTimeout:       5 * time.Second,
+		CheckConcurrency:   50,
 	}
 }
 
@@ -34,26 +132,53 @@ type Pool struct {
 	alive    []*Proxy          // Available proxies for rotation
 	dead     []*Proxy          // Dead proxies
 	quarantine []*Proxy        // Temporarily quarantined proxies
+	unchecked  []*Proxy        // Not yet health checked (lazy mode)
+	released   []*Proxy        // Out of quarantine, awaiting the next health check
+	sources    []ProxySource   // Where proxies are loaded from
+	sourced    map[string]bool // IDs of proxies loaded from sources
 
 	config   PoolConfig
 	rng      *rand.Rand
 	stopCh   chan struct{}
+	topUpCh  chan struct{}
+	checkFn  func(ctx context.Context, proxy *Proxy) error
+	topUpMu  sync.Mutex
+
+	// Called for every proxy taken out of the pool
+	onRemove func(proxyID string)
 	
+	// Next index for round-robin selection
+	rrNext int
+
+	// Alive proxies by when they were last handed out
+	lru lruQueue
+
 	// Statistics
 	totalRotations int64
 	totalRequests  int64
+	totalChecked   int64
+	totalEvicted   int64
 }
 
 // NewPool creates a new proxy pool
 func NewPool(config PoolConfig) *Pool {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
 	return &Pool{
 		proxies:    make(map[string]*Proxy),
 		alive:      make([]*Proxy, 0),
 		dead:       make([]*Proxy, 0),
 		quarantine: make([]*Proxy, 0),
+		unchecked:  make([]*Proxy, 0),
+		sourced:    make(map[string]bool),
 		config:     config,
-		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:        rand.New(rand.NewSource(seed)),
 		stopCh:     make(chan struct{}),
+		topUpCh:    make(chan struct{}, 1),
+		checkFn:    dialCheck,
 	}
 }
 
@@ -66,11 +191,156 @@ func (p *Pool) AddProxy(proxy *Proxy) error {
 		return fmt.Errorf("proxy %s already exists", proxy.ID)
 	}
 
-	proxy.Status = ProxyStatusAlive
+	p.addProxy(proxy)
+	return nil
+}
+
+// addProxy adds a proxy not yet in the pool (must hold lock)
+func (p *Pool) addProxy(proxy *Proxy) {
 	p.proxies[proxy.ID] = proxy
+
+	if p.config.TargetAlive > 0 {
+		proxy.Status = ProxyStatusUnknown
+		p.unchecked = append(p.unchecked, proxy)
+		return
+	}
+
+	proxy.Status = ProxyStatusAlive
 	p.alive = append(p.alive, proxy)
+	p.queueLRU(proxy)
+}
 
-	return nil
+// AddSource registers a proxy source; call LoadSources to fetch from it
+func (p *Pool) AddSource(source ProxySource) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sources = append(p.sources, source)
+}
+
+// LoadSources fetches every source and merges new proxies into the pool.
+// Proxies already in the pool (by ID) are skipped and keep their stats,
+// so sources may overlap. Proxies loaded from a source that no source
+// lists any more are removed, but only once they are dead, so good
+// proxies are not churned by a flaky list.
+func (p *Pool) LoadSources(ctx context.Context) (added int, errors []error) {
+	return p.loadSources(ctx, false)
+}
+
+// loadSources fetches all sources, optionally asking them to refresh first
+func (p *Pool) loadSources(ctx context.Context, refresh bool) (added int, errors []error) {
+	p.mu.RLock()
+	sources := make([]ProxySource, len(p.sources))
+	copy(sources, p.sources)
+	p.mu.RUnlock()
+
+	// A source that failed outright says nothing about what vanished
+	seen := make(map[string]bool)
+	complete := true
+
+	for _, source := range sources {
+		if refresh {
+			source.Refresh()
+		}
+
+		proxies, err := source.Fetch(ctx)
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errors = append(errors, joined.Unwrap()...)
+		} else if err != nil {
+			errors = append(errors, err)
+		}
+		if err != nil && len(proxies) == 0 {
+			complete = false
+		}
+
+		p.mu.Lock()
+		for _, proxy := range proxies {
+			seen[proxy.ID] = true
+			if _, exists := p.proxies[proxy.ID]; exists {
+				continue
+			}
+			p.addProxy(proxy)
+			p.sourced[proxy.ID] = true
+			added++
+		}
+		p.mu.Unlock()
+	}
+
+	if complete {
+		p.pruneVanished(seen)
+	}
+
+	return added, errors
+}
+
+// pruneVanished removes dead source proxies missing from seen
+func (p *Pool) pruneVanished(seen map[string]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id := range p.sourced {
+		proxy, ok := p.proxies[id]
+		if !ok {
+			delete(p.sourced, id)
+			continue
+		}
+		if seen[id] || proxy.Status != ProxyStatusDead {
+			continue
+		}
+
+		p.remove(proxy)
+	}
+}
+
+// Remove takes a proxy out of the pool for good, returning false if it is
+// not in the pool. A proxy handed out earlier stays usable by its holder,
+// but reports for it are ignored and it is never handed out again.
+func (p *Pool) Remove(proxyID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy, exists := p.proxies[proxyID]
+	if !exists {
+		return false
+	}
+
+	p.remove(proxy)
+	p.signalIfLow()
+	return true
+}
+
+// remove drops a proxy from every list (must hold lock)
+func (p *Pool) remove(proxy *Proxy) {
+	p.alive = removeProxy(p.alive, proxy)
+	p.dead = removeProxy(p.dead, proxy)
+	p.quarantine = removeProxy(p.quarantine, proxy)
+	p.unchecked = removeProxy(p.unchecked, proxy)
+	p.released = removeProxy(p.released, proxy)
+	if proxy.inLRU {
+		heap.Remove(&p.lru, proxy.lruIndex)
+	}
+	delete(p.proxies, proxy.ID)
+	delete(p.sourced, proxy.ID)
+
+	if p.onRemove != nil {
+		p.onRemove(proxy.ID)
+	}
+}
+
+// OnRemove sets a callback invoked with the ID of every proxy removed
+// from the pool, whether by Remove, eviction or a source refresh, so state
+// kept per proxy can be released. It runs with the pool locked and must
+// not call back into the pool.
+func (p *Pool) OnRemove(fn func(proxyID string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onRemove = fn
+}
+
+// contains reports whether proxy is still in the pool, as opposed to
+// removed while being checked (must hold lock)
+func (p *Pool) contains(proxy *Proxy) bool {
+	return p.proxies[proxy.ID] == proxy
 }
 
 // AddProxies adds multiple proxies to the pool
@@ -97,31 +367,190 @@ func (p *Pool) LoadFromFile(filepath string) (added int, errors []error) {
 	return addedCount, errors
 }
 
-// Get returns an available proxy using weighted random selection
-// Proxies with better success rates are more likely to be selected
+// Get returns an available proxy chosen by the configured selection
+// strategy
 func (p *Pool) Get() (*Proxy, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	p.totalRotations++
 
-	// Filter available proxies
+	if p.config.RotateEveryRequest {
+		if proxy := p.takeLRU(); proxy != nil {
+			return proxy, nil
+		}
+		return nil, fmt.Errorf("no available proxies")
+	}
+
+	available := p.available()
+	if len(available) == 0 {
+		return nil, fmt.Errorf("no available proxies")
+	}
+
+	proxy := p.selectProxy(available)
+	proxy.lease(p.config.MaxConcurrentPerProxy, p.leaseTimeout())
+	p.touchLRU(proxy)
+	return proxy, nil
+}
+
+// GetN returns up to n distinct available proxies chosen by the
+// configured selection strategy, each leased as by Get, so report every
+// one. Fewer than n are returned when fewer are available; it fails only
+// when none are. n below 1 returns nothing.
+func (p *Pool) GetN(n int) ([]*Proxy, error) {
+	if n < 1 {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.config.RotateEveryRequest {
+		return p.takeLRUN(n)
+	}
+
+	available := p.available()
+	if len(available) == 0 {
+		p.totalRotations++
+		return nil, fmt.Errorf("no available proxies")
+	}
+
+	selected := p.selectProxies(available, n)
+	for _, proxy := range selected {
+		proxy.lease(p.config.MaxConcurrentPerProxy, p.leaseTimeout())
+		p.touchLRU(proxy)
+	}
+	p.totalRotations += int64(len(selected))
+	return selected, nil
+}
+
+// available returns the alive proxies that can be handed out now,
+// skipping those at their in-flight limit (must hold lock)
+func (p *Pool) available() []*Proxy {
+	limit := p.config.MaxConcurrentPerProxy
 	available := make([]*Proxy, 0, len(p.alive))
 	for _, proxy := range p.alive {
-		if proxy.IsAvailable() {
+		if proxy.IsAvailable() && (limit <= 0 || proxy.ActiveLeases() < limit) {
 			available = append(available, proxy)
 		}
 	}
+	return available
+}
 
-	if len(available) == 0 {
-		return nil, fmt.Errorf("no available proxies")
+// Acquire hands out a specific proxy the way Get would, for callers
+// pinned to one exit. It fails if the proxy is unknown or unavailable,
+// and with ErrProxyBusy if it is at MaxConcurrentPerProxy.
+func (p *Pool) Acquire(id string) (*Proxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy, exists := p.proxies[id]
+	if !exists || !proxy.IsAvailable() {
+		return nil, fmt.Errorf("proxy %s unavailable", id)
+	}
+	if !proxy.lease(p.config.MaxConcurrentPerProxy, p.leaseTimeout()) {
+		return nil, fmt.Errorf("proxy %s: %w", id, ErrProxyBusy)
 	}
 
-	// Weighted random selection based on success rate
-	proxy := p.weightedSelect(available)
+	p.totalRotations++
+	p.touchLRU(proxy)
 	return proxy, nil
 }
 
+// leaseTimeout returns how long an unreported lease lasts
+func (p *Pool) leaseTimeout() time.Duration {
+	if p.config.LeaseTimeout > 0 {
+		return p.config.LeaseTimeout
+	}
+	return DefaultLeaseTimeout
+}
+
+// selectProxy picks one of the available proxies (must hold lock)
+func (p *Pool) selectProxy(available []*Proxy) *Proxy {
+	switch p.config.Selection {
+	case SelectionRoundRobin:
+		proxy := available[p.rrNext%len(available)]
+		p.rrNext++
+		return proxy
+
+	case SelectionLeastUsed:
+		best := available[0]
+		bestUsed := best.requestCount()
+		for _, proxy := range available[1:] {
+			if used := proxy.requestCount(); used < bestUsed {
+				best, bestUsed = proxy, used
+			}
+		}
+		return best
+
+	case SelectionRandom:
+		return available[p.rng.Intn(len(available))]
+
+	default:
+		return p.weightedSelect(available)
+	}
+}
+
+// selectProxies picks up to n distinct proxies from available (must hold
+// lock). Round robin takes the next n in order; the other strategies draw
+// one at a time from those not yet picked.
+func (p *Pool) selectProxies(available []*Proxy, n int) []*Proxy {
+	if n > len(available) {
+		n = len(available)
+	}
+	selected := make([]*Proxy, 0, n)
+
+	if p.config.Selection == SelectionRoundRobin {
+		for i := 0; i < n; i++ {
+			selected = append(selected, available[(p.rrNext+i)%len(available)])
+		}
+		p.rrNext += n
+		return selected
+	}
+
+	remaining := append([]*Proxy(nil), available...)
+	for len(selected) < n {
+		proxy := p.selectProxy(remaining)
+		selected = append(selected, proxy)
+		for i, candidate := range remaining {
+			if candidate == proxy {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// NextAvailableTime returns when the next proxy becomes usable: now if one
+// is available, the earliest cooldown expiry if every alive or quarantined
+// proxy is cooling down, or the zero time if nothing is waiting to recover
+func (p *Pool) NextAvailableTime() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	var next time.Time
+
+	for _, list := range [][]*Proxy{p.alive, p.quarantine} {
+		for _, proxy := range list {
+			if proxy.IsAvailable() {
+				return now
+			}
+
+			proxy.mu.RLock()
+			until := proxy.CooldownUntil
+			proxy.mu.RUnlock()
+
+			if until.After(now) && (next.IsZero() || until.Before(next)) {
+				next = until
+			}
+		}
+	}
+
+	return next
+}
+
 // weightedSelect selects a proxy based on success rate weights
 func (p *Pool) weightedSelect(proxies []*Proxy) *Proxy {
 	if len(proxies) == 1 {
@@ -133,15 +562,13 @@ func (p *Pool) weightedSelect(proxies []*Proxy) *Proxy {
 	totalWeight := 0.0
 
 	for i, proxy := range proxies {
-		// Base weight of 1, plus success rate bonus
+		// Base weight of 1, plus recent success bonus
 		weight := 1.0
-		if proxy.TotalRequests > 0 {
-			weight += proxy.SuccessRate() / 100.0 * 2.0 // Max bonus of 2.0
-		}
-		// Penalize slow proxies
-		if proxy.AvgLatency() > 5*time.Second {
-			weight *= 0.5
+		if score, ok := proxy.Score(); ok {
+			weight += score / 100.0 * 2.0 // Max bonus of 2.0
 		}
+		// Penalize slow proxies, halving the weight at 5s
+		weight /= 1 + proxy.AvgLatency().Seconds()/5
 		weights[i] = weight
 		totalWeight += weight
 	}
@@ -180,11 +607,25 @@ func (p *Pool) ReportSuccess(proxyID string, latency time.Duration) {
 		return
 	}
 
+	proxy.unlease()
 	proxy.RecordSuccess(latency)
+	proxy.recordOutcome(true, time.Now(), p.config.ScoreHalfLife)
 	p.totalRequests++
 }
 
-// ReportFailure reports a failed request for a proxy
+// Release returns a proxy's lease without recording an outcome, for a
+// request aborted before it could tell anything about the proxy
+func (p *Pool) Release(proxyID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if proxy, exists := p.proxies[proxyID]; exists {
+		proxy.unlease()
+	}
+}
+
+// ReportFailure reports a failed request for a proxy, cooling it down
+// for FailureCooldown
 func (p *Pool) ReportFailure(proxyID string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -194,13 +635,27 @@ func (p *Pool) ReportFailure(proxyID string) {
 		return
 	}
 
+	proxy.unlease()
 	proxy.RecordFail()
+	proxy.recordOutcome(false, time.Now(), p.config.ScoreHalfLife)
 	p.totalRequests++
 
-	// Check if should be quarantined
-	if proxy.FailCount >= int64(p.config.MaxFailures) {
+	// Quarantine after too many failures in a row
+	if proxy.Status == ProxyStatusAlive && proxy.FailStreak >= int64(p.quarantineThreshold()) {
 		p.quarantineProxy(proxy)
+		return
+	}
+	if p.config.FailureCooldown > 0 {
+		proxy.SetCooldown(p.config.FailureCooldown)
+	}
+}
+
+// quarantineThreshold returns the failure streak that quarantines a proxy
+func (p *Pool) quarantineThreshold() int {
+	if p.config.QuarantineThreshold > 0 {
+		return p.config.QuarantineThreshold
 	}
+	return p.config.MaxFailures
 }
 
 // ReportCaptcha reports a CAPTCHA encounter for a proxy
@@ -213,10 +668,44 @@ func (p *Pool) ReportCaptcha(proxyID string) {
 		return
 	}
 
+	proxy.unlease()
 	proxy.RecordCaptcha()
+	proxy.recordOutcome(false, time.Now(), p.config.ScoreHalfLife)
 	proxy.SetCooldown(p.config.CooldownDuration)
 }
 
+// ReportRateLimit reports a rate-limited (429) request for a proxy,
+// cooling it down for retryAfter capped at MaxRetryAfter. Without a
+// Retry-After (retryAfter <= 0) the CAPTCHA cooldown applies. Rate limits
+// do not count towards quarantine.
+func (p *Pool) ReportRateLimit(proxyID string, retryAfter time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy, exists := p.proxies[proxyID]
+	if !exists {
+		return
+	}
+
+	proxy.unlease()
+	proxy.recordOutcome(false, time.Now(), p.config.ScoreHalfLife)
+	p.totalRequests++
+
+	cooldown := p.config.CooldownDuration
+	if retryAfter > 0 {
+		cooldown = min(retryAfter, p.maxRetryAfter())
+	}
+	proxy.SetCooldown(cooldown)
+}
+
+// maxRetryAfter returns the cap on Retry-After cooldowns
+func (p *Pool) maxRetryAfter() time.Duration {
+	if p.config.MaxRetryAfter > 0 {
+		return p.config.MaxRetryAfter
+	}
+	return DefaultMaxRetryAfter
+}
+
 // ReportBlock reports that a proxy has been blocked
 func (p *Pool) ReportBlock(proxyID string) {
 	p.mu.Lock()
@@ -227,7 +716,26 @@ func (p *Pool) ReportBlock(proxyID string) {
 		return
 	}
 
+	proxy.unlease()
+	proxy.recordOutcome(false, time.Now(), p.config.ScoreHalfLife)
 	p.quarantineProxy(proxy)
+	if p.config.BlockCooldown > 0 {
+		proxy.SetCooldown(p.config.BlockCooldown)
+	}
+}
+
+// SetCooldowns changes the CAPTCHA and block cooldowns for future reports.
+// Zero values leave the current setting unchanged.
+func (p *Pool) SetCooldowns(captcha, block time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if captcha > 0 {
+		p.config.CooldownDuration = captcha
+	}
+	if block > 0 {
+		p.config.BlockCooldown = block
+	}
 }
 
 // quarantineProxy moves a proxy to quarantine (must hold lock)
@@ -244,11 +752,13 @@ func (p *Pool) quarantineProxy(proxy *Proxy) {
 	}
 
 	p.quarantine = append(p.quarantine, proxy)
+	p.signalIfLow()
 }
 
 // markDead marks a proxy as permanently dead (must hold lock)
 func (p *Pool) markDead(proxy *Proxy) {
 	proxy.Status = ProxyStatusDead
+	proxy.deadSince = time.Now()
 
 	// Remove from alive list
 	for i, ap := range p.alive {
@@ -267,22 +777,23 @@ func (p *Pool) markDead(proxy *Proxy) {
 	}
 
 	p.dead = append(p.dead, proxy)
+	p.signalIfLow()
 }
 
-// reviveProxy moves a proxy from quarantine back to alive (must hold lock)
-func (p *Pool) reviveProxy(proxy *Proxy) {
-	proxy.Status = ProxyStatusAlive
-	proxy.FailCount = 0 // Reset fail count
-
-	// Remove from quarantine
-	for i, qp := range p.quarantine {
-		if qp.ID == proxy.ID {
-			p.quarantine = append(p.quarantine[:i], p.quarantine[i+1:]...)
-			break
-		}
-	}
-
-	p.alive = append(p.alive, proxy)
+// releaseProxy takes a proxy out of quarantine as unknown, with a fresh
+// failure streak and score, so the next health check can revive it and
+// it is not judged on the failures that got it quarantined (must hold
+// lock)
+func (p *Pool) releaseProxy(proxy *Proxy) {
+	proxy.mu.Lock()
+	proxy.FailStreak = 0
+	proxy.mu.Unlock()
+	proxy.resetScore()
+	proxy.releasedAt = proxy.requestCount()
+
+	p.quarantine = removeProxy(p.quarantine, proxy)
+	proxy.Status = ProxyStatusUnknown
+	p.released = append(p.released, proxy)
 }
 
 // StartHealthCheck starts the background health check routine
@@ -291,10 +802,24 @@ func (p *Pool) StartHealthCheck() {
 		ticker := time.NewTicker(p.config.HealthCheckInterval)
 		defer ticker.Stop()
 
+		// Periodically pull new proxies from sources when configured
+		var refreshC <-chan time.Time
+		if p.config.SourceRefreshInterval > 0 {
+			refresh := time.NewTicker(p.config.SourceRefreshInterval)
+			defer refresh.Stop()
+			refreshC = refresh.C
+		}
+
 		for {
 			select {
 			case <-ticker.C:
 				p.performHealthCheck()
+				p.topUpIfLow()
+			case <-p.topUpCh:
+				p.topUpIfLow()
+			case <-refreshC:
+				p.loadSources(context.Background(), true)
+				p.topUpIfLow()
 			case <-p.stopCh:
 				return
 			}
@@ -307,33 +832,241 @@ func (p *Pool) StopHealthCheck() {
 	close(p.stopCh)
 }
 
-// performHealthCheck checks quarantined proxies and revives eligible ones
+// performHealthCheck revives proxies released by the previous pass that
+// still connect, releases quarantined proxies whose time is up,
+// quarantines poor performers and evicts proxies dead for too long
 func (p *Pool) performHealthCheck() {
+	revived := p.recheckReleased(context.Background())
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	now := time.Now()
 
-	// Check quarantined proxies
-	toRevive := make([]*Proxy, 0)
+	// Release quarantined proxies whose quarantine has expired
+	expired := make([]*Proxy, 0)
 	for _, proxy := range p.quarantine {
 		if now.After(proxy.CooldownUntil) {
-			toRevive = append(toRevive, proxy)
+			expired = append(expired, proxy)
 		}
 	}
 
-	for _, proxy := range toRevive {
-		p.reviveProxy(proxy)
+	for _, proxy := range expired {
+		p.releaseProxy(proxy)
 	}
 
-	// Check alive proxies for poor performance
+	// Check alive proxies for poor performance on their recent score,
+	// once they have made enough requests since any quarantine
+	poor := make([]*Proxy, 0)
 	for _, proxy := range p.alive {
-		if proxy.TotalRequests >= 10 && proxy.SuccessRate() < p.config.MinSuccessRate {
-			p.quarantineProxy(proxy)
+		if revived[proxy] || proxy.requestCount()-proxy.releasedAt < 10 {
+			continue
 		}
+		if score, ok := proxy.Score(); ok && score < p.config.MinSuccessRate {
+			poor = append(poor, proxy)
+		}
+	}
+
+	for _, proxy := range poor {
+		p.quarantineProxy(proxy)
+	}
+
+	p.evictDead(now)
+}
+
+// evictDead removes proxies dead for longer than EvictAfterDeadDuration
+// (must hold lock)
+func (p *Pool) evictDead(now time.Time) {
+	if p.config.EvictAfterDeadDuration <= 0 {
+		return
+	}
+
+	evict := make([]*Proxy, 0)
+	for _, proxy := range p.dead {
+		if now.Sub(proxy.deadSince) >= p.config.EvictAfterDeadDuration {
+			evict = append(evict, proxy)
+		}
+	}
+
+	for _, proxy := range evict {
+		p.remove(proxy)
+		p.totalEvicted++
 	}
 }
 
+// recheckReleased checks the proxies released from quarantine, reviving
+// those that connect and marking the rest dead. It returns the proxies
+// revived.
+func (p *Pool) recheckReleased(ctx context.Context) map[*Proxy]bool {
+	p.mu.Lock()
+	batch := p.released
+	p.released = nil
+	p.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	errs := p.checkBatch(ctx, batch)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	revived := make(map[*Proxy]bool)
+	for i, proxy := range batch {
+		// Reloaded, restored or removed since it was released
+		if proxy.Status != ProxyStatusUnknown || !p.contains(proxy) {
+			continue
+		}
+		p.totalChecked++
+		if errs[i] != nil {
+			p.place(proxy, ProxyStatusDead)
+			continue
+		}
+		p.place(proxy, ProxyStatusAlive)
+		revived[proxy] = true
+	}
+	return revived
+}
+
+// WarmUp health checks unchecked proxies until TargetAlive are alive or
+// the list is exhausted, returning how many were checked
+func (p *Pool) WarmUp(ctx context.Context) int {
+	return p.topUp(ctx, p.config.TargetAlive)
+}
+
+// lowWater returns the alive count below which the pool is topped up
+func (p *Pool) lowWater() int {
+	if p.config.LowWater > 0 {
+		return p.config.LowWater
+	}
+	return p.config.TargetAlive / 2
+}
+
+// signalIfLow wakes the topper when alive drops below the low-water
+// mark (must hold lock)
+func (p *Pool) signalIfLow() {
+	if p.config.TargetAlive <= 0 || len(p.unchecked) == 0 || len(p.alive) >= p.lowWater() {
+		return
+	}
+
+	select {
+	case p.topUpCh <- struct{}{}:
+	default:
+	}
+}
+
+// topUpIfLow checks more proxies when alive is below the low-water mark
+func (p *Pool) topUpIfLow() {
+	if p.config.TargetAlive <= 0 {
+		return
+	}
+
+	p.mu.RLock()
+	low := len(p.alive) < p.lowWater()
+	p.mu.RUnlock()
+
+	if low {
+		p.topUp(context.Background(), p.config.TargetAlive)
+	}
+}
+
+// topUp checks unchecked proxies in parallel batches until target are
+// alive, returning how many were checked
+func (p *Pool) topUp(ctx context.Context, target int) int {
+	p.topUpMu.Lock()
+	defer p.topUpMu.Unlock()
+
+	concurrency := p.config.CheckConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	checked := 0
+	for ctx.Err() == nil {
+		p.mu.Lock()
+		need := target - len(p.alive)
+		if need <= 0 || len(p.unchecked) == 0 {
+			p.mu.Unlock()
+			break
+		}
+
+		// Check a few extra since some will fail
+		n := need * 2
+		if n > concurrency {
+			n = concurrency
+		}
+		if n > len(p.unchecked) {
+			n = len(p.unchecked)
+		}
+		batch := p.unchecked[:n:n]
+		p.unchecked = p.unchecked[n:]
+		p.mu.Unlock()
+
+		errs := p.checkBatch(ctx, batch)
+
+		p.mu.Lock()
+		canceled := ctx.Err() != nil
+		batchChecked := 0
+		for i, proxy := range batch {
+			// Removed while being checked
+			if !p.contains(proxy) {
+				continue
+			}
+			if errs[i] != nil && canceled {
+				// Interrupted, not necessarily dead
+				p.unchecked = append(p.unchecked, proxy)
+				continue
+			}
+			batchChecked++
+			if errs[i] != nil {
+				p.place(proxy, ProxyStatusDead)
+				continue
+			}
+			proxy.Status = ProxyStatusAlive
+			p.alive = append(p.alive, proxy)
+			p.queueLRU(proxy)
+		}
+		p.totalChecked += int64(batchChecked)
+		p.mu.Unlock()
+
+		checked += batchChecked
+	}
+
+	return checked
+}
+
+// checkBatch health checks proxies in parallel, returning each one's error
+func (p *Pool) checkBatch(ctx context.Context, batch []*Proxy) []error {
+	errs := make([]error, len(batch))
+	var wg sync.WaitGroup
+	for i, proxy := range batch {
+		wg.Add(1)
+		go func(i int, proxy *Proxy) {
+			defer wg.Done()
+			checkCtx := ctx
+			if p.config.CheckTimeout > 0 {
+				var cancel context.CancelFunc
+				checkCtx, cancel = context.WithTimeout(ctx, p.config.CheckTimeout)
+				defer cancel()
+			}
+			errs[i] = p.checkFn(checkCtx, proxy)
+		}(i, proxy)
+	}
+	wg.Wait()
+	return errs
+}
+
+// dialCheck verifies a proxy accepts TCP connections
+func dialCheck(ctx context.Context, proxy *Proxy) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(proxy.Host, proxy.Port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
 // Stats returns current pool statistics
 func (p *Pool) Stats() PoolStats {
 	p.mu.RLock()
@@ -344,6 +1077,9 @@ func (p *Pool) Stats() PoolStats {
 		Alive:       len(p.alive),
 		Dead:        len(p.dead),
 		Quarantined: len(p.quarantine),
+		Unchecked:   len(p.unchecked) + len(p.released),
+		Checked:     p.totalChecked,
+		Evicted:     p.totalEvicted,
 		Rotations:   p.totalRotations,
 		Requests:    p.totalRequests,
 	}
@@ -378,6 +1114,9 @@ type PoolStats struct {
 	Available      int     `json:"available"`
 	Dead           int     `json:"dead"`
 	Quarantined    int     `json:"quarantined"`
+	Unchecked      int     `json:"unchecked"`
+	Checked        int64   `json:"checked"`
+	Evicted        int64   `json:"evicted"` // Removed after EvictAfterDeadDuration
 	Rotations      int64   `json:"rotations"`
 	Requests       int64   `json:"requests"`
 	AvgSuccessRate float64 `json:"avg_success_rate"`
@@ -411,6 +1150,13 @@ func (p *Pool) GetAllDead() []*Proxy {
 	return result
 }
 
+// QuarantinedCount returns how many proxies are in quarantine
+func (p *Pool) QuarantinedCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.quarantine)
+}
+
 // GetAllQuarantined returns all quarantined proxies (for display purposes)
 func (p *Pool) GetAllQuarantined() []*Proxy {
 	p.mu.RLock()
@@ -421,6 +1167,89 @@ func (p *Pool) GetAllQuarantined() []*Proxy {
 	return result
 }
 
+// ProxySnapshot is a credential-free view of one proxy for display
+type ProxySnapshot struct {
+	ID            string        `json:"id"`   // Opaque, see OpaqueID
+	Host          string        `json:"host"` // Masked, see MaskHost
+	Port          string        `json:"port"`
+	Type          ProxyType     `json:"type"`
+	Status        ProxyStatus   `json:"status"`
+	SuccessRate   float64       `json:"success_rate"`
+	Score         float64       `json:"score"` // Recency-weighted success rate
+	AvgLatency    time.Duration `json:"avg_latency"`
+	TotalRequests int64         `json:"total_requests"`
+	CaptchaCount  int64         `json:"captcha_count"`
+	FailStreak    int64         `json:"fail_streak"`   // Failures in a row
+	ActiveLeases  int           `json:"active_leases"` // Requests in flight
+	CooldownUntil time.Time     `json:"cooldown_until"`
+}
+
+// Snapshot returns every proxy in the pool sorted by its opaque ID, or
+// only those with the given status when status is not empty
+func (p *Pool) Snapshot(status ProxyStatus) []ProxySnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snapshots := make([]ProxySnapshot, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		if status != "" && proxy.Status != status {
+			continue
+		}
+
+		proxy.mu.RLock()
+		snapshot := ProxySnapshot{
+			ID:            OpaqueID(proxy.ID),
+			Host:          MaskHost(proxy.Host),
+			Port:          proxy.Port,
+			Type:          proxy.Type,
+			Status:        proxy.Status,
+			TotalRequests: proxy.TotalRequests,
+			CaptchaCount:  proxy.CaptchaCount,
+			FailStreak:    proxy.FailStreak,
+			CooldownUntil: proxy.CooldownUntil,
+		}
+		proxy.mu.RUnlock()
+
+		snapshot.SuccessRate = proxy.SuccessRate()
+		snapshot.Score, _ = proxy.Score()
+		snapshot.AvgLatency = proxy.AvgLatency()
+		snapshot.ActiveLeases = proxy.ActiveLeases()
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID < snapshots[j].ID
+	})
+	return snapshots
+}
+
+// OpaqueID stands in for a proxy ID, which is built from its host and
+// port, wherever proxies are shown: the same ID always gives the same
+// opaque one, so they can be told apart and followed between snapshots
+// without revealing the proxy.
+func OpaqueID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "px_" + hex.EncodeToString(sum[:6])
+}
+
+// MaskHost hides the identifying part of a proxy host: the last two
+// octets of an IPv4 address, all but the first group of an IPv6 address,
+// or the leading label of a hostname
+func MaskHost(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return fmt.Sprintf("%d.%d.x.x", ip4[0], ip4[1])
+		}
+		return strings.SplitN(host, ":", 2)[0] + ":x"
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) <= 2 {
+		return "***"
+	}
+	return "***." + strings.Join(labels[1:], ".")
+}
+
 // RecommendedWorkers returns recommended worker count based on pool size
 func (p *Pool) RecommendedWorkers() int {
 	p.mu.RLock()
@@ -1,7 +1,10 @@
 package proxy
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -217,6 +220,97 @@ func TestPoolReportCaptcha(t *testing.T) {
 	}
 }
 
+func TestPoolCaptchaCooldownSkipsGet(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.CooldownDuration = 100 * time.Millisecond
+	pool := NewPool(config)
+	pool.AddProxy(&Proxy{ID: "flagged", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	pool.AddProxy(&Proxy{ID: "clean", Host: "192.168.1.2", Port: "8080", Type: ProxyTypeHTTP})
+
+	pool.ReportCaptcha("flagged")
+
+	for i := 0; i < 20; i++ {
+		p, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if p.ID == "flagged" {
+			t.Fatal("Get() returned a proxy cooling down after a CAPTCHA")
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	seen := false
+	for i := 0; i < 50 && !seen; i++ {
+		p, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		seen = p.ID == "flagged"
+	}
+	if !seen {
+		t.Error("Get() never returned the proxy after its cooldown")
+	}
+}
+
+func TestPoolFailureCooldown(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.FailureCooldown = time.Minute
+	config.CooldownDuration = time.Hour
+	pool := NewPool(config)
+	pool.AddProxy(&Proxy{ID: "test_1", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	pool.AddProxy(&Proxy{ID: "test_2", Host: "192.168.1.2", Port: "8080", Type: ProxyTypeHTTP})
+
+	pool.ReportFailure("test_1")
+	pool.ReportCaptcha("test_2")
+
+	failed, _ := pool.GetByID("test_1")
+	if remaining := time.Until(failed.CooldownUntil); remaining < 50*time.Second || remaining > time.Minute {
+		t.Errorf("failure cooldown = %v, want about %v", remaining, time.Minute)
+	}
+	captcha, _ := pool.GetByID("test_2")
+	if remaining := time.Until(captcha.CooldownUntil); remaining < 59*time.Minute {
+		t.Errorf("captcha cooldown = %v, want about %v", remaining, time.Hour)
+	}
+
+	if _, err := pool.Get(); err == nil {
+		t.Error("Get() should fail while every proxy cools down")
+	}
+}
+
+func TestPoolReportRateLimit(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.CooldownDuration = time.Minute
+	config.MaxRetryAfter = 10 * time.Minute
+	pool := NewPool(config)
+
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{"retry after", 2 * time.Minute, 2 * time.Minute},
+		{"clamped", time.Hour, 10 * time.Minute},
+		{"missing", 0, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool.AddProxy(&Proxy{ID: tt.name, Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+			pool.ReportRateLimit(tt.name, tt.retryAfter)
+
+			proxy, _ := pool.GetByID(tt.name)
+			if remaining := time.Until(proxy.CooldownUntil); remaining > tt.want || remaining < tt.want-time.Second {
+				t.Errorf("cooldown = %v, want about %v", remaining, tt.want)
+			}
+			if proxy.FailStreak != 0 || proxy.Status != ProxyStatusAlive {
+				t.Errorf("FailStreak = %d, Status = %s, want 0 and alive", proxy.FailStreak, proxy.Status)
+			}
+		})
+	}
+}
+
 func TestPoolReportBlock(t *testing.T) {
 	pool := NewPool(DefaultPoolConfig())
 
@@ -239,11 +333,66 @@ func TestPoolReportBlock(t *testing.T) {
 	}
 }
 
+func TestPoolSetCooldowns(t *testing.T) {
+	pool := NewPool(DefaultPoolConfig())
+	pool.SetCooldowns(time.Minute, time.Hour)
+
+	for _, id := range []string{"test_1", "test_2"} {
+		pool.AddProxy(&Proxy{ID: id, Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+
+	pool.ReportCaptcha("test_1")
+	pool.ReportBlock("test_2")
+
+	captcha, _ := pool.GetByID("test_1")
+	if remaining := time.Until(captcha.CooldownUntil); remaining < 50*time.Second || remaining > time.Minute {
+		t.Errorf("CAPTCHA cooldown = %v, want ~1m", remaining)
+	}
+
+	blocked, _ := pool.GetByID("test_2")
+	if remaining := time.Until(blocked.CooldownUntil); remaining < 59*time.Minute {
+		t.Errorf("block cooldown = %v, want ~1h", remaining)
+	}
+
+	// Zero values keep the current cooldowns
+	pool.SetCooldowns(0, 0)
+	if pool.config.CooldownDuration != time.Minute || pool.config.BlockCooldown != time.Hour {
+		t.Errorf("SetCooldowns(0, 0) changed cooldowns to %v/%v", pool.config.CooldownDuration, pool.config.BlockCooldown)
+	}
+}
+
+func TestPoolNextAvailableTime(t *testing.T) {
+	pool := NewPool(DefaultPoolConfig())
+
+	if next := pool.NextAvailableTime(); !next.IsZero() {
+		t.Errorf("empty pool NextAvailableTime() = %v, want zero", next)
+	}
+
+	for _, id := range []string{"test_1", "test_2"} {
+		pool.AddProxy(&Proxy{ID: id, Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+
+	if next := pool.NextAvailableTime(); time.Since(next) > time.Second {
+		t.Errorf("NextAvailableTime() = %v, want now", next)
+	}
+
+	first, _ := pool.GetByID("test_1")
+	second, _ := pool.GetByID("test_2")
+	first.SetCooldown(time.Minute)
+	second.SetCooldown(time.Hour)
+
+	next := pool.NextAvailableTime()
+	if !next.Equal(first.CooldownUntil) {
+		t.Errorf("NextAvailableTime() = %v, want earliest cooldown %v", next, first.CooldownUntil)
+	}
+}
+
 func TestPoolHealthCheck(t *testing.T) {
 	config := DefaultPoolConfig()
 	config.QuarantineDuration = 100 * time.Millisecond
 	config.HealthCheckInterval = 50 * time.Millisecond
 	pool := NewPool(config)
+	pool.checkFn = func(ctx context.Context, proxy *Proxy) error { return nil }
 
 	proxy := &Proxy{
 		ID:   "test_1",
@@ -265,8 +414,8 @@ func TestPoolHealthCheck(t *testing.T) {
 	pool.StartHealthCheck()
 	defer pool.StopHealthCheck()
 
-	// Wait for quarantine to expire and health check to run
-	time.Sleep(200 * time.Millisecond)
+	// Wait for quarantine to expire, the release and the recheck
+	time.Sleep(300 * time.Millisecond)
 
 	stats = pool.Stats()
 	if stats.Alive != 1 {
@@ -277,6 +426,126 @@ func TestPoolHealthCheck(t *testing.T) {
 	}
 }
 
+func TestPoolQuarantineLifecycle(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.QuarantineThreshold = 3
+	config.QuarantineDuration = time.Hour
+	pool := NewPool(config)
+
+	pool.checkFn = func(ctx context.Context, proxy *Proxy) error {
+		if proxy.ID == "test_2" {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+
+	for _, id := range []string{"test_1", "test_2"} {
+		pool.AddProxy(&Proxy{ID: id, Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+
+	// A success breaks the streak
+	pool.ReportFailure("test_1")
+	pool.ReportFailure("test_1")
+	pool.ReportSuccess("test_1", time.Millisecond)
+	pool.ReportFailure("test_1")
+	pool.ReportFailure("test_1")
+	if n := pool.QuarantinedCount(); n != 0 {
+		t.Fatalf("QuarantinedCount() = %d after broken streak, want 0", n)
+	}
+
+	pool.ReportFailure("test_1")
+	for i := 0; i < 3; i++ {
+		pool.ReportFailure("test_2")
+	}
+	if n := pool.QuarantinedCount(); n != 2 {
+		t.Fatalf("QuarantinedCount() = %d, want 2", n)
+	}
+
+	// Nothing is released before the quarantine expires
+	pool.performHealthCheck()
+	if stats := pool.Stats(); stats.Quarantined != 2 || stats.Unchecked != 0 {
+		t.Fatalf("stats = %+v, want both still quarantined", stats)
+	}
+
+	first, _ := pool.GetByID("test_1")
+	second, _ := pool.GetByID("test_2")
+	first.SetCooldown(0)
+	second.SetCooldown(0)
+
+	// Expired: released as unknown with a fresh streak
+	pool.performHealthCheck()
+	stats := pool.Stats()
+	if stats.Quarantined != 0 || stats.Unchecked != 2 || stats.Alive != 0 {
+		t.Fatalf("stats = %+v, want 2 unchecked after release", stats)
+	}
+	if first.Status != ProxyStatusUnknown || first.FailStreak != 0 {
+		t.Errorf("released proxy status = %s, streak = %d, want unknown, 0", first.Status, first.FailStreak)
+	}
+	if first.FailCount != 5 {
+		t.Errorf("FailCount = %d, want 5 (kept across quarantine)", first.FailCount)
+	}
+
+	// The next check revives what connects and kills the rest
+	pool.performHealthCheck()
+
+	stats = pool.Stats()
+	if stats.Alive != 1 || stats.Dead != 1 || stats.Unchecked != 0 {
+		t.Errorf("stats = %+v, want 1 alive, 1 dead", stats)
+	}
+	if first.Status != ProxyStatusAlive || second.Status != ProxyStatusDead {
+		t.Errorf("statuses = %s, %s, want alive, dead", first.Status, second.Status)
+	}
+	if stats.Checked != 2 {
+		t.Errorf("checked = %d, want 2", stats.Checked)
+	}
+}
+
+func TestPoolRevivedProxyJudgedAfresh(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.QuarantineThreshold = 3
+	pool := NewPool(config)
+	pool.checkFn = func(ctx context.Context, proxy *Proxy) error { return nil }
+	pool.AddProxy(&Proxy{ID: "test_1", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+
+	// 3 of 10 requests succeed, the last three failing in a row
+	for _, ok := range []bool{true, false, false, true, false, false, true, false, false, false} {
+		if ok {
+			pool.ReportSuccess("test_1", time.Millisecond)
+		} else {
+			pool.ReportFailure("test_1")
+		}
+	}
+	if n := pool.QuarantinedCount(); n != 1 {
+		t.Fatalf("QuarantinedCount() = %d, want 1", n)
+	}
+
+	proxy, _ := pool.GetByID("test_1")
+	proxy.SetCooldown(0)
+	pool.performHealthCheck() // Released
+	pool.performHealthCheck() // Revived
+
+	if proxy.Status != ProxyStatusAlive {
+		t.Fatalf("status after revival = %s, want alive despite a %.0f%% lifetime success rate", proxy.Status, proxy.SuccessRate())
+	}
+	pool.performHealthCheck()
+	if proxy.Status != ProxyStatusAlive {
+		t.Fatalf("status a pass after revival = %s, want alive", proxy.Status)
+	}
+
+	// Judged again once it has done poorly since
+	for i := 0; i < 10; i++ {
+		if i%3 == 0 {
+			pool.ReportSuccess("test_1", time.Millisecond)
+		} else {
+			pool.ReportFailure("test_1")
+		}
+	}
+	pool.performHealthCheck()
+	if proxy.Status != ProxyStatusQuarantined {
+		t.Errorf("status after poor requests since revival = %s, want quarantined", proxy.Status)
+	}
+}
+
 func TestPoolWeightedSelection(t *testing.T) {
 	pool := NewPool(DefaultPoolConfig())
 
@@ -329,6 +598,326 @@ func TestPoolWeightedSelection(t *testing.T) {
 	}
 }
 
+func TestPoolSelectionWeightedBySuccessRate(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.MaxFailures = 100
+	pool := NewPool(config)
+
+	pool.AddProxy(&Proxy{ID: "good", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	pool.AddProxy(&Proxy{ID: "poor", Host: "192.168.1.2", Port: "8080", Type: ProxyTypeHTTP})
+
+	// 90% and 20% success at the same latency
+	for i := 0; i < 10; i++ {
+		if i < 9 {
+			pool.ReportSuccess("good", 100*time.Millisecond)
+		} else {
+			pool.ReportFailure("good")
+		}
+		if i < 2 {
+			pool.ReportSuccess("poor", 100*time.Millisecond)
+		} else {
+			pool.ReportFailure("poor")
+		}
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 5000; i++ {
+		p, err := pool.Get()
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		counts[p.ID]++
+	}
+
+	// Expected ratio is 2.8:1.4; allow plenty of room for chance
+	if counts["good"] < counts["poor"]*3/2 {
+		t.Errorf("good picked %d times, poor %d; want good picked substantially more", counts["good"], counts["poor"])
+	}
+}
+
+func TestParseSelectionStrategy(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    SelectionStrategy
+		wantErr bool
+	}{
+		{"", SelectionWeighted, false},
+		{"weighted", SelectionWeighted, false},
+		{"round_robin", SelectionRoundRobin, false},
+		{"least_used", SelectionLeastUsed, false},
+		{"random", SelectionRandom, false},
+		{"fastest", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSelectionStrategy(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSelectionStrategy(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseSelectionStrategy("fastest"); err == nil || !strings.Contains(err.Error(), "round_robin, least_used or random") {
+		t.Errorf("ParseSelectionStrategy() error = %v, want the valid strategies listed", err)
+	}
+}
+
+func TestPoolSelectionStrategies(t *testing.T) {
+	newSelectPool := func(strategy SelectionStrategy) *Pool {
+		config := DefaultPoolConfig()
+		config.Selection = strategy
+		pool := NewPool(config)
+		for _, id := range []string{"a", "b", "c", "cooling"} {
+			pool.AddProxy(&Proxy{ID: id, Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+		}
+		pool.proxies["cooling"].SetCooldown(time.Hour)
+		return pool
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		pool := newSelectPool(SelectionRoundRobin)
+		var got []string
+		for i := 0; i < 6; i++ {
+			p, _ := pool.Get()
+			got = append(got, p.ID)
+		}
+		if want := "a b c a b c"; strings.Join(got, " ") != want {
+			t.Errorf("picks = %v, want %s", got, want)
+		}
+	})
+
+	t.Run("least used", func(t *testing.T) {
+		pool := newSelectPool(SelectionLeastUsed)
+		pool.ReportSuccess("a", time.Millisecond)
+		pool.ReportSuccess("a", time.Millisecond)
+		pool.ReportFailure("c")
+
+		if p, _ := pool.Get(); p.ID != "b" {
+			t.Errorf("Get() = %s, want the unused b", p.ID)
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		pool := newSelectPool(SelectionRandom)
+		seen := make(map[string]bool)
+		for i := 0; i < 200; i++ {
+			p, _ := pool.Get()
+			seen[p.ID] = true
+		}
+		if len(seen) != 3 || seen["cooling"] {
+			t.Errorf("picked %v, want a, b and c only", seen)
+		}
+	})
+}
+
+func TestPoolSeededSelection(t *testing.T) {
+	picks := func(strategy SelectionStrategy) []string {
+		config := DefaultPoolConfig()
+		config.Selection = strategy
+		config.Seed = 42
+		pool := NewPool(config)
+		for _, id := range []string{"a", "b", "c", "d"} {
+			pool.AddProxy(&Proxy{ID: id, Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+		}
+
+		var ids []string
+		for i := 0; i < 20; i++ {
+			p, _ := pool.Get()
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	for _, strategy := range []SelectionStrategy{SelectionRandom, SelectionWeighted} {
+		first, second := picks(strategy), picks(strategy)
+		if strings.Join(first, ",") != strings.Join(second, ",") {
+			t.Errorf("%s picks with the same seed differ: %v and %v", strategy, first, second)
+		}
+	}
+}
+
+func TestPoolGetN(t *testing.T) {
+	newGetNPool := func(strategy SelectionStrategy) *Pool {
+		config := DefaultPoolConfig()
+		config.Selection = strategy
+		pool := NewPool(config)
+		for _, id := range []string{"a", "b", "c", "cooling"} {
+			pool.AddProxy(&Proxy{ID: id, Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+		}
+		pool.proxies["cooling"].SetCooldown(time.Hour)
+		return pool
+	}
+	ids := func(proxies []*Proxy) string {
+		var out []string
+		for _, p := range proxies {
+			out = append(out, p.ID)
+		}
+		return strings.Join(out, " ")
+	}
+
+	t.Run("empty pool", func(t *testing.T) {
+		if got, err := NewPool(DefaultPoolConfig()).GetN(2); err == nil {
+			t.Errorf("GetN() = %v, want error", got)
+		}
+	})
+
+	t.Run("distinct", func(t *testing.T) {
+		for _, strategy := range []SelectionStrategy{SelectionWeighted, SelectionLeastUsed, SelectionRandom} {
+			got, err := newGetNPool(strategy).GetN(3)
+			if err != nil {
+				t.Fatalf("GetN(%s) error = %v", strategy, err)
+			}
+			seen := make(map[string]bool)
+			for _, p := range got {
+				seen[p.ID] = true
+			}
+			if len(got) != 3 || len(seen) != 3 || seen["cooling"] {
+				t.Errorf("GetN(%s) = %s, want a, b and c once each", strategy, ids(got))
+			}
+		}
+	})
+
+	t.Run("fewer available", func(t *testing.T) {
+		got, err := newGetNPool(SelectionWeighted).GetN(10)
+		if err != nil || len(got) != 3 {
+			t.Errorf("GetN(10) = %s, %v, want the 3 available", ids(got), err)
+		}
+	})
+
+	t.Run("round robin", func(t *testing.T) {
+		pool := newGetNPool(SelectionRoundRobin)
+		pool.Get()
+		got, _ := pool.GetN(2)
+		if want := "b c"; ids(got) != want {
+			t.Errorf("GetN(2) = %s, want %s", ids(got), want)
+		}
+		if p, _ := pool.Get(); p.ID != "a" {
+			t.Errorf("Get() after GetN = %s, want a", p.ID)
+		}
+	})
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		config := DefaultPoolConfig()
+		config.MaxConcurrentPerProxy = 1
+		pool := NewPool(config)
+		for _, id := range []string{"a", "b"} {
+			pool.AddProxy(&Proxy{ID: id, Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+		}
+
+		held, _ := pool.Acquire("a")
+		got, err := pool.GetN(2)
+		if err != nil || ids(got) != "b" {
+			t.Errorf("GetN(2) = %s, %v, want b only", ids(got), err)
+		}
+		if _, err := pool.GetN(1); err == nil {
+			t.Error("GetN() should fail while every proxy is at its limit")
+		}
+		if n := held.ActiveLeases(); n != 1 {
+			t.Errorf("ActiveLeases() = %d, want 1", n)
+		}
+	})
+}
+
+func TestPoolRemove(t *testing.T) {
+	pool := NewPool(DefaultPoolConfig())
+	pool.AddProxy(&Proxy{ID: "test_1", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	pool.AddProxy(&Proxy{ID: "test_2", Host: "192.168.1.2", Port: "8080", Type: ProxyTypeHTTP})
+
+	held, _ := pool.GetByID("test_1")
+	if !pool.Remove("test_1") {
+		t.Fatal("Remove() = false, want true")
+	}
+	if pool.Remove("test_1") {
+		t.Error("second Remove() = true, want false")
+	}
+	if _, ok := pool.GetByID("test_1"); ok {
+		t.Error("removed proxy still found by GetByID()")
+	}
+	if stats := pool.Stats(); stats.Total != 1 || stats.Alive != 1 {
+		t.Errorf("stats = %+v, want 1 total, 1 alive", stats)
+	}
+
+	// Late reports for a removed proxy are ignored
+	pool.ReportFailure(held.ID)
+	for i := 0; i < 10; i++ {
+		if p, err := pool.Get(); err != nil || p.ID != "test_2" {
+			t.Fatalf("Get() = %v, %v, want test_2", p, err)
+		}
+	}
+	if _, err := pool.Acquire("test_1"); err == nil {
+		t.Error("Acquire() of a removed proxy should fail")
+	}
+}
+
+func TestPoolOnRemove(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.EvictAfterDeadDuration = time.Hour
+	pool := NewPool(config)
+	for _, id := range []string{"removed", "evicted", "kept"} {
+		pool.AddProxy(&Proxy{ID: id, Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+
+	var removed []string
+	pool.OnRemove(func(proxyID string) {
+		removed = append(removed, proxyID)
+	})
+
+	pool.Remove("removed")
+	pool.Remove("removed")
+
+	pool.mu.Lock()
+	pool.markDead(pool.proxies["evicted"])
+	pool.proxies["evicted"].deadSince = time.Now().Add(-2 * time.Hour)
+	pool.mu.Unlock()
+	pool.performHealthCheck()
+
+	if len(removed) != 2 || removed[0] != "removed" || removed[1] != "evicted" {
+		t.Errorf("OnRemove() calls = %v, want [removed evicted]", removed)
+	}
+}
+
+func TestPoolEvictDead(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.EvictAfterDeadDuration = time.Hour
+	pool := NewPool(config)
+	for _, id := range []string{"old", "new", "alive"} {
+		pool.AddProxy(&Proxy{ID: id, Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+
+	pool.mu.Lock()
+	pool.markDead(pool.proxies["old"])
+	pool.markDead(pool.proxies["new"])
+	pool.proxies["old"].deadSince = time.Now().Add(-2 * time.Hour)
+	pool.mu.Unlock()
+
+	pool.performHealthCheck()
+
+	stats := pool.Stats()
+	if stats.Dead != 1 || stats.Alive != 1 || stats.Total != 2 {
+		t.Errorf("stats = %+v, want 1 dead, 1 alive, 2 total", stats)
+	}
+	if stats.Evicted != 1 {
+		t.Errorf("evicted = %d, want 1", stats.Evicted)
+	}
+	if _, ok := pool.GetByID("old"); ok {
+		t.Error("proxy dead past the threshold was not evicted")
+	}
+}
+
+func TestPoolRemoveDuringCheck(t *testing.T) {
+	pool := newLazyPool(0, 1)
+	pool.AddProxy(&Proxy{ID: "gone", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+
+	pool.checkFn = func(ctx context.Context, proxy *Proxy) error {
+		pool.Remove(proxy.ID)
+		return nil
+	}
+	pool.WarmUp(context.Background())
+
+	if stats := pool.Stats(); stats.Total != 0 || stats.Alive != 0 || stats.Unchecked != 0 {
+		t.Errorf("stats = %+v, want the removed proxy gone from every list", stats)
+	}
+}
+
 func TestPoolConcurrency(t *testing.T) {
 	pool := NewPool(DefaultPoolConfig())
 
@@ -487,3 +1076,225 @@ func TestPoolGetAllMethods(t *testing.T) {
 		t.Errorf("dead count = %d, want 0", len(dead))
 	}
 }
+
+func TestPoolSnapshot(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.MaxFailures = 1
+	pool := NewPool(config)
+
+	pool.AddProxy(&Proxy{ID: "b", Host: "192.168.1.2", Port: "8080", Type: ProxyTypeHTTP, Username: "u", Password: "secret"})
+	pool.AddProxy(&Proxy{ID: "a", Host: "proxy.example.com", Port: "1080", Type: ProxyTypeSOCKS5})
+	pool.ReportSuccess("a", 100*time.Millisecond)
+	pool.ReportCaptcha("a")
+	pool.ReportFailure("b")
+
+	// IDs are opaque, and b's sorts first
+	snapshots := pool.Snapshot("")
+	if len(snapshots) != 2 || snapshots[0].ID != OpaqueID("b") || snapshots[1].ID != OpaqueID("a") {
+		t.Fatalf("Snapshot() = %+v, want b and a sorted by opaque ID", snapshots)
+	}
+	all := []ProxySnapshot{snapshots[1], snapshots[0]}
+	if all[0].Host != "***.example.com" || all[1].Host != "192.168.x.x" {
+		t.Errorf("hosts = %q, %q, want masked", all[0].Host, all[1].Host)
+	}
+	if all[0].SuccessRate != 100 || all[0].AvgLatency != 100*time.Millisecond {
+		t.Errorf("a = %+v, want 100%% success at 100ms", all[0])
+	}
+	if all[0].CaptchaCount != 1 {
+		t.Errorf("a captcha count = %d, want 1", all[0].CaptchaCount)
+	}
+
+	quarantined := pool.Snapshot(ProxyStatusQuarantined)
+	if len(quarantined) != 1 || quarantined[0].ID != OpaqueID("b") {
+		t.Errorf("Snapshot(quarantined) = %+v, want only b", quarantined)
+	}
+}
+
+func TestPoolMaxConcurrentPerProxy(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.MaxConcurrentPerProxy = 1
+	config.CooldownDuration = 0
+	pool := NewPool(config)
+
+	pool.AddProxy(&Proxy{ID: "a", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+	pool.AddProxy(&Proxy{ID: "b", Host: "192.168.1.2", Port: "8080", Type: ProxyTypeHTTP})
+
+	first, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	second, err := pool.Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Errorf("Get() returned %s twice, want the busy proxy skipped", first.ID)
+	}
+	if _, err := pool.Get(); err == nil {
+		t.Error("Get() should fail while every proxy is at its limit")
+	}
+	if _, err := pool.Acquire(first.ID); !errors.Is(err, ErrProxyBusy) {
+		t.Errorf("Acquire() error = %v, want ErrProxyBusy", err)
+	}
+	if n := first.ActiveLeases(); n != 1 {
+		t.Errorf("ActiveLeases() = %d, want 1", n)
+	}
+
+	// Any report returns the lease
+	pool.ReportFailure(first.ID)
+	if n := first.ActiveLeases(); n != 0 {
+		t.Errorf("ActiveLeases() after report = %d, want 0", n)
+	}
+	if got, err := pool.Get(); err != nil || got.ID != first.ID {
+		t.Errorf("Get() = %v, %v, want %s once released", got, err, first.ID)
+	}
+}
+
+func TestPoolLeaseTimeout(t *testing.T) {
+	config := DefaultPoolConfig()
+	config.MaxConcurrentPerProxy = 1
+	config.LeaseTimeout = 20 * time.Millisecond
+	pool := NewPool(config)
+	pool.AddProxy(&Proxy{ID: "a", Host: "192.168.1.1", Port: "8080", Type: ProxyTypeHTTP})
+
+	// A lease that is never reported must not starve the proxy
+	if _, err := pool.Get(); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := pool.Get(); err == nil {
+		t.Fatal("Get() should fail while the lease is held")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	prx, err := pool.Acquire("a")
+	if err != nil {
+		t.Fatalf("Acquire() after the lease expired error = %v", err)
+	}
+	if n := prx.ActiveLeases(); n != 1 {
+		t.Errorf("ActiveLeases() = %d, want 1", n)
+	}
+}
+
+func TestOpaqueID(t *testing.T) {
+	id := "http_192.168.1.2_8080"
+	got := OpaqueID(id)
+	if got != OpaqueID(id) {
+		t.Errorf("OpaqueID(%q) is not stable", id)
+	}
+	if strings.Contains(got, "192.168") || strings.Contains(got, "8080") {
+		t.Errorf("OpaqueID(%q) = %q, want the host hidden", id, got)
+	}
+	if OpaqueID("http_192.168.1.3_8080") == got {
+		t.Errorf("OpaqueID() gives two proxies the same ID %q", got)
+	}
+}
+
+func TestMaskHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"203.0.113.7", "203.0.x.x"},
+		{"2001:db8::1", "2001:x"},
+		{"gw.proxy.example.com", "***.proxy.example.com"},
+		{"localhost", "***"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskHost(tt.host); got != tt.want {
+			t.Errorf("MaskHost(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+// newLazyPool returns a lazy pool of n proxies where every third fails its check
+func newLazyPool(n, target int) *Pool {
+	config := DefaultPoolConfig()
+	config.TargetAlive = target
+	config.CheckConcurrency = 4
+
+	pool := NewPool(config)
+	pool.checkFn = func(ctx context.Context, proxy *Proxy) error {
+		var i int
+		fmt.Sscanf(proxy.ID, "proxy_%d", &i)
+		if i%3 == 0 {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+
+	for i := 0; i < n; i++ {
+		pool.AddProxy(&Proxy{ID: fmt.Sprintf("proxy_%d", i), Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+	return pool
+}
+
+func TestPoolLazyAdd(t *testing.T) {
+	pool := newLazyPool(10, 5)
+
+	stats := pool.Stats()
+	if stats.Alive != 0 || stats.Unchecked != 10 {
+		t.Errorf("alive = %d, unchecked = %d, want 0 and 10", stats.Alive, stats.Unchecked)
+	}
+
+	if _, err := pool.Get(); err == nil {
+		t.Error("Get() should fail before warm up")
+	}
+}
+
+func TestPoolWarmUp(t *testing.T) {
+	pool := newLazyPool(100, 10)
+
+	checked := pool.WarmUp(context.Background())
+
+	stats := pool.Stats()
+	if stats.Alive < 10 {
+		t.Errorf("alive = %d, want at least 10", stats.Alive)
+	}
+	if checked >= 100 {
+		t.Errorf("checked = %d, should stop well before the whole list", checked)
+	}
+	if stats.Checked != int64(checked) {
+		t.Errorf("stats.Checked = %d, want %d", stats.Checked, checked)
+	}
+	if stats.Alive+stats.Dead != checked {
+		t.Errorf("alive+dead = %d, want %d", stats.Alive+stats.Dead, checked)
+	}
+	if stats.Unchecked != 100-checked {
+		t.Errorf("unchecked = %d, want %d", stats.Unchecked, 100-checked)
+	}
+}
+
+func TestPoolWarmUpExhausted(t *testing.T) {
+	pool := newLazyPool(6, 50)
+
+	checked := pool.WarmUp(context.Background())
+	if checked != 6 {
+		t.Errorf("checked = %d, want 6", checked)
+	}
+	if alive := pool.Stats().Alive; alive != 4 {
+		t.Errorf("alive = %d, want 4", alive)
+	}
+}
+
+func TestPoolTopUpBelowLowWater(t *testing.T) {
+	pool := newLazyPool(100, 10)
+	pool.WarmUp(context.Background())
+
+	// Lose most alive proxies
+	for _, proxy := range pool.GetAllAlive()[:8] {
+		pool.ReportBlock(proxy.ID)
+	}
+
+	select {
+	case <-pool.topUpCh:
+	default:
+		t.Fatal("dropping below low water should signal the topper")
+	}
+
+	pool.topUpIfLow()
+	if alive := pool.Stats().Alive; alive < 10 {
+		t.Errorf("alive after top up = %d, want at least 10", alive)
+	}
+}
@@ -3,6 +3,9 @@ package proxy
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/url"
 	"os"
 	"regexp"
@@ -42,17 +45,43 @@ type Proxy struct {
 	Type     ProxyType   `json:"type"`
 	Status   ProxyStatus `json:"status"`
 
+	// Address the proxy exits from, when known
+	ExternalIP string `json:"external_ip,omitempty"`
+
 	// Statistics
 	mu            sync.RWMutex
 	TotalRequests int64         `json:"total_requests"`
 	SuccessCount  int64         `json:"success_count"`
 	FailCount     int64         `json:"fail_count"`
+	FailStreak    int64         `json:"fail_streak"` // Consecutive failures, reset on success
 	CaptchaCount  int64         `json:"captcha_count"`
 	TotalLatency  time.Duration `json:"total_latency"`
 	LastUsed      time.Time     `json:"last_used"`
 	LastSuccess   time.Time     `json:"last_success"`
 	LastFail      time.Time     `json:"last_fail"`
 	CooldownUntil time.Time     `json:"cooldown_until"`
+
+	// Recency-weighted success: decayed sums of outcomes (1 = success)
+	// and of their weights, last updated at scoreAt
+	scoreSum    float64
+	scoreWeight float64
+	scoreAt     time.Time
+
+	// Expiry of each lease handed out by the pool and not yet reported
+	// back, oldest first
+	leases []time.Time
+
+	// When the pool last marked it dead, guarded by the pool lock
+	deadSince time.Time
+
+	// TotalRequests when the pool last released it from quarantine, so
+	// it is judged on requests since; guarded by the pool lock
+	releasedAt int64
+
+	// Place in the pool's LRU queue, guarded by the pool lock
+	lruAt    time.Time // Last handed out
+	lruIndex int
+	inLRU    bool
 }
 
 // URL returns the proxy URL string for use in HTTP clients
@@ -61,7 +90,7 @@ func (p *Proxy) URL() string {
 	if p.Username != "" && p.Password != "" {
 		auth = fmt.Sprintf("%s:%s@", url.QueryEscape(p.Username), url.QueryEscape(p.Password))
 	}
-	return fmt.Sprintf("%s://%s%s:%s", p.Type, auth, p.Host, p.Port)
+	return fmt.Sprintf("%s://%s%s", p.Type, auth, net.JoinHostPort(p.Host, p.Port))
 }
 
 // SuccessRate returns the success rate as a percentage
@@ -84,12 +113,113 @@ func (p *Proxy) AvgLatency() time.Duration {
 	return p.TotalLatency / time.Duration(p.SuccessCount)
 }
 
+// SetExternalIP records the address the proxy exits from
+func (p *Proxy) SetExternalIP(ip string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ExternalIP = ip
+}
+
+// ActiveLeases returns how many in-flight requests currently hold the
+// proxy. Leases past their timeout no longer count.
+func (p *Proxy) ActiveLeases() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	active := 0
+	for _, expiry := range p.leases {
+		if now.Before(expiry) {
+			active++
+		}
+	}
+	return active
+}
+
+// lease takes an in-flight slot unless limit slots are already held
+// (0 = no limit). A lease not released within timeout expires, so a lost
+// request cannot starve the proxy.
+func (p *Proxy) lease(limit int, timeout time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	active := p.leases[:0]
+	for _, expiry := range p.leases {
+		if now.Before(expiry) {
+			active = append(active, expiry)
+		}
+	}
+	p.leases = active
+
+	if limit > 0 && len(p.leases) >= limit {
+		return false
+	}
+	p.leases = append(p.leases, now.Add(timeout))
+	return true
+}
+
+// unlease releases the oldest in-flight slot
+func (p *Proxy) unlease() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.leases) > 0 {
+		p.leases = p.leases[1:]
+	}
+}
+
+// requestCount returns the number of requests made through the proxy
+func (p *Proxy) requestCount() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.TotalRequests
+}
+
+// Score returns the exponentially-weighted success rate as a percentage,
+// where an outcome's weight halves every halfLife, and false before any
+// outcome was recorded
+func (p *Proxy) Score() (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.scoreWeight == 0 {
+		return 0, false
+	}
+	return p.scoreSum / p.scoreWeight * 100, true
+}
+
+// resetScore forgets the outcomes behind Score
+func (p *Proxy) resetScore() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scoreSum, p.scoreWeight, p.scoreAt = 0, 0, time.Time{}
+}
+
+// recordOutcome folds an outcome at now into the score, first decaying
+// older outcomes by the time since the last one (halfLife 0 = no decay)
+func (p *Proxy) recordOutcome(success bool, now time.Time, halfLife time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if halfLife > 0 && !p.scoreAt.IsZero() && now.After(p.scoreAt) {
+		decay := math.Pow(0.5, float64(now.Sub(p.scoreAt))/float64(halfLife))
+		p.scoreSum *= decay
+		p.scoreWeight *= decay
+	}
+	p.scoreAt = now
+
+	if success {
+		p.scoreSum++
+	}
+	p.scoreWeight++
+}
+
 // RecordSuccess records a successful request
 func (p *Proxy) RecordSuccess(latency time.Duration) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.TotalRequests++
 	p.SuccessCount++
+	p.FailStreak = 0
 	p.TotalLatency += latency
 	p.LastUsed = time.Now()
 	p.LastSuccess = time.Now()
@@ -101,6 +231,7 @@ func (p *Proxy) RecordFail() {
 	defer p.mu.Unlock()
 	p.TotalRequests++
 	p.FailCount++
+	p.FailStreak++
 	p.LastUsed = time.Now()
 	p.LastFail = time.Now()
 }
@@ -132,6 +263,16 @@ func (p *Proxy) SetCooldown(duration time.Duration) {
 	p.CooldownUntil = time.Now().Add(duration)
 }
 
+// ExtendCooldown puts the proxy on cooldown for duration unless it is
+// already cooling down for longer
+func (p *Proxy) ExtendCooldown(duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(duration); until.After(p.CooldownUntil) {
+		p.CooldownUntil = until
+	}
+}
+
 // Parser handles parsing proxies from various formats
 type Parser struct {
 	// Regex patterns for different formats
@@ -156,6 +297,14 @@ func NewParser() *Parser {
 			"host_port": regexp.MustCompile(`^([a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+):(\d{1,5})$`),
 			// protocol://hostname:port
 			"proto_host_port": regexp.MustCompile(`^(https?|socks[45]):\/\/([a-zA-Z0-9][-a-zA-Z0-9]*(?:\.[a-zA-Z0-9][-a-zA-Z0-9]*)+):(\d{1,5})$`),
+			// [ipv6]:port
+			"ipv6_port": regexp.MustCompile(`^\[([0-9a-fA-F:.]+)\]:(\d{1,5})$`),
+			// user:pass@[ipv6]:port
+			"user_pass_at_ipv6_port": regexp.MustCompile(`^([^:]+):([^@]+)@\[([0-9a-fA-F:.]+)\]:(\d{1,5})$`),
+			// protocol://[ipv6]:port
+			"proto_ipv6_port": regexp.MustCompile(`^(https?|socks[45]):\/\/\[([0-9a-fA-F:.]+)\]:(\d{1,5})$`),
+			// protocol://user:pass@[ipv6]:port
+			"proto_user_pass_ipv6_port": regexp.MustCompile(`^(https?|socks[45]):\/\/([^:]+):([^@]+)@\[([0-9a-fA-F:.]+)\]:(\d{1,5})$`),
 		},
 	}
 }
@@ -169,6 +318,8 @@ func (p *Parser) ParseLine(line string) (*Proxy, error) {
 		return nil, nil
 	}
 
+	line = normalizeScheme(line)
+
 	proxy := &Proxy{
 		Status: ProxyStatusUnknown,
 		Type:   ProxyTypeHTTP, // Default type
@@ -240,9 +391,62 @@ func (p *Parser) ParseLine(line string) (*Proxy, error) {
 		return proxy, nil
 	}
 
+	if proxy, ok := p.parseIPv6(line); ok {
+		if net.ParseIP(proxy.Host) == nil {
+			return nil, fmt.Errorf("invalid IPv6 address in proxy: %s", line)
+		}
+		proxy.ID = generateProxyID(proxy)
+		return proxy, nil
+	}
+
 	return nil, fmt.Errorf("invalid proxy format: %s", line)
 }
 
+// parseIPv6 matches the bracketed IPv6 formats. Host is set without the
+// brackets, which URL and the dialer add back.
+func (p *Parser) parseIPv6(line string) (*Proxy, bool) {
+	proxy := &Proxy{
+		Status: ProxyStatusUnknown,
+		Type:   ProxyTypeHTTP,
+	}
+
+	// Pattern: protocol://user:pass@[ipv6]:port
+	if matches := p.patterns["proto_user_pass_ipv6_port"].FindStringSubmatch(line); matches != nil {
+		proxy.Type = parseProxyType(matches[1])
+		proxy.Username = matches[2]
+		proxy.Password = matches[3]
+		proxy.Host = matches[4]
+		proxy.Port = matches[5]
+		return proxy, true
+	}
+
+	// Pattern: protocol://[ipv6]:port
+	if matches := p.patterns["proto_ipv6_port"].FindStringSubmatch(line); matches != nil {
+		proxy.Type = parseProxyType(matches[1])
+		proxy.Host = matches[2]
+		proxy.Port = matches[3]
+		return proxy, true
+	}
+
+	// Pattern: user:pass@[ipv6]:port
+	if matches := p.patterns["user_pass_at_ipv6_port"].FindStringSubmatch(line); matches != nil {
+		proxy.Username = matches[1]
+		proxy.Password = matches[2]
+		proxy.Host = matches[3]
+		proxy.Port = matches[4]
+		return proxy, true
+	}
+
+	// Pattern: [ipv6]:port
+	if matches := p.patterns["ipv6_port"].FindStringSubmatch(line); matches != nil {
+		proxy.Host = matches[1]
+		proxy.Port = matches[2]
+		return proxy, true
+	}
+
+	return nil, false
+}
+
 // ParseFile parses a file containing proxies (one per line)
 func (p *Parser) ParseFile(filepath string) ([]*Proxy, []error) {
 	file, err := os.Open(filepath)
@@ -251,10 +455,15 @@ func (p *Parser) ParseFile(filepath string) ([]*Proxy, []error) {
 	}
 	defer file.Close()
 
+	return p.ParseReader(file)
+}
+
+// ParseReader parses proxies from a reader (one per line)
+func (p *Parser) ParseReader(r io.Reader) ([]*Proxy, []error) {
 	var proxies []*Proxy
 	var errors []error
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	lineNum := 0
 
 	for scanner.Scan() {
@@ -295,7 +504,33 @@ func parseProxyType(s string) ProxyType {
 	}
 }
 
-// generateProxyID creates a unique ID for a proxy
+// normalizeScheme lowercases a protocol prefix, maps the ambiguous
+// socks:// to socks5:// and drops a trailing slash or path after the port
+func normalizeScheme(line string) string {
+	scheme, rest, ok := strings.Cut(line, "://")
+	if !ok {
+		return line
+	}
+
+	scheme = strings.ToLower(scheme)
+	if scheme == "socks" {
+		scheme = "socks5"
+	}
+
+	// Credentials may contain a slash, so only look for a path after them
+	hostStart := strings.LastIndex(rest, "@") + 1
+	if i := strings.Index(rest[hostStart:], "/"); i >= 0 {
+		rest = rest[:hostStart+i]
+	}
+
+	return scheme + "://" + rest
+}
+
+// generateProxyID creates a unique ID for a proxy, bracketing IPv6 hosts
 func generateProxyID(p *Proxy) string {
-	return fmt.Sprintf("%s_%s_%s", p.Type, p.Host, p.Port)
+	host := p.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("%s_%s_%s", p.Type, host, p.Port)
 }
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProxySource supplies proxies to a pool. Fetch may return proxies together
+// with an error when only some entries could be parsed. Refresh drops any
+// cached state (tokens, cached lists) so the next Fetch gets a fresh list.
+type ProxySource interface {
+	Fetch(ctx context.Context) ([]*Proxy, error)
+	Refresh()
+}
+
+// FileSource reads proxies from a file, one per line
+type FileSource struct {
+	Path string
+}
+
+// NewFileSource creates a source backed by a proxy file
+func NewFileSource(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// Fetch reads and parses the file
+func (s *FileSource) Fetch(ctx context.Context) ([]*Proxy, error) {
+	proxies, errs := NewParser().ParseFile(s.Path)
+	return proxies, errors.Join(errs...)
+}
+
+// Refresh is a no-op; the file is re-read on every Fetch
+func (s *FileSource) Refresh() {}
+
+// HTTPSource downloads a proxy list from a URL, one proxy per line
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewHTTPSource creates a source backed by a proxy list URL
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{
+		URL:    url,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Fetch downloads and parses the list
+func (s *HTTPSource) Fetch(ctx context.Context) ([]*Proxy, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch proxy list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch proxy list: bad status code: %d", resp.StatusCode)
+	}
+
+	proxies, errs := NewParser().ParseReader(resp.Body)
+	return proxies, errors.Join(errs...)
+}
+
+// Refresh is a no-op; the URL is requested on every Fetch
+func (s *HTTPSource) Refresh() {}
+
+// StaticSource serves a fixed list of proxy lines
+type StaticSource struct {
+	Lines []string
+}
+
+// NewStaticSource creates a source from proxy lines
+func NewStaticSource(lines []string) *StaticSource {
+	return &StaticSource{Lines: lines}
+}
+
+// Fetch parses the configured lines
+func (s *StaticSource) Fetch(ctx context.Context) ([]*Proxy, error) {
+	proxies, errs := NewParser().ParseReader(strings.NewReader(strings.Join(s.Lines, "\n")))
+	return proxies, errors.Join(errs...)
+}
+
+// Refresh is a no-op for a static list
+func (s *StaticSource) Refresh() {}
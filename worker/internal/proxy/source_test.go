@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxies.txt")
+	content := "1.1.1.1:8080\n2.2.2.2:3128\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	proxies, err := NewFileSource(path).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(proxies) != 2 {
+		t.Errorf("Fetch() = %d proxies, want 2", len(proxies))
+	}
+}
+
+func TestFileSourceMissing(t *testing.T) {
+	_, err := NewFileSource(filepath.Join(t.TempDir(), "missing.txt")).Fetch(context.Background())
+	if err == nil {
+		t.Error("Fetch() should fail for a missing file")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1.1.1.1:8080\nsocks5://3.3.3.3:1080\n")
+	}))
+	defer server.Close()
+
+	proxies, err := NewHTTPSource(server.URL).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(proxies) != 2 {
+		t.Fatalf("Fetch() = %d proxies, want 2", len(proxies))
+	}
+	if proxies[1].Type != ProxyTypeSOCKS5 {
+		t.Errorf("proxies[1].Type = %v, want %v", proxies[1].Type, ProxyTypeSOCKS5)
+	}
+}
+
+func TestHTTPSourceBadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	if _, err := NewHTTPSource(server.URL).Fetch(context.Background()); err == nil {
+		t.Error("Fetch() should fail on a non-200 response")
+	}
+}
+
+func TestStaticSourcePartial(t *testing.T) {
+	proxies, err := NewStaticSource([]string{"1.1.1.1:8080", "not a proxy"}).Fetch(context.Background())
+	if err == nil {
+		t.Error("Fetch() should report the invalid line")
+	}
+	if len(proxies) != 1 {
+		t.Errorf("Fetch() = %d proxies, want 1", len(proxies))
+	}
+}
+
+func TestPoolLoadSourcesDedupe(t *testing.T) {
+	pool := NewPool(DefaultPoolConfig())
+	pool.AddSource(NewStaticSource([]string{"1.1.1.1:8080", "2.2.2.2:8080"}))
+	pool.AddSource(NewStaticSource([]string{"2.2.2.2:8080", "3.3.3.3:8080", "bad line"}))
+
+	added, errs := pool.LoadSources(context.Background())
+	if added != 3 {
+		t.Errorf("added = %d, want 3", added)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errors = %d, want 1", len(errs))
+	}
+
+	// Loading again adds nothing new
+	added, _ = pool.LoadSources(context.Background())
+	if added != 0 {
+		t.Errorf("second load added = %d, want 0", added)
+	}
+
+	if total := pool.Stats().Total; total != 3 {
+		t.Errorf("Total = %d, want 3", total)
+	}
+}
+
+func TestPoolLoadSourcesPrunesVanishedDead(t *testing.T) {
+	pool := NewPool(DefaultPoolConfig())
+	source := NewStaticSource([]string{"1.1.1.1:8080", "2.2.2.2:8080", "3.3.3.3:8080"})
+	pool.AddSource(source)
+	pool.AddProxy(&Proxy{ID: "manual", Host: "4.4.4.4", Port: "8080", Type: ProxyTypeHTTP})
+
+	if added, _ := pool.LoadSources(context.Background()); added != 3 {
+		t.Fatalf("added = %d, want 3", added)
+	}
+
+	byHost := make(map[string]*Proxy)
+	for _, prx := range pool.proxies {
+		byHost[prx.Host] = prx
+	}
+	dead, alive := byHost["1.1.1.1"], byHost["2.2.2.2"]
+
+	pool.mu.Lock()
+	pool.place(dead, ProxyStatusDead)
+	pool.place(byHost["4.4.4.4"], ProxyStatusDead)
+	pool.mu.Unlock()
+	pool.ReportSuccess(alive.ID, 10*time.Millisecond)
+
+	// Both vanish from the list; only the dead one goes
+	source.Lines = []string{"3.3.3.3:8080"}
+	pool.LoadSources(context.Background())
+
+	if _, ok := pool.proxies[dead.ID]; ok {
+		t.Error("dead proxy missing from the source was kept")
+	}
+	kept, ok := pool.proxies[alive.ID]
+	if !ok {
+		t.Fatal("alive proxy missing from the source was removed")
+	}
+	if kept.SuccessCount != 1 {
+		t.Errorf("SuccessCount = %d, want stats preserved", kept.SuccessCount)
+	}
+	if _, ok := pool.proxies["manual"]; !ok {
+		t.Error("dead proxy not loaded from a source was removed")
+	}
+	if total := pool.Stats().Total; total != 3 {
+		t.Errorf("Total = %d, want 3", total)
+	}
+}
+
+func TestPoolLoadSourcesFailedSourceKeepsProxies(t *testing.T) {
+	pool := NewPool(DefaultPoolConfig())
+	source := NewFileSource(filepath.Join(t.TempDir(), "proxies.txt"))
+	if err := os.WriteFile(source.Path, []byte("1.1.1.1:8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pool.AddSource(source)
+	pool.LoadSources(context.Background())
+
+	pool.mu.Lock()
+	for _, prx := range pool.proxies {
+		pool.place(prx, ProxyStatusDead)
+	}
+	pool.mu.Unlock()
+
+	// An unreadable source is not an empty one
+	os.Remove(source.Path)
+	if _, errs := pool.LoadSources(context.Background()); len(errs) == 0 {
+		t.Fatal("LoadSources() errors = 0, want the read error")
+	}
+	if total := pool.Stats().Total; total != 1 {
+		t.Errorf("Total = %d, want 1", total)
+	}
+}
@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateVersion is bumped when the state file layout changes incompatibly
+const stateVersion = 1
+
+// poolState is the on-disk form of the learned pool state
+type poolState struct {
+	Version int          `json:"version"`
+	SavedAt time.Time    `json:"saved_at"`
+	Proxies []proxyState `json:"proxies"`
+}
+
+// proxyState holds the learned stats of one proxy
+type proxyState struct {
+	ID            string        `json:"id"`
+	Status        ProxyStatus   `json:"status"`
+	TotalRequests int64         `json:"total_requests"`
+	SuccessCount  int64         `json:"success_count"`
+	FailCount     int64         `json:"fail_count"`
+	CaptchaCount  int64         `json:"captcha_count"`
+	TotalLatency  time.Duration `json:"total_latency"`
+	LastUsed      time.Time     `json:"last_used"`
+	LastSuccess   time.Time     `json:"last_success"`
+	LastFail      time.Time     `json:"last_fail"`
+	CooldownUntil time.Time     `json:"cooldown_until"`
+	ScoreSum      float64       `json:"score_sum"`
+	ScoreWeight   float64       `json:"score_weight"`
+	ScoreAt       time.Time     `json:"score_at"`
+}
+
+// SaveState writes every proxy's learned stats and status to path as
+// JSON. The file is replaced atomically so a crash never leaves it torn.
+func (p *Pool) SaveState(path string) error {
+	p.mu.RLock()
+	state := poolState{
+		Version: stateVersion,
+		SavedAt: time.Now(),
+		Proxies: make([]proxyState, 0, len(p.proxies)),
+	}
+	for _, proxy := range p.proxies {
+		proxy.mu.RLock()
+		state.Proxies = append(state.Proxies, proxyState{
+			ID:            proxy.ID,
+			Status:        proxy.Status,
+			TotalRequests: proxy.TotalRequests,
+			SuccessCount:  proxy.SuccessCount,
+			FailCount:     proxy.FailCount,
+			CaptchaCount:  proxy.CaptchaCount,
+			TotalLatency:  proxy.TotalLatency,
+			LastUsed:      proxy.LastUsed,
+			LastSuccess:   proxy.LastSuccess,
+			LastFail:      proxy.LastFail,
+			CooldownUntil: proxy.CooldownUntil,
+			ScoreSum:      proxy.scoreSum,
+			ScoreWeight:   proxy.scoreWeight,
+			ScoreAt:       proxy.scoreAt,
+		})
+		proxy.mu.RUnlock()
+	}
+	p.mu.RUnlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode proxy state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write proxy state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write proxy state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write proxy state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write proxy state: %w", err)
+	}
+
+	return nil
+}
+
+// LoadState restores stats and status saved by SaveState onto the
+// proxies currently in the pool; saved proxies no longer in the pool are
+// ignored. With RecheckDead set, proxies saved as dead are queued for a
+// health check instead of staying dead. A missing file returns an error
+// satisfying errors.Is(err, os.ErrNotExist).
+func (p *Pool) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read proxy state: %w", err)
+	}
+
+	var state poolState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid proxy state: %w", err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("unsupported proxy state version %d", state.Version)
+	}
+
+	p.mu.Lock()
+	recheck := 0
+	for _, saved := range state.Proxies {
+		proxy, ok := p.proxies[saved.ID]
+		if !ok {
+			continue
+		}
+
+		proxy.mu.Lock()
+		proxy.TotalRequests = saved.TotalRequests
+		proxy.SuccessCount = saved.SuccessCount
+		proxy.FailCount = saved.FailCount
+		proxy.CaptchaCount = saved.CaptchaCount
+		proxy.TotalLatency = saved.TotalLatency
+		proxy.LastUsed = saved.LastUsed
+		proxy.LastSuccess = saved.LastSuccess
+		proxy.LastFail = saved.LastFail
+		proxy.CooldownUntil = saved.CooldownUntil
+		proxy.scoreSum = saved.ScoreSum
+		proxy.scoreWeight = saved.ScoreWeight
+		proxy.scoreAt = saved.ScoreAt
+		proxy.mu.Unlock()
+
+		switch saved.Status {
+		case ProxyStatusAlive:
+			p.place(proxy, ProxyStatusAlive)
+		case ProxyStatusQuarantined:
+			p.place(proxy, ProxyStatusQuarantined)
+		case ProxyStatusDead:
+			if p.config.RecheckDead {
+				p.place(proxy, ProxyStatusUnknown)
+				recheck++
+			} else {
+				p.place(proxy, ProxyStatusDead)
+			}
+		}
+	}
+	alive := len(p.alive)
+	lazy := p.config.TargetAlive > 0
+	p.mu.Unlock()
+
+	// In lazy mode the rechecks wait in the unchecked list for WarmUp;
+	// otherwise nothing else drains it, so check them now
+	if recheck > 0 && !lazy {
+		p.topUp(context.Background(), alive+recheck)
+	}
+
+	return nil
+}
+
+// place moves a proxy to the list for status (must hold lock). Unknown
+// means unchecked.
+func (p *Pool) place(proxy *Proxy, status ProxyStatus) {
+	p.alive = removeProxy(p.alive, proxy)
+	p.dead = removeProxy(p.dead, proxy)
+	p.quarantine = removeProxy(p.quarantine, proxy)
+	p.unchecked = removeProxy(p.unchecked, proxy)
+	p.released = removeProxy(p.released, proxy)
+
+	proxy.Status = status
+	switch status {
+	case ProxyStatusAlive:
+		p.alive = append(p.alive, proxy)
+		p.queueLRU(proxy)
+	case ProxyStatusDead:
+		proxy.deadSince = time.Now()
+		p.dead = append(p.dead, proxy)
+	case ProxyStatusQuarantined:
+		p.quarantine = append(p.quarantine, proxy)
+	default:
+		p.unchecked = append(p.unchecked, proxy)
+	}
+}
+
+// removeProxy removes proxy from list by ID
+func removeProxy(list []*Proxy, proxy *Proxy) []*Proxy {
+	for i, lp := range list {
+		if lp.ID == proxy.ID {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
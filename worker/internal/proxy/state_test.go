@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newStatePool(config PoolConfig, ids ...string) *Pool {
+	pool := NewPool(config)
+	for _, id := range ids {
+		pool.AddProxy(&Proxy{ID: id, Host: "10.0.0.1", Port: "8080", Type: ProxyTypeHTTP})
+	}
+	return pool
+}
+
+func TestPoolSaveLoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxies.json")
+
+	config := DefaultPoolConfig()
+	config.MaxFailures = 1
+	src := newStatePool(config, "good", "bad", "gone")
+	src.ReportSuccess("good", 200*time.Millisecond)
+	src.ReportSuccess("good", 400*time.Millisecond)
+	src.ReportCaptcha("good")
+	src.ReportFailure("bad")
+	src.mu.Lock()
+	src.markDead(src.proxies["bad"])
+	src.mu.Unlock()
+
+	if err := src.SaveState(path); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	// "gone" left the proxy list; "new" was added since
+	dst := newStatePool(DefaultPoolConfig(), "good", "bad", "new")
+	if err := dst.LoadState(path); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+
+	good, _ := dst.GetByID("good")
+	if good.SuccessCount != 2 || good.CaptchaCount != 1 || good.AvgLatency() != 300*time.Millisecond {
+		t.Errorf("good = %d successes, %d captchas, %v latency, want 2, 1, 300ms",
+			good.SuccessCount, good.CaptchaCount, good.AvgLatency())
+	}
+	if score, ok := good.Score(); !ok || score == 0 {
+		t.Errorf("good.Score() = %v, %v, want restored score", score, ok)
+	}
+
+	bad, _ := dst.GetByID("bad")
+	if bad.Status != ProxyStatusDead || bad.FailCount != 1 {
+		t.Errorf("bad = %s with %d failures, want dead with 1", bad.Status, bad.FailCount)
+	}
+
+	if _, ok := dst.GetByID("gone"); ok {
+		t.Error("LoadState() added a proxy missing from the pool")
+	}
+
+	stats := dst.Stats()
+	if stats.Alive != 2 || stats.Dead != 1 {
+		t.Errorf("alive = %d, dead = %d, want 2 and 1", stats.Alive, stats.Dead)
+	}
+}
+
+func TestPoolLoadStateRecheckDead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxies.json")
+
+	src := newStatePool(DefaultPoolConfig(), "a", "b")
+	src.mu.Lock()
+	src.markDead(src.proxies["a"])
+	src.markDead(src.proxies["b"])
+	src.mu.Unlock()
+	if err := src.SaveState(path); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	config := DefaultPoolConfig()
+	config.RecheckDead = true
+	dst := newStatePool(config, "a", "b")
+
+	// a recovered since the last run, b is still down
+	var mu sync.Mutex
+	var checked []string
+	dst.checkFn = func(ctx context.Context, proxy *Proxy) error {
+		mu.Lock()
+		checked = append(checked, proxy.ID)
+		mu.Unlock()
+		if proxy.ID == "b" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	if err := dst.LoadState(path); err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+
+	if len(checked) != 2 {
+		t.Errorf("checked %v, want both dead proxies", checked)
+	}
+	if a, _ := dst.GetByID("a"); a.Status != ProxyStatusAlive {
+		t.Errorf("a.Status = %s, want alive", a.Status)
+	}
+	if b, _ := dst.GetByID("b"); b.Status != ProxyStatusDead {
+		t.Errorf("b.Status = %s, want dead", b.Status)
+	}
+}
+
+func TestPoolLoadStateMissing(t *testing.T) {
+	pool := newStatePool(DefaultPoolConfig(), "a")
+
+	err := pool.LoadState(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadState() error = %v, want os.ErrNotExist", err)
+	}
+}
@@ -1,7 +1,12 @@
 package stealth
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
 	"math/rand"
+	"os"
 	"sync"
 	"time"
 )
@@ -23,6 +28,8 @@ const (
 	OSWindows OSType = "windows"
 	OSMacOS   OSType = "macos"
 	OSLinux   OSType = "linux"
+	OSAndroid OSType = "android"
+	OSIOS     OSType = "ios"
 )
 
 // Fingerprint represents a browser fingerprint for stealth requests
@@ -39,6 +46,7 @@ type Fingerprint struct {
 	SecChUa        string            `json:"sec_ch_ua"`
 	SecChUaPlatform string           `json:"sec_ch_ua_platform"`
 	SecChUaMobile  string            `json:"sec_ch_ua_mobile"`
+	Mobile         bool              `json:"mobile"` // Phone browser, served mobile result pages
 	Headers        map[string]string `json:"headers"`
 	JA3            string            `json:"ja3"`
 }
@@ -46,25 +54,44 @@ type Fingerprint struct {
 // Manager handles fingerprint rotation and stealth settings
 type Manager struct {
 	mu           sync.RWMutex
-	fingerprints []*Fingerprint
+	all          []*Fingerprint // Every fingerprint, of both device classes
+	fingerprints []*Fingerprint // Those of the rotated device class
+	mobile       bool           // Rotated device class
 	rng          *rand.Rand
 
 	// Settings
 	rotateEvery    int // Rotate fingerprint every N requests
 	requestCounter int
 	current        *Fingerprint
+
+	// Per-proxy fingerprints, kept for the session unless rotateProxies
+	// is set, in which case the mapping is reassigned every rotateEvery
+	// proxy requests
+	assigned      map[string]*Fingerprint
+	rotateProxies bool
+	proxyCounter  int
+	generation    uint32
 }
 
 // NewManager creates a new stealth manager
 func NewManager() *Manager {
+	return NewManagerWithSeed(time.Now().UnixNano())
+}
+
+// NewManagerWithSeed creates a stealth manager whose fingerprint rotation
+// is reproducible: managers with the same seed rotate through the same
+// sequence
+func NewManagerWithSeed(seed int64) *Manager {
 	m := &Manager{
 		fingerprints: make([]*Fingerprint, 0),
-		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		rng:          rand.New(rand.NewSource(seed)),
 		rotateEvery:  100,
+		assigned:     make(map[string]*Fingerprint),
 	}
 
-	// Load default fingerprints
+	// Load default fingerprints, rotating the desktop ones
 	m.loadDefaultFingerprints()
+	m.fingerprints = m.byDevice(false)
 
 	// Set initial fingerprint
 	if len(m.fingerprints) > 0 {
@@ -76,7 +103,7 @@ func NewManager() *Manager {
 
 // loadDefaultFingerprints loads a set of realistic browser fingerprints
 func (m *Manager) loadDefaultFingerprints() {
-	m.fingerprints = []*Fingerprint{
+	m.all = []*Fingerprint{
 		// Chrome on Windows
 		{
 			ID:              "chrome_win_120",
@@ -205,6 +232,87 @@ func (m *Manager) loadDefaultFingerprints() {
 			SecChUaMobile:   "",
 			JA3:             "771,4865-4867-4866-49195-49199-52393-52392-49196-49200-49162-49161-49171-49172-156-157-47-53,0-23-65281-10-11-35-16-5-34-51-43-13-45-28-21,29-23-24-25-256-257,0",
 		},
+		// Chrome on Android
+		{
+			ID:              "chrome_android_120",
+			Browser:         BrowserChrome,
+			BrowserVersion:  "120.0.0.0",
+			OS:              OSAndroid,
+			OSVersion:       "14",
+			UserAgent:       "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			AcceptLanguage:  "en-US,en;q=0.9",
+			AcceptEncoding:  "gzip, deflate, br",
+			Accept:          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+			SecChUa:         `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
+			SecChUaPlatform: `"Android"`,
+			SecChUaMobile:   "?1",
+			Mobile:          true,
+			JA3:             "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,0-23-65281-10-11-35-16-5-13-18-51-45-43-27-17513,29-23-24,0",
+		},
+		{
+			ID:              "chrome_android_samsung_120",
+			Browser:         BrowserChrome,
+			BrowserVersion:  "120.0.0.0",
+			OS:              OSAndroid,
+			OSVersion:       "13",
+			UserAgent:       "Mozilla/5.0 (Linux; Android 13; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			AcceptLanguage:  "en-US,en;q=0.9",
+			AcceptEncoding:  "gzip, deflate, br",
+			Accept:          "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+			SecChUa:         `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
+			SecChUaPlatform: `"Android"`,
+			SecChUaMobile:   "?1",
+			Mobile:          true,
+			JA3:             "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,0-23-65281-10-11-35-16-5-13-18-51-45-43-27-17513,29-23-24,0",
+		},
+		// Safari on iOS, which like desktop Safari sends no client hints
+		{
+			ID:              "safari_ios_17",
+			Browser:         BrowserSafari,
+			BrowserVersion:  "17.0",
+			OS:              OSIOS,
+			OSVersion:       "17.0",
+			UserAgent:       "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			AcceptLanguage:  "en-US,en;q=0.9",
+			AcceptEncoding:  "gzip, deflate, br",
+			Accept:          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			SecChUa:         "",
+			SecChUaPlatform: "",
+			SecChUaMobile:   "",
+			Mobile:          true,
+			JA3:             "771,4865-4866-4867-49196-49195-52393-49200-49199-52392-49188-49187-49192-49191-49162-49161-49172-49171-157-156-53-47-49160-49170-10,0-23-65281-10-11-16-5-13-18-51-45-43-27,29-23-24-25,0",
+		},
+	}
+}
+
+// byDevice returns the fingerprints of one device class (must hold lock)
+func (m *Manager) byDevice(mobile bool) []*Fingerprint {
+	fps := make([]*Fingerprint, 0, len(m.all))
+	for _, fp := range m.all {
+		if fp.Mobile == mobile {
+			fps = append(fps, fp)
+		}
+	}
+	return fps
+}
+
+// FilterByDevice restricts rotation to mobile or desktop fingerprints;
+// desktop is the default. Google serves mobile browsers a different
+// result layout, which the engine parsers may not fully understand, so
+// check the results before switching a run to mobile. Per-proxy
+// assignments are dropped so every proxy picks from the new class.
+func (m *Manager) FilterByDevice(mobile bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mobile = mobile
+	m.fingerprints = m.byDevice(mobile)
+	m.assigned = make(map[string]*Fingerprint)
+	m.proxyCounter = 0
+
+	m.current = nil
+	if len(m.fingerprints) > 0 {
+		m.current = m.fingerprints[0]
 	}
 }
 
@@ -246,23 +354,182 @@ func (m *Manager) rotate() {
 	m.current = m.fingerprints[idx]
 }
 
-// SetRotationInterval sets how often fingerprints rotate
+// SetRotationInterval sets how often fingerprints rotate. It also
+// applies to the per-proxy mapping when SetRotateProxies is enabled.
 func (m *Manager) SetRotationInterval(requests int) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.rotateEvery = requests
 }
 
-// AddFingerprint adds a custom fingerprint
+// SetRotateProxies sets whether the per-proxy fingerprint mapping is
+// reassigned every rotation interval instead of kept for the session
+func (m *Manager) SetRotateProxies(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rotateProxies = enabled
+	m.proxyCounter = 0
+}
+
+// GetFingerprintForProxy returns the fingerprint assigned to a proxy, so
+// one proxy keeps the same browser identity across requests. The
+// assignment is derived from the proxy ID and cached. An empty ID gets
+// the rotating fingerprint from GetFingerprint.
+func (m *Manager) GetFingerprintForProxy(proxyID string) *Fingerprint {
+	if proxyID == "" {
+		return m.GetFingerprint()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.rotateProxies {
+		m.proxyCounter++
+		if m.proxyCounter >= m.rotateEvery {
+			m.assigned = make(map[string]*Fingerprint)
+			m.generation++
+			m.proxyCounter = 0
+		}
+	}
+
+	if fp, ok := m.assigned[proxyID]; ok {
+		return fp
+	}
+	if len(m.fingerprints) == 0 {
+		return nil
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(proxyID))
+	idx := (h.Sum32() + m.generation) % uint32(len(m.fingerprints))
+
+	fp := m.fingerprints[idx]
+	m.assigned[proxyID] = fp
+	return fp
+}
+
+// AddFingerprint adds a custom fingerprint, rotated if it matches the
+// device class set by FilterByDevice
 func (m *Manager) AddFingerprint(fp *Fingerprint) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.fingerprints = append(m.fingerprints, fp)
+	m.all = append(m.all, fp)
+	if fp.Mobile == m.mobile {
+		m.fingerprints = append(m.fingerprints, fp)
+	}
+}
+
+// LoadFingerprintsFromFile loads fingerprints from a JSON array of
+// Fingerprint objects. A fingerprint whose ID matches a loaded one
+// replaces it, the others are added; one without an ID is given one.
+// Nothing is loaded unless every fingerprint has a UserAgent. Returns how
+// many fingerprints the file held.
+func (m *Manager) LoadFingerprintsFromFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var fps []*Fingerprint
+	if err := json.Unmarshal(data, &fps); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i, fp := range fps {
+		if fp == nil || fp.UserAgent == "" {
+			return 0, fmt.Errorf("%s: fingerprint %d has no user_agent", path, i+1)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, fp := range fps {
+		if fp.ID == "" {
+			fp.ID = fmt.Sprintf("custom_%d", len(m.all)+1)
+		}
+		if i := m.indexOf(fp.ID); i >= 0 {
+			m.all[i] = fp
+		} else {
+			m.all = append(m.all, fp)
+		}
+	}
+	m.refresh()
+
+	return len(fps), nil
+}
+
+// RemoveFingerprint removes the fingerprint with the given ID. Proxies
+// assigned it get another on their next request. Returns false if there
+// is no such fingerprint.
+func (m *Manager) RemoveFingerprint(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOf(id)
+	if i < 0 {
+		return false
+	}
+	m.all = append(m.all[:i], m.all[i+1:]...)
+	m.refresh()
+	return true
+}
+
+// Count returns the number of fingerprints, of both device classes
+func (m *Manager) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.all)
+}
+
+// indexOf returns the position of a fingerprint ID in all, or -1 (must
+// hold lock)
+func (m *Manager) indexOf(id string) int {
+	for i, fp := range m.all {
+		if fp.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// refresh rebuilds the rotated fingerprints after all changed, dropping
+// the current fingerprint and proxy assignments that are no longer
+// rotated (must hold lock)
+func (m *Manager) refresh() {
+	m.fingerprints = m.byDevice(m.mobile)
+
+	rotated := make(map[*Fingerprint]bool, len(m.fingerprints))
+	for _, fp := range m.fingerprints {
+		rotated[fp] = true
+	}
+	for proxyID, fp := range m.assigned {
+		if !rotated[fp] {
+			delete(m.assigned, proxyID)
+		}
+	}
+
+	if !rotated[m.current] {
+		m.current = nil
+		if len(m.fingerprints) > 0 {
+			m.current = m.fingerprints[0]
+		}
+	}
 }
 
 // GetHeaders returns HTTP headers for the current fingerprint
 func (m *Manager) GetHeaders() map[string]string {
-	fp := m.GetFingerprint()
+	return m.HeadersFor(m.GetFingerprint())
+}
+
+// GetHeadersForProxy returns HTTP headers for the fingerprint assigned to
+// a proxy, see GetFingerprintForProxy
+func (m *Manager) GetHeadersForProxy(proxyID string) map[string]string {
+	return m.HeadersFor(m.GetFingerprintForProxy(proxyID))
+}
+
+// HeadersFor builds the HTTP headers for a fingerprint. A nil fingerprint
+// gets the default headers.
+func (m *Manager) HeadersFor(fp *Fingerprint) map[string]string {
 	if fp == nil {
 		return m.getDefaultHeaders()
 	}
@@ -368,7 +635,7 @@ func GaussianDelay(mean, stddev time.Duration, rng *rand.Rand) time.Duration {
 		u1 = rng.Float64()
 	}
 
-	z := math_Sqrt(-2*math_Log(u1)) * math_Cos(2*math_Pi*u2)
+	z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
 
 	delay := float64(mean) + z*float64(stddev)
 
@@ -379,54 +646,3 @@ func GaussianDelay(mean, stddev time.Duration, rng *rand.Rand) time.Duration {
 
 	return time.Duration(delay)
 }
-
-// Simple math functions to avoid import
-func math_Sqrt(x float64) float64 {
-	if x < 0 {
-		return 0
-	}
-	z := x / 2
-	for i := 0; i < 10; i++ {
-		z = z - (z*z-x)/(2*z)
-	}
-	return z
-}
-
-func math_Log(x float64) float64 {
-	if x <= 0 {
-		return 0
-	}
-	// Newton's method approximation
-	result := 0.0
-	for x >= 2 {
-		x /= 2.718281828
-		result++
-	}
-	x--
-	term := x
-	for i := 1; i < 20; i++ {
-		result += term / float64(i)
-		term *= -x
-	}
-	return result
-}
-
-const math_Pi = 3.14159265358979323846
-
-func math_Cos(x float64) float64 {
-	// Reduce to [0, 2*pi]
-	for x < 0 {
-		x += 2 * math_Pi
-	}
-	for x > 2*math_Pi {
-		x -= 2 * math_Pi
-	}
-	// Taylor series
-	result := 1.0
-	term := 1.0
-	for i := 1; i < 20; i++ {
-		term *= -x * x / float64((2*i-1)*(2*i))
-		result += term
-	}
-	return result
-}
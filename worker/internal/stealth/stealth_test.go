@@ -1,6 +1,11 @@
 package stealth
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -203,6 +208,215 @@ func TestManagerAddFingerprint(t *testing.T) {
 	}
 }
 
+func TestManagerLoadFingerprintsFromFile(t *testing.T) {
+	m := NewManager()
+	initialCount := m.Count()
+
+	path := filepath.Join(t.TempDir(), "fingerprints.json")
+	os.WriteFile(path, []byte(`[
+		{"id": "chrome_win_120", "browser": "chrome", "user_agent": "Chrome 131 Agent"},
+		{"id": "chrome_win_131", "browser": "chrome", "user_agent": "Chrome 131 Agent"},
+		{"browser": "firefox", "user_agent": "Firefox 133 Agent"}
+	]`), 0644)
+
+	n, err := m.LoadFingerprintsFromFile(path)
+	if err != nil || n != 3 {
+		t.Fatalf("LoadFingerprintsFromFile() = %d, %v, want 3, nil", n, err)
+	}
+	// The first replaces a default, the other two are added
+	if got := m.Count(); got != initialCount+2 {
+		t.Errorf("Count() = %d, want %d", got, initialCount+2)
+	}
+	for _, fp := range m.fingerprints {
+		if fp.ID == "chrome_win_120" && fp.UserAgent != "Chrome 131 Agent" {
+			t.Errorf("chrome_win_120 User-Agent = %q, want the loaded one", fp.UserAgent)
+		}
+		if fp.ID == "" {
+			t.Error("loaded fingerprint without an ID was not given one")
+		}
+	}
+
+	// One fingerprint without a User-Agent rejects the whole file
+	os.WriteFile(path, []byte(`[{"id": "ok", "user_agent": "Agent"}, {"id": "bad"}]`), 0644)
+	if _, err := m.LoadFingerprintsFromFile(path); err == nil {
+		t.Error("LoadFingerprintsFromFile() with a missing user_agent should fail")
+	}
+	if got := m.Count(); got != initialCount+2 {
+		t.Errorf("Count() after a rejected file = %d, want %d", got, initialCount+2)
+	}
+
+	if _, err := m.LoadFingerprintsFromFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadFingerprintsFromFile() of a missing file should fail")
+	}
+}
+
+func TestManagerRemoveFingerprint(t *testing.T) {
+	m := NewManager()
+	initialCount := m.Count()
+
+	assigned := m.GetFingerprintForProxy("proxy-1")
+	if !m.RemoveFingerprint(assigned.ID) {
+		t.Fatalf("RemoveFingerprint(%s) = false, want true", assigned.ID)
+	}
+	if m.RemoveFingerprint(assigned.ID) {
+		t.Error("second RemoveFingerprint() = true, want false")
+	}
+	if got := m.Count(); got != initialCount-1 {
+		t.Errorf("Count() = %d, want %d", got, initialCount-1)
+	}
+
+	// Neither the proxy nor rotation keeps using the removed fingerprint
+	if fp := m.GetFingerprintForProxy("proxy-1"); fp == nil || fp.ID == assigned.ID {
+		t.Errorf("proxy fingerprint = %v, want another than %s", fp, assigned.ID)
+	}
+	for _, fp := range m.fingerprints {
+		if fp.ID == assigned.ID {
+			t.Errorf("removed fingerprint %s still rotated", assigned.ID)
+		}
+	}
+}
+
+func TestManagerWithSeedReproducible(t *testing.T) {
+	rotation := func() []string {
+		m := NewManagerWithSeed(42)
+		m.SetRotationInterval(1)
+
+		var ids []string
+		for i := 0; i < 20; i++ {
+			ids = append(ids, m.GetFingerprint().ID, m.GetRandomFingerprint().ID)
+		}
+		return ids
+	}
+
+	first, second := rotation(), rotation()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("fingerprint %d = %s and %s, want the same sequence for the same seed", i, first[i], second[i])
+		}
+	}
+}
+
+func TestManagerFingerprintForProxy(t *testing.T) {
+	m := NewManager()
+	m.SetRotationInterval(2)
+
+	first := m.GetFingerprintForProxy("proxy-1")
+	if first == nil {
+		t.Fatal("fingerprint should not be nil")
+	}
+
+	// Global rotation must not move a proxy's fingerprint
+	for i := 0; i < 20; i++ {
+		m.GetFingerprint()
+		if fp := m.GetFingerprintForProxy("proxy-1"); fp != first {
+			t.Fatalf("request %d: fingerprint = %s, want %s", i, fp.ID, first.ID)
+		}
+	}
+
+	// The assignment is derived from the ID, so a fresh manager agrees
+	if fp := NewManager().GetFingerprintForProxy("proxy-1"); fp.ID != first.ID {
+		t.Errorf("new manager fingerprint = %s, want %s", fp.ID, first.ID)
+	}
+
+	headers := m.GetHeadersForProxy("proxy-1")
+	if headers["User-Agent"] != first.UserAgent {
+		t.Errorf("User-Agent = %q, want %q", headers["User-Agent"], first.UserAgent)
+	}
+
+	if fp := m.GetFingerprintForProxy(""); fp == nil {
+		t.Error("empty proxy ID should fall back to the rotating fingerprint")
+	}
+}
+
+func TestManagerRotateProxies(t *testing.T) {
+	m := NewManager()
+	m.SetRotationInterval(3)
+	m.SetRotateProxies(true)
+
+	first := m.GetFingerprintForProxy("proxy-1")
+	if fp := m.GetFingerprintForProxy("proxy-1"); fp != first {
+		t.Errorf("fingerprint changed before the interval: %s, want %s", fp.ID, first.ID)
+	}
+
+	// The third request reassigns the mapping
+	if fp := m.GetFingerprintForProxy("proxy-1"); fp == first {
+		t.Errorf("fingerprint = %s after the interval, want a new assignment", fp.ID)
+	}
+}
+
+func TestManagerFilterByDevice(t *testing.T) {
+	m := NewManager()
+
+	for _, fp := range m.fingerprints {
+		if fp.Mobile {
+			t.Fatalf("default rotation includes mobile fingerprint %s", fp.ID)
+		}
+	}
+
+	m.FilterByDevice(true)
+	if len(m.fingerprints) == 0 {
+		t.Fatal("no mobile fingerprints")
+	}
+	for i := 0; i < 50; i++ {
+		if fp := m.GetFingerprintForProxy(fmt.Sprintf("proxy_%d", i)); !fp.Mobile {
+			t.Fatalf("GetFingerprintForProxy() = %s, want a mobile fingerprint", fp.ID)
+		}
+		if fp := m.GetRandomFingerprint(); !fp.Mobile {
+			t.Fatalf("GetRandomFingerprint() = %s, want a mobile fingerprint", fp.ID)
+		}
+	}
+	if !m.GetFingerprint().Mobile {
+		t.Error("GetFingerprint() should return a mobile fingerprint")
+	}
+
+	// Custom fingerprints join rotation only in their own class
+	m.AddFingerprint(&Fingerprint{ID: "custom_desktop", UserAgent: "x"})
+	for _, fp := range m.fingerprints {
+		if fp.ID == "custom_desktop" {
+			t.Error("desktop fingerprint added to mobile rotation")
+		}
+	}
+
+	m.FilterByDevice(false)
+	if m.GetFingerprint().Mobile {
+		t.Error("GetFingerprint() should return a desktop fingerprint after FilterByDevice(false)")
+	}
+}
+
+func TestMobileFingerprintHeaders(t *testing.T) {
+	m := NewManager()
+	m.FilterByDevice(true)
+
+	platforms := map[OSType]string{OSAndroid: `"Android"`}
+	for _, fp := range m.fingerprints {
+		if !containsString(fp.UserAgent, "Mobile") {
+			t.Errorf("%s UA doesn't contain 'Mobile': %s", fp.ID, fp.UserAgent)
+		}
+
+		headers := m.HeadersFor(fp)
+		if fp.SecChUa == "" {
+			// Safari sends no client hints
+			if _, ok := headers["Sec-Ch-Ua-Mobile"]; ok {
+				t.Errorf("%s sends Sec-Ch-Ua-Mobile without Sec-Ch-Ua", fp.ID)
+			}
+			continue
+		}
+		if got := headers["Sec-Ch-Ua-Mobile"]; got != "?1" {
+			t.Errorf("%s Sec-Ch-Ua-Mobile = %q, want ?1", fp.ID, got)
+		}
+		if got, want := headers["Sec-Ch-Ua-Platform"], platforms[fp.OS]; got != want {
+			t.Errorf("%s Sec-Ch-Ua-Platform = %q, want %q", fp.ID, got, want)
+		}
+	}
+
+	m.FilterByDevice(false)
+	for _, fp := range m.fingerprints {
+		if fp.SecChUa != "" && m.HeadersFor(fp)["Sec-Ch-Ua-Mobile"] != "?0" {
+			t.Errorf("%s desktop Sec-Ch-Ua-Mobile = %q, want ?0", fp.ID, fp.SecChUaMobile)
+		}
+	}
+}
+
 func TestDefaultTimingConfig(t *testing.T) {
 	config := DefaultTimingConfig()
 
@@ -370,27 +584,27 @@ func TestFingerprintUserAgents(t *testing.T) {
 	}
 }
 
-func TestMathFunctions(t *testing.T) {
-	// Test sqrt
-	sqrt4 := math_Sqrt(4)
-	if sqrt4 < 1.99 || sqrt4 > 2.01 {
-		t.Errorf("sqrt(4) = %v, want ~2", sqrt4)
-	}
+func TestGaussianDelayDistribution(t *testing.T) {
+	mean := 5 * time.Second
+	stddev := 1 * time.Second
+	rng := rand.New(rand.NewSource(1))
 
-	sqrt9 := math_Sqrt(9)
-	if sqrt9 < 2.99 || sqrt9 > 3.01 {
-		t.Errorf("sqrt(9) = %v, want ~3", sqrt9)
+	const samples = 20000
+	var sum, sumSq float64
+	for i := 0; i < samples; i++ {
+		d := GaussianDelay(mean, stddev, rng).Seconds()
+		sum += d
+		sumSq += d * d
 	}
 
-	// Test cos
-	cos0 := math_Cos(0)
-	if cos0 < 0.99 || cos0 > 1.01 {
-		t.Errorf("cos(0) = %v, want ~1", cos0)
-	}
+	gotMean := sum / samples
+	gotStddev := math.Sqrt(sumSq/samples - gotMean*gotMean)
 
-	cosPi := math_Cos(math_Pi)
-	if cosPi < -1.01 || cosPi > -0.99 {
-		t.Errorf("cos(pi) = %v, want ~-1", cosPi)
+	if math.Abs(gotMean-mean.Seconds()) > 0.05 {
+		t.Errorf("mean = %.3fs, want %.3fs ± 0.05", gotMean, mean.Seconds())
+	}
+	if math.Abs(gotStddev-stddev.Seconds()) > 0.05 {
+		t.Errorf("stddev = %.3fs, want %.3fs ± 0.05", gotStddev, stddev.Seconds())
 	}
 }
 
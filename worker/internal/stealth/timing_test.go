@@ -0,0 +1,149 @@
+package stealth
+
+import (
+	"testing"
+	"time"
+)
+
+// quickProfile is a profile with fixed millisecond delays
+func quickProfile() ProfileConfig {
+	return ProfileConfig{
+		Profile:         TimingNormal,
+		MinDelay:        time.Millisecond,
+		MaxDelay:        time.Millisecond,
+		BurstSize:       3,
+		BurstPause:      time.Hour,
+		SessionMaxReqs:  100,
+		SessionCooldown: 24 * time.Hour,
+		SlowdownFactor:  1,
+		CaptchaCooldown: time.Minute,
+		ErrorCooldown:   time.Second,
+		BlockCooldown:   time.Hour,
+	}
+}
+
+func TestNewTimingManagerUnknownProfile(t *testing.T) {
+	tm := NewTimingManager("bogus")
+	if tm.config.Profile != TimingNormal {
+		t.Errorf("profile = %q, want %q", tm.config.Profile, TimingNormal)
+	}
+}
+
+func TestTimingManagerWithSeedReproducible(t *testing.T) {
+	delays := func() []time.Duration {
+		tm := NewTimingManagerWithSeed(TimingNormal, 42)
+
+		var ds []time.Duration
+		for i := 0; i < 10; i++ {
+			ds = append(ds, tm.GetDelay("proxy-1"))
+			tm.RecordRequest("proxy-1")
+		}
+		return ds
+	}
+
+	first, second := delays(), delays()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("delay %d = %v and %v, want the same sequence for the same seed", i, first[i], second[i])
+		}
+	}
+}
+
+func TestValidTimingProfile(t *testing.T) {
+	for _, name := range []string{"aggressive", "normal", "cautious", "stealth"} {
+		if !ValidTimingProfile(name) {
+			t.Errorf("ValidTimingProfile(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"", "fast", "Normal"} {
+		if ValidTimingProfile(name) {
+			t.Errorf("ValidTimingProfile(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestTimingManagerBurstPause(t *testing.T) {
+	tm := NewTimingManagerWithConfig(quickProfile())
+
+	for i := 0; i < 2; i++ {
+		tm.RecordRequest("p1")
+	}
+	if delay := tm.GetDelay("p1"); delay >= time.Minute {
+		t.Errorf("GetDelay() before burst end = %v, want no pause", delay)
+	}
+
+	tm.RecordRequest("p1")
+	if delay := tm.GetDelay("p1"); delay < time.Hour {
+		t.Errorf("GetDelay() after burst = %v, want at least %v", delay, time.Hour)
+	}
+	if delay := tm.GetDelay("p1"); delay >= time.Minute {
+		t.Errorf("GetDelay() after pause = %v, want burst reset", delay)
+	}
+
+	// Other proxies keep their own sessions
+	if delay := tm.GetDelay("p2"); delay >= time.Minute {
+		t.Errorf("GetDelay(p2) = %v, want no pause", delay)
+	}
+}
+
+func TestTimingManagerSessionCap(t *testing.T) {
+	config := quickProfile()
+	config.BurstSize = 0
+	config.SessionMaxReqs = 2
+	tm := NewTimingManagerWithConfig(config)
+
+	tm.RecordRequest("p1")
+	tm.RecordRequest("p1")
+	if delay := tm.GetDelay("p1"); delay < 24*time.Hour {
+		t.Errorf("GetDelay() at session cap = %v, want at least %v", delay, 24*time.Hour)
+	}
+	if stats := tm.GetSessionStats("p1"); stats.RequestCount != 0 {
+		t.Errorf("RequestCount = %d, want 0 after session cooldown", stats.RequestCount)
+	}
+}
+
+func TestTimingManagerCooldowns(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(tm *TimingManager, id string)
+		want   time.Duration
+	}{
+		{"captcha", (*TimingManager).RecordCaptcha, time.Minute},
+		{"error", (*TimingManager).RecordError, time.Second},
+		{"block", (*TimingManager).RecordBlock, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTimingManagerWithConfig(quickProfile())
+			tt.record(tm, "p1")
+
+			if !tm.IsInCooldown("p1") {
+				t.Fatal("IsInCooldown() = false, want true")
+			}
+			if delay := tm.GetDelay("p1"); delay > tt.want || delay < tt.want-time.Second/2 {
+				t.Errorf("GetDelay() = %v, want about %v", delay, tt.want)
+			}
+			if tm.IsInCooldown("p2") {
+				t.Error("IsInCooldown(p2) = true, want false")
+			}
+		})
+	}
+}
+
+func TestTimingManagerApplyEngineCooldowns(t *testing.T) {
+	tm := NewTimingManager(TimingNormal)
+	tm.ApplyEngineCooldowns(time.Minute, 0)
+	if tm.config.CaptchaCooldown != time.Minute {
+		t.Errorf("CaptchaCooldown = %v, want %v", tm.config.CaptchaCooldown, time.Minute)
+	}
+	if want := DefaultProfileConfigs[TimingNormal].BlockCooldown; tm.config.BlockCooldown != want {
+		t.Errorf("BlockCooldown = %v, want %v", tm.config.BlockCooldown, want)
+	}
+
+	custom := NewTimingManagerWithConfig(quickProfile())
+	custom.ApplyEngineCooldowns(time.Hour, time.Minute)
+	if custom.config.CaptchaCooldown != time.Minute || custom.config.BlockCooldown != time.Hour {
+		t.Errorf("custom cooldowns = %v/%v, want unchanged", custom.config.CaptchaCooldown, custom.config.BlockCooldown)
+	}
+}
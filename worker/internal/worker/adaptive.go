@@ -0,0 +1,167 @@
+package worker
+
+import "sync"
+
+// Adaptive concurrency defaults, used when the config leaves them at zero
+const (
+	defaultAdaptiveWindow    = 20
+	defaultAdaptiveThreshold = 0.2
+)
+
+// adaptiveLimiter caps how many workers process tasks at once. It keeps
+// the outcome of the last window responses: when the share of CAPTCHAs
+// and blocks reaches threshold the limit halves, and once it drops below
+// half the threshold the limit grows back by one, up to the worker count.
+// Workers over the limit park in acquire. A nil limiter never parks.
+type adaptiveLimiter struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	limit  int // Current limit
+	max    int // Configured worker count
+	active int // Workers holding a slot
+	closed bool
+
+	// Ring of recent outcomes, true for a CAPTCHA or block
+	window    []bool
+	next      int
+	filled    int
+	flagged   int
+	threshold float64
+}
+
+// newAdaptiveLimiter creates a limiter for workers goroutines, starting
+// unthrottled
+func newAdaptiveLimiter(workers, window int, threshold float64) *adaptiveLimiter {
+	if window <= 0 {
+		window = defaultAdaptiveWindow
+	}
+	if threshold <= 0 {
+		threshold = defaultAdaptiveThreshold
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	l := &adaptiveLimiter{
+		limit:     workers,
+		max:       workers,
+		window:    make([]bool, window),
+		threshold: threshold,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until the worker may process a task. Returns false once
+// the limiter is closed.
+func (l *adaptiveLimiter) acquire() bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for !l.closed && l.active >= l.limit {
+		l.cond.Wait()
+	}
+	if l.closed {
+		return false
+	}
+	l.active++
+	return true
+}
+
+// release gives back a slot taken by acquire
+func (l *adaptiveLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// record adds one response outcome and adjusts the limit once the window
+// is full. The window restarts after every change so each step is judged
+// on fresh responses.
+func (l *adaptiveLimiter) record(flagged bool) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.filled == len(l.window) {
+		if l.window[l.next] {
+			l.flagged--
+		}
+	} else {
+		l.filled++
+	}
+	l.window[l.next] = flagged
+	l.next = (l.next + 1) % len(l.window)
+	if flagged {
+		l.flagged++
+	}
+
+	if l.filled < len(l.window) {
+		return
+	}
+
+	rate := float64(l.flagged) / float64(l.filled)
+	switch {
+	case rate >= l.threshold && l.limit > 1:
+		l.limit /= 2
+	case rate < l.threshold/2 && l.limit < l.max:
+		l.limit++
+		l.cond.Broadcast()
+	default:
+		return
+	}
+	l.reset()
+}
+
+// reset empties the outcome window (must hold lock)
+func (l *adaptiveLimiter) reset() {
+	l.next, l.filled, l.flagged = 0, 0, 0
+}
+
+// setMax follows a worker count change. An unthrottled limiter moves
+// with it; a throttled one keeps its limit unless over the new count.
+func (l *adaptiveLimiter) setMax(workers int) {
+	if l == nil || workers < 1 {
+		return
+	}
+
+	l.mu.Lock()
+	if l.limit >= l.max || l.limit > workers {
+		l.limit = workers
+	}
+	l.max = workers
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// current returns the current limit
+func (l *adaptiveLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// close wakes parked workers so they can exit
+func (l *adaptiveLimiter) close() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterThrottles(t *testing.T) {
+	l := newAdaptiveLimiter(8, 4, 0.5)
+
+	// One block in four stays under the threshold
+	for _, flagged := range []bool{false, true, false, false} {
+		l.record(flagged)
+	}
+	if got := l.current(); got != 8 {
+		t.Errorf("limit after 1/4 blocked = %d, want 8", got)
+	}
+
+	// The window slides: two of the last four reach it
+	l.record(true)
+	if got := l.current(); got != 4 {
+		t.Errorf("limit after 2/4 blocked = %d, want 4", got)
+	}
+
+	for i := 0; i < 8; i++ {
+		l.record(true)
+	}
+	if got := l.current(); got != 1 {
+		t.Errorf("limit after sustained blocks = %d, want 1", got)
+	}
+	for i := 0; i < 4; i++ {
+		l.record(true)
+	}
+	if got := l.current(); got != 1 {
+		t.Errorf("limit = %d, want floor of 1", got)
+	}
+
+	// Clean windows ramp back up one worker at a time
+	for i := 0; i < 4; i++ {
+		l.record(false)
+	}
+	if got := l.current(); got != 2 {
+		t.Errorf("limit after clean window = %d, want 2", got)
+	}
+	for i := 0; i < 40; i++ {
+		l.record(false)
+	}
+	if got := l.current(); got != 8 {
+		t.Errorf("limit after recovery = %d, want 8", got)
+	}
+}
+
+func TestAdaptiveLimiterParks(t *testing.T) {
+	l := newAdaptiveLimiter(2, 2, 0.5)
+	l.record(true)
+	l.record(true)
+
+	if !l.acquire() {
+		t.Fatal("acquire() = false, want true")
+	}
+
+	acquired := make(chan bool, 1)
+	go func() { acquired <- l.acquire() }()
+
+	select {
+	case <-acquired:
+		t.Fatal("second acquire() should park at limit 1")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.release()
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Error("acquire() = false after release, want true")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("parked worker not woken by release")
+	}
+
+	go func() { acquired <- l.acquire() }()
+	l.close()
+	select {
+	case ok := <-acquired:
+		if ok {
+			t.Error("acquire() = true after close, want false")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("parked worker not woken by close")
+	}
+}
+
+func TestAdaptiveLimiterSetMax(t *testing.T) {
+	l := newAdaptiveLimiter(4, 2, 0.5)
+	l.setMax(6)
+	if got := l.current(); got != 6 {
+		t.Errorf("unthrottled limit = %d, want 6", got)
+	}
+
+	l.record(true)
+	l.record(true)
+	l.setMax(8)
+	if got := l.current(); got != 3 {
+		t.Errorf("throttled limit = %d, want 3", got)
+	}
+	l.setMax(2)
+	if got := l.current(); got != 2 {
+		t.Errorf("limit over new worker count = %d, want 2", got)
+	}
+}
+
+func TestAdaptiveLimiterNil(t *testing.T) {
+	var l *adaptiveLimiter
+	if !l.acquire() {
+		t.Error("nil acquire() = false, want true")
+	}
+	l.record(true)
+	l.release()
+	l.setMax(4)
+	l.close()
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// errBodyTooLarge fails a response past Config.MaxBodyBytes
+var errBodyTooLarge = errors.New("response body too large")
+
+// readBody reads and decodes a response body, returning the bytes that
+// came over the connection and the decoded size. The worker sends its own
+// Accept-Encoding, so the transport leaves decompression to us. A body
+// decoding to more than limit bytes (0 = no limit) fails with
+// errBodyTooLarge once the limit is passed, without reading the rest.
+func readBody(resp *http.Response, limit int64) (body []byte, wireBytes int64, err error) {
+	if limit > 0 && resp.ContentLength > limit {
+		return nil, 0, fmt.Errorf("%w: %d bytes, limit %d", errBodyTooLarge, resp.ContentLength, limit)
+	}
+	wire := &countingReader{r: resp.Body}
+
+	decoded, err := decodeBody(wire, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, wire.n, err
+	}
+
+	if limit > 0 {
+		decoded = io.LimitReader(decoded, limit+1)
+	}
+	body, err = io.ReadAll(decoded)
+	if err == nil && limit > 0 && int64(len(body)) > limit {
+		body, err = nil, fmt.Errorf("%w: over %d bytes", errBodyTooLarge, limit)
+	}
+	if resp.Uncompressed {
+		// The transport already decoded it; the wire size is unknown
+		return body, int64(len(body)), err
+	}
+	return body, wire.n, err
+}
+
+// decodeBody wraps r in a decoder for the given Content-Encoding
+func decodeBody(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return r, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// Servers disagree on whether deflate is zlib-wrapped
+		br := bufio.NewReader(r)
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return brotli.NewReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// isZlibHeader reports whether b starts a zlib stream (RFC 1950)
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
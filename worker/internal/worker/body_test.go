@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, encoding, text string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		buf.WriteString(text)
+		return buf.Bytes()
+	}
+	io.WriteString(w, text)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestReadBody(t *testing.T) {
+	html := strings.Repeat("<div>https://a.example.com/admin</div>\n", 200)
+
+	tests := []struct {
+		encoding string
+		header   string
+	}{
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"raw-deflate", "deflate"},
+		{"br", "br"},
+		{"identity", ""},
+	}
+
+	for _, tt := range tests {
+		wire := compress(t, tt.encoding, html)
+		resp := &http.Response{
+			Header: http.Header{"Content-Encoding": {tt.header}},
+			Body:   io.NopCloser(bytes.NewReader(wire)),
+		}
+
+		body, wireBytes, err := readBody(resp, 0)
+		if err != nil {
+			t.Errorf("%s: readBody() error = %v", tt.encoding, err)
+			continue
+		}
+		if string(body) != html {
+			t.Errorf("%s: body was not decoded", tt.encoding)
+		}
+		if wireBytes != int64(len(wire)) {
+			t.Errorf("%s: wireBytes = %d, want %d", tt.encoding, wireBytes, len(wire))
+		}
+	}
+
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"zstd"}},
+		Body:   io.NopCloser(strings.NewReader("x")),
+	}
+	if _, _, err := readBody(resp, 0); err == nil {
+		t.Error("readBody() with an unknown encoding should fail")
+	}
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		size     int
+		tooLarge bool
+	}{
+		{"under", "identity", 999, false},
+		{"at limit", "identity", 1000, false},
+		{"over", "identity", 1001, true},
+		{"gzip under", "gzip", 1000, false},
+		{"gzip bomb", "gzip", 1 << 20, true},
+	}
+
+	for _, tt := range tests {
+		wire := compress(t, tt.encoding, strings.Repeat("a", tt.size))
+		header := http.Header{}
+		if tt.encoding != "identity" {
+			header.Set("Content-Encoding", tt.encoding)
+		}
+		resp := &http.Response{Header: header, Body: io.NopCloser(bytes.NewReader(wire)), ContentLength: -1}
+
+		body, _, err := readBody(resp, 1000)
+		if got := errors.Is(err, errBodyTooLarge); got != tt.tooLarge {
+			t.Errorf("%s: readBody() error = %v, want too large %v", tt.name, err, tt.tooLarge)
+		}
+		if !tt.tooLarge && len(body) != tt.size {
+			t.Errorf("%s: len(body) = %d, want %d", tt.name, len(body), tt.size)
+		}
+	}
+
+	// A declared length over the limit fails before reading
+	resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(unreadBody{}), ContentLength: 1001}
+	if _, wireBytes, err := readBody(resp, 1000); !errors.Is(err, errBodyTooLarge) || wireBytes != 0 {
+		t.Errorf("readBody() with Content-Length over the limit = %d, %v, want too large unread", wireBytes, err)
+	}
+}
+
+// unreadBody fails any read, for bodies that must not be read
+type unreadBody struct{}
+
+func (unreadBody) Read(p []byte) (int, error) { return 0, errors.New("body should not be read") }
+
+func TestWorkerMaxBodyBytes(t *testing.T) {
+	// Streams until the client gives up, or far past any sane page
+	chunk := bytes.Repeat([]byte("https://a.example.com/admin\n"), 1024)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		for sent := 0; sent < 1<<30; sent += len(chunk) {
+			if _, err := rw.Write(chunk); err != nil {
+				return
+			}
+		}
+	})
+	w.config.MaxBodyBytes = 64 << 10
+
+	result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{})
+	if result.Status != StatusError || !strings.Contains(result.Error, "too large") {
+		t.Fatalf("result = %s (%s), want a too large error", result.Status, result.Error)
+	}
+	if p, _ := w.pool.GetByID("mock"); p.FailCount != 1 {
+		t.Errorf("FailCount = %d, want 1", p.FailCount)
+	}
+
+	// Reading stopped just past the limit
+	if stats := w.Stats(); stats.WireBytes > 2*w.config.MaxBodyBytes || stats.DecodedBytes != 0 {
+		t.Errorf("WireBytes = %d, DecodedBytes = %d, want reading stopped near %d", stats.WireBytes, stats.DecodedBytes, w.config.MaxBodyBytes)
+	}
+}
+
+func TestWorkerByteStats(t *testing.T) {
+	html := strings.Repeat("https://a.example.com/admin\n", 500)
+	wire := compress(t, "gzip", html)
+
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Encoding", "gzip")
+		rw.Write(wire)
+	})
+
+	result, err := w.SearchOnce(context.Background(), "inurl:admin", 0)
+	if err != nil || result.Status != StatusSuccess {
+		t.Fatalf("SearchOnce() = %+v, %v, want success", result, err)
+	}
+
+	stats := w.Stats()
+	if stats.WireBytes != int64(len(wire)) {
+		t.Errorf("WireBytes = %d, want %d", stats.WireBytes, len(wire))
+	}
+	if stats.DecodedBytes != int64(len(html)) {
+		t.Errorf("DecodedBytes = %d, want %d", stats.DecodedBytes, len(html))
+	}
+}
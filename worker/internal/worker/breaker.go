@@ -0,0 +1,251 @@
+package worker
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker defaults, used when the config leaves them at zero
+const (
+	defaultBreakerWindow    = 50
+	defaultBreakerThreshold = 0.8
+	defaultBreakerCooldown  = 5 * time.Minute
+	defaultBreakerProbes    = 3
+)
+
+// BreakerState is the state of the circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every task through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen holds every task until the cooldown ends
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a few probe tasks through at once to test
+	// whether requests get through again
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// circuitBreaker halts dispatch when nearly every request is flagged, so
+// a ban wave does not burn through the whole proxy pool. It keeps the
+// outcome of the last window responses like adaptiveLimiter; when the
+// share of CAPTCHAs and blocks reaches threshold it opens and workers park
+// in acquire for the cooldown. It then half-opens: probes workers may run
+// at once, probes clean responses in a row close it and a flagged one
+// opens it again. A nil breaker never parks.
+type circuitBreaker struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	state    BreakerState
+	cooldown time.Duration
+	timer    *time.Timer // Half-opens the breaker at until
+	until    time.Time
+	trips    int64
+	stopped  bool
+	onChange func(state BreakerState, until time.Time)
+
+	// Half-open probing. gen tells apart probes of different half-open
+	// periods, so a late release cannot free a slot of the next one.
+	probes  int
+	probing int
+	passed  int
+	gen     int
+
+	// Ring of recent outcomes, true for a CAPTCHA or block
+	window    []bool
+	next      int
+	filled    int
+	flagged   int
+	threshold float64
+}
+
+// newCircuitBreaker creates a closed breaker
+func newCircuitBreaker(window int, threshold float64, cooldown time.Duration, probes int) *circuitBreaker {
+	if window <= 0 {
+		window = defaultBreakerWindow
+	}
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	if probes <= 0 {
+		probes = defaultBreakerProbes
+	}
+
+	b := &circuitBreaker{
+		state:     BreakerClosed,
+		cooldown:  cooldown,
+		probes:    probes,
+		window:    make([]bool, window),
+		threshold: threshold,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire blocks while the breaker is open, or half-open with every probe
+// slot taken. It returns a probe ticket to hand to release (0 = not a
+// probe), and false once the breaker is stopped.
+func (b *circuitBreaker) acquire() (int, bool) {
+	if b == nil {
+		return 0, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for !b.stopped {
+		switch {
+		case b.state == BreakerClosed:
+			return 0, true
+		case b.state == BreakerHalfOpen && b.probing < b.probes:
+			b.probing++
+			return b.gen, true
+		}
+		b.cond.Wait()
+	}
+	return 0, false
+}
+
+// release frees the probe slot taken by acquire
+func (b *circuitBreaker) release(ticket int) {
+	if b == nil || ticket == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	if ticket == b.gen && b.state == BreakerHalfOpen {
+		b.probing--
+	}
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// record adds one response outcome. Closed, the breaker trips once the
+// window is full and the flagged share reaches threshold; half-open, the
+// outcome decides whether it closes or opens again. Responses arriving
+// while open were sent before the trip and are ignored.
+func (b *circuitBreaker) record(flagged bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	changed := false
+	switch b.state {
+	case BreakerClosed:
+		b.push(flagged)
+		if b.filled == len(b.window) && float64(b.flagged)/float64(b.filled) >= b.threshold {
+			b.trip()
+			changed = true
+		}
+	case BreakerHalfOpen:
+		if flagged {
+			b.trip()
+			changed = true
+			break
+		}
+		b.passed++
+		if b.passed >= b.probes {
+			b.state = BreakerClosed
+			b.reset()
+			b.cond.Broadcast()
+			changed = true
+		}
+	}
+	b.mu.Unlock()
+
+	if changed {
+		b.notify()
+	}
+}
+
+// push adds an outcome to the window (must hold lock)
+func (b *circuitBreaker) push(flagged bool) {
+	if b.filled == len(b.window) {
+		if b.window[b.next] {
+			b.flagged--
+		}
+	} else {
+		b.filled++
+	}
+	b.window[b.next] = flagged
+	b.next = (b.next + 1) % len(b.window)
+	if flagged {
+		b.flagged++
+	}
+}
+
+// reset empties the outcome window (must hold lock)
+func (b *circuitBreaker) reset() {
+	b.next, b.filled, b.flagged = 0, 0, 0
+}
+
+// trip opens the breaker for the cooldown (must hold lock)
+func (b *circuitBreaker) trip() {
+	b.state = BreakerOpen
+	b.trips++
+	b.reset()
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.until = time.Now().Add(b.cooldown)
+	b.timer = time.AfterFunc(b.cooldown, b.halfOpen)
+}
+
+// halfOpen ends the cooldown and lets the first probes through
+func (b *circuitBreaker) halfOpen() {
+	b.mu.Lock()
+	if b.stopped || b.state != BreakerOpen {
+		b.mu.Unlock()
+		return
+	}
+	b.state = BreakerHalfOpen
+	b.gen++
+	b.probing, b.passed = 0, 0
+	b.mu.Unlock()
+	b.cond.Broadcast()
+
+	b.notify()
+}
+
+// notify reports the current state to the callback, with when the
+// cooldown of an open breaker ends
+func (b *circuitBreaker) notify() {
+	b.mu.Lock()
+	state, until, fn := b.state, b.until, b.onChange
+	b.mu.Unlock()
+
+	if state != BreakerOpen {
+		until = time.Time{}
+	}
+
+	if fn != nil {
+		fn(state, until)
+	}
+}
+
+// current returns the breaker's state and how many times it tripped
+func (b *circuitBreaker) current() (BreakerState, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.trips
+}
+
+// stop wakes parked workers so they can exit
+func (b *circuitBreaker) stop() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.stopped = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAndRecovers(t *testing.T) {
+	b := newCircuitBreaker(4, 0.75, 50*time.Millisecond, 2)
+
+	// Two blocks in four stay under the threshold
+	for _, flagged := range []bool{false, true, false, true} {
+		b.record(flagged)
+	}
+	if state, _ := b.current(); state != BreakerClosed {
+		t.Errorf("state after 2/4 blocked = %s, want closed", state)
+	}
+
+	// The window slides: three of the last four trip it
+	b.record(true)
+	if state, trips := b.current(); state != BreakerOpen || trips != 1 {
+		t.Fatalf("state after 3/4 blocked = %s with %d trips, want open with 1", state, trips)
+	}
+
+	// Workers park until the cooldown ends, then probes go through one
+	// slot at a time
+	start := time.Now()
+	probe, ok := b.acquire()
+	if !ok || probe == 0 {
+		t.Fatalf("acquire() = %d, %v, want a probe ticket", probe, ok)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("acquire() returned after %v, want the 50ms cooldown", waited)
+	}
+	if state, _ := b.current(); state != BreakerHalfOpen {
+		t.Errorf("state after the cooldown = %s, want half_open", state)
+	}
+	second, _ := b.acquire()
+
+	acquired := make(chan int, 1)
+	go func() {
+		ticket, _ := b.acquire()
+		acquired <- ticket
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("third acquire() should park with both probe slots taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A flagged probe opens the breaker again
+	b.record(true)
+	if state, trips := b.current(); state != BreakerOpen || trips != 2 {
+		t.Fatalf("state after a blocked probe = %s with %d trips, want open with 2", state, trips)
+	}
+	b.release(probe)
+	b.release(second)
+
+	// Clean probes close it
+	select {
+	case probe = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("parked worker not let through after the second cooldown")
+	}
+	b.record(false)
+	b.record(false)
+	if state, _ := b.current(); state != BreakerClosed {
+		t.Errorf("state after clean probes = %s, want closed", state)
+	}
+	b.release(probe)
+
+	if ticket, ok := b.acquire(); !ok || ticket != 0 {
+		t.Errorf("acquire() once closed = %d, %v, want 0, true", ticket, ok)
+	}
+}
+
+func TestCircuitBreakerStop(t *testing.T) {
+	b := newCircuitBreaker(1, 1, time.Hour, 1)
+	b.record(true)
+
+	acquired := make(chan bool, 1)
+	go func() {
+		_, ok := b.acquire()
+		acquired <- ok
+	}()
+
+	b.stop()
+	select {
+	case ok := <-acquired:
+		if ok {
+			t.Error("acquire() = true after stop, want false")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("parked worker not woken by stop")
+	}
+}
+
+func TestCircuitBreakerNil(t *testing.T) {
+	var b *circuitBreaker
+	if ticket, ok := b.acquire(); !ok || ticket != 0 {
+		t.Errorf("nil acquire() = %d, %v, want 0, true", ticket, ok)
+	}
+	b.record(true)
+	b.release(0)
+	b.stop()
+}
@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"dorker/worker/internal/engine"
+	"dorker/worker/internal/proxy"
+)
+
+// CaptchaSolver solves the CAPTCHA on a page, e.g. through a 2captcha or
+// anti-captcha account. Solve is given the CAPTCHA page and the URL it was
+// served from (e.g. Google's /sorry/ page), and returns the response token
+// to submit.
+type CaptchaSolver interface {
+	Solve(ctx context.Context, html, pageURL string) (token string, err error)
+}
+
+// captchaTokenParam carries a solved token in the CAPTCHA form, the
+// field reCAPTCHA fills in
+const captchaTokenParam = "g-recaptcha-response"
+
+// SetCaptchaSolver makes the worker solve CAPTCHAs with s before giving
+// up on the proxy; nil (the default) rotates to another proxy straight
+// away. Set it before Start.
+func (w *Worker) SetCaptchaSolver(s CaptchaSolver) {
+	w.captchaSolver = s
+}
+
+// solveCaptcha solves the CAPTCHA html served from pageURL (Google's
+// /sorry/ page) and submits its form through the same proxy with the
+// token, as a browser would, returning the page that leads to and its
+// status code. It reports false when no solver is set, solving fails or
+// the engine still answers with a CAPTCHA.
+func (w *Worker) solveCaptcha(ctx context.Context, e engine.SearchEngine, prx *proxy.Proxy, pageURL, html string) (string, int, bool) {
+	if w.captchaSolver == nil {
+		return "", 0, false
+	}
+
+	token, err := w.captchaSolver.Solve(ctx, html, pageURL)
+	if err != nil || token == "" {
+		return "", 0, false
+	}
+
+	action, fields, err := captchaForm(pageURL, html)
+	if err != nil {
+		return "", 0, false
+	}
+	fields.Set(captchaTokenParam, token)
+
+	// Posted from the CAPTCHA page; Google redirects to the "continue"
+	// URL once the token checks out
+	solved, err := w.fetch(ctx, action, pageURL, fields, prx, nil)
+	if err != nil || e.DetectCaptcha(solved.html) {
+		return "", solved.statusCode, false
+	}
+	return solved.html, solved.statusCode, true
+}
+
+// captchaForm finds the form the CAPTCHA page at pageURL submits its
+// token with, returning where it posts to and its hidden fields (the
+// "q" and "continue" of Google's /sorry/ page). Without a form it posts
+// back to the page with the "q" and "continue" from pageURL's query.
+func captchaForm(pageURL, body string) (string, url.Values, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fields := url.Values{}
+	var action string
+	found, inForm := false, false
+	z := html.NewTokenizer(strings.NewReader(body))
+	for !found {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// An unclosed form still counts
+			found = inForm && len(fields) > 0
+			break
+		}
+		tok := z.Token()
+		switch {
+		case tok.DataAtom == atom.Form && tt == html.EndTagToken:
+			// The first form with hidden fields is the CAPTCHA's
+			found = inForm && len(fields) > 0
+			inForm = false
+		case tok.DataAtom == atom.Form:
+			inForm, action, fields = true, attr(tok, "action"), url.Values{}
+		case inForm && tok.DataAtom == atom.Input && strings.EqualFold(attr(tok, "type"), "hidden"):
+			if name := attr(tok, "name"); name != "" {
+				fields.Add(name, attr(tok, "value"))
+			}
+		}
+	}
+
+	if !found {
+		action = ""
+		fields = url.Values{}
+		for _, key := range []string{"q", "continue"} {
+			if v := base.Query().Get(key); v != "" {
+				fields.Set(key, v)
+			}
+		}
+	}
+
+	target, err := base.Parse(action)
+	if err != nil {
+		return "", nil, err
+	}
+	if !found {
+		target.RawQuery = ""
+	}
+	return target.String(), fields, nil
+}
+
+// attr returns the value of tok's attribute key, empty when unset
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
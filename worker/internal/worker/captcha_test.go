@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSolver answers every CAPTCHA with a canned token, or err
+type fakeSolver struct {
+	token string
+	err   error
+
+	mu    sync.Mutex
+	pages []string // URL and page of each call
+}
+
+func (s *fakeSolver) Solve(ctx context.Context, html, pageURL string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages = append(s.pages, pageURL+" "+html)
+	return s.token, s.err
+}
+
+// sorryPage is a CAPTCHA page in the shape of Google's /sorry/index
+const sorryPage = `<html><body>please solve the captcha
+<form id="captcha-form" action="index" method="post">
+<div class="g-recaptcha" data-sitekey="key"></div>
+<input type='hidden' name='q' value='EgQKAAAB'><input type="hidden" name="continue" value="%s">
+</form></body></html>`
+
+// newCaptchaWorker returns a worker whose engine, like Google, redirects
+// searches to a /sorry/ CAPTCHA page until its form is posted with the
+// token "tok-123"
+func newCaptchaWorker(t *testing.T) (*Worker, *[]string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []string
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		req := r.Method + " " + r.URL.String() + " referer=" + r.Header.Get("Referer")
+		if r.Method == http.MethodPost {
+			req += " form=" + r.PostForm.Encode()
+		}
+		requests = append(requests, req)
+		mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/sorry/index" && r.Method == http.MethodPost:
+			if r.PostForm.Get("g-recaptcha-response") != "tok-123" || r.PostForm.Get("q") != "EgQKAAAB" {
+				rw.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprintf(rw, sorryPage, r.PostForm.Get("continue"))
+				return
+			}
+			http.SetCookie(rw, &http.Cookie{Name: "GOOGLE_ABUSE_EXEMPTION", Value: "ok", Path: "/"})
+			http.Redirect(rw, r, r.PostForm.Get("continue"), http.StatusFound)
+		case r.URL.Path == "/sorry/index":
+			// Google serves its CAPTCHA page with a 429
+			rw.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintf(rw, sorryPage, r.URL.Query().Get("continue"))
+		default:
+			if _, err := r.Cookie("GOOGLE_ABUSE_EXEMPTION"); err != nil {
+				sorry := url.Values{"continue": {r.URL.String()}, "q": {"EgQKAAAB"}}
+				http.Redirect(rw, r, "/sorry/index?"+sorry.Encode(), http.StatusFound)
+				return
+			}
+			fmt.Fprint(rw, "https://a.example.com/admin\n")
+		}
+	})
+	return w, &requests
+}
+
+func TestWorkerCaptchaSolver(t *testing.T) {
+	w, requests := newCaptchaWorker(t)
+	solver := &fakeSolver{token: "tok-123"}
+	w.SetCaptchaSolver(solver)
+
+	task := &Task{ID: "t", Dork: "inurl:admin"}
+	result, retryable := w.executeOn(context.Background(), task, mockEngine{})
+	if result.Status != StatusSuccess || retryable || len(result.URLs) != 1 {
+		t.Fatalf("executeOn() = %s (%s), retryable %v, want the solved page's results", result.Status, result.Error, retryable)
+	}
+
+	// The solver gets the /sorry/ page the search was redirected to
+	searchURL := w.buildSearchURL(mockEngine{}, task, "")
+	sorryURL := "http://search.test/sorry/index?" + url.Values{"continue": {searchURL}, "q": {"EgQKAAAB"}}.Encode()
+	if len(solver.pages) != 1 || solver.pages[0] != sorryURL+" "+fmt.Sprintf(sorryPage, searchURL) {
+		t.Errorf("solver calls = %q, want the CAPTCHA page at %s once", solver.pages, sorryURL)
+	}
+
+	// The token is posted with the form's fields through the same proxy
+	// from the CAPTCHA page, which sends it back to the search
+	form := url.Values{"continue": {searchURL}, "g-recaptcha-response": {"tok-123"}, "q": {"EgQKAAAB"}}
+	want := []string{
+		"GET " + searchURL + " referer=",
+		"GET " + sorryURL + " referer=" + searchURL,
+		"POST http://search.test/sorry/index referer=" + sorryURL + " form=" + form.Encode(),
+		"GET " + searchURL + " referer=" + sorryURL,
+	}
+	if strings.Join(*requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests =\n%s\nwant\n%s", strings.Join(*requests, "\n"), strings.Join(want, "\n"))
+	}
+
+	if p, _ := w.pool.GetByID("mock"); p.CaptchaCount != 0 {
+		t.Errorf("CaptchaCount = %d, want 0 for a solved CAPTCHA", p.CaptchaCount)
+	}
+	if stats := w.Stats(); stats.CaptchaCount != 0 {
+		t.Errorf("Stats().CaptchaCount = %d, want 0", stats.CaptchaCount)
+	}
+}
+
+func TestCaptchaForm(t *testing.T) {
+	tests := []struct {
+		name       string
+		pageURL    string
+		body       string
+		wantAction string
+		wantFields url.Values
+	}{
+		{
+			name:       "sorry form",
+			pageURL:    "https://www.google.com/sorry/index?continue=x&q=y",
+			body:       fmt.Sprintf(sorryPage, "https://www.google.com/search?q=a"),
+			wantAction: "https://www.google.com/sorry/index",
+			wantFields: url.Values{"q": {"EgQKAAAB"}, "continue": {"https://www.google.com/search?q=a"}},
+		},
+		{
+			name:       "skips forms without hidden fields",
+			pageURL:    "https://www.google.com/sorry/index",
+			body:       `<form action="/search"><input name="q"></form><form action="/verify" method="post"><input type="hidden" name="continue" value="c">`,
+			wantAction: "https://www.google.com/verify",
+			wantFields: url.Values{"continue": {"c"}},
+		},
+		{
+			name:       "no form",
+			pageURL:    "https://www.google.com/sorry/index?continue=https%3A%2F%2Fwww.google.com%2Fsearch&q=EgQ&hl=en",
+			body:       "please solve the captcha",
+			wantAction: "https://www.google.com/sorry/index",
+			wantFields: url.Values{"q": {"EgQ"}, "continue": {"https://www.google.com/search"}},
+		},
+	}
+
+	for _, tt := range tests {
+		action, fields, err := captchaForm(tt.pageURL, tt.body)
+		if err != nil {
+			t.Errorf("%s: captchaForm() error = %v", tt.name, err)
+			continue
+		}
+		if action != tt.wantAction || fields.Encode() != tt.wantFields.Encode() {
+			t.Errorf("%s: captchaForm() = %s %v, want %s %v", tt.name, action, fields, tt.wantAction, tt.wantFields)
+		}
+	}
+}
+
+func TestWorkerCaptchaSolverFails(t *testing.T) {
+	tests := []struct {
+		name   string
+		solver *fakeSolver
+	}{
+		{"error", &fakeSolver{err: errors.New("no balance")}},
+		{"wrong token", &fakeSolver{token: "tok-999"}},
+	}
+
+	for _, tt := range tests {
+		w, _ := newCaptchaWorker(t)
+		w.SetCaptchaSolver(tt.solver)
+
+		// Falls back to rotating proxies
+		result, retryable := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{})
+		if result.Status != StatusCaptcha || !retryable {
+			t.Errorf("%s: executeOn() = %s, retryable %v, want a retryable CAPTCHA", tt.name, result.Status, retryable)
+		}
+		if p, _ := w.pool.GetByID("mock"); p.CaptchaCount != 1 {
+			t.Errorf("%s: CaptchaCount = %d, want 1", tt.name, p.CaptchaCount)
+		}
+	}
+}
+
+func TestWorkerWithoutCaptchaSolver(t *testing.T) {
+	w, requests := newCaptchaWorker(t)
+
+	// Only the search and the /sorry/ page it redirects to
+	result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{})
+	if result.Status != StatusCaptcha || len(*requests) != 2 {
+		t.Errorf("executeOn() = %s after %d requests, want a CAPTCHA after 2", result.Status, len(*requests))
+	}
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// checkpointVersion is bumped when the checkpoint layout changes
+// incompatibly
+const checkpointVersion = 1
+
+// checkpoint is the on-disk form of a run's progress
+type checkpoint struct {
+	Version   int       `json:"version"`
+	SavedAt   time.Time `json:"saved_at"`
+	Completed []string  `json:"completed"` // Task IDs that succeeded
+	Failed    []string  `json:"failed"`    // Task IDs that failed for good
+	Pending   []*Task   `json:"pending"`   // Queued tasks, retries first
+	Stats     Stats     `json:"stats"`
+}
+
+// SaveCheckpoint writes the IDs of finished tasks, the queued tasks and
+// the cumulative stats to path as JSON, replacing the file atomically.
+// Tasks running at that moment are in neither list; a resumed run
+// submits them again.
+func (w *Worker) SaveCheckpoint(path string) error {
+	cp := checkpoint{
+		Version: checkpointVersion,
+		SavedAt: time.Now(),
+		Pending: w.tasks.snapshot(),
+	}
+
+	// Finished IDs and the counters they feed are read together so a
+	// task finishing meanwhile is in both or neither
+	w.finishedMu.Lock()
+	for id, completed := range w.finished {
+		if completed {
+			cp.Completed = append(cp.Completed, id)
+		} else {
+			cp.Failed = append(cp.Failed, id)
+		}
+	}
+	cp.Stats = w.Stats()
+	w.finishedMu.Unlock()
+
+	sort.Strings(cp.Completed)
+	sort.Strings(cp.Failed)
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCheckpoint restores the finished task IDs and cumulative stats
+// saved by SaveCheckpoint and returns the tasks that were queued. Call it
+// before Start and skip tasks for which Finished reports true when
+// resubmitting, so nothing is counted twice. Unique domain and dedup
+// state is not restored. A missing file returns an error satisfying
+// errors.Is(err, os.ErrNotExist).
+func (w *Worker) LoadCheckpoint(path string) ([]*Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", cp.Version)
+	}
+
+	w.finishedMu.Lock()
+	defer w.finishedMu.Unlock()
+
+	// Cancelled tasks count as failed but are not finished, so they run
+	// again and are counted then
+	failed := cp.Stats.TasksFailed - cp.Stats.TasksCancelled
+
+	for _, id := range cp.Failed {
+		w.finished[id] = false
+	}
+	for _, id := range cp.Completed {
+		w.finished[id] = true
+	}
+
+	// Only finished tasks count towards the total; the rest are
+	// counted again when resubmitted
+	atomic.StoreInt64(&w.stats.TasksCompleted, cp.Stats.TasksCompleted)
+	atomic.StoreInt64(&w.stats.TasksFailed, failed)
+	atomic.StoreInt64(&w.stats.TasksTotal, cp.Stats.TasksCompleted+failed)
+	atomic.StoreInt64(&w.stats.URLsFound, cp.Stats.URLsFound)
+	atomic.StoreInt64(&w.stats.URLsFiltered, cp.Stats.URLsFiltered)
+	atomic.StoreInt64(&w.stats.CaptchaCount, cp.Stats.CaptchaCount)
+	atomic.StoreInt64(&w.stats.BlockCount, cp.Stats.BlockCount)
+	atomic.StoreInt64(&w.stats.WireBytes, cp.Stats.WireBytes)
+	atomic.StoreInt64(&w.stats.DecodedBytes, cp.Stats.DecodedBytes)
+	w.priorElapsed = cp.Stats.TotalDuration
+
+	return cp.Pending, nil
+}
+
+// Finished reports whether a task with this ID has completed or failed
+// for good, in this run or one restored by LoadCheckpoint. Cancelled
+// tasks are not finished.
+func (w *Worker) Finished(taskID string) bool {
+	w.finishedMu.Lock()
+	defer w.finishedMu.Unlock()
+
+	_, ok := w.finished[taskID]
+	return ok
+}
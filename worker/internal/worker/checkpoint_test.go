@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dorker/worker/internal/proxy"
+)
+
+func TestWorkerSaveLoadCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	config := DefaultConfig()
+	config.Workers = 0
+	src := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+	src.running.Store(true)
+
+	for _, id := range []string{"done", "failed", "queued", "cancelled"} {
+		if err := src.Submit(&Task{ID: id, Dork: id}); err != nil {
+			t.Fatalf("Submit(%s) error = %v", id, err)
+		}
+	}
+	src.tasks.remove("cancelled")
+	src.sendCancelled(&Task{ID: "cancelled", Dork: "cancelled"})
+	for _, status := range []ResultStatus{StatusSuccess, StatusError} {
+		task, _ := src.tasks.pop()
+		src.recordResult(&Result{TaskID: task.ID, Dork: task.Dork, Status: status})
+		src.tasks.finish()
+	}
+	src.stats.URLsFound = 7
+
+	if err := src.SaveCheckpoint(path); err != nil {
+		t.Fatalf("SaveCheckpoint() error = %v", err)
+	}
+
+	dst := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+	pending, err := dst.LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+
+	if len(pending) != 1 || pending[0].ID != "queued" {
+		t.Errorf("pending = %v, want [queued]", pending)
+	}
+
+	for id, want := range map[string]bool{"done": true, "failed": true, "queued": false, "cancelled": false} {
+		if got := dst.Finished(id); got != want {
+			t.Errorf("Finished(%s) = %v, want %v", id, got, want)
+		}
+	}
+
+	// The cancelled task runs again, so only finished tasks are counted
+	stats := dst.Stats()
+	if stats.TasksTotal != 2 || stats.TasksCompleted != 1 || stats.TasksFailed != 1 {
+		t.Errorf("total/completed/failed = %d/%d/%d, want 2/1/1",
+			stats.TasksTotal, stats.TasksCompleted, stats.TasksFailed)
+	}
+	if stats.URLsFound != 7 {
+		t.Errorf("URLsFound = %d, want 7", stats.URLsFound)
+	}
+}
+
+func TestWorkerRetryAfterStopResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	config := DefaultConfig()
+	config.Workers = 0
+	src := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+	src.running.Store(true)
+
+	if err := src.Submit(&Task{ID: "retry", Dork: "retry"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	task, _ := src.tasks.pop()
+
+	// Stop closes the queue while the retry delay runs
+	src.tasks.close()
+	src.retryTask(context.Background(), task, 0)
+	src.tasks.finish()
+
+	if result := <-src.results; result.Status != StatusCancelled {
+		t.Errorf("result status = %s, want %s", result.Status, StatusCancelled)
+	}
+	if err := src.SaveCheckpoint(path); err != nil {
+		t.Fatalf("SaveCheckpoint() error = %v", err)
+	}
+
+	dst := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+	if _, err := dst.LoadCheckpoint(path); err != nil {
+		t.Fatalf("LoadCheckpoint() error = %v", err)
+	}
+	if dst.Finished("retry") {
+		t.Error("Finished(retry) = true, want it to run again")
+	}
+	if stats := dst.Stats(); stats.TasksTotal != 0 || stats.TasksFailed != 0 {
+		t.Errorf("total/failed = %d/%d, want 0/0", stats.TasksTotal, stats.TasksFailed)
+	}
+}
+
+func TestWorkerLoadCheckpointErrors(t *testing.T) {
+	dir := t.TempDir()
+	w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+
+	if _, err := w.LoadCheckpoint(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadCheckpoint(missing) error = %v, want os.ErrNotExist", err)
+	}
+
+	path := filepath.Join(dir, "future.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.LoadCheckpoint(path); err == nil {
+		t.Error("LoadCheckpoint(unknown version) should fail")
+	}
+}
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+
+	"dorker/worker/internal/engine"
+)
+
+// cookieJars keeps one cookie jar per proxy, so cookies an engine sets
+// are sent back from the same exit the way a browser would
+type cookieJars struct {
+	mu   sync.Mutex
+	jars map[string]http.CookieJar
+}
+
+func newCookieJars() *cookieJars {
+	return &cookieJars{jars: make(map[string]http.CookieJar)}
+}
+
+// get returns the jar for a proxy ID, creating it on first use
+func (c *cookieJars) get(proxyID string) http.CookieJar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jar, ok := c.jars[proxyID]
+	if !ok {
+		jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		c.jars[proxyID] = jar
+	}
+	return jar
+}
+
+// clear drops the jar for a proxy ID
+func (c *cookieJars) clear(proxyID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.jars, proxyID)
+}
+
+// seed gives a proxy the engine's first-contact cookies for targetURL
+// when its jar holds none for that site yet
+func (c *cookieJars) seed(proxyID string, e engine.SearchEngine, targetURL string) {
+	seeder, ok := e.(engine.CookieSeeder)
+	if !ok {
+		return
+	}
+
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return
+	}
+
+	jar := c.get(proxyID)
+	if len(jar.Cookies(u)) == 0 {
+		jar.SetCookies(u, seeder.SeedCookies())
+	}
+}
+
+// ClearCookies forgets the cookies collected through a proxy, giving it
+// a fresh identity on its next request (e.g. after a block). The next
+// page it fetches is sent as a fresh navigation too, after a warm-up
+// when WarmUp is set.
+func (w *Worker) ClearCookies(proxyID string) {
+	w.cookies.clear(proxyID)
+	w.nav.reset(proxyID)
+	w.warmed.reset(proxyID)
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"dorker/worker/internal/engine"
+	"dorker/worker/internal/proxy"
+)
+
+func TestWorkerCookiesPerProxy(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+
+	// Acts as the HTTP proxy and the search engine behind it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Header.Get("Cookie"))
+		mu.Unlock()
+		http.SetCookie(w, &http.Cookie{Name: "NID", Value: "abc", Path: "/"})
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	p1 := &proxy.Proxy{ID: "p1", Host: host, Port: port, Type: proxy.ProxyTypeHTTP}
+	p2 := &proxy.Proxy{ID: "p2", Host: host, Port: port, Type: proxy.ProxyTypeHTTP}
+
+	w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+	target := "http://www.google.test/search?q=x"
+
+	request := func(prx *proxy.Proxy) string {
+		t.Helper()
+		if _, _, err := w.makeRequest(context.Background(), target, "", prx, nil); err != nil {
+			t.Fatalf("makeRequest() error = %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return sent[len(sent)-1]
+	}
+
+	w.cookies.seed(p1.ID, engine.NewGoogle(), target)
+	if got := request(p1); !strings.Contains(got, "CONSENT=YES+") || strings.Contains(got, "NID") {
+		t.Errorf("first request cookies = %q, want only the seeded CONSENT", got)
+	}
+	first := request(p1)
+	if !strings.Contains(first, "NID=abc") || !strings.Contains(first, "CONSENT=") {
+		t.Errorf("second request cookies = %q, want CONSENT and the NID set by the server", first)
+	}
+
+	// Seeding again must not replace the cookies the jar collected
+	w.cookies.seed(p1.ID, engine.NewGoogle(), target)
+	if got := request(p1); got != first {
+		t.Errorf("cookies after reseed = %q, want %q", got, first)
+	}
+
+	if got := request(p2); got != "" {
+		t.Errorf("other proxy cookies = %q, want none", got)
+	}
+
+	w.ClearCookies(p1.ID)
+	if got := request(p1); got != "" {
+		t.Errorf("cookies after ClearCookies = %q, want none", got)
+	}
+}
+
+func TestCookieSeedNeedsSeeder(t *testing.T) {
+	jars := newCookieJars()
+	jars.seed("p1", mockEngine{}, "http://www.google.test/")
+
+	u, _ := url.Parse("http://www.google.test/")
+	if got := jars.get("p1").Cookies(u); len(got) != 0 {
+		t.Errorf("cookies = %v, want none for an engine without seed cookies", got)
+	}
+}
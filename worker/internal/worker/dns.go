@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsEntry holds resolved addresses for a host
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// dnsCache is a small in-process DNS cache used by the HTTP dialer.
+// Every request goes through a proxy, and the proxy resolves the search
+// engine's host (an HTTP proxy when it handles CONNECT, a SOCKS proxy
+// remotely), so the only names the worker resolves, and caches, are the
+// hostnames of HTTP proxies.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+	size    int
+	ttl     time.Duration
+
+	dialer     *net.Dialer
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// newDNSCache creates a cache holding up to size hosts for ttl each
+func newDNSCache(size int, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		entries: make(map[string]dnsEntry),
+		size:    size,
+		ttl:     ttl,
+		dialer: &net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// lookup returns cached addresses for host, resolving on miss or expiry
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[host]; !exists && len(c.entries) >= c.size {
+		c.evict(now)
+	}
+	c.entries[host] = dnsEntry{addrs: addrs, expires: now.Add(c.ttl)}
+
+	return addrs, nil
+}
+
+// evict drops expired entries, or the entry closest to expiry when none
+// have expired (must hold lock)
+func (c *dnsCache) evict(now time.Time) {
+	var oldest string
+	var oldestExpires time.Time
+
+	for host, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, host)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(oldestExpires) {
+			oldest = host
+			oldestExpires = entry.expires
+		}
+	}
+
+	if len(c.entries) >= c.size && oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
+
+// Len returns the number of cached hosts
+func (c *dnsCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// DialContext dials addr using cached addresses for its host
+func (c *dnsCache) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
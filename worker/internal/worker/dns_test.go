@@ -0,0 +1,109 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// countingLookup returns a lookup func that resolves every host to
+// 127.0.0.1 and counts calls per host
+func countingLookup(calls map[string]int) func(ctx context.Context, host string) ([]string, error) {
+	return func(ctx context.Context, host string) ([]string, error) {
+		calls[host]++
+		return []string{"127.0.0.1"}, nil
+	}
+}
+
+func TestDNSCacheLookupCached(t *testing.T) {
+	calls := make(map[string]int)
+	cache := newDNSCache(10, time.Minute)
+	cache.lookupHost = countingLookup(calls)
+
+	for i := 0; i < 3; i++ {
+		addrs, err := cache.lookup(context.Background(), "www.google.de")
+		if err != nil {
+			t.Fatalf("lookup() error = %v", err)
+		}
+		if len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+			t.Errorf("lookup() = %v, want [127.0.0.1]", addrs)
+		}
+	}
+
+	if calls["www.google.de"] != 1 {
+		t.Errorf("resolver calls = %d, want 1", calls["www.google.de"])
+	}
+
+	// Each ccTLD is its own entry
+	cache.lookup(context.Background(), "www.google.fr")
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+}
+
+func TestDNSCacheExpiry(t *testing.T) {
+	calls := make(map[string]int)
+	cache := newDNSCache(10, 10*time.Millisecond)
+	cache.lookupHost = countingLookup(calls)
+
+	cache.lookup(context.Background(), "www.google.com")
+	time.Sleep(20 * time.Millisecond)
+	cache.lookup(context.Background(), "www.google.com")
+
+	if calls["www.google.com"] != 2 {
+		t.Errorf("resolver calls = %d, want 2 after expiry", calls["www.google.com"])
+	}
+}
+
+func TestDNSCacheEviction(t *testing.T) {
+	calls := make(map[string]int)
+	cache := newDNSCache(2, time.Minute)
+	cache.lookupHost = countingLookup(calls)
+
+	cache.lookup(context.Background(), "www.google.com")
+	time.Sleep(time.Millisecond)
+	cache.lookup(context.Background(), "www.google.de")
+	cache.lookup(context.Background(), "www.google.fr")
+
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+
+	// The oldest entry was evicted and must be resolved again
+	cache.lookup(context.Background(), "www.google.com")
+	if calls["www.google.com"] != 2 {
+		t.Errorf("resolver calls = %d, want 2 after eviction", calls["www.google.com"])
+	}
+}
+
+func TestDNSCacheDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	calls := make(map[string]int)
+	cache := newDNSCache(10, time.Minute)
+	cache.lookupHost = countingLookup(calls)
+
+	conn, err := cache.DialContext(context.Background(), "tcp", net.JoinHostPort("proxy.example", port))
+	if err != nil {
+		t.Fatalf("DialContext() error = %v", err)
+	}
+	conn.Close()
+
+	if calls["proxy.example"] != 1 {
+		t.Errorf("resolver calls = %d, want 1", calls["proxy.example"])
+	}
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"regexp"
+	"strings"
+
+	"dorker/worker/internal/engine"
+)
+
+// FilterURLs keeps the results whose URL matches at least one include
+// pattern (any URL when there are none) and no exclude pattern,
+// renumbering their positions
+func FilterURLs(urls []engine.SearchResult, inc, exc []*regexp.Regexp) []engine.SearchResult {
+	kept := make([]engine.SearchResult, 0, len(urls))
+	for _, r := range urls {
+		if len(inc) > 0 && !matchesAny(inc, r.URL) {
+			continue
+		}
+		if matchesAny(exc, r.URL) {
+			continue
+		}
+
+		r.Position = len(kept) + 1
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// matchesAny reports whether any pattern matches s
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainPattern matches URLs on domain or any of its subdomains
+func domainPattern(domain string) *regexp.Regexp {
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return regexp.MustCompile(`(?i)^[a-z][a-z0-9+.-]*://([^/?#@]*@)?([^/?#@:]*\.)?` +
+		regexp.QuoteMeta(domain) + `\.?(:\d*)?([/?#]|$)`)
+}
+
+// urlExcludes combines the configured exclude patterns with the domain
+// denylist
+func urlExcludes(config Config) []*regexp.Regexp {
+	exclude := make([]*regexp.Regexp, 0, len(config.ExcludePatterns)+len(config.DenyDomains))
+	exclude = append(exclude, config.ExcludePatterns...)
+	for _, domain := range config.DenyDomains {
+		if strings.Trim(strings.TrimSpace(domain), ".") != "" {
+			exclude = append(exclude, domainPattern(domain))
+		}
+	}
+	return exclude
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"regexp"
+	"testing"
+
+	"dorker/worker/internal/engine"
+)
+
+func TestFilterURLs(t *testing.T) {
+	urls := []engine.SearchResult{
+		{URL: "https://example.com/admin", Position: 1},
+		{URL: "https://www.facebook.com/page", Position: 2},
+		{URL: "https://shop.example.org/login.php?id=1", Position: 3},
+		{URL: "https://d1.cloudfront.net/file.pdf", Position: 4},
+		{URL: "https://notfacebook.com/", Position: 5},
+	}
+
+	tests := []struct {
+		name string
+		inc  []*regexp.Regexp
+		exc  []*regexp.Regexp
+		want []string
+	}{
+		{
+			name: "no filters",
+			want: []string{"https://example.com/admin", "https://www.facebook.com/page", "https://shop.example.org/login.php?id=1", "https://d1.cloudfront.net/file.pdf", "https://notfacebook.com/"},
+		},
+		{
+			name: "include",
+			inc:  []*regexp.Regexp{regexp.MustCompile(`\.php`), regexp.MustCompile(`/admin$`)},
+			want: []string{"https://example.com/admin", "https://shop.example.org/login.php?id=1"},
+		},
+		{
+			name: "exclude",
+			exc:  []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)},
+			want: []string{"https://example.com/admin", "https://www.facebook.com/page", "https://shop.example.org/login.php?id=1", "https://notfacebook.com/"},
+		},
+		{
+			name: "exclude wins over include",
+			inc:  []*regexp.Regexp{regexp.MustCompile(`example`)},
+			exc:  []*regexp.Regexp{regexp.MustCompile(`\.org/`)},
+			want: []string{"https://example.com/admin"},
+		},
+		{
+			name: "deny domains",
+			exc:  []*regexp.Regexp{domainPattern("facebook.com"), domainPattern(".CloudFront.net")},
+			want: []string{"https://example.com/admin", "https://shop.example.org/login.php?id=1", "https://notfacebook.com/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterURLs(urls, tt.inc, tt.exc)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FilterURLs() returned %d URLs, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, r := range got {
+				if r.URL != tt.want[i] {
+					t.Errorf("URL %d = %q, want %q", i, r.URL, tt.want[i])
+				}
+				if r.Position != i+1 {
+					t.Errorf("%s position = %d, want %d", r.URL, r.Position, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestDomainPattern(t *testing.T) {
+	re := domainPattern("facebook.com")
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://facebook.com", true},
+		{"http://m.facebook.com:8080/x", true},
+		{"https://FACEBOOK.COM/?q=1", true},
+		{"https://user@facebook.com/", true},
+		{"https://facebook.com.evil.net/", false},
+		{"https://notfacebook.com/", false},
+		{"https://example.com/?ref=facebook.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := re.MatchString(tt.url); got != tt.want {
+			t.Errorf("domainPattern(facebook.com) matches %q = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"runtime"
+	"runtime/metrics"
+)
+
+// heapObjectsMetric is the runtime/metrics name of MemStats.HeapAlloc.
+// Reading it does not stop the world the way runtime.ReadMemStats does.
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// Health is a cheap snapshot of the worker process, for spotting a stuck
+// or backed-up worker. It is safe to poll every second.
+type Health struct {
+	Goroutines     int    `json:"goroutines"`
+	TaskQueueLen   int    `json:"task_queue_len"`   // Tasks waiting for a worker
+	ResultQueueLen int    `json:"result_queue_len"` // Results not yet consumed
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	ActiveWorkers  int    `json:"active_workers"` // Workers processing a task
+	Workers        int    `json:"workers"`        // Workers allowed to run at once
+}
+
+// Health returns the current goroutine count, queue depths, heap size and
+// how many workers are busy
+func (w *Worker) Health() Health {
+	w.cancelMu.Lock()
+	active := len(w.inflight)
+	w.cancelMu.Unlock()
+
+	var workers int
+	if w.adaptive != nil {
+		workers = w.adaptive.current()
+	} else {
+		w.configMu.RLock()
+		workers = w.config.Workers
+		w.configMu.RUnlock()
+	}
+
+	return Health{
+		Goroutines:     runtime.NumGoroutine(),
+		TaskQueueLen:   w.TaskQueueLength(),
+		ResultQueueLen: w.ResultQueueLength(),
+		HeapAllocBytes: heapAlloc(),
+		ActiveWorkers:  active,
+		Workers:        workers,
+	}
+}
+
+// heapAlloc returns the bytes of allocated heap objects
+func heapAlloc() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWorkerHealth(t *testing.T) {
+	release := make(chan struct{})
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+	w.config.Workers = 2
+	w.config.MaxDelay = time.Millisecond
+
+	health := w.Health()
+	if health.Goroutines <= 0 || health.HeapAllocBytes == 0 {
+		t.Errorf("Health() = %+v, want goroutines and heap counted", health)
+	}
+	if health.Workers != 2 || health.ActiveWorkers != 0 {
+		t.Errorf("idle Health() = %+v, want 2 workers, none active", health)
+	}
+
+	w.Start()
+	defer w.Stop()
+	for i := 0; i < 3; i++ {
+		if err := w.Submit(&Task{ID: fmt.Sprintf("t%d", i), Dork: "inurl:admin"}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	// Two tasks hang on the proxy, the third waits in the queue
+	waitFor(t, "two active workers", func() bool { return w.Health().ActiveWorkers == 2 })
+	if health := w.Health(); health.TaskQueueLen != 1 || health.ResultQueueLen != 0 {
+		t.Errorf("busy Health() = %+v, want 1 queued task, no results", health)
+	}
+
+	close(release)
+	// A worker publishes its result before it marks itself idle
+	waitFor(t, "three results and idle workers", func() bool {
+		health := w.Health()
+		return health.ResultQueueLen == 3 && health.ActiveWorkers == 0
+	})
+	if health := w.Health(); health.TaskQueueLen != 0 {
+		t.Errorf("done Health() = %+v, want nothing queued", health)
+	}
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// navigations remembers the last search page each proxy fetched from each
+// engine host, like a browser tab per engine. A request for the page that
+// follows it is sent as a click on "Next": with that page as Referer and
+// Sec-Fetch-Site same-origin. Anything else is sent as a fresh navigation
+// from the address bar, without a Referer.
+type navigations struct {
+	mu   sync.Mutex
+	tabs map[string]map[string]navPage // Proxy ID -> engine host -> last page
+}
+
+// navPage is a search results page a proxy has fetched
+type navPage struct {
+	dork string
+	page int
+	url  string
+}
+
+func newNavigations() *navigations {
+	return &navigations{tabs: make(map[string]map[string]navPage)}
+}
+
+// referer returns the page a request for page of dork on searchURL's host
+// follows from, or "" when the proxy's last page there was not the one
+// before it
+func (n *navigations) referer(proxyID, dork string, page int, searchURL string) string {
+	if page < 1 {
+		return ""
+	}
+	u, err := url.Parse(searchURL)
+	if err != nil {
+		return ""
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	last, ok := n.tabs[proxyID][u.Host]
+	if !ok || last.dork != dork || last.page != page-1 {
+		return ""
+	}
+	return last.url
+}
+
+// visit records a search page a proxy fetched
+func (n *navigations) visit(proxyID, dork string, page int, searchURL string) {
+	u, err := url.Parse(searchURL)
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tabs, ok := n.tabs[proxyID]
+	if !ok {
+		tabs = make(map[string]navPage)
+		n.tabs[proxyID] = tabs
+	}
+	tabs[u.Host] = navPage{dork: dork, page: page, url: searchURL}
+}
+
+// reset forgets where a proxy has been
+func (n *navigations) reset(proxyID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.tabs, proxyID)
+}
+
+// setNavigationHeaders sets the headers of a navigation from referer, or
+// of one typed into the address bar when referer is empty. Sec-Fetch-Site
+// is only changed for fingerprints that send it.
+func setNavigationHeaders(header http.Header, referer string) {
+	if referer == "" {
+		header.Del("Referer")
+		if header.Get("Sec-Fetch-Site") != "" {
+			header.Set("Sec-Fetch-Site", "none")
+		}
+		return
+	}
+
+	header.Set("Referer", referer)
+	if header.Get("Sec-Fetch-Site") != "" {
+		header.Set("Sec-Fetch-Site", "same-origin")
+	}
+}
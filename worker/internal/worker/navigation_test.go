@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNavigationsReferer(t *testing.T) {
+	n := newNavigations()
+	page0 := "https://www.google.com/search?q=x"
+	page1 := "https://www.google.com/search?q=x&start=10"
+
+	if got := n.referer("p1", "x", 0, page0); got != "" {
+		t.Errorf("referer(page 0) = %q, want none", got)
+	}
+	n.visit("p1", "x", 0, page0)
+
+	tests := []struct {
+		name    string
+		proxyID string
+		dork    string
+		page    int
+		url     string
+		want    string
+	}{
+		{"next page", "p1", "x", 1, page1, page0},
+		{"other proxy", "p2", "x", 1, page1, ""},
+		{"other dork", "p1", "y", 1, "https://www.google.com/search?q=y&start=10", ""},
+		{"skipped page", "p1", "x", 2, "https://www.google.com/search?q=x&start=20", ""},
+		{"other engine", "p1", "x", 1, "https://www.bing.com/search?q=x&first=11", ""},
+	}
+	for _, tt := range tests {
+		if got := n.referer(tt.proxyID, tt.dork, tt.page, tt.url); got != tt.want {
+			t.Errorf("%s: referer() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	n.reset("p1")
+	if got := n.referer("p1", "x", 1, page1); got != "" {
+		t.Errorf("referer() after reset = %q, want none", got)
+	}
+}
+
+func TestSetNavigationHeaders(t *testing.T) {
+	chrome := func() http.Header {
+		h := http.Header{}
+		h.Set("Sec-Fetch-Site", "none")
+		h.Set("Sec-Fetch-User", "?1")
+		return h
+	}
+
+	first := chrome()
+	setNavigationHeaders(first, "")
+	if first.Get("Referer") != "" || first.Get("Sec-Fetch-Site") != "none" {
+		t.Errorf("first page headers = %v, want no Referer and Sec-Fetch-Site none", first)
+	}
+
+	next := chrome()
+	setNavigationHeaders(next, "https://www.google.com/search?q=x")
+	if next.Get("Referer") != "https://www.google.com/search?q=x" || next.Get("Sec-Fetch-Site") != "same-origin" {
+		t.Errorf("next page headers = %v, want the previous page as Referer and Sec-Fetch-Site same-origin", next)
+	}
+
+	// Fingerprints without Sec-Fetch headers do not gain them
+	plain := http.Header{}
+	setNavigationHeaders(plain, "https://www.google.com/search?q=x")
+	if _, ok := plain["Sec-Fetch-Site"]; ok {
+		t.Errorf("headers = %v, want no Sec-Fetch-Site", plain)
+	}
+}
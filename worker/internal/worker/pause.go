@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pauseGate holds workers and their delay timers while the worker is
+// paused, and keeps track of how long it has been paused
+type pauseGate struct {
+	mu      sync.Mutex
+	since   time.Time     // Start of the current pause (zero = running)
+	total   time.Duration // Length of the pauses that ended
+	resumed chan struct{} // Closed while running
+	paused  chan struct{} // Closed while paused
+}
+
+// newPauseGate creates a gate in the running state
+func newPauseGate() *pauseGate {
+	g := &pauseGate{
+		resumed: make(chan struct{}),
+		paused:  make(chan struct{}),
+	}
+	close(g.resumed)
+	return g
+}
+
+// pause closes the gate. Returns false if it was already paused.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.since.IsZero() {
+		return false
+	}
+	g.since = time.Now()
+	g.resumed = make(chan struct{})
+	close(g.paused)
+	return true
+}
+
+// resume opens the gate. Returns false if it was not paused.
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.since.IsZero() {
+		return false
+	}
+	g.total += time.Since(g.since)
+	g.since = time.Time{}
+	g.paused = make(chan struct{})
+	close(g.resumed)
+	return true
+}
+
+// isPaused reports whether the gate is closed
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return !g.since.IsZero()
+}
+
+// pausedFor returns the total time spent paused, including the current
+// pause
+func (g *pauseGate) pausedFor() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	total := g.total
+	if !g.since.IsZero() {
+		total += time.Since(g.since)
+	}
+	return total
+}
+
+// channels returns the channels signalling the next resume and pause
+func (g *pauseGate) channels() (resumed, paused <-chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed, g.paused
+}
+
+// wait blocks while the gate is paused. Returns false if ctx ends first.
+func (g *pauseGate) wait(ctx context.Context) bool {
+	resumed, _ := g.channels()
+	select {
+	case <-resumed:
+		return ctx.Err() == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sleep waits until d has passed outside of pauses: the timer stops while
+// the gate is paused and continues with what was left on resume. Returns
+// false if ctx ends first.
+func (g *pauseGate) sleep(ctx context.Context, d time.Duration) bool {
+	for d > 0 {
+		if !g.wait(ctx) {
+			return false
+		}
+
+		_, paused := g.channels()
+		start := time.Now()
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+			return true
+		case <-paused:
+			timer.Stop()
+			d -= time.Since(start)
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
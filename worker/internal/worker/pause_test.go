@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPauseGateSleep(t *testing.T) {
+	g := newPauseGate()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		g.pause()
+		time.Sleep(200 * time.Millisecond)
+		g.resume()
+	}()
+
+	start := time.Now()
+	if !g.sleep(context.Background(), 150*time.Millisecond) {
+		t.Fatal("sleep() = false, want true")
+	}
+
+	// 150ms of sleep plus the 200ms pause
+	if elapsed := time.Since(start); elapsed < 340*time.Millisecond {
+		t.Errorf("sleep() returned after %v, want the pause added", elapsed)
+	}
+	if paused := g.pausedFor(); paused < 190*time.Millisecond || paused > 300*time.Millisecond {
+		t.Errorf("pausedFor() = %v, want about 200ms", paused)
+	}
+}
+
+func TestPauseGateCancel(t *testing.T) {
+	g := newPauseGate()
+	g.pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if g.wait(ctx) {
+		t.Error("wait() = true while paused, want false once ctx ends")
+	}
+	if g.sleep(ctx, time.Millisecond) {
+		t.Error("sleep() = true after ctx ended")
+	}
+	if g.resume(); g.resume() {
+		t.Error("resume() = true on a running gate")
+	}
+}
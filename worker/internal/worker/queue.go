@@ -0,0 +1,280 @@
+package worker
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// queuedTask is a task waiting in the queue
+type queuedTask struct {
+	task  *Task
+	score float64 // Priority adjusted for time spent waiting
+	seq   uint64  // Submission order, breaks ties
+}
+
+// taskHeap orders queued tasks by score, then submission order
+type taskHeap []*queuedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].score != h[j].score {
+		return h[i].score > h[j].score
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(*queuedTask)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// taskQueue is a bounded priority queue feeding the worker goroutines.
+// Higher Priority tasks pop first. A waiting task gains one priority level
+// per aging interval so bulk work is not starved by a stream of urgent tasks.
+// Retries go to a separate unbounded FIFO that is drained before new tasks,
+// so a full buffer never turns a transient failure into a lost task.
+type taskQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	space    *sync.Cond // Wakes pushWait callers when a slot frees up
+	items    taskHeap
+	retries  []*Task
+	capacity int // 0 = unbounded
+	aging    time.Duration
+	start    time.Time
+	seq      uint64
+	closed   bool
+	active   int // Tasks popped but not yet finished
+	retiring int // Pending pop calls to turn away so workers exit
+
+	now func() time.Time
+}
+
+// newTaskQueue creates a queue holding up to capacity tasks
+func newTaskQueue(capacity int, aging time.Duration) *taskQueue {
+	q := &taskQueue{
+		capacity: capacity,
+		aging:    aging,
+		start:    time.Now(),
+		now:      time.Now,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	q.space = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds a task, failing when the queue is full or closed
+func (q *taskQueue) push(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return fmt.Errorf("task queue closed")
+	}
+	if q.full() {
+		return fmt.Errorf("task buffer full")
+	}
+
+	q.enqueue(task)
+	return nil
+}
+
+// pushWait adds a task, blocking while the queue is full. It only fails
+// once the queue is closed.
+func (q *taskQueue) pushWait(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.full() && !q.closed {
+		q.space.Wait()
+	}
+	if q.closed {
+		return fmt.Errorf("task queue closed")
+	}
+
+	q.enqueue(task)
+	return nil
+}
+
+// full reports whether new tasks are turned away. Callers hold q.mu.
+func (q *taskQueue) full() bool {
+	return q.capacity > 0 && len(q.items) >= q.capacity
+}
+
+// enqueue adds a task to the heap. Callers hold q.mu.
+func (q *taskQueue) enqueue(task *Task) {
+	// Every queued task ages at the same rate, so folding the enqueue time
+	// into a fixed score keeps the heap ordering valid as time passes
+	score := float64(task.Priority)
+	if q.aging > 0 {
+		score -= float64(q.now().Sub(q.start)) / float64(q.aging)
+	}
+
+	q.seq++
+	heap.Push(&q.items, &queuedTask{task: task, score: score, seq: q.seq})
+	q.cond.Signal()
+}
+
+// pushRetry queues a task for retry ahead of new tasks. It ignores the
+// capacity and only fails once the queue is closed.
+func (q *taskQueue) pushRetry(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return fmt.Errorf("task queue closed")
+	}
+
+	q.retries = append(q.retries, task)
+	q.cond.Signal()
+
+	return nil
+}
+
+// pop blocks until a task is available, returning false once closed or
+// when the caller is retired. Retries are handed out before new tasks.
+func (q *taskQueue) pop() (*Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.items) == 0 && len(q.retries) == 0 && !q.closed && q.retiring == 0 {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	if q.retiring > 0 {
+		q.retiring--
+		return nil, false
+	}
+
+	q.active++
+
+	if len(q.retries) > 0 {
+		task := q.retries[0]
+		q.retries[0] = nil
+		q.retries = q.retries[1:]
+		return task, true
+	}
+
+	item := heap.Pop(&q.items).(*queuedTask)
+	q.space.Signal()
+	return item.task, true
+}
+
+// remove takes a queued task out by ID, returning nil when it is not
+// queued (already popped or unknown)
+func (q *taskQueue) remove(taskID string) *Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, task := range q.retries {
+		if task.ID == taskID {
+			q.retries = append(q.retries[:i], q.retries[i+1:]...)
+			return task
+		}
+	}
+
+	for i, item := range q.items {
+		if item.task.ID == taskID {
+			heap.Remove(&q.items, i)
+			q.space.Signal()
+			return item.task
+		}
+	}
+
+	return nil
+}
+
+// retire makes the next n pop calls return false so that many workers
+// exit. Idle workers leave at once, busy ones after their current task.
+func (q *taskQueue) retire(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.retiring += n
+	q.cond.Broadcast()
+}
+
+// unretire withdraws up to n pending retirements, returning how many were
+// withdrawn
+func (q *taskQueue) unretire(n int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n > q.retiring {
+		n = q.retiring
+	}
+	q.retiring -= n
+	return n
+}
+
+// finish marks a popped task as done and reports whether the queue
+// is now idle
+func (q *taskQueue) finish() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.active--
+	return len(q.items) == 0 && len(q.retries) == 0 && q.active == 0
+}
+
+// idle reports whether nothing is queued or in flight
+func (q *taskQueue) idle() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items) == 0 && len(q.retries) == 0 && q.active == 0
+}
+
+// close wakes all waiting workers; queued tasks are kept but not handed out
+func (q *taskQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+	q.space.Broadcast()
+}
+
+// snapshot returns the queued tasks, retries first, then new tasks in
+// the order they would be handed out
+func (q *taskQueue) snapshot() []*Task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make(taskHeap, len(q.items))
+	copy(items, q.items)
+	sort.Sort(items)
+
+	tasks := make([]*Task, 0, len(q.retries)+len(items))
+	tasks = append(tasks, q.retries...)
+	for _, item := range items {
+		tasks = append(tasks, item.task)
+	}
+	return tasks
+}
+
+// len returns the number of queued tasks, including retries
+func (q *taskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items) + len(q.retries)
+}
+
+// retryLen returns the number of tasks waiting to be retried
+func (q *taskQueue) retryLen() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.retries)
+}
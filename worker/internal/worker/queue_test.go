@@ -0,0 +1,237 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskQueuePriorityOrder(t *testing.T) {
+	q := newTaskQueue(10, 0)
+
+	q.push(&Task{ID: "low", Priority: 0})
+	q.push(&Task{ID: "high", Priority: 10})
+	q.push(&Task{ID: "mid-1", Priority: 5})
+	q.push(&Task{ID: "mid-2", Priority: 5})
+
+	want := []string{"high", "mid-1", "mid-2", "low"}
+	for _, id := range want {
+		task, ok := q.pop()
+		if !ok {
+			t.Fatal("pop() returned closed")
+		}
+		if task.ID != id {
+			t.Errorf("pop() = %q, want %q", task.ID, id)
+		}
+	}
+}
+
+func TestTaskQueueAging(t *testing.T) {
+	now := time.Now()
+	q := newTaskQueue(10, time.Second)
+	q.start = now
+	q.now = func() time.Time { return now }
+
+	q.push(&Task{ID: "old-low", Priority: 0})
+
+	// Five seconds later a priority 3 task arrives; the waiting task has
+	// gained five levels and should still go first
+	now = now.Add(5 * time.Second)
+	q.push(&Task{ID: "new-high", Priority: 3})
+
+	task, _ := q.pop()
+	if task.ID != "old-low" {
+		t.Errorf("pop() = %q, want old-low after aging", task.ID)
+	}
+
+	// A much more urgent task still jumps ahead of aged work
+	q.push(&Task{ID: "old-low-2", Priority: 0})
+	now = now.Add(time.Second)
+	q.push(&Task{ID: "urgent", Priority: 100})
+
+	task, _ = q.pop()
+	if task.ID != "urgent" {
+		t.Errorf("pop() = %q, want urgent", task.ID)
+	}
+}
+
+func TestTaskQueueCapacity(t *testing.T) {
+	q := newTaskQueue(2, 0)
+
+	if err := q.push(&Task{ID: "1"}); err != nil {
+		t.Fatalf("push() error = %v", err)
+	}
+	if err := q.push(&Task{ID: "2"}); err != nil {
+		t.Fatalf("push() error = %v", err)
+	}
+	if err := q.push(&Task{ID: "3"}); err == nil {
+		t.Error("push() should fail when full")
+	}
+
+	if q.len() != 2 {
+		t.Errorf("len() = %d, want 2", q.len())
+	}
+}
+
+func TestTaskQueuePushWait(t *testing.T) {
+	q := newTaskQueue(1, 0)
+	if err := q.pushWait(&Task{ID: "1"}); err != nil {
+		t.Fatalf("pushWait() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- q.pushWait(&Task{ID: "2"}) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("pushWait() = %v on a full queue, want it to block", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if task, _ := q.pop(); task.ID != "1" {
+		t.Errorf("pop() = %s, want 1", task.ID)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("pushWait() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pushWait() did not wake when a slot freed")
+	}
+
+	go func() { done <- q.pushWait(&Task{ID: "3"}) }()
+	time.Sleep(10 * time.Millisecond)
+	q.close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("pushWait() should fail once closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pushWait() did not wake on close")
+	}
+}
+
+func TestTaskQueueRetryFirst(t *testing.T) {
+	q := newTaskQueue(2, 0)
+
+	q.push(&Task{ID: "new-1", Priority: 10})
+	q.push(&Task{ID: "new-2"})
+
+	// Retries ignore capacity and run ahead of new tasks
+	for _, id := range []string{"retry-1", "retry-2"} {
+		if err := q.pushRetry(&Task{ID: id}); err != nil {
+			t.Fatalf("pushRetry() error = %v", err)
+		}
+	}
+
+	if q.len() != 4 || q.retryLen() != 2 {
+		t.Errorf("len() = %d, retryLen() = %d, want 4 and 2", q.len(), q.retryLen())
+	}
+
+	want := []string{"retry-1", "retry-2", "new-1", "new-2"}
+	for _, id := range want {
+		task, _ := q.pop()
+		if task.ID != id {
+			t.Errorf("pop() = %q, want %q", task.ID, id)
+		}
+		q.finish()
+	}
+
+	if !q.idle() {
+		t.Error("idle() = false after draining")
+	}
+
+	q.close()
+	if err := q.pushRetry(&Task{ID: "late"}); err == nil {
+		t.Error("pushRetry() should fail after close")
+	}
+}
+
+func TestTaskQueueCloseWakesPop(t *testing.T) {
+	q := newTaskQueue(10, 0)
+
+	done := make(chan bool)
+	go func() {
+		_, ok := q.pop()
+		done <- ok
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.close()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Error("pop() should report closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pop() did not wake on close")
+	}
+
+	if err := q.push(&Task{ID: "late"}); err == nil {
+		t.Error("push() should fail after close")
+	}
+}
+
+func TestTaskQueueRemove(t *testing.T) {
+	q := newTaskQueue(10, 0)
+
+	q.push(&Task{ID: "a", Priority: 2})
+	q.push(&Task{ID: "b", Priority: 1})
+	q.push(&Task{ID: "c", Priority: 0})
+	q.pushRetry(&Task{ID: "r"})
+
+	if task := q.remove("b"); task == nil || task.ID != "b" {
+		t.Errorf("remove(b) = %v, want task b", task)
+	}
+	if task := q.remove("r"); task == nil || task.ID != "r" {
+		t.Errorf("remove(r) = %v, want task r", task)
+	}
+	if task := q.remove("missing"); task != nil {
+		t.Errorf("remove(missing) = %v, want nil", task)
+	}
+	if q.len() != 2 || q.retryLen() != 0 {
+		t.Errorf("len() = %d, retryLen() = %d, want 2 and 0", q.len(), q.retryLen())
+	}
+
+	for _, id := range []string{"a", "c"} {
+		task, _ := q.pop()
+		if task.ID != id {
+			t.Errorf("pop() = %q, want %q", task.ID, id)
+		}
+	}
+}
+
+func TestTaskQueueRetire(t *testing.T) {
+	q := newTaskQueue(10, 0)
+
+	done := make(chan bool)
+	go func() {
+		_, ok := q.pop()
+		done <- ok
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.retire(1)
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Error("pop() should turn away a retired caller")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pop() did not wake on retire")
+	}
+
+	// Pending retirements win over queued tasks and can be withdrawn
+	q.push(&Task{ID: "a"})
+	q.retire(2)
+	if n := q.unretire(5); n != 2 {
+		t.Errorf("unretire(5) = %d, want 2", n)
+	}
+	if task, ok := q.pop(); !ok || task.ID != "a" {
+		t.Errorf("pop() = %v, %v, want task a", task, ok)
+	}
+}
@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dorker/worker/internal/proxy"
+)
+
+// exchange is one recorded request and the response it got
+type exchange struct {
+	Time       time.Time         `json:"time"`
+	TaskID     string            `json:"task_id"`
+	Dork       string            `json:"dork"`
+	Page       int               `json:"page"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers"`
+	Proxy      string            `json:"proxy"`
+	StatusCode int               `json:"status_code"`
+	Size       int               `json:"size"`
+	Status     ResultStatus      `json:"status"` // How the response was classified
+	Error      string            `json:"error,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	HTML       string            `json:"html,omitempty"`
+}
+
+// recorder writes request/response exchanges to a directory as JSON files
+// for diagnosing blocks and empty results. Recording stops once maxBytes
+// have been written. A nil recorder records nothing.
+type recorder struct {
+	dir      string
+	maxBytes int64
+	html     bool
+
+	mu      sync.Mutex
+	written int64
+	seq     uint64
+	full    atomic.Bool
+}
+
+// newRecorder creates a recorder writing to dir
+func newRecorder(dir string, maxBytes int64, html bool) *recorder {
+	return &recorder{
+		dir:      dir,
+		maxBytes: maxBytes,
+		html:     html,
+	}
+}
+
+// start begins recording an exchange for a task, returning nil when
+// recording is disabled or the size cap was reached
+func (r *recorder) start(task *Task, prx *proxy.Proxy) *exchange {
+	if r == nil || r.full.Load() {
+		return nil
+	}
+
+	return &exchange{
+		Time:   time.Now(),
+		TaskID: task.ID,
+		Dork:   task.Dork,
+		Page:   task.Page,
+		Proxy:  scrubProxy(prx),
+	}
+}
+
+// save completes an exchange with the result and writes it out
+func (r *recorder) save(ex *exchange, result *Result, html string) error {
+	if r == nil || ex == nil {
+		return nil
+	}
+
+	ex.Status = result.Status
+	ex.Error = result.Error
+	ex.DurationMs = result.Duration.Milliseconds()
+	ex.Size = len(html)
+	if r.html {
+		ex.HTML = html
+	}
+
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxBytes > 0 && r.written+int64(len(data)) > r.maxBytes {
+		r.full.Store(true)
+		return nil
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create record directory: %w", err)
+	}
+
+	r.seq++
+	name := fmt.Sprintf("%d_%06d_%s.json", ex.Time.Unix(), r.seq, ex.Status)
+	if err := os.WriteFile(filepath.Join(r.dir, name), data, 0644); err != nil {
+		return err
+	}
+	r.written += int64(len(data))
+
+	return nil
+}
+
+// recordRequest copies the outgoing URL and headers, dropping any that
+// carry credentials
+func (ex *exchange) recordRequest(req *http.Request) {
+	if ex == nil {
+		return
+	}
+
+	ex.URL = req.URL.String()
+	ex.Headers = make(map[string]string, len(req.Header))
+	for key := range req.Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Proxy-Authorization", "Authorization", "Cookie":
+			ex.Headers[key] = "[redacted]"
+		default:
+			ex.Headers[key] = req.Header.Get(key)
+		}
+	}
+}
+
+// recordResponse copies the response status code
+func (ex *exchange) recordResponse(resp *http.Response) {
+	if ex == nil {
+		return
+	}
+	ex.StatusCode = resp.StatusCode
+}
+
+// scrubProxy describes a proxy without its credentials
+func scrubProxy(prx *proxy.Proxy) string {
+	if prx.Username != "" {
+		return fmt.Sprintf("%s://***@%s", prx.Type, net.JoinHostPort(prx.Host, prx.Port))
+	}
+	return fmt.Sprintf("%s://%s", prx.Type, net.JoinHostPort(prx.Host, prx.Port))
+}
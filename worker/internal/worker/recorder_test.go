@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dorker/worker/internal/proxy"
+)
+
+func readExchanges(t *testing.T, dir string) []exchange {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+
+	var exchanges []exchange
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		var ex exchange
+		if err := json.Unmarshal(data, &ex); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		exchanges = append(exchanges, ex)
+	}
+	return exchanges
+}
+
+func TestRecorderSearch(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+
+	dir := filepath.Join(t.TempDir(), "record")
+	w.recorder = newRecorder(dir, 1<<20, true)
+
+	// Credentials must not reach the dump
+	prx, _ := w.pool.GetByID("mock")
+	prx.Username = "user"
+	prx.Password = "hunter2"
+
+	if _, err := w.SearchOnce(context.Background(), "inurl:admin", 0); err != nil {
+		t.Fatalf("SearchOnce() error = %v", err)
+	}
+
+	exchanges := readExchanges(t, dir)
+	if len(exchanges) != 1 {
+		t.Fatalf("recorded %d exchanges, want 1", len(exchanges))
+	}
+
+	ex := exchanges[0]
+	if ex.Dork != "inurl:admin" || !strings.Contains(ex.URL, "inurl") {
+		t.Errorf("exchange = %+v, want the inurl:admin request", ex)
+	}
+	if ex.StatusCode != http.StatusOK || ex.Status != StatusSuccess {
+		t.Errorf("StatusCode = %d, Status = %v, want 200 success", ex.StatusCode, ex.Status)
+	}
+	if !strings.Contains(ex.HTML, "a.example.com") {
+		t.Errorf("HTML = %q, want response body", ex.HTML)
+	}
+	if len(ex.Headers) == 0 {
+		t.Error("Headers is empty")
+	}
+	if auth := ex.Headers["Proxy-Authorization"]; auth != "" && auth != "[redacted]" {
+		t.Errorf("Proxy-Authorization = %q, want redacted", auth)
+	}
+
+	data, _ := json.Marshal(ex)
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("proxy password leaked into the recording")
+	}
+}
+
+func TestRecorderSizeCap(t *testing.T) {
+	dir := t.TempDir()
+	rec := newRecorder(dir, 600, false)
+	prx := &proxy.Proxy{ID: "p", Type: proxy.ProxyTypeHTTP, Host: "1.2.3.4", Port: "8080"}
+
+	for i := 0; i < 10; i++ {
+		ex := rec.start(&Task{ID: fmt.Sprintf("task_%d", i), Dork: "test"}, prx)
+		if ex == nil {
+			break
+		}
+		rec.save(ex, &Result{Status: StatusNoResults, Duration: time.Millisecond}, "<html></html>")
+	}
+
+	exchanges := readExchanges(t, dir)
+	if len(exchanges) == 0 || len(exchanges) >= 10 {
+		t.Errorf("recorded %d exchanges, want the cap to stop recording", len(exchanges))
+	}
+	if exchanges[0].HTML != "" {
+		t.Error("HTML recorded with html disabled")
+	}
+	if rec.start(&Task{ID: "late"}, prx) != nil {
+		t.Error("start() should return nil once the cap is reached")
+	}
+}
+
+func TestRecorderDisabled(t *testing.T) {
+	var rec *recorder
+
+	ex := rec.start(&Task{ID: "task_1"}, &proxy.Proxy{})
+	if ex != nil {
+		t.Errorf("nil recorder start() = %+v, want nil", ex)
+	}
+	if err := rec.save(ex, &Result{}, ""); err != nil {
+		t.Errorf("nil recorder save() error = %v", err)
+	}
+}
+
+func TestScrubProxy(t *testing.T) {
+	prx := &proxy.Proxy{Type: proxy.ProxyTypeSOCKS5, Host: "1.2.3.4", Port: "1080", Username: "u", Password: "p"}
+
+	if got := scrubProxy(prx); got != "socks5://***@1.2.3.4:1080" {
+		t.Errorf("scrubProxy() = %q", got)
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimitError is returned by makeRequest for a 429 response
+type rateLimitError struct {
+	retryAfter time.Duration // Zero when the response gave no usable Retry-After
+}
+
+func (e *rateLimitError) Error() string {
+	if e.retryAfter > 0 {
+		return fmt.Sprintf("bad status code: %d (retry after %s)", http.StatusTooManyRequests, e.retryAfter)
+	}
+	return fmt.Sprintf("bad status code: %d", http.StatusTooManyRequests)
+}
+
+// parseRetryAfter reads a Retry-After header value, given either as
+// delay seconds or as an HTTP date relative to now. It returns 0 when the
+// value is missing, malformed or already past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(min(seconds, math.MaxInt64/int64(time.Second))) * time.Second
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	if wait := at.Sub(now); wait > 0 {
+		return wait.Round(time.Second)
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"seconds", "120", 2 * time.Minute},
+		{"seconds padded", " 30 ", 30 * time.Second},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{"rfc850 date", now.Add(time.Hour).Format(time.RFC850), time.Hour},
+		{"past date", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"zero", "0", 0},
+		{"negative", "-5", 0},
+		{"missing", "", 0},
+		{"garbage", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
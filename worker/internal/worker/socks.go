@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	netproxy "golang.org/x/net/proxy"
+
+	"dorker/worker/internal/proxy"
+)
+
+// socksDialer returns a dial func that tunnels connections through a
+// SOCKS5 proxy, authenticating when the proxy has credentials. Target
+// hostnames are sent to the proxy unresolved.
+func socksDialer(prx *proxy.Proxy) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if prx.Type != proxy.ProxyTypeSOCKS5 {
+		return nil, fmt.Errorf("unsupported SOCKS proxy type %s for %s", prx.Type, prx.ID)
+	}
+
+	var auth *netproxy.Auth
+	if prx.Username != "" {
+		auth = &netproxy.Auth{User: prx.Username, Password: prx.Password}
+	}
+
+	forward := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	dialer, err := netproxy.SOCKS5("tcp", net.JoinHostPort(prx.Host, prx.Port), auth, forward)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy %s: %w", prx.ID, err)
+	}
+
+	contextDialer, ok := dialer.(netproxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS5 dialer for %s does not support contexts", prx.ID)
+	}
+	return contextDialer.DialContext, nil
+}
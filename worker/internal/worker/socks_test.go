@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"dorker/worker/internal/proxy"
+)
+
+// socksServer is a minimal SOCKS5 server supporting CONNECT with either
+// no auth or username/password auth
+type socksServer struct {
+	listener net.Listener
+	user     string
+	pass     string
+	connects int64
+}
+
+func newSOCKSServer(t *testing.T, user, pass string) *socksServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &socksServer{listener: listener, user: user, pass: pass}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// proxy returns the server as a pool proxy
+func (s *socksServer) proxy(user, pass string) *proxy.Proxy {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return &proxy.Proxy{ID: "socks", Host: host, Port: port, Type: proxy.ProxyTypeSOCKS5, Username: user, Password: pass}
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	target, err := s.handshake(conn)
+	if err != nil {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	atomic.AddInt64(&s.connects, 1)
+
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
+
+// handshake negotiates auth and reads the CONNECT target
+func (s *socksServer) handshake(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil || header[0] != 5 {
+		return "", errors.New("bad greeting")
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+
+	if s.user == "" {
+		conn.Write([]byte{5, 0})
+	} else {
+		conn.Write([]byte{5, 2})
+
+		// RFC 1929: ver, ulen, user, plen, pass
+		ver := make([]byte, 2)
+		if _, err := io.ReadFull(conn, ver); err != nil {
+			return "", err
+		}
+		user := make([]byte, ver[1])
+		io.ReadFull(conn, user)
+		plen := make([]byte, 1)
+		io.ReadFull(conn, plen)
+		pass := make([]byte, plen[0])
+		io.ReadFull(conn, pass)
+
+		if string(user) != s.user || string(pass) != s.pass {
+			conn.Write([]byte{1, 1})
+			return "", errors.New("bad credentials")
+		}
+		conn.Write([]byte{1, 0})
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil || request[1] != 1 {
+		return "", errors.New("bad request")
+	}
+
+	var host string
+	switch request[3] {
+	case 1:
+		addr := make([]byte, 4)
+		io.ReadFull(conn, addr)
+		host = net.IP(addr).String()
+	case 3:
+		n := make([]byte, 1)
+		io.ReadFull(conn, n)
+		name := make([]byte, n[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return "", errors.New("unsupported address type")
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+func TestMakeRequestThroughSOCKS5(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("through socks"))
+	}))
+	defer target.Close()
+
+	tests := []struct {
+		name       string
+		serverUser string
+		serverPass string
+		user       string
+		pass       string
+		wantErr    bool
+	}{
+		{name: "no auth"},
+		{name: "auth", serverUser: "admin", serverPass: "s3cret", user: "admin", pass: "s3cret"},
+		{name: "wrong password", serverUser: "admin", serverPass: "s3cret", user: "admin", pass: "nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSOCKSServer(t, tt.serverUser, tt.serverPass)
+			w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+
+			body, _, err := w.makeRequest(context.Background(), target.URL, "", server.proxy(tt.user, tt.pass), nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("makeRequest() = %q, want error", body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("makeRequest() error = %v", err)
+			}
+			if body != "through socks" {
+				t.Errorf("body = %q, want %q", body, "through socks")
+			}
+			if n := atomic.LoadInt64(&server.connects); n != 1 {
+				t.Errorf("SOCKS connects = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestSOCKSDialerRejectsSOCKS4(t *testing.T) {
+	prx := &proxy.Proxy{ID: "s4", Host: "127.0.0.1", Port: "1080", Type: proxy.ProxyTypeSOCKS4}
+	if _, err := socksDialer(prx); err == nil {
+		t.Error("socksDialer(socks4) should fail")
+	}
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// recentRateWindow is roughly how far back Stats.RequestsPerSecRecent
+// looks: the time constant of its moving average
+const recentRateWindow = 30 * time.Second
+
+// rateMeter is an exponentially weighted moving average of an event
+// rate. Each event adds 1/window to the rate, which decays by e every
+// window, so events much older than the window barely count and a
+// slowdown shows within seconds.
+type rateMeter struct {
+	mu     sync.Mutex
+	window float64   // Seconds
+	start  time.Time // When counting began
+	at     time.Time // When rate was last updated
+	rate   float64   // Events per second as of at
+}
+
+func newRateMeter(window time.Duration) *rateMeter {
+	return &rateMeter{window: window.Seconds()}
+}
+
+// reset starts counting afresh from now
+func (m *rateMeter) reset(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.start, m.at, m.rate = now, now, 0
+}
+
+// mark records an event at now
+func (m *rateMeter) mark(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rate = m.decayed(now) + 1/m.window
+	m.at = now
+}
+
+// decayed returns the rate as of now with no events since at (must hold
+// lock)
+func (m *rateMeter) decayed(now time.Time) float64 {
+	return m.rate * math.Exp(-now.Sub(m.at).Seconds()/m.window)
+}
+
+// perSecond returns the rate as of now. Within the first few windows the
+// average is scaled up for the time before counting began, which would
+// otherwise read as idle, so a young meter reads as the plain average.
+func (m *rateMeter) perSecond(now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elapsed := now.Sub(m.start).Seconds()
+	if m.start.IsZero() || elapsed <= 0 {
+		return 0
+	}
+	return m.decayed(now) / (1 - math.Exp(-elapsed/m.window))
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// feed marks events at perSec for d from *now, advancing *now
+func feed(m *rateMeter, now *time.Time, perSec float64, d time.Duration) {
+	step := time.Duration(float64(time.Second) / perSec)
+	for end := now.Add(d); now.Before(end); {
+		*now = now.Add(step)
+		m.mark(*now)
+	}
+}
+
+func TestRateMeter(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := newRateMeter(30 * time.Second)
+	if got := m.perSecond(start); got != 0 {
+		t.Errorf("perSecond() before reset = %v, want 0", got)
+	}
+
+	m.reset(start)
+	now := start
+	near := func(what string, got, want, tolerance float64) {
+		t.Helper()
+		if math.Abs(got-want) > tolerance*want {
+			t.Errorf("%s: perSecond() = %.2f, want %.2f ±%.0f%%", what, got, want, tolerance*100)
+		}
+	}
+
+	// A young meter reads as the plain average rather than ramping up
+	feed(m, &now, 10, 2*time.Second)
+	near("after 2s at 10/s", m.perSecond(now), 10, 0.05)
+	feed(m, &now, 10, time.Minute)
+	near("after a minute at 10/s", m.perSecond(now), 10, 0.05)
+
+	// A slowdown shows within a window or two, where the lifetime
+	// average stays high
+	feed(m, &now, 1, time.Minute)
+	lifetime := float64(620+60) / now.Sub(start).Seconds()
+	if got := m.perSecond(now); got > 3.5 || got < 1 || got > lifetime/2 {
+		t.Errorf("a minute into 1/s: perSecond() = %.2f, want between 1 and 3.5, under half the lifetime %.2f", got, lifetime)
+	}
+	feed(m, &now, 1, 5*time.Minute)
+	near("after 5 minutes at 1/s", m.perSecond(now), 1, 0.1)
+
+	// Idle, it decays towards zero
+	now = now.Add(2 * time.Minute)
+	if got := m.perSecond(now); got > 0.05 {
+		t.Errorf("after 2 idle minutes: perSecond() = %.3f, want near 0", got)
+	}
+
+	// Reset forgets the past
+	m.reset(now)
+	feed(m, &now, 4, 5*time.Second)
+	near("after reset at 4/s", m.perSecond(now), 4, 0.05)
+}
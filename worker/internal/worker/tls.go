@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+
+	"dorker/worker/internal/proxy"
+	"dorker/worker/internal/stealth"
+)
+
+// dialFunc is the signature of http.Transport dial hooks
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// helloID picks the uTLS ClientHello preset for a fingerprint's browser.
+// Edge is Chromium-based and shares Chrome's hello; nil gets Chrome.
+// Presets are pinned to the versions closest to the stealth User-Agents
+// rather than the _Auto aliases, which move with uTLS releases (Chrome
+// _Auto is 133 in uTLS v1.8).
+func helloID(fp *stealth.Fingerprint) utls.ClientHelloID {
+	if fp == nil {
+		return utls.HelloChrome_120
+	}
+
+	switch fp.Browser {
+	case stealth.BrowserFirefox:
+		return utls.HelloFirefox_120
+	case stealth.BrowserSafari:
+		if fp.OS == stealth.OSIOS {
+			return utls.HelloIOS_14
+		}
+		return utls.HelloSafari_16_0
+	default:
+		return utls.HelloChrome_120
+	}
+}
+
+// applyTLSFingerprint makes transport tunnel through prx itself and
+// handshake with the ClientHello of fp's browser. net/http only calls
+// DialTLSContext for direct connections, so the proxy is dialed here
+// rather than set as transport.Proxy.
+func (w *Worker) applyTLSFingerprint(transport *http.Transport, prx *proxy.Proxy, fp *stealth.Fingerprint) error {
+	dial, err := w.tunnelDialer(prx, transport.TLSClientConfig)
+	if err != nil {
+		return err
+	}
+
+	base := transport.TLSClientConfig
+	id := helloID(fp)
+
+	transport.Proxy = nil
+	transport.DialContext = dial
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn, err := utlsHandshake(ctx, conn, addr, id, base)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	// HTTP/2 needs a *tls.Conn, which uTLS connections are not
+	transport.ForceAttemptHTTP2 = false
+
+	return nil
+}
+
+// tunnelDialer returns a dial func reaching targets through prx: a SOCKS5
+// handshake or an HTTP CONNECT. HTTP proxies are dialed via the DNS cache
+// when enabled; tlsConfig verifies HTTPS proxies.
+func (w *Worker) tunnelDialer(prx *proxy.Proxy, tlsConfig *tls.Config) (dialFunc, error) {
+	var base dialFunc = (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	if w.dnsCache != nil {
+		base = w.dnsCache.DialContext
+	}
+
+	if prx == nil {
+		return base, nil
+	}
+
+	switch prx.Type {
+	case proxy.ProxyTypeSOCKS4, proxy.ProxyTypeSOCKS5:
+		return socksDialer(prx)
+	case proxy.ProxyTypeHTTP, proxy.ProxyTypeHTTPS:
+		return connectDialer(prx, base, tlsConfig), nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy type %s for %s", prx.Type, prx.ID)
+	}
+}
+
+// connectDialer returns a dial func opening a CONNECT tunnel through an
+// HTTP or HTTPS proxy, sending its credentials as Basic auth
+func connectDialer(prx *proxy.Proxy, base dialFunc, tlsConfig *tls.Config) dialFunc {
+	proxyAddr := net.JoinHostPort(prx.Host, prx.Port)
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := base(ctx, "tcp", proxyAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		if prx.Type == proxy.ProxyTypeHTTPS {
+			config := &tls.Config{}
+			if tlsConfig != nil {
+				config = tlsConfig.Clone()
+			}
+			config.ServerName = prx.Host
+			tlsConn := tls.Client(conn, config)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("TLS handshake with proxy %s failed: %w", prx.ID, err)
+			}
+			conn = tlsConn
+		}
+
+		if err := connectTunnel(ctx, conn, prx, addr); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// connectTunnel asks the proxy on conn to connect to addr
+func connectTunnel(ctx context.Context, conn net.Conn, prx *proxy.Proxy, addr string) error {
+	// Unblock reads and writes when the context ends
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if prx.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(prx.Username + ":" + prx.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("CONNECT through proxy %s failed: %w", prx.ID, err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fmt.Errorf("CONNECT through proxy %s failed: %w", prx.ID, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy %s refused CONNECT to %s: %s", prx.ID, addr, resp.Status)
+	}
+	return nil
+}
+
+// utlsHandshake runs a TLS handshake on conn sending the ClientHello of
+// id. ALPN offers only HTTP/1.1, since the transport cannot speak HTTP/2
+// over a uTLS connection; the JA3 hash does not cover ALPN values. Root
+// CAs and InsecureSkipVerify are taken from base.
+func utlsHandshake(ctx context.Context, conn net.Conn, addr string, id utls.ClientHelloID, base *tls.Config) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &utls.Config{ServerName: host}
+	if base != nil {
+		if base.ServerName != "" {
+			config.ServerName = base.ServerName
+		}
+		config.RootCAs = base.RootCAs
+		config.InsecureSkipVerify = base.InsecureSkipVerify
+	}
+
+	spec, err := utls.UTLSIdToSpec(id)
+	if err != nil {
+		return nil, fmt.Errorf("no TLS preset for %s: %w", id.Str(), err)
+	}
+	for _, ext := range spec.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = []string{"http/1.1"}
+		}
+	}
+
+	uconn := utls.UClient(conn, config, utls.HelloCustom)
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		return nil, fmt.Errorf("failed to apply TLS preset %s: %w", id.Str(), err)
+	}
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("TLS handshake with %s failed: %w", host, err)
+	}
+
+	return uconn, nil
+}
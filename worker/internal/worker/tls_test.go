@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+
+	"dorker/worker/internal/proxy"
+	"dorker/worker/internal/stealth"
+)
+
+// connectServer is a minimal HTTP proxy supporting CONNECT, optionally
+// requiring Basic auth
+type connectServer struct {
+	server   *httptest.Server
+	connects int64
+}
+
+func newConnectServer(t *testing.T, user, pass string) *connectServer {
+	t.Helper()
+
+	s := &connectServer{}
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		if user != "" && r.Header.Get("Proxy-Authorization") != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		atomic.AddInt64(&s.connects, 1)
+
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// proxy returns the server as a pool proxy
+func (s *connectServer) proxy(user, pass string) *proxy.Proxy {
+	host, port, _ := net.SplitHostPort(s.server.Listener.Addr().String())
+	return &proxy.Proxy{ID: "connect", Host: host, Port: port, Type: proxy.ProxyTypeHTTP, Username: user, Password: pass}
+}
+
+// helloServer is a TLS server recording the ClientHello of each handshake
+func helloServer(t *testing.T) (*httptest.Server, <-chan tls.ClientHelloInfo) {
+	t.Helper()
+
+	hellos := make(chan tls.ClientHelloInfo, 4)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("fingerprinted"))
+	}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			hellos <- tls.ClientHelloInfo{
+				CipherSuites:    append([]uint16(nil), hello.CipherSuites...),
+				SupportedProtos: append([]string(nil), hello.SupportedProtos...),
+			}
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, hellos
+}
+
+// normalizeGREASE maps every GREASE value (RFC 8701) to 0x0a0a, since
+// uTLS picks them at random per connection
+func normalizeGREASE(values []uint16) []uint16 {
+	out := make([]uint16, len(values))
+	for i, v := range values {
+		if v&0x0f0f == 0x0a0a && v>>8 == v&0xff {
+			v = 0x0a0a
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func TestHelloID(t *testing.T) {
+	tests := []struct {
+		fp   *stealth.Fingerprint
+		want utls.ClientHelloID
+	}{
+		{nil, utls.HelloChrome_120},
+		{&stealth.Fingerprint{Browser: stealth.BrowserChrome, OS: stealth.OSWindows}, utls.HelloChrome_120},
+		{&stealth.Fingerprint{Browser: stealth.BrowserEdge, OS: stealth.OSWindows}, utls.HelloChrome_120},
+		{&stealth.Fingerprint{Browser: stealth.BrowserFirefox, OS: stealth.OSLinux}, utls.HelloFirefox_120},
+		{&stealth.Fingerprint{Browser: stealth.BrowserSafari, OS: stealth.OSMacOS}, utls.HelloSafari_16_0},
+		{&stealth.Fingerprint{Browser: stealth.BrowserSafari, OS: stealth.OSIOS}, utls.HelloIOS_14},
+	}
+
+	for _, tt := range tests {
+		got := helloID(tt.fp)
+		if got != tt.want {
+			name := "nil"
+			if tt.fp != nil {
+				name = string(tt.fp.Browser) + "/" + string(tt.fp.OS)
+			}
+			t.Errorf("helloID(%s) = %s, want %s", name, got.Str(), tt.want.Str())
+		}
+	}
+}
+
+func TestTLSFingerprintThroughConnectProxy(t *testing.T) {
+	target, hellos := helloServer(t)
+	roots := target.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	tests := []struct {
+		name   string
+		fp     *stealth.Fingerprint
+		grease bool // Chrome sends GREASE cipher suites, Firefox does not
+	}{
+		{"chrome", &stealth.Fingerprint{Browser: stealth.BrowserChrome, OS: stealth.OSWindows}, true},
+		{"firefox", &stealth.Fingerprint{Browser: stealth.BrowserFirefox, OS: stealth.OSWindows}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newConnectServer(t, "admin", "s3cret")
+
+			config := DefaultConfig()
+			config.TLSFingerprint = true
+			config.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+			w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+
+			rt, err := w.transportFor(server.proxy("admin", "s3cret"), tt.fp)
+			if err != nil {
+				t.Fatalf("transportFor() error = %v", err)
+			}
+			client := &http.Client{Transport: rt}
+			resp, err := client.Get(target.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "fingerprinted" {
+				t.Errorf("body = %q, want %q", body, "fingerprinted")
+			}
+			if n := atomic.LoadInt64(&server.connects); n != 1 {
+				t.Errorf("CONNECT tunnels = %d, want 1", n)
+			}
+
+			hello := <-hellos
+			if len(hello.SupportedProtos) != 1 || hello.SupportedProtos[0] != "http/1.1" {
+				t.Errorf("ALPN = %v, want [http/1.1]", hello.SupportedProtos)
+			}
+
+			spec, err := utls.UTLSIdToSpec(helloID(tt.fp))
+			if err != nil {
+				t.Fatalf("UTLSIdToSpec() error = %v", err)
+			}
+			got, want := normalizeGREASE(hello.CipherSuites), normalizeGREASE(spec.CipherSuites)
+			if len(got) != len(want) {
+				t.Fatalf("cipher suites = %#04x, want %#04x", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("cipher suites = %#04x, want %#04x", got, want)
+				}
+			}
+			if hasGREASE := got[0] == 0x0a0a; hasGREASE != tt.grease {
+				t.Errorf("GREASE cipher suite = %v, want %v", hasGREASE, tt.grease)
+			}
+		})
+	}
+}
+
+func TestTLSFingerprintConnectAuthRejected(t *testing.T) {
+	target, _ := helloServer(t)
+	server := newConnectServer(t, "admin", "s3cret")
+
+	config := DefaultConfig()
+	config.TLSFingerprint = true
+	w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+
+	body, _, err := w.makeRequest(context.Background(), target.URL, "", server.proxy("admin", "nope"), nil)
+	if err == nil {
+		t.Errorf("makeRequest() = %q, want error", body)
+	}
+	if n := atomic.LoadInt64(&server.connects); n != 0 {
+		t.Errorf("CONNECT tunnels = %d, want 0", n)
+	}
+}
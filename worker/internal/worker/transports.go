@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"net/http"
+	"sync"
+)
+
+// transportCache keeps the transport built for each proxy, so requests
+// through a proxy reuse its idle connections instead of dialing, and with
+// TLS fingerprinting handshaking, every time. A transport is rebuilt when
+// the proxy's address or fingerprint changes.
+type transportCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedTransport // Proxy ID -> transport
+}
+
+// cachedTransport is a transport and the proxy URL and fingerprint it
+// was built for
+type cachedTransport struct {
+	key       string
+	transport http.RoundTripper
+}
+
+func newTransportCache() *transportCache {
+	return &transportCache{entries: make(map[string]cachedTransport)}
+}
+
+// get returns the cached transport for a proxy ID if it was built for
+// key, otherwise one from build, which is cached in its place
+func (c *transportCache) get(proxyID, key string, build func() (http.RoundTripper, error)) (http.RoundTripper, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[proxyID]
+	if ok && entry.key == key {
+		return entry.transport, nil
+	}
+
+	transport, err := build()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		closeIdle(entry.transport)
+	}
+	c.entries[proxyID] = cachedTransport{key: key, transport: transport}
+	return transport, nil
+}
+
+// evict drops the transport for a proxy ID, closing its idle connections
+func (c *transportCache) evict(proxyID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[proxyID]; ok {
+		closeIdle(entry.transport)
+		delete(c.entries, proxyID)
+	}
+}
+
+// closeAll closes the idle connections of every cached transport and
+// empties the cache
+func (c *transportCache) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.entries {
+		closeIdle(entry.transport)
+		delete(c.entries, id)
+	}
+}
+
+// len returns how many transports are cached
+func (c *transportCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// closeIdle closes a transport's idle connections if it keeps any
+func closeIdle(rt http.RoundTripper) {
+	if t, ok := rt.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,157 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"dorker/worker/internal/proxy"
+)
+
+// newConnCountingProxy returns a proxy answering every request with a
+// result page, and a counter of the connections made to it
+func newConnCountingProxy(tb testing.TB) (*proxy.Proxy, *atomic.Int64) {
+	tb.Helper()
+
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "http://example.com/admin")
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	tb.Cleanup(server.Close)
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	return &proxy.Proxy{ID: "counting", Host: host, Port: port, Type: proxy.ProxyTypeHTTP}, &conns
+}
+
+func TestTransportForCachesPerProxy(t *testing.T) {
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+	pool.AddProxy(&proxy.Proxy{ID: "a", Host: "192.168.1.1", Port: "8080", Type: proxy.ProxyTypeHTTP})
+	pool.AddProxy(&proxy.Proxy{ID: "b", Host: "192.168.1.2", Port: "8080", Type: proxy.ProxyTypeHTTP})
+	w := New(DefaultConfig(), pool)
+
+	a, _ := pool.GetByID("a")
+	b, _ := pool.GetByID("b")
+
+	first, _ := w.transportFor(a, nil)
+	if again, _ := w.transportFor(a, nil); again != first {
+		t.Error("transportFor() built a second transport for the same proxy")
+	}
+	if other, _ := w.transportFor(b, nil); other == first {
+		t.Error("transportFor() shared a transport between proxies")
+	}
+
+	// A changed address gets a new transport in place of the old one
+	moved := &proxy.Proxy{ID: "a", Host: a.Host, Port: "8081", Type: a.Type}
+	if rt, _ := w.transportFor(moved, nil); rt == first {
+		t.Error("transportFor() reused a transport after the proxy moved")
+	}
+	if n := w.transports.len(); n != 2 {
+		t.Errorf("cached transports = %d, want 2", n)
+	}
+
+	// Removing a proxy drops its transport
+	pool.Remove("a")
+	if n := w.transports.len(); n != 1 {
+		t.Errorf("cached transports after Remove() = %d, want 1", n)
+	}
+
+	w.running.Store(true)
+	w.Stop()
+	if n := w.transports.len(); n != 0 {
+		t.Errorf("cached transports after Stop() = %d, want 0", n)
+	}
+}
+
+func TestWorkerReusesConnections(t *testing.T) {
+	prx, conns := newConnCountingProxy(t)
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+	pool.AddProxy(prx)
+
+	config := DefaultConfig()
+	config.MaxRetries = 0
+	w := New(config, pool)
+	w.SetEngine(mockEngine{})
+
+	for i := 0; i < 3; i++ {
+		result, err := w.SearchOnce(context.Background(), fmt.Sprintf("inurl:admin %d", i), 0)
+		if err != nil || result.Status != StatusSuccess {
+			t.Fatalf("SearchOnce() = %+v, %v, want success", result, err)
+		}
+	}
+
+	if n := conns.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1 reused for every request", n)
+	}
+}
+
+func TestForceHTTP1(t *testing.T) {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}))
+	target.EnableHTTP2 = true
+	target.StartTLS()
+	t.Cleanup(target.Close)
+	roots := target.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	tests := []struct {
+		name       string
+		forceHTTP1 bool
+		want       int
+	}{
+		{"http2 negotiated", false, 2},
+		{"forced http1", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newConnectServer(t, "admin", "s3cret")
+
+			config := DefaultConfig()
+			config.ForceHTTP1 = tt.forceHTTP1
+			config.Transport = &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: roots},
+				ForceAttemptHTTP2: true,
+			}
+			w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+
+			rt, err := w.transportFor(server.proxy("admin", "s3cret"), nil)
+			if err != nil {
+				t.Fatalf("transportFor() error = %v", err)
+			}
+			resp, err := (&http.Client{Transport: rt}).Get(target.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != tt.want {
+				t.Errorf("protocol = %s, want HTTP/%d", resp.Proto, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkMakeRequest(b *testing.B) {
+	prx, conns := newConnCountingProxy(b)
+	w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := w.makeRequest(ctx, "http://search.test/search?q=x", "", prx, nil); err != nil {
+			b.Fatalf("makeRequest() error = %v", err)
+		}
+	}
+	b.ReportMetric(float64(conns.Load())/float64(b.N), "conns/op")
+}
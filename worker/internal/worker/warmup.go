@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"dorker/worker/internal/proxy"
+)
+
+// warmUps remembers which engine hosts each proxy has opened a session
+// with by loading the homepage, so it is only done on first contact
+type warmUps struct {
+	mu   sync.Mutex
+	done map[string]map[string]bool // Proxy ID -> engine host
+}
+
+func newWarmUps() *warmUps {
+	return &warmUps{done: make(map[string]map[string]bool)}
+}
+
+// claim reports whether a proxy still has to warm up on host, marking it
+// done so requests running alongside do not repeat it
+func (wu *warmUps) claim(proxyID, host string) bool {
+	wu.mu.Lock()
+	defer wu.mu.Unlock()
+
+	hosts, ok := wu.done[proxyID]
+	if !ok {
+		hosts = make(map[string]bool)
+		wu.done[proxyID] = hosts
+	}
+	if hosts[host] {
+		return false
+	}
+	hosts[host] = true
+	return true
+}
+
+// release undoes a claim whose warm-up failed, so the next request
+// through the proxy tries again
+func (wu *warmUps) release(proxyID, host string) {
+	wu.mu.Lock()
+	defer wu.mu.Unlock()
+	delete(wu.done[proxyID], host)
+}
+
+// reset forgets the sessions a proxy has opened
+func (wu *warmUps) reset(proxyID string) {
+	wu.mu.Lock()
+	defer wu.mu.Unlock()
+	delete(wu.done, proxyID)
+}
+
+// warmUp loads the homepage of searchURL's site through a proxy the first
+// time the proxy searches there, collecting the cookies a browser would
+// arrive with. It shares the proxy's cookie jar and fingerprint with the
+// search, and returns the homepage for the search to navigate from, or ""
+// when no warm-up was needed, along with the homepage's status code.
+func (w *Worker) warmUp(ctx context.Context, prx *proxy.Proxy, searchURL string) (string, int, error) {
+	if !w.config.WarmUp {
+		return "", 0, nil
+	}
+	u, err := url.Parse(searchURL)
+	if err != nil || u.Host == "" {
+		return "", 0, nil
+	}
+	if !w.warmed.claim(prx.ID, u.Host) {
+		return "", 0, nil
+	}
+
+	// The homepage is a request like any other under GlobalRPM
+	if err := w.waitForRate(ctx); err != nil {
+		w.warmed.release(prx.ID, u.Host)
+		return "", 0, fmt.Errorf("warm-up: rate limit wait: %w", err)
+	}
+
+	home := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+	_, statusCode, err := w.makeRequest(ctx, home, "", prx, nil)
+	if err != nil {
+		w.warmed.release(prx.ID, u.Host)
+		return "", statusCode, fmt.Errorf("warm-up: %w", err)
+	}
+	return home, statusCode, nil
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dorker/worker/internal/proxy"
+)
+
+func TestWorkerWarmUp(t *testing.T) {
+	var mu sync.Mutex
+	homes := make(map[string]int) // Proxy user -> homepage hits
+	failHome := true
+	var searches []string
+
+	// Acts as the HTTP proxies and the search engine behind them; the
+	// proxy's user tells them apart
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		user, _, _ := (&http.Request{Header: http.Header{"Authorization": r.Header["Proxy-Authorization"]}}).BasicAuth()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/" {
+			homes[user]++
+			if user == "p3" && failHome {
+				failHome = false
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			http.SetCookie(rw, &http.Cookie{Name: "sid", Value: user, Path: "/"})
+			return
+		}
+		searches = append(searches, fmt.Sprintf("%s cookie=%s referer=%s", user, r.Header.Get("Cookie"), r.Header.Get("Referer")))
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	poolConfig := proxy.DefaultPoolConfig()
+	poolConfig.Selection = proxy.SelectionRoundRobin
+	poolConfig.CooldownDuration = 0
+	pool := proxy.NewPool(poolConfig)
+	for _, id := range []string{"p1", "p2", "p3"} {
+		pool.AddProxy(&proxy.Proxy{ID: id, Host: host, Port: port, Username: id, Password: "x", Type: proxy.ProxyTypeHTTP})
+	}
+
+	config := DefaultConfig()
+	config.WarmUp = true
+	w := New(config, pool)
+	w.SetEngine(mockEngine{})
+
+	var statuses []string
+	for i := 0; i < 6; i++ {
+		result, _ := w.executeOn(context.Background(), &Task{ID: fmt.Sprint(i), Dork: "inurl:admin"}, mockEngine{})
+		statuses = append(statuses, fmt.Sprintf("%s:%s", result.ProxyID, result.Status))
+	}
+
+	// p3's failed warm-up fails its request and is retried on its next
+	if got := strings.Join(statuses, " "); got != "p1:success p2:success p3:error p1:success p2:success p3:success" {
+		t.Errorf("results = %s", got)
+	}
+	if p, _ := pool.GetByID("p3"); p.FailCount != 1 {
+		t.Errorf("p3 FailCount = %d, want 1 for the failed warm-up", p.FailCount)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if homes["p1"] != 1 || homes["p2"] != 1 || homes["p3"] != 2 {
+		t.Errorf("homepage hits = %v, want p1:1 p2:1 p3:2", homes)
+	}
+
+	// The search after a warm-up navigates from the homepage with its
+	// cookies; later ones keep the cookies
+	want := []string{
+		"p1 cookie=sid=p1 referer=http://search.test/",
+		"p2 cookie=sid=p2 referer=http://search.test/",
+		"p1 cookie=sid=p1 referer=",
+		"p2 cookie=sid=p2 referer=",
+		"p3 cookie=sid=p3 referer=http://search.test/",
+	}
+	if strings.Join(searches, "\n") != strings.Join(want, "\n") {
+		t.Errorf("searches =\n%s\nwant\n%s", strings.Join(searches, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWorkerWarmUpAfterClearCookies(t *testing.T) {
+	var mu sync.Mutex
+	homes := 0
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			mu.Lock()
+			homes++
+			mu.Unlock()
+			return
+		}
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+	w.config.WarmUp = true
+
+	search := func() {
+		t.Helper()
+		if result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{}); result.Status != StatusSuccess {
+			t.Fatalf("status = %s (%s), want success", result.Status, result.Error)
+		}
+	}
+
+	search()
+	search()
+	w.ClearCookies("mock")
+	search()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if homes != 2 {
+		t.Errorf("homepage hits = %d, want 2: once at first, once after ClearCookies", homes)
+	}
+}
+
+func TestWorkerWarmUpWaitsForRate(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+	w.config.WarmUp = true
+
+	// 1200 RPM is one request every 50ms: the homepage and the search
+	// are two requests, so the first search waits for a second token
+	update := w.config
+	update.GlobalRPM = 1200
+	w.UpdateConfig(update)
+
+	start := time.Now()
+	if result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{}); result.Status != StatusSuccess {
+		t.Fatalf("status = %s (%s), want success", result.Status, result.Error)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("search with a warm-up took %v, want it paced by GlobalRPM", elapsed)
+	}
+}
+
+func TestWorkerWarmUpForgetsRemovedProxy(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+	w.config.WarmUp = true
+
+	w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{})
+	w.pool.Remove("mock")
+
+	w.warmed.mu.Lock()
+	defer w.warmed.mu.Unlock()
+	if n := len(w.warmed.done); n != 0 {
+		t.Errorf("warm-ups remembered after Remove() = %d, want 0", n)
+	}
+}
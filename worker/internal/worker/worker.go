@@ -2,14 +2,21 @@ package worker
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"golang.org/x/time/rate"
+
 	"dorker/worker/internal/engine"
 	"dorker/worker/internal/proxy"
 	"dorker/worker/internal/stealth"
@@ -21,43 +28,251 @@ type Config struct {
 	Workers    int `json:"workers"`
 	BufferSize int `json:"buffer_size"`
 
+	// OverflowPolicy decides what happens to a result when the results
+	// buffer is full ("" = OverflowDropNewest). Dropped results are
+	// counted in Stats.ResultsDropped.
+	OverflowPolicy OverflowPolicy `json:"overflow_policy"`
+
+	// AdaptiveConcurrency parks workers while the CAPTCHA and block rate
+	// over the last AdaptiveWindow responses reaches AdaptiveThreshold,
+	// and unparks them one at a time as it recovers (0 = 20 responses,
+	// 0.2 rate)
+	AdaptiveConcurrency bool    `json:"adaptive_concurrency"`
+	AdaptiveWindow      int     `json:"adaptive_window"`
+	AdaptiveThreshold   float64 `json:"adaptive_threshold"`
+
+	// CircuitBreaker halts every worker once the CAPTCHA and block rate
+	// over the last BreakerWindow responses reaches BreakerThreshold.
+	// After BreakerCooldown it lets BreakerProbes tasks through at a time;
+	// that many clean responses resume the run, a flagged one halts it
+	// again (0 = 50 responses, 0.8 rate, 5 minutes, 3 probes)
+	CircuitBreaker   bool          `json:"circuit_breaker"`
+	BreakerWindow    int           `json:"breaker_window"`
+	BreakerThreshold float64       `json:"breaker_threshold"`
+	BreakerCooldown  time.Duration `json:"breaker_cooldown"`
+	BreakerProbes    int           `json:"breaker_probes"`
+
 	// Timing
 	RequestTimeout time.Duration `json:"request_timeout"`
 	BaseDelay      time.Duration `json:"base_delay"`
 	MinDelay       time.Duration `json:"min_delay"`
 	MaxDelay       time.Duration `json:"max_delay"`
 
-	// Retry
-	MaxRetries int           `json:"max_retries"`
-	RetryDelay time.Duration `json:"retry_delay"`
+	// MaxBodyBytes fails a response whose decoded body is larger, so a
+	// misbehaving proxy cannot stream an endless page into memory
+	// (0 = no limit)
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+
+	// TimingProfile paces each proxy with bursts, session caps and
+	// cooldowns after CAPTCHAs, blocks and errors, replacing the delay
+	// above: aggressive, normal, cautious or stealth ("" = off)
+	TimingProfile string `json:"timing_profile"`
+
+	// Retry backoff doubles with every retry of a task, starting from
+	// RetryDelay after errors and BlockRetryDelay after a CAPTCHA or
+	// block, up to MaxRetryDelay (see computeBackoff)
+	MaxRetries      int           `json:"max_retries"`
+	RetryDelay      time.Duration `json:"retry_delay"`
+	BlockRetryDelay time.Duration `json:"block_retry_delay"`
+	MaxRetryDelay   time.Duration `json:"max_retry_delay"`
+
+	// Proxy cooldowns after a CAPTCHA or block. Zero adopts the engine's
+	// recommended timing when the engine is set.
+	CaptchaCooldown time.Duration `json:"captcha_cooldown"`
+	BlockCooldown   time.Duration `json:"block_cooldown"`
+
+	// GlobalRPM caps requests per minute across all workers and proxies
+	// (0 = unlimited)
+	GlobalRPM int `json:"global_rpm"`
+
+	// PriorityAging is how long a queued task waits to gain one priority
+	// level, so low-priority tasks are not starved (0 = no aging)
+	PriorityAging time.Duration `json:"priority_aging"`
 
 	// Results
 	ResultsPerPage int `json:"results_per_page"`
 	MaxPages       int `json:"max_pages"`
+
+	// MaxResultsPerDork stops paging once a task has collected this many
+	// URLs, counting only those Dedup keeps, and cuts the page that
+	// reached it short (0 = unlimited)
+	MaxResultsPerDork int `json:"max_results_per_dork"`
+
+	// UniqueDomains collapses result URLs to their registrable domain
+	// and emits each domain only once per run
+	UniqueDomains bool `json:"unique_domains"`
+
+	// Dedup drops result URLs already emitted in this run, comparing
+	// them as normalized by engine.NormalizeURL
+	Dedup bool `json:"dedup"`
+
+	// IncludePatterns keeps only result URLs matching one of them and
+	// ExcludePatterns drops those matching any, before results are
+	// emitted. DenyDomains drops URLs on these domains or their
+	// subdomains. Dropped URLs are counted in Stats.URLsFiltered.
+	IncludePatterns []*regexp.Regexp `json:"-"`
+	ExcludePatterns []*regexp.Regexp `json:"-"`
+	DenyDomains     []string         `json:"deny_domains"`
+
+	// DryRun sends no requests: each task succeeds with the URLs its
+	// pages would be fetched from on every engine, for checking dork
+	// syntax and engine parameters without using proxies
+	DryRun bool `json:"dry_run"`
+
+	// StrictDorks fails tasks whose dork engine.ValidateDork finds
+	// hopeless, such as an empty one or one that only excludes terms,
+	// instead of searching them as written
+	StrictDorks bool `json:"strict_dorks"`
+
+	// Seed makes the run reproducible: fingerprint rotation, delays,
+	// backoff jitter and Google's cookies draw from sources seeded with
+	// it (0 = seeded from the clock). Seed the proxy pool separately via
+	// proxy.PoolConfig.Seed.
+	Seed int64 `json:"seed"`
+
+	// MaxRuntime stops the worker once this much wall-clock time has
+	// passed since Start (0 = no limit)
+	MaxRuntime time.Duration `json:"max_runtime"`
+
+	// DNS cache for HTTP proxy hostnames (0 size = disabled). Search
+	// engine hosts are resolved by the proxies, so they are not cached.
+	DNSCacheSize int           `json:"dns_cache_size"`
+	DNSCacheTTL  time.Duration `json:"dns_cache_ttl"`
+
+	// TLSFingerprint sends the ClientHello of the browser in the proxy's
+	// stealth fingerprint via uTLS, so the JA3 matches the User-Agent.
+	// Requests then negotiate HTTP/1.1. Not applied to a ProxyTransport.
+	TLSFingerprint bool `json:"tls_fingerprint"`
+
+	// ForceHTTP1 keeps requests on HTTP/1.1 where they would otherwise
+	// negotiate HTTP/2, for targets that fingerprint h2 frames. Not
+	// applied to a ProxyTransport.
+	ForceHTTP1 bool `json:"force_http1"`
+
+	// WarmUp loads the engine's homepage through each proxy before its
+	// first search there, collecting cookies the way a browser arriving
+	// at the site would. A failed warm-up counts against the proxy.
+	WarmUp bool `json:"warm_up"`
+
+	// FingerprintRotation reassigns every proxy's stealth fingerprint
+	// after this many requests (0 = each proxy keeps one browser identity
+	// for the session)
+	FingerprintRotation int `json:"fingerprint_rotation"`
+
+	// RecordDir dumps every request and response as JSON for debugging
+	// (empty = disabled). Recording stops after RecordMaxBytes; RecordHTML
+	// includes response bodies.
+	RecordDir      string `json:"record_dir"`
+	RecordMaxBytes int64  `json:"record_max_bytes"`
+	RecordHTML     bool   `json:"record_html"`
+
+	// Transport replaces the built-in per-proxy transport. An
+	// *http.Transport is cloned and routed through the proxy like the
+	// default; a ProxyTransport routes itself via ForProxy on every
+	// request. Any other
+	// RoundTripper cannot carry a proxy, so requests through it fail.
+	Transport http.RoundTripper `json:"-"`
+}
+
+// OverflowPolicy is how results are handled while the results buffer is
+// full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for the consumer to make room, holding up the
+	// worker that sent the result, until the worker stops
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropNewest discards the result being sent
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest discards the oldest buffered result to make room
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+)
+
+// ValidOverflowPolicy reports whether p is a known overflow policy
+func ValidOverflowPolicy(p string) bool {
+	switch OverflowPolicy(p) {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		return true
+	}
+	return false
+}
+
+// ProxyTransport is a custom transport that applies a proxy itself,
+// e.g. a uTLS transport dialing through SOCKS
+type ProxyTransport interface {
+	http.RoundTripper
+	ForProxy(prx *proxy.Proxy) (http.RoundTripper, error)
 }
 
+// MaxPagesLimit caps how many result pages a single task may crawl
+const MaxPagesLimit = 10
+
 // DefaultConfig returns sensible defaults
 func DefaultConfig() Config {
 	return Config{
-		Workers:        10,
-		BufferSize:     1000,
-		RequestTimeout: 30 * time.Second,
-		BaseDelay:      8 * time.Second,
-		MinDelay:       3 * time.Second,
-		MaxDelay:       15 * time.Second,
-		MaxRetries:     3,
-		RetryDelay:     5 * time.Second,
-		ResultsPerPage: 100,
-		MaxPages:       1,
+		Workers:         10,
+		BufferSize:      1000,
+		RequestTimeout:  30 * time.Second,
+		BaseDelay:       8 * time.Second,
+		MinDelay:        3 * time.Second,
+		MaxDelay:        15 * time.Second,
+		MaxBodyBytes:    5 << 20,
+		MaxRetries:      3,
+		RetryDelay:      5 * time.Second,
+		BlockRetryDelay: 30 * time.Second,
+		MaxRetryDelay:   5 * time.Minute,
+		PriorityAging:   30 * time.Second,
+		ResultsPerPage:  100,
+		MaxPages:        1,
+		DNSCacheTTL:     5 * time.Minute,
+		RecordMaxBytes:  100 << 20,
 	}
 }
 
 // Task represents a single dork query task
 type Task struct {
-	ID    string `json:"id"`
-	Dork  string `json:"dork"`
-	Page  int    `json:"page"`
-	Retry int    `json:"retry"`
+	ID       string `json:"id"`
+	Dork     string `json:"dork"`
+	Page     int    `json:"page"`
+	Retry    int    `json:"retry"`
+	Priority int    `json:"priority"` // Higher runs first
+	MaxPages int    `json:"max_pages"` // Pages to crawl from Page (0 = Config.MaxPages)
+
+	// Sticky pins every task with the same dork to one proxy, so all pages
+	// of a dork leave through one exit. Pinned proxies still honour pool
+	// cooldown and quarantine: once the pinned proxy is unavailable, further
+	// sticky tasks for that dork fail rather than switch exits. A pinned
+	// proxy at the pool's per-proxy concurrency limit is waited for.
+	Sticky bool `json:"sticky"`
+
+	// SessionKey pins every task with the same key to one proxy, like
+	// Sticky but failing over: once a request through the pinned proxy
+	// fails or the proxy becomes unavailable, the next task picks a new
+	// proxy and the session moves to it. Takes precedence over Sticky.
+	SessionKey string `json:"session_key,omitempty"`
+
+	// TimeRange limits results to those indexed within the past d, w, m
+	// or y on engines that support it; other values are ignored
+	TimeRange string `json:"time_range,omitempty"`
+
+	// Country and Language are two-letter codes targeting regional
+	// results (Google's gl and hl); empty keeps the engine's defaults
+	Country  string `json:"country,omitempty"`
+	Language string `json:"language,omitempty"`
+
+	// query is the dork as searched, cleaned up by engine.ValidateDork,
+	// and warnings what was changed or looks wrong about it
+	query    string
+	warnings []string
+}
+
+// searchQuery returns what to search for a task: its cleaned-up dork
+// once checked, otherwise the dork as written
+func (t *Task) searchQuery() string {
+	if t.query != "" {
+		return t.query
+	}
+	return t.Dork
 }
 
 // Result represents the result of a task
@@ -67,9 +282,21 @@ type Result struct {
 	URLs      []engine.SearchResult  `json:"urls"`
 	Status    ResultStatus           `json:"status"`
 	Error     string                 `json:"error,omitempty"`
+	Warnings  []string               `json:"warnings,omitempty"` // About the dork, from engine.ValidateDork
 	ProxyID   string                 `json:"proxy_id"`
+	Pages     int                    `json:"pages"`               // Result pages fetched
+	Truncated bool                   `json:"truncated,omitempty"` // Cut short by MaxResultsPerDork
 	Duration  time.Duration          `json:"duration"`
 	Timestamp time.Time              `json:"timestamp"`
+
+	// StatusCode is the HTTP status of the last response fetched (0 =
+	// none), and Latency the time spent on requests, leaving out waits
+	// for the rate limit, a proxy and pacing that Duration includes
+	StatusCode int           `json:"status_code,omitempty"`
+	Latency    time.Duration `json:"latency"`
+
+	// nextPage reports that the last page fetched links to another
+	nextPage bool
 }
 
 // ResultStatus represents the status of a result
@@ -82,6 +309,7 @@ const (
 	StatusBlocked   ResultStatus = "blocked"
 	StatusError     ResultStatus = "error"
 	StatusRetry     ResultStatus = "retry"
+	StatusCancelled ResultStatus = "cancelled"
 )
 
 // Stats holds worker statistics
@@ -89,11 +317,40 @@ type Stats struct {
 	TasksTotal      int64         `json:"tasks_total"`
 	TasksCompleted  int64         `json:"tasks_completed"`
 	TasksFailed     int64         `json:"tasks_failed"`
+	TasksCancelled  int64         `json:"tasks_cancelled"` // Also counted in TasksFailed
 	URLsFound       int64         `json:"urls_found"`
+	URLsFiltered    int64         `json:"urls_filtered"` // Dropped by the URL filters
 	CaptchaCount    int64         `json:"captcha_count"`
 	BlockCount      int64         `json:"block_count"`
+	ResultsDropped  int64         `json:"results_dropped"` // Lost to a full results buffer
+	UniqueDomains   int64         `json:"unique_domains"`
+	RetryQueued     int64         `json:"retry_queued"` // Tasks waiting to be retried
+	WireBytes       int64         `json:"wire_bytes"`    // Response bytes over the connection
+	DecodedBytes    int64         `json:"decoded_bytes"` // Response bytes after decompression
 	TotalDuration   time.Duration `json:"total_duration"`
 	RequestsPerSec  float64       `json:"requests_per_sec"`
+
+	// RequestsPerSecRecent is the completion rate over roughly the last
+	// 30 seconds, a moving average that follows slowdowns the lifetime
+	// RequestsPerSec hides
+	RequestsPerSecRecent float64 `json:"requests_per_sec_recent"`
+
+	// EffectiveConcurrency is how many workers may process tasks at once,
+	// below the worker count while adaptive concurrency throttles
+	EffectiveConcurrency int `json:"effective_concurrency"`
+
+	// RemainingRuntime is the time left before MaxRuntime (0 = no limit)
+	RemainingRuntime time.Duration `json:"remaining_runtime"`
+
+	// Circuit breaker state and how often it tripped ("" = disabled)
+	BreakerState BreakerState `json:"breaker_state,omitempty"`
+	BreakerTrips int64        `json:"breaker_trips"`
+}
+
+// DedupStats counts result URLs seen in dedup mode
+type DedupStats struct {
+	Total  int64 `json:"total"`  // URLs before deduplication
+	Unique int64 `json:"unique"` // URLs emitted
 }
 
 // Worker handles the actual work
@@ -102,41 +359,197 @@ type Worker struct {
 	pool     *proxy.Pool
 	stealth  *stealth.Manager
 	engine   engine.SearchEngine
+	engines  []engine.SearchEngine // Fan-out set in multi-engine mode
 
-	// Channels
-	tasks    chan *Task
+	// Queues
+	tasks    *taskQueue
 	results  chan *Result
+	drained  chan struct{}
 	stopCh   chan struct{}
 
+	// Parent of every task context, cancelled by Stop so in-flight
+	// requests are aborted rather than waited out
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
+
+	// Guards result sends against Stop closing the channel
+	resultsMu     sync.RWMutex
+	resultsClosed bool
+
 	// State
 	running  atomic.Bool
+	pause    *pauseGate
 	wg       sync.WaitGroup
+	nextID   int // ID for the next worker goroutine
+
+	// Guards the config fields UpdateConfig may change
+	configMu sync.RWMutex
+
+	// Paces requests to Config.GlobalRPM, shared by all workers
+	limiter *rate.Limiter
+
+	// Parks workers in adaptive concurrency mode (nil = disabled)
+	adaptive *adaptiveLimiter
+
+	// Parks every worker while the block rate spikes (nil = disabled)
+	breaker *circuitBreaker
+
+	// Paces each proxy per the timing profile (nil = disabled)
+	timing *stealth.TimingManager
+
+	// Randomizes delays and backoff jitter
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// Run deadline
+	deadline     *time.Timer
+	deadlineMu   sync.Mutex
+	deadlineHit  atomic.Bool
+	deadlineCh   chan struct{}
 
 	// Stats
 	stats    Stats
 	statsMu  sync.RWMutex
 	startTime time.Time
-
-	// HTTP client (will be replaced per-request with proxy)
-	baseTransport *http.Transport
+	priorElapsed time.Duration // Run time restored from a checkpoint
+	throughput   *rateMeter    // Recent completions
+
+	// Finished task ID -> completed (false = failed), guarding the
+	// completion counters so checkpoints see both consistently
+	finished   map[string]bool
+	finishedMu sync.Mutex
+
+	// Domains already emitted in unique-domains mode
+	seenDomains map[string]bool
+	domainsMu   sync.Mutex
+
+	// Exclude patterns including the domain denylist
+	excludeURLs []*regexp.Regexp
+
+	// Normalized URLs already emitted in dedup mode
+	seenURLs   map[string]bool
+	dedupStats DedupStats
+	urlsMu     sync.Mutex
+
+	// Dork -> proxy ID for sticky tasks, and session key -> proxy ID
+	sticky   map[string]string
+	sessions map[string]string
+	stickyMu sync.Mutex
+
+	// In-flight task ID -> cancel func, and IDs cancelled before a
+	// worker picked them up -> when, forgotten after cancelMemory
+	inflight  map[string]context.CancelFunc
+	cancelled map[string]time.Time
+	cancelMu  sync.Mutex
+
+	// Set while workers wait out a pool-wide cooldown
+	poolCooling    atomic.Bool
+	onPoolCooldown func(until time.Time)
+
+	captchaSolver CaptchaSolver // nil = rotate proxies on a CAPTCHA
+
+	transports *transportCache // Per proxy ID
+	dnsCache   *dnsCache
+	recorder   *recorder
+	cookies    *cookieJars // Per proxy ID
+	nav        *navigations
+	warmed     *warmUps
 }
 
 // New creates a new worker
 func New(config Config, proxyPool *proxy.Pool) *Worker {
-	return &Worker{
+	var cache *dnsCache
+	if config.DNSCacheSize > 0 {
+		ttl := config.DNSCacheTTL
+		if ttl <= 0 {
+			ttl = 5 * time.Minute
+		}
+		cache = newDNSCache(config.DNSCacheSize, ttl)
+	}
+
+	var rec *recorder
+	if config.RecordDir != "" {
+		rec = newRecorder(config.RecordDir, config.RecordMaxBytes, config.RecordHTML)
+	}
+
+	var adaptive *adaptiveLimiter
+	if config.AdaptiveConcurrency {
+		adaptive = newAdaptiveLimiter(config.Workers, config.AdaptiveWindow, config.AdaptiveThreshold)
+	}
+
+	var breaker *circuitBreaker
+	if config.CircuitBreaker {
+		breaker = newCircuitBreaker(config.BreakerWindow, config.BreakerThreshold, config.BreakerCooldown, config.BreakerProbes)
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	var timing *stealth.TimingManager
+	if config.TimingProfile != "" {
+		timing = stealth.NewTimingManagerWithSeed(stealth.TimingProfile(config.TimingProfile), seed)
+	}
+
+	stopCtx, stopCancel := context.WithCancel(context.Background())
+
+	w := &Worker{
 		config:  config,
 		pool:    proxyPool,
-		stealth: stealth.NewManager(),
-		engine:  engine.NewGoogle(),
-		tasks:   make(chan *Task, config.BufferSize),
+		stealth: stealth.NewManagerWithSeed(seed),
+		engine:  engine.NewGoogleWithSeed(seed),
+		tasks:   newTaskQueue(config.BufferSize, config.PriorityAging),
 		results: make(chan *Result, config.BufferSize),
+		drained: make(chan struct{}, 1),
 		stopCh:  make(chan struct{}),
-		baseTransport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     90 * time.Second,
-		},
+		stopCtx:     stopCtx,
+		pause:       newPauseGate(),
+		stopCancel:  stopCancel,
+		deadlineCh:  make(chan struct{}),
+		seenDomains: make(map[string]bool),
+		seenURLs:    make(map[string]bool),
+		finished:    make(map[string]bool),
+		sticky:      make(map[string]string),
+		sessions:    make(map[string]string),
+		inflight:    make(map[string]context.CancelFunc),
+		cancelled:   make(map[string]time.Time),
+		excludeURLs: urlExcludes(config),
+		transports:  newTransportCache(),
+		dnsCache:    cache,
+		recorder:    rec,
+		cookies:     newCookieJars(),
+		nav:         newNavigations(),
+		warmed:      newWarmUps(),
+		limiter:     rate.NewLimiter(rpmLimit(config.GlobalRPM), 1),
+		adaptive:    adaptive,
+		breaker:     breaker,
+		timing:      timing,
+		rng:         rand.New(rand.NewSource(seed)),
+		throughput:  newRateMeter(recentRateWindow),
+	}
+
+	w.configureStealth(w.stealth)
+
+	// A removed proxy's connections and sessions are not coming back
+	// into use
+	if proxyPool != nil {
+		proxyPool.OnRemove(func(proxyID string) {
+			w.transports.evict(proxyID)
+			w.warmed.reset(proxyID)
+		})
+	}
+
+	return w
+}
+
+// rpmLimit converts requests per minute to a limiter rate, where 0 means
+// unlimited
+func rpmLimit(rpm int) rate.Limit {
+	if rpm <= 0 {
+		return rate.Inf
 	}
+	return rate.Limit(float64(rpm) / 60)
 }
 
 // Start starts the worker pool
@@ -147,24 +560,194 @@ func (w *Worker) Start() {
 
 	w.running.Store(true)
 	w.startTime = time.Now()
+	w.throughput.reset(w.startTime)
 
 	// Start worker goroutines
-	for i := 0; i < w.config.Workers; i++ {
+	w.configMu.Lock()
+	w.spawnWorkers(w.config.Workers)
+	w.configMu.Unlock()
+
+	if w.config.MaxRuntime > 0 {
+		w.deadlineMu.Lock()
+		w.deadline = time.AfterFunc(w.config.MaxRuntime, w.expire)
+		w.deadlineMu.Unlock()
+	}
+}
+
+// spawnWorkers starts n more worker goroutines. configMu must be held.
+func (w *Worker) spawnWorkers(n int) {
+	for i := 0; i < n; i++ {
 		w.wg.Add(1)
-		go w.worker(i)
+		go w.worker(w.nextID)
+		w.nextID++
 	}
 }
 
-// Stop stops the worker pool
-func (w *Worker) Stop() {
+// UpdateConfig applies the delay, retry, global rate and worker count
+// settings of config while the worker runs; other fields are ignored. In-flight
+// requests are not interrupted: new delays apply from the next request,
+// and surplus workers exit once their current task is done. With a
+// TimingProfile the profile paces requests, so the delays have no effect.
+func (w *Worker) UpdateConfig(config Config) {
+	w.configMu.Lock()
+	defer w.configMu.Unlock()
+
+	w.config.BaseDelay = config.BaseDelay
+	w.config.MinDelay = config.MinDelay
+	w.config.MaxDelay = config.MaxDelay
+	w.config.MaxRetries = config.MaxRetries
+	w.config.GlobalRPM = config.GlobalRPM
+	w.limiter.SetLimit(rpmLimit(config.GlobalRPM))
+
+	if config.Workers < 1 || config.Workers == w.config.Workers {
+		return
+	}
+	delta := config.Workers - w.config.Workers
+	w.config.Workers = config.Workers
+	w.adaptive.setMax(config.Workers)
 	if !w.running.Load() {
 		return
 	}
 
-	w.running.Store(false)
+	if delta > 0 {
+		w.spawnWorkers(delta - w.tasks.unretire(delta))
+	} else {
+		w.tasks.retire(-delta)
+	}
+}
+
+// maxRetries returns the current retry limit
+func (w *Worker) maxRetries() int {
+	w.configMu.RLock()
+	defer w.configMu.RUnlock()
+	return w.config.MaxRetries
+}
+
+// expire stops the worker when MaxRuntime elapses
+func (w *Worker) expire() {
+	if !w.running.Load() || !w.deadlineHit.CompareAndSwap(false, true) {
+		return
+	}
+
+	close(w.deadlineCh)
+	w.Stop()
+}
+
+// Stop stops the worker pool, aborting requests in flight without
+// holding them against their proxies
+func (w *Worker) Stop() {
+	if !w.running.CompareAndSwap(true, false) {
+		return
+	}
+
+	// Cancel the deadline so a finished run is not stopped late
+	w.deadlineMu.Lock()
+	if w.deadline != nil {
+		w.deadline.Stop()
+	}
+	w.deadlineMu.Unlock()
+
 	close(w.stopCh)
+	w.stopCancel()
+	w.tasks.close()
+	w.adaptive.close()
+	w.breaker.stop()
 	w.wg.Wait()
+
+	w.resultsMu.Lock()
+	w.resultsClosed = true
 	close(w.results)
+	w.resultsMu.Unlock()
+
+	w.transports.closeAll()
+}
+
+// Pause halts task dispatch without stopping the worker: running tasks
+// finish, but queued tasks wait and delay and retry timers stand still
+// until Resume. Paused time counts towards neither Stats().TotalDuration
+// nor MaxRuntime. Returns false if the worker is stopped or already
+// paused.
+func (w *Worker) Pause() bool {
+	if !w.running.Load() || !w.pause.pause() {
+		return false
+	}
+
+	w.deadlineMu.Lock()
+	if w.deadline != nil {
+		w.deadline.Stop()
+	}
+	w.deadlineMu.Unlock()
+	return true
+}
+
+// Resume continues a paused worker. Returns false if it was not paused.
+func (w *Worker) Resume() bool {
+	if !w.pause.resume() {
+		return false
+	}
+
+	// The deadline restarts with the active time left
+	w.deadlineMu.Lock()
+	if w.deadline != nil && w.running.Load() {
+		w.deadline.Reset(max(w.config.MaxRuntime-w.activeTime(), 0))
+	}
+	w.deadlineMu.Unlock()
+	return true
+}
+
+// IsPaused returns whether the worker is paused
+func (w *Worker) IsPaused() bool {
+	return w.pause.isPaused()
+}
+
+// activeTime returns the time since Start spent outside of pauses
+func (w *Worker) activeTime() time.Duration {
+	return time.Since(w.startTime) - w.pause.pausedFor()
+}
+
+// Drain stops the worker gracefully: new tasks are refused and queued
+// ones are no longer handed out, but tasks already running finish and
+// send their results before the results channel closes. If ctx ends
+// first, running tasks are cancelled, the worker stops and ctx's error
+// is returned.
+func (w *Worker) Drain(ctx context.Context) error {
+	if !w.running.Load() {
+		return nil
+	}
+
+	w.tasks.close()
+	w.adaptive.close()
+	w.breaker.stop()
+
+	// Running tasks cannot finish while paused
+	w.Resume()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		w.cancelInflight()
+	}
+
+	w.Stop()
+	return err
+}
+
+// cancelInflight cancels every running task
+func (w *Worker) cancelInflight() {
+	w.cancelMu.Lock()
+	defer w.cancelMu.Unlock()
+
+	for _, cancel := range w.inflight {
+		cancel()
+	}
 }
 
 // Submit submits a task to the worker pool
@@ -173,13 +756,56 @@ func (w *Worker) Submit(task *Task) error {
 		return fmt.Errorf("worker not running")
 	}
 
-	select {
-	case w.tasks <- task:
-		atomic.AddInt64(&w.stats.TasksTotal, 1)
-		return nil
-	default:
-		return fmt.Errorf("task buffer full")
+	// A resubmitted ID starts fresh
+	w.cancelMu.Lock()
+	delete(w.cancelled, task.ID)
+	w.cancelMu.Unlock()
+
+	// Count before queueing so a fast worker never sees finished > total
+	atomic.AddInt64(&w.stats.TasksTotal, 1)
+	if err := w.tasks.push(task); err != nil {
+		atomic.AddInt64(&w.stats.TasksTotal, -1)
+		return err
+	}
+
+	return nil
+}
+
+// SubmitWait submits a task like Submit, but waits for room instead of
+// failing while the task buffer is full. It fails once the worker stops.
+func (w *Worker) SubmitWait(task *Task) error {
+	if !w.running.Load() {
+		return fmt.Errorf("worker not running")
+	}
+
+	w.cancelMu.Lock()
+	delete(w.cancelled, task.ID)
+	w.cancelMu.Unlock()
+
+	atomic.AddInt64(&w.stats.TasksTotal, 1)
+	if err := w.tasks.pushWait(task); err != nil {
+		atomic.AddInt64(&w.stats.TasksTotal, -1)
+		return err
+	}
+
+	return nil
+}
+
+// SubmitBatch submits tasks in order and returns the IDs of those that
+// could not be queued, e.g. because the task buffer is full. It fails
+// without queueing anything if the worker is not running.
+func (w *Worker) SubmitBatch(tasks []*Task) ([]string, error) {
+	if !w.running.Load() {
+		return nil, fmt.Errorf("worker not running")
+	}
+
+	var rejected []string
+	for _, task := range tasks {
+		if err := w.Submit(task); err != nil {
+			rejected = append(rejected, task.ID)
+		}
 	}
+	return rejected, nil
 }
 
 // Results returns the results channel
@@ -193,182 +819,1195 @@ func (w *Worker) Stats() Stats {
 	defer w.statsMu.RUnlock()
 
 	stats := w.stats
-	stats.TotalDuration = time.Since(w.startTime)
+	stats.TotalDuration = w.activeTime() + w.priorElapsed
+	stats.RetryQueued = int64(w.tasks.retryLen())
 
 	if stats.TotalDuration.Seconds() > 0 {
 		stats.RequestsPerSec = float64(stats.TasksCompleted) / stats.TotalDuration.Seconds()
 	}
+	stats.RequestsPerSecRecent = w.throughput.perSecond(time.Now())
+
+	if w.adaptive != nil {
+		stats.EffectiveConcurrency = w.adaptive.current()
+	} else {
+		w.configMu.RLock()
+		stats.EffectiveConcurrency = w.config.Workers
+		w.configMu.RUnlock()
+	}
+
+	if w.breaker != nil {
+		stats.BreakerState, stats.BreakerTrips = w.breaker.current()
+	}
+
+	if w.config.MaxRuntime > 0 && w.running.Load() {
+		if remaining := w.config.MaxRuntime - stats.TotalDuration; remaining > 0 {
+			stats.RemainingRuntime = remaining
+		}
+	}
 
 	return stats
 }
 
+// Drained returns a channel signalled whenever the last queued task
+// finishes. Results for those tasks are already on the results channel.
+func (w *Worker) Drained() <-chan struct{} {
+	return w.drained
+}
+
+// IsDrained returns whether every submitted task has finished and
+// nothing is queued or in flight
+func (w *Worker) IsDrained() bool {
+	total := atomic.LoadInt64(&w.stats.TasksTotal)
+	finished := atomic.LoadInt64(&w.stats.TasksCompleted) + atomic.LoadInt64(&w.stats.TasksFailed)
+	return total > 0 && finished >= total && w.tasks.idle()
+}
+
+// DeadlineReached returns a channel closed when MaxRuntime stops the worker
+func (w *Worker) DeadlineReached() <-chan struct{} {
+	return w.deadlineCh
+}
+
+// HitDeadline returns whether the run was stopped by MaxRuntime
+func (w *Worker) HitDeadline() bool {
+	return w.deadlineHit.Load()
+}
+
 // worker is the main worker goroutine
 func (w *Worker) worker(id int) {
 	defer w.wg.Done()
 
 	for {
-		select {
-		case <-w.stopCh:
+		if !w.pause.wait(w.stopCtx) {
 			return
-		case task, ok := <-w.tasks:
-			if !ok {
-				return
+		}
+		probe, ok := w.breaker.acquire()
+		if !ok {
+			return
+		}
+		if !w.adaptive.acquire() {
+			w.breaker.release(probe)
+			return
+		}
+		task, ok := w.tasks.pop()
+		if !ok {
+			w.adaptive.release()
+			w.breaker.release(probe)
+			return
+		}
+
+		// A task popped as the worker paused waits for the resume; on
+		// Stop, processTask reports it cancelled
+		w.pause.wait(w.stopCtx)
+		w.processTask(id, task)
+		w.adaptive.release()
+		w.breaker.release(probe)
+
+		if w.tasks.finish() {
+			select {
+			case w.drained <- struct{}{}:
+			default:
 			}
-			w.processTask(id, task)
 		}
 	}
 }
 
-// processTask processes a single task
-func (w *Worker) processTask(workerID int, task *Task) {
-	startTime := time.Now()
-
-	// Get a proxy
-	prx, err := w.pool.Get()
-	if err != nil {
-		w.sendResult(&Result{
-			TaskID:    task.ID,
-			Dork:      task.Dork,
-			Status:    StatusError,
-			Error:     fmt.Sprintf("no proxy available: %v", err),
-			Duration:  time.Since(startTime),
-			Timestamp: time.Now(),
-		})
-		atomic.AddInt64(&w.stats.TasksFailed, 1)
-		return
+// Cancel cancels a task by ID. A queued task is dropped; a running one
+// has its request aborted and is not retried. Either way a cancelled
+// result is sent for it. Returns false when the task is neither queued
+// nor running; the ID is still remembered so a task being picked up at
+// that moment is cancelled too.
+func (w *Worker) Cancel(taskID string) bool {
+	if task := w.tasks.remove(taskID); task != nil {
+		w.sendCancelled(task)
+		if w.tasks.idle() {
+			select {
+			case w.drained <- struct{}{}:
+			default:
+			}
+		}
+		return true
 	}
 
-	// Build search URL
-	searchURL := w.engine.(*engine.Google).BuildSearchURL(task.Dork, task.Page, w.config.ResultsPerPage)
+	w.cancelMu.Lock()
+	defer w.cancelMu.Unlock()
 
-	// Make request
-	html, err := w.makeRequest(searchURL, prx)
-	duration := time.Since(startTime)
+	if cancel, ok := w.inflight[taskID]; ok {
+		cancel()
+		return true
+	}
 
-	if err != nil {
-		w.pool.ReportFailure(prx.ID)
-		w.handleRequestError(task, prx, err, duration)
-		return
+	// Only a task being picked up right now can still match, so IDs that
+	// never turn up are dropped rather than kept for the whole run
+	now := time.Now()
+	for id, at := range w.cancelled {
+		if now.Sub(at) > cancelMemory {
+			delete(w.cancelled, id)
+		}
 	}
+	w.cancelled[taskID] = now
+	return false
+}
 
-	// Check for CAPTCHA
-	if w.engine.(*engine.Google).DetectCaptcha(html) {
-		w.pool.ReportCaptcha(prx.ID)
-		atomic.AddInt64(&w.stats.CaptchaCount, 1)
+// cancelMemory is how long Cancel remembers an ID that was neither queued
+// nor running, in case a worker was just picking the task up
+const cancelMemory = time.Minute
 
-		// Retry with different proxy
-		if task.Retry < w.config.MaxRetries {
-			task.Retry++
-			w.retryTask(task)
-			return
-		}
+// beginTask registers a task as in flight and returns its context, which
+// Cancel and Stop cancel. The context is already cancelled if Cancel got to the
+// task first.
+func (w *Worker) beginTask(task *Task) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(w.stopCtx)
 
-		w.sendResult(&Result{
-			TaskID:    task.ID,
-			Dork:      task.Dork,
-			Status:    StatusCaptcha,
-			ProxyID:   prx.ID,
-			Duration:  duration,
-			Timestamp: time.Now(),
-		})
-		atomic.AddInt64(&w.stats.TasksFailed, 1)
-		return
+	w.cancelMu.Lock()
+	defer w.cancelMu.Unlock()
+
+	if _, ok := w.cancelled[task.ID]; ok {
+		delete(w.cancelled, task.ID)
+		cancel()
 	}
+	w.inflight[task.ID] = cancel
+	return ctx, cancel
+}
 
-	// Check for block
-	if w.engine.(*engine.Google).DetectBlock(html) {
-		w.pool.ReportBlock(prx.ID)
-		atomic.AddInt64(&w.stats.BlockCount, 1)
+// endTask unregisters an in-flight task
+func (w *Worker) endTask(task *Task, cancel context.CancelFunc) {
+	w.cancelMu.Lock()
+	delete(w.inflight, task.ID)
+	w.cancelMu.Unlock()
+	cancel()
+}
 
-		// Retry with different proxy
-		if task.Retry < w.config.MaxRetries {
-			task.Retry++
-			w.retryTask(task)
-			return
-		}
+// sendCancelled records and sends the final result for a cancelled task
+func (w *Worker) sendCancelled(task *Task) {
+	result := &Result{
+		TaskID:    task.ID,
+		Dork:      task.Dork,
+		Status:    StatusCancelled,
+		Error:     "task cancelled",
+		Timestamp: time.Now(),
+	}
+	atomic.AddInt64(&w.stats.TasksCancelled, 1)
+	w.recordResult(result)
+	w.sendResult(result)
+}
 
-		w.sendResult(&Result{
-			TaskID:    task.ID,
-			Dork:      task.Dork,
-			Status:    StatusBlocked,
-			ProxyID:   prx.ID,
-			Duration:  duration,
-			Timestamp: time.Now(),
-		})
-		atomic.AddInt64(&w.stats.TasksFailed, 1)
+// processTask processes a single task
+func (w *Worker) processTask(workerID int, task *Task) {
+	ctx, cancel := w.beginTask(task)
+	defer w.endTask(task, cancel)
+
+	if ctx.Err() != nil {
+		w.sendCancelled(task)
 		return
 	}
 
-	// Parse results
-	results := w.engine.(*engine.Google).ParseResults(html)
+	if result := w.checkDork(task); result != nil {
+		w.recordResult(result)
+		w.sendResult(result)
+		return
+	}
 
-	// Report success
-	w.pool.ReportSuccess(prx.ID, duration)
+	result, retryable := w.execute(ctx, task)
+	if ctx.Err() != nil {
+		w.sendCancelled(task)
+		return
+	}
 
-	// Check for no results
-	if len(results) == 0 {
-		if w.engine.(*engine.Google).DetectNoResults(html) {
-			w.sendResult(&Result{
-				TaskID:    task.ID,
-				Dork:      task.Dork,
-				Status:    StatusNoResults,
-				URLs:      results,
-				ProxyID:   prx.ID,
-				Duration:  duration,
-				Timestamp: time.Now(),
-			})
-		} else {
-			w.sendResult(&Result{
-				TaskID:    task.ID,
-				Dork:      task.Dork,
-				Status:    StatusSuccess,
-				URLs:      results,
-				ProxyID:   prx.ID,
-				Duration:  duration,
-				Timestamp: time.Now(),
-			})
-		}
-		atomic.AddInt64(&w.stats.TasksCompleted, 1)
+	// Retry with different proxy
+	if retryable && task.Retry < w.maxRetries() {
+		delay := w.computeBackoff(task.Retry, result.Status)
+		task.Retry++
+		w.retryTask(ctx, task, delay)
 		return
 	}
 
-	// Success with results
-	atomic.AddInt64(&w.stats.URLsFound, int64(len(results)))
-	atomic.AddInt64(&w.stats.TasksCompleted, 1)
+	if result.Status == StatusSuccess {
+		more := result.nextPage && result.Pages < w.pageLimit(task)
+		if !w.capResults(result, more) && result.nextPage {
+			w.crawlPages(ctx, task, result)
+			if ctx.Err() != nil {
+				w.sendCancelled(task)
+				return
+			}
+		}
+	}
 
-	w.sendResult(&Result{
-		TaskID:    task.ID,
-		Dork:      task.Dork,
-		Status:    StatusSuccess,
-		URLs:      results,
-		ProxyID:   prx.ID,
-		Duration:  duration,
-		Timestamp: time.Now(),
-	})
+	if w.config.UniqueDomains {
+		result.URLs = w.claimDomains(result.URLs)
+	}
+	if w.config.Dedup {
+		result.URLs = w.dedupURLs(result.URLs)
+	}
+
+	result.Warnings = task.warnings
+	w.recordResult(result)
+	w.sendResult(result)
 
 	// Apply delay before next request
-	w.applyDelay()
+	if result.Status == StatusSuccess && len(result.URLs) > 0 && !w.config.DryRun {
+		w.applyDelay()
+	}
 }
 
-// makeRequest makes an HTTP request through a proxy
-func (w *Worker) makeRequest(targetURL string, prx *proxy.Proxy) (string, error) {
-	// Parse proxy URL
-	proxyURL, err := url.Parse(prx.URL())
-	if err != nil {
-		return "", fmt.Errorf("invalid proxy URL: %w", err)
+// checkDork runs a task's dork through engine.ValidateDork, keeping the
+// cleaned-up query to search. A dork that cannot match anything is still
+// searched as written, with the reason among the warnings, unless
+// StrictDorks is set: then the failed result is returned.
+func (w *Worker) checkDork(task *Task) *Result {
+	if task.query != "" {
+		return nil
 	}
 
-	// Create transport with proxy
-	transport := &http.Transport{
-		Proxy:               http.ProxyURL(proxyURL),
-		MaxIdleConns:        10,
-		IdleConnTimeout:     30 * time.Second,
-		TLSHandshakeTimeout: 10 * time.Second,
+	query, warnings, err := engine.ValidateDork(task.Dork)
+	if err != nil {
+		if w.config.StrictDorks {
+			return &Result{
+				TaskID:    task.ID,
+				Dork:      task.Dork,
+				Status:    StatusError,
+				Error:     err.Error(),
+				Warnings:  warnings,
+				Timestamp: time.Now(),
+			}
+		}
+		query = task.Dork
+		warnings = append(warnings, err.Error())
 	}
 
-	// Create client
+	task.query, task.warnings = query, warnings
+	return nil
+}
+
+// pageLimit returns how many pages a task may crawl, clamped to
+// 1..MaxPagesLimit
+func (w *Worker) pageLimit(task *Task) int {
+	pages := task.MaxPages
+	if pages <= 0 {
+		pages = w.config.MaxPages
+	}
+	if pages < 1 {
+		pages = 1
+	}
+	if pages > MaxPagesLimit {
+		pages = MaxPagesLimit
+	}
+	return pages
+}
+
+// crawlPages fetches the pages following the task's first page into
+// result through the usual proxy rotation and delays, stopping at the
+// page limit, the last page, an empty page or the first failed page,
+// such as a CAPTCHA or block. A page left empty by filtering does not end
+// the crawl. Later pages are not retried; what was fetched so far is kept.
+func (w *Worker) crawlPages(ctx context.Context, task *Task, result *Result) {
+	limit := w.pageLimit(task)
+
+	for result.Pages < limit {
+		w.applyDelay()
+		if ctx.Err() != nil {
+			return
+		}
+
+		next := *task
+		next.Page = task.Page + result.Pages
+		pageResult, _ := w.execute(ctx, &next)
+		if pageResult.Status != StatusSuccess || (len(pageResult.URLs) == 0 && !pageResult.nextPage) {
+			return
+		}
+
+		for _, r := range pageResult.URLs {
+			r.Position = len(result.URLs) + 1
+			result.URLs = append(result.URLs, r)
+		}
+		result.Pages++
+		result.Duration += pageResult.Duration
+		result.Latency += pageResult.Latency
+		result.Timestamp = pageResult.Timestamp
+
+		if w.capResults(result, pageResult.nextPage && result.Pages < limit) || !pageResult.nextPage {
+			return
+		}
+	}
+}
+
+// capResults cuts result back to MaxResultsPerDork URLs, counting only
+// those dedup will keep. It reports whether the cap was reached; result
+// is marked truncated when URLs were cut or more pages would have been
+// fetched.
+func (w *Worker) capResults(result *Result, more bool) bool {
+	limit := w.config.MaxResultsPerDork
+	if limit <= 0 {
+		return false
+	}
+
+	cut, reached := w.capIndex(result.URLs, limit)
+	if !reached {
+		return false
+	}
+	if cut < len(result.URLs) || more {
+		result.Truncated = true
+	}
+	result.URLs = result.URLs[:cut]
+	return true
+}
+
+// capIndex returns how many of urls to keep so that limit of them count,
+// and whether that many do. With Dedup only URLs not yet emitted and not
+// repeated in urls count, so duplicates never use up the cap. With
+// UniqueDomains a domain repeated on a later page counts once.
+func (w *Worker) capIndex(urls []engine.SearchResult, limit int) (int, bool) {
+	if !w.config.Dedup && !w.config.UniqueDomains {
+		if len(urls) < limit {
+			return len(urls), false
+		}
+		return limit, true
+	}
+
+	w.urlsMu.Lock()
+	defer w.urlsMu.Unlock()
+
+	seen := make(map[string]bool, len(urls))
+	count := 0
+	for i, r := range urls {
+		key := engine.NormalizeURL(r.URL)
+		if (w.config.Dedup && w.seenURLs[key]) || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		count++
+		if count == limit {
+			return i + 1, true
+		}
+	}
+	return len(urls), false
+}
+
+// buildSearchURL builds the URL for a task searched through proxyID,
+// passing its search options, with the proxy as the session, to engines
+// that accept them
+func (w *Worker) buildSearchURL(e engine.SearchEngine, task *Task, proxyID string) string {
+	if b, ok := e.(engine.OptionsBuilder); ok {
+		opts := engine.SearchOptions{
+			TimeRange: task.TimeRange,
+			Country:   task.Country,
+			Language:  task.Language,
+			Session:   proxyID,
+		}
+		return b.BuildSearchURLWithOptions(task.searchQuery(), task.Page, w.config.ResultsPerPage, opts)
+	}
+	return e.BuildSearchURL(task.searchQuery(), task.Page, w.config.ResultsPerPage)
+}
+
+// SearchOnce runs a single query synchronously, retrying with other proxies
+// as processTask would, and returns the result directly instead of sending
+// it on the results channel
+func (w *Worker) SearchOnce(ctx context.Context, dork string, page int) (*Result, error) {
+	task := &Task{
+		ID:   fmt.Sprintf("once-%d", time.Now().UnixNano()),
+		Dork: dork,
+		Page: page,
+	}
+	atomic.AddInt64(&w.stats.TasksTotal, 1)
+
+	if result := w.checkDork(task); result != nil {
+		w.recordResult(result)
+		return result, fmt.Errorf("search failed: %s", result.Error)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			atomic.AddInt64(&w.stats.TasksFailed, 1)
+			return nil, err
+		}
+
+		result, retryable := w.execute(ctx, task)
+		if retryable && task.Retry < w.maxRetries() && ctx.Err() == nil {
+			delay := w.computeBackoff(task.Retry, result.Status)
+			task.Retry++
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		if w.config.UniqueDomains {
+			result.URLs = w.claimDomains(result.URLs)
+		}
+		result.Warnings = task.warnings
+		w.recordResult(result)
+
+		switch result.Status {
+		case StatusSuccess, StatusNoResults:
+			return result, nil
+		case StatusError:
+			return result, fmt.Errorf("search failed: %s", result.Error)
+		default:
+			return result, fmt.Errorf("search failed: %s", result.Status)
+		}
+	}
+}
+
+// execute performs one attempt of a task and classifies the outcome.
+// retryable reports whether another proxy might succeed.
+func (w *Worker) execute(ctx context.Context, task *Task) (result *Result, retryable bool) {
+	if w.config.DryRun {
+		return w.previewURLs(task), false
+	}
+
+	if len(w.engines) > 1 {
+		result, retryable = w.executeAll(ctx, task)
+	} else {
+		result, retryable = w.executeOn(ctx, task, w.engine)
+	}
+
+	if len(result.URLs) > 0 && (len(w.config.IncludePatterns) > 0 || len(w.excludeURLs) > 0) {
+		kept := FilterURLs(result.URLs, w.config.IncludePatterns, w.excludeURLs)
+		atomic.AddInt64(&w.stats.URLsFiltered, int64(len(result.URLs)-len(kept)))
+		result.URLs = kept
+	}
+
+	if len(result.URLs) > 0 {
+		atomic.AddInt64(&w.stats.URLsFound, int64(len(result.URLs)))
+
+		if w.config.UniqueDomains {
+			result.URLs = w.collapseDomains(result.URLs)
+		}
+	}
+
+	return result, retryable
+}
+
+// previewURLs returns a dry-run result listing the search URL of every
+// page the task would fetch, on each engine in turn
+func (w *Worker) previewURLs(task *Task) *Result {
+	engines := w.engines
+	if len(engines) == 0 {
+		engines = []engine.SearchEngine{w.engine}
+	}
+
+	result := &Result{
+		TaskID: task.ID,
+		Dork:   task.Dork,
+		Status: StatusSuccess,
+		Pages:  w.pageLimit(task),
+	}
+
+	page := *task
+	for _, e := range engines {
+		for i := 0; i < result.Pages; i++ {
+			page.Page = task.Page + i
+			preview := engine.SearchResult{
+				URL:      w.buildSearchURL(e, &page, ""),
+				Position: len(result.URLs) + 1,
+			}
+			// Tagged with the engine as merged results are
+			if len(engines) > 1 {
+				preview.Engines = []string{e.Name()}
+			}
+			result.URLs = append(result.URLs, preview)
+		}
+	}
+
+	result.Timestamp = time.Now()
+	return result
+}
+
+// executeAll runs one attempt of a task on every engine at once, each
+// with its own proxy, and merges the URLs. The merged result succeeds if
+// any engine did and is retryable only when every engine failed.
+func (w *Worker) executeAll(ctx context.Context, task *Task) (*Result, bool) {
+	results := make([]*Result, len(w.engines))
+	retryable := make([]bool, len(w.engines))
+
+	var wg sync.WaitGroup
+	for i, e := range w.engines {
+		wg.Add(1)
+		go func(i int, e engine.SearchEngine) {
+			defer wg.Done()
+			results[i], retryable[i] = w.executeOn(ctx, task, e)
+		}(i, e)
+	}
+	wg.Wait()
+
+	return mergeResults(task, w.engines, results, retryable)
+}
+
+// mergeResults combines per-engine results for a task, deduplicating URLs
+// and tagging each with the engines that returned it
+func mergeResults(task *Task, engines []engine.SearchEngine, results []*Result, retryable []bool) (*Result, bool) {
+	merged := &Result{
+		TaskID:    task.ID,
+		Dork:      task.Dork,
+		Timestamp: time.Now(),
+	}
+
+	index := make(map[string]int)
+	var proxyIDs, errs []string
+	var failed *Result
+	anyRetryable := false
+
+	for i, r := range results {
+		name := engines[i].Name()
+		if r.ProxyID != "" {
+			proxyIDs = append(proxyIDs, r.ProxyID)
+		}
+		if r.Duration > merged.Duration {
+			merged.Duration = r.Duration
+		}
+		if r.Latency > merged.Latency {
+			merged.Latency = r.Latency
+		}
+
+		switch r.Status {
+		case StatusSuccess, StatusNoResults:
+			if merged.Status != StatusSuccess {
+				merged.Status = r.Status
+			}
+			if r.Pages > merged.Pages {
+				merged.Pages = r.Pages
+			}
+			merged.StatusCode = r.StatusCode
+			merged.nextPage = merged.nextPage || r.nextPage
+		default:
+			if failed == nil {
+				failed = r
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", name, firstNonEmpty(r.Error, string(r.Status))))
+			anyRetryable = anyRetryable || retryable[i]
+			continue
+		}
+
+		for _, u := range r.URLs {
+			if at, ok := index[u.URL]; ok {
+				merged.URLs[at].Engines = append(merged.URLs[at].Engines, name)
+				continue
+			}
+			u.Engines = []string{name}
+			u.Position = len(merged.URLs) + 1
+			index[u.URL] = len(merged.URLs)
+			merged.URLs = append(merged.URLs, u)
+		}
+	}
+
+	merged.ProxyID = strings.Join(proxyIDs, ",")
+
+	// Every engine failed: report the first failure and retry if any might
+	// succeed on another proxy
+	if merged.Status == "" {
+		merged.Status = failed.Status
+		merged.StatusCode = failed.StatusCode
+		merged.Error = strings.Join(errs, "; ")
+		return merged, anyRetryable
+	}
+
+	// URLs found on one engine outweigh an empty page on another
+	if len(merged.URLs) > 0 {
+		merged.Status = StatusSuccess
+	}
+
+	return merged, false
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// executeOn performs one attempt of a task on a single engine
+func (w *Worker) executeOn(ctx context.Context, task *Task, e engine.SearchEngine) (result *Result, retryable bool) {
+	startTime := time.Now()
+
+	// Wait for the global rate limit before taking a proxy
+	if err := w.waitForRate(ctx); err != nil {
+		return &Result{
+			TaskID:    task.ID,
+			Dork:      task.Dork,
+			Status:    StatusError,
+			Error:     fmt.Sprintf("rate limit wait: %v", err),
+			Duration:  time.Since(startTime),
+			Timestamp: time.Now(),
+		}, false
+	}
+
+	// Get a proxy
+	prx, err := w.getProxy(task)
+	if err != nil && (!w.strictSticky(task) || errors.Is(err, proxy.ErrProxyBusy)) {
+		prx, err = w.waitForProxy(ctx, task)
+	}
+	if err != nil {
+		return &Result{
+			TaskID:    task.ID,
+			Dork:      task.Dork,
+			Status:    StatusError,
+			Error:     fmt.Sprintf("no proxy available: %v", err),
+			Duration:  time.Since(startTime),
+			Timestamp: time.Now(),
+		}, false
+	}
+
+	// Pace the proxy; the pool held it back through any profile
+	// cooldown, and a cancelled wait fails the request below
+	w.waitTiming(ctx, prx.ID)
+
+	// Build search URL
+	searchURL := w.buildSearchURL(e, task, prx.ID)
+	w.cookies.seed(prx.ID, e, searchURL)
+
+	// Make request, following on from the previous page when this
+	// proxy fetched it, or from the homepage when it just warmed up
+	referer := w.nav.referer(prx.ID, task.Dork, task.Page, searchURL)
+	ex := w.recorder.start(task, prx)
+	var html string
+	pageURL := searchURL
+	requestStart := time.Now()
+	home, statusCode, err := w.warmUp(ctx, prx, searchURL)
+	if err == nil {
+		if home != "" {
+			referer = home
+		}
+		var pg page
+		pg, err = w.fetch(ctx, searchURL, referer, nil, prx, ex)
+		html, statusCode = pg.html, pg.statusCode
+		if pg.url != "" {
+			pageURL = pg.url
+		}
+
+		// A CAPTCHA page sent with a 429 goes to the CAPTCHA check
+		// below, where a solver can clear it
+		var limited *rateLimitError
+		if errors.As(err, &limited) && e.DetectCaptcha(html) {
+			err = nil
+		}
+	}
+	duration := time.Since(startTime)
+
+	defer func() {
+		w.recorder.save(ex, result, html)
+	}()
+
+	result = &Result{
+		TaskID:     task.ID,
+		Dork:       task.Dork,
+		ProxyID:    prx.ID,
+		StatusCode: statusCode,
+		Duration:   duration,
+		Latency:    time.Since(requestStart),
+	}
+
+	if err != nil {
+		// Aborted by Cancel or Stop, which cancels every task: the proxy
+		// did nothing wrong, so shutting down never quarantines one
+		if ctx.Err() != nil {
+			w.pool.Release(prx.ID)
+			result.Status = StatusError
+			result.Error = err.Error()
+			result.Timestamp = time.Now()
+			return result, false
+		}
+
+		var limited *rateLimitError
+		if errors.As(err, &limited) {
+			w.pool.ReportRateLimit(prx.ID, limited.retryAfter)
+		} else {
+			w.pool.ReportFailure(prx.ID)
+		}
+		w.recordTiming(prx, StatusError)
+		w.unpinSession(task, prx.ID)
+		result.Status = StatusError
+		result.Error = err.Error()
+		result.Timestamp = time.Now()
+		return result, true
+	}
+
+	// Check for CAPTCHA, which Google serves from the /sorry/ page it
+	// redirected to; a solved one carries on with the page it led to
+	captcha := e.DetectCaptcha(html)
+	if captcha {
+		if solved, statusCode, ok := w.solveCaptcha(ctx, e, prx, pageURL, html); ok {
+			html, captcha = solved, false
+			result.StatusCode = statusCode
+		}
+	}
+	if captcha {
+		w.reportCaptcha(prx, e)
+		atomic.AddInt64(&w.stats.CaptchaCount, 1)
+		w.recordOutcome(true)
+		w.recordTiming(prx, StatusCaptcha)
+		w.unpinSession(task, prx.ID)
+
+		result.Status = StatusCaptcha
+		result.Timestamp = time.Now()
+		return result, true
+	}
+
+	// Check for block
+	if e.DetectBlock(html) {
+		w.reportBlock(prx, e)
+		atomic.AddInt64(&w.stats.BlockCount, 1)
+		w.recordOutcome(true)
+		w.recordTiming(prx, StatusBlocked)
+		w.unpinSession(task, prx.ID)
+
+		result.Status = StatusBlocked
+		result.Timestamp = time.Now()
+		return result, true
+	}
+
+	// Parse results
+	results := e.ParseResults(html)
+
+	// An empty 200 page can still be a block
+	if s, ok := e.(engine.SoftBlockDetector); ok && s.DetectSoftBlock(html, task.searchQuery(), len(results)) {
+		w.reportBlock(prx, e)
+		atomic.AddInt64(&w.stats.BlockCount, 1)
+		w.recordOutcome(true)
+		w.recordTiming(prx, StatusBlocked)
+		w.unpinSession(task, prx.ID)
+
+		result.Status = StatusBlocked
+		result.Timestamp = time.Now()
+		return result, true
+	}
+
+	// Report success
+	w.pool.ReportSuccess(prx.ID, duration)
+	w.recordOutcome(false)
+	w.recordTiming(prx, StatusSuccess)
+
+	result.Status = StatusSuccess
+	result.Pages = 1
+	w.nav.visit(prx.ID, task.Dork, task.Page, searchURL)
+
+	// Check for no results
+	if len(results) == 0 && e.DetectNoResults(html) {
+		result.Status = StatusNoResults
+	}
+
+	// Without pager detection, assume more while pages have results
+	result.nextPage = len(results) > 0
+	if p, ok := e.(engine.Paginator); ok && result.nextPage {
+		result.nextPage = p.HasNextPage(html)
+	}
+
+	result.URLs = results
+	result.Timestamp = time.Now()
+	return result, false
+}
+
+// reportCaptcha cools a proxy down after a CAPTCHA. With several engines
+// the pool's cooldown only fits one of them, so the engine's own timing
+// is applied on top.
+func (w *Worker) reportCaptcha(prx *proxy.Proxy, e engine.SearchEngine) {
+	w.pool.ReportCaptcha(prx.ID)
+	if cooldown := w.timingFor(e).CaptchaCooldown; len(w.engines) > 1 && cooldown > 0 {
+		prx.SetCooldown(cooldown)
+	}
+}
+
+// reportBlock quarantines a blocked proxy, for the blocking engine's
+// cooldown when running several engines
+func (w *Worker) reportBlock(prx *proxy.Proxy, e engine.SearchEngine) {
+	w.pool.ReportBlock(prx.ID)
+	if cooldown := w.timingFor(e).BlockCooldown; len(w.engines) > 1 && cooldown > 0 {
+		prx.SetCooldown(cooldown)
+	}
+}
+
+// waitForRate blocks until the global rate limit allows another request,
+// giving up when ctx is done or the worker stops
+func (w *Worker) waitForRate(ctx context.Context) error {
+	if w.limiter.Limit() == rate.Inf {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-w.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return w.limiter.Wait(ctx)
+}
+
+// getProxy returns a proxy for the task, pinning sticky tasks to the
+// proxy first used for their dork and session tasks to their session's
+func (w *Worker) getProxy(task *Task) (*proxy.Proxy, error) {
+	if task.SessionKey != "" {
+		return w.getSessionProxy(task.SessionKey)
+	}
+	if !task.Sticky {
+		return w.pool.Get()
+	}
+
+	w.stickyMu.Lock()
+	defer w.stickyMu.Unlock()
+
+	if id, ok := w.sticky[task.Dork]; ok {
+		prx, err := w.pool.Acquire(id)
+		if err != nil {
+			return nil, fmt.Errorf("sticky %w", err)
+		}
+		return prx, nil
+	}
+
+	prx, err := w.pool.Get()
+	if err != nil {
+		return nil, err
+	}
+	w.sticky[task.Dork] = prx.ID
+
+	return prx, nil
+}
+
+// strictSticky reports whether the task fails rather than switching
+// proxies when its pinned one is unavailable
+func (w *Worker) strictSticky(task *Task) bool {
+	return task.Sticky && task.SessionKey == ""
+}
+
+// getSessionProxy returns the proxy pinned to a session, waiting for it
+// while busy. A session whose proxy is unavailable moves to a new one.
+func (w *Worker) getSessionProxy(key string) (*proxy.Proxy, error) {
+	w.stickyMu.Lock()
+	defer w.stickyMu.Unlock()
+
+	if id, ok := w.sessions[key]; ok {
+		prx, err := w.pool.Acquire(id)
+		if err == nil || errors.Is(err, proxy.ErrProxyBusy) {
+			return prx, err
+		}
+		delete(w.sessions, key)
+	}
+
+	prx, err := w.pool.Get()
+	if err != nil {
+		return nil, err
+	}
+	w.sessions[key] = prx.ID
+
+	return prx, nil
+}
+
+// unpinSession ends the task's session on a proxy whose request failed,
+// so the next task of the session picks another proxy. A session already
+// moved by a concurrent task is left alone.
+func (w *Worker) unpinSession(task *Task, proxyID string) {
+	if task.SessionKey == "" {
+		return
+	}
+
+	w.stickyMu.Lock()
+	defer w.stickyMu.Unlock()
+	if w.sessions[task.SessionKey] == proxyID {
+		delete(w.sessions, task.SessionKey)
+	}
+}
+
+// SessionProxies returns a copy of the session key to proxy ID mappings,
+// for debugging
+func (w *Worker) SessionProxies() map[string]string {
+	w.stickyMu.Lock()
+	defer w.stickyMu.Unlock()
+
+	sessions := make(map[string]string, len(w.sessions))
+	for key, id := range w.sessions {
+		sessions[key] = id
+	}
+	return sessions
+}
+
+// recordOutcome feeds whether a response was a CAPTCHA or block to
+// adaptive concurrency and the circuit breaker
+func (w *Worker) recordOutcome(flagged bool) {
+	w.adaptive.record(flagged)
+	w.breaker.record(flagged)
+}
+
+// waitForProxy sleeps while every proxy is cooling down or busy and
+// returns the first one to recover, so a block storm pauses tasks instead
+// of failing them. It gives up when no proxy is due to recover, or when a
+// sticky task's pinned proxy becomes unavailable.
+func (w *Worker) waitForProxy(ctx context.Context, task *Task) (*proxy.Proxy, error) {
+	for {
+		next := w.pool.NextAvailableTime()
+		if next.IsZero() {
+			return nil, fmt.Errorf("no available proxies")
+		}
+
+		// Report once per cooling period, not once per waiting goroutine.
+		// Proxies that are merely busy are not cooling down.
+		if next.After(time.Now()) && w.poolCooling.CompareAndSwap(false, true) && w.onPoolCooldown != nil {
+			w.onPoolCooldown(next)
+		}
+
+		wait := time.Until(next)
+		if wait < 10*time.Millisecond {
+			wait = 10 * time.Millisecond
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-w.stopCh:
+			timer.Stop()
+			return nil, fmt.Errorf("worker stopped")
+		case <-timer.C:
+		}
+
+		prx, err := w.getProxy(task)
+		if err == nil {
+			w.poolCooling.Store(false)
+			return prx, nil
+		}
+		if w.strictSticky(task) && !errors.Is(err, proxy.ErrProxyBusy) {
+			return nil, err
+		}
+	}
+}
+
+// recordResult updates completion stats for a final result
+func (w *Worker) recordResult(result *Result) {
+	w.finishedMu.Lock()
+	defer w.finishedMu.Unlock()
+
+	switch result.Status {
+	case StatusSuccess, StatusNoResults:
+		atomic.AddInt64(&w.stats.TasksCompleted, 1)
+		w.throughput.mark(time.Now())
+		w.finished[result.TaskID] = true
+	case StatusCancelled:
+		atomic.AddInt64(&w.stats.TasksFailed, 1)
+	default:
+		atomic.AddInt64(&w.stats.TasksFailed, 1)
+		w.finished[result.TaskID] = false
+	}
+}
+
+// dedupURLs drops results whose normalized URL was already emitted
+func (w *Worker) dedupURLs(results []engine.SearchResult) []engine.SearchResult {
+	w.urlsMu.Lock()
+	defer w.urlsMu.Unlock()
+
+	unique := make([]engine.SearchResult, 0, len(results))
+	for _, r := range results {
+		key := engine.NormalizeURL(r.URL)
+		if w.seenURLs[key] {
+			continue
+		}
+		w.seenURLs[key] = true
+
+		r.Position = len(unique) + 1
+		unique = append(unique, r)
+	}
+
+	w.dedupStats.Total += int64(len(results))
+	w.dedupStats.Unique += int64(len(unique))
+	return unique
+}
+
+// DedupStats returns how many result URLs dedup mode has seen and how
+// many of them were unique
+func (w *Worker) DedupStats() DedupStats {
+	w.urlsMu.Lock()
+	defer w.urlsMu.Unlock()
+	return w.dedupStats
+}
+
+// collapseDomains reduces results to registrable domains not yet emitted,
+// each once. The domains are only marked emitted by claimDomains, after
+// MaxResultsPerDork has cut the result, so a cut domain stays free for
+// other dorks.
+func (w *Worker) collapseDomains(results []engine.SearchResult) []engine.SearchResult {
+	w.domainsMu.Lock()
+	defer w.domainsMu.Unlock()
+
+	seen := make(map[string]bool, len(results))
+	collapsed := make([]engine.SearchResult, 0, len(results))
+	for _, r := range results {
+		domain := engine.RegistrableDomain(r.URL)
+		if domain == "" || w.seenDomains[domain] || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+
+		r.URL = domain
+		r.Position = len(collapsed) + 1
+		collapsed = append(collapsed, r)
+	}
+
+	return collapsed
+}
+
+// claimDomains marks the domains of collapsed results as emitted, dropping
+// those already emitted since they were collapsed, e.g. by an earlier page
+// of the same dork or by another dork
+func (w *Worker) claimDomains(results []engine.SearchResult) []engine.SearchResult {
+	w.domainsMu.Lock()
+	defer w.domainsMu.Unlock()
+
+	claimed := make([]engine.SearchResult, 0, len(results))
+	for _, r := range results {
+		if w.seenDomains[r.URL] {
+			continue
+		}
+		w.seenDomains[r.URL] = true
+
+		r.Position = len(claimed) + 1
+		claimed = append(claimed, r)
+	}
+
+	atomic.StoreInt64(&w.stats.UniqueDomains, int64(len(w.seenDomains)))
+	return claimed
+}
+
+// transportFor returns the transport for a request through a proxy. The
+// transport is built once per proxy, and again only when the proxy's
+// address or, with TLSFingerprint, its fingerprint changes, so requests
+// through a proxy share its idle connections.
+func (w *Worker) transportFor(prx *proxy.Proxy, fp *stealth.Fingerprint) (http.RoundTripper, error) {
+	switch custom := w.config.Transport.(type) {
+	case nil, *http.Transport:
+	case ProxyTransport:
+		return custom.ForProxy(prx)
+	default:
+		return nil, fmt.Errorf("custom transport %T cannot apply proxy %s", custom, prx.ID)
+	}
+
+	key := prx.URL()
+	if w.config.TLSFingerprint && fp != nil {
+		key += "|" + fp.ID
+	}
+	return w.transports.get(prx.ID, key, func() (http.RoundTripper, error) {
+		return w.buildTransport(prx, fp)
+	})
+}
+
+// buildTransport builds the transport for a proxy, starting from
+// Config.Transport when it is an *http.Transport. With TLSFingerprint the
+// handshake mimics fp's browser.
+func (w *Worker) buildTransport(prx *proxy.Proxy, fp *stealth.Fingerprint) (*http.Transport, error) {
+	var transport *http.Transport
+	if custom, ok := w.config.Transport.(*http.Transport); ok {
+		transport = custom.Clone()
+	} else {
+		transport = &http.Transport{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     30 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}
+	}
+
+	if w.config.ForceHTTP1 {
+		forceHTTP1(transport)
+	}
+
+	if w.config.TLSFingerprint {
+		if err := w.applyTLSFingerprint(transport, prx, fp); err != nil {
+			return nil, err
+		}
+		return transport, nil
+	}
+
+	// SOCKS proxies get a dialer doing the handshake and are dialed by
+	// address; the DNS cache only resolves HTTP proxy hostnames
+	if prx.Type == proxy.ProxyTypeSOCKS4 || prx.Type == proxy.ProxyTypeSOCKS5 {
+		dial, err := socksDialer(prx)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = nil
+		transport.DialContext = dial
+		return transport, nil
+	}
+
+	// Parse proxy URL
+	proxyURL, err := url.Parse(prx.URL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	transport.Proxy = http.ProxyURL(proxyURL)
+
+	// net/http dials the proxy, not the target, with DialContext
+	if w.dnsCache != nil {
+		transport.DialContext = w.dnsCache.DialContext
+	}
+
+	return transport, nil
+}
+
+// forceHTTP1 turns off HTTP/2 on transport: a non-nil, empty TLSNextProto
+// stops it being used, and dropping h2 from the ALPN list stops servers
+// choosing it. Cloning a transport set up for HTTP/2 copies "h2" into the
+// clone's TLS config.
+func forceHTTP1(transport *http.Transport) {
+	transport.ForceAttemptHTTP2 = false
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+
+	if transport.TLSClientConfig == nil {
+		return
+	}
+	var protos []string
+	for _, proto := range transport.TLSClientConfig.NextProtos {
+		if proto != "h2" {
+			protos = append(protos, proto)
+		}
+	}
+	transport.TLSClientConfig.NextProtos = protos
+}
+
+// makeRequest makes an HTTP request through a proxy, navigating from
+// referer (empty = typed into the address bar). It returns the body and
+// the response's status code, 0 when there was no response.
+func (w *Worker) makeRequest(ctx context.Context, targetURL, referer string, prx *proxy.Proxy, ex *exchange) (string, int, error) {
+	pg, err := w.fetch(ctx, targetURL, referer, nil, prx, ex)
+	return pg.html, pg.statusCode, err
+}
+
+// page is a response fetched by the worker
+type page struct {
+	html       string
+	statusCode int    // 0 when there was no response
+	url        string // Where redirects ended up
+}
+
+// fetch requests targetURL through a proxy like makeRequest, submitting
+// form as a POST when it isn't nil, and also returns the URL the
+// response came from after redirects.
+func (w *Worker) fetch(ctx context.Context, targetURL, referer string, form url.Values, prx *proxy.Proxy, ex *exchange) (page, error) {
+	// Cookies and fingerprint are kept per proxy; direct requests share
+	// the empty ID
+	var proxyID string
+	if prx != nil {
+		proxyID = prx.ID
+	}
+
+	// The fingerprint is pinned to the proxy so its browser identity
+	// stays consistent, and shared by the headers and TLS handshake
+	fp := w.stealth.GetFingerprintForProxy(proxyID)
+
+	transport, err := w.transportFor(prx, fp)
+	if err != nil {
+		return page{}, err
+	}
+
+	// Create client
 	client := &http.Client{
 		Transport: transport,
+		Jar:       w.cookies.get(proxyID),
 		Timeout:   w.config.RequestTimeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 3 {
@@ -379,117 +2018,286 @@ func (w *Worker) makeRequest(targetURL string, prx *proxy.Proxy) (string, error)
 	}
 
 	// Create request
-	req, err := http.NewRequestWithContext(context.Background(), "GET", targetURL, nil)
+	method, reqBody := "GET", io.Reader(nil)
+	if form != nil {
+		method, reqBody = "POST", strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return page{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers from stealth manager
-	headers := w.stealth.GetHeaders()
+	headers := w.stealth.HeadersFor(fp)
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
 	// Additional headers
-	req.Header.Set("Referer", "https://www.google.com/")
+	setNavigationHeaders(req.Header, referer)
 	req.Header.Set("DNT", "1")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", req.URL.Scheme+"://"+req.URL.Host)
+	}
+	ex.recordRequest(req)
 
 	// Make request
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return page{}, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("bad status code: %d", resp.StatusCode)
+	ex.recordResponse(resp)
+	pg := page{statusCode: resp.StatusCode, url: resp.Request.URL.String()}
+
+	// Check status code; a 429 says how long to back off, but its body
+	// is still read since Google serves its CAPTCHA page with one
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	if !limited && resp.StatusCode != http.StatusOK {
+		return pg, fmt.Errorf("bad status code: %d", resp.StatusCode)
 	}
 
 	// Read body
-	body, err := io.ReadAll(resp.Body)
+	body, wireBytes, err := readBody(resp, w.config.MaxBodyBytes)
+	atomic.AddInt64(&w.stats.WireBytes, wireBytes)
+	atomic.AddInt64(&w.stats.DecodedBytes, int64(len(body)))
+	if err == nil {
+		pg.html = string(body)
+	}
+	if limited {
+		return pg, &rateLimitError{retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to read body: %w", err)
+		return pg, fmt.Errorf("failed to read body: %w", err)
 	}
 
-	return string(body), nil
+	return pg, nil
 }
 
-// handleRequestError handles request errors
-func (w *Worker) handleRequestError(task *Task, prx *proxy.Proxy, err error, duration time.Duration) {
-	// Retry if possible
-	if task.Retry < w.config.MaxRetries {
-		task.Retry++
-		w.retryTask(task)
-		return
+// backoffJitter is the random spread applied to retry delays (±20%)
+const backoffJitter = 0.2
+
+// computeBackoff returns how long to wait before retrying a task that has
+// already been retried retry times and last failed with reason. The delay
+// is base * 2^retry with ±20% jitter, capped at MaxRetryDelay. CAPTCHAs
+// and blocks start from BlockRetryDelay since the engine is pushing back;
+// other failures start from the shorter RetryDelay.
+func (w *Worker) computeBackoff(retry int, reason ResultStatus) time.Duration {
+	base := w.config.RetryDelay
+	if reason == StatusCaptcha || reason == StatusBlocked {
+		base = w.config.BlockRetryDelay
 	}
+	max := w.config.MaxRetryDelay
 
-	w.sendResult(&Result{
-		TaskID:    task.ID,
-		Dork:      task.Dork,
-		Status:    StatusError,
-		Error:     err.Error(),
-		ProxyID:   prx.ID,
-		Duration:  duration,
-		Timestamp: time.Now(),
-	})
-	atomic.AddInt64(&w.stats.TasksFailed, 1)
+	delay := base
+	for i := 0; i < retry && (max <= 0 || delay < max); i++ {
+		delay *= 2
+	}
+
+	w.rngMu.Lock()
+	jitter := 1 + backoffJitter*(2*w.rng.Float64()-1)
+	w.rngMu.Unlock()
+	delay = time.Duration(float64(delay) * jitter)
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay
 }
 
-// retryTask requeues a task for retry
-func (w *Worker) retryTask(task *Task) {
+// retryTask requeues a task for retry after delay, unless it is cancelled
+// while waiting
+func (w *Worker) retryTask(ctx context.Context, task *Task, delay time.Duration) {
 	// Apply retry delay
-	time.Sleep(w.config.RetryDelay)
+	if !w.pause.sleep(ctx, delay) {
+		w.sendCancelled(task)
+		return
+	}
 
-	select {
-	case w.tasks <- task:
-		// Requeued successfully
-	default:
-		// Buffer full, send error
-		w.sendResult(&Result{
-			TaskID:    task.ID,
-			Dork:      task.Dork,
-			Status:    StatusError,
-			Error:     "retry buffer full",
-			Timestamp: time.Now(),
-		})
-		atomic.AddInt64(&w.stats.TasksFailed, 1)
+	// Stop closed the queue during the delay: like the other shutdown
+	// paths the task is cancelled, so a resumed run submits it again
+	if err := w.tasks.pushRetry(task); err != nil {
+		w.sendCancelled(task)
 	}
 }
 
-// sendResult sends a result to the results channel
+// sendResult sends a result to the results channel, applying the
+// overflow policy while it is full. Results sent after Stop, e.g. for a
+// late Cancel, are dropped.
 func (w *Worker) sendResult(result *Result) {
+	w.resultsMu.RLock()
+	defer w.resultsMu.RUnlock()
+
+	if w.resultsClosed {
+		return
+	}
 	select {
 	case w.results <- result:
-		// Sent successfully
+		return
 	default:
-		// Results buffer full, drop oldest or log
+	}
+
+	switch w.config.OverflowPolicy {
+	case OverflowBlock:
+		select {
+		case w.results <- result:
+		case <-w.stopCh:
+			atomic.AddInt64(&w.stats.ResultsDropped, 1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.results <- result:
+				return
+			default:
+			}
+
+			// The consumer may empty the buffer meanwhile
+			select {
+			case <-w.results:
+				atomic.AddInt64(&w.stats.ResultsDropped, 1)
+			default:
+			}
+		}
+	default:
+		atomic.AddInt64(&w.stats.ResultsDropped, 1)
 	}
 }
 
-// applyDelay applies a randomized delay between requests
+// applyDelay applies a randomized delay between requests. With a timing
+// profile each proxy is paced before its requests instead. The delay
+// stands still while the worker is paused and ends early on Stop.
 func (w *Worker) applyDelay() {
+	if w.timing != nil {
+		return
+	}
+
+	w.configMu.RLock()
 	config := stealth.TimingConfig{
 		BaseDelay:     w.config.BaseDelay,
 		MinDelay:      w.config.MinDelay,
 		MaxDelay:      w.config.MaxDelay,
 		JitterPercent: 0.3,
 	}
+	w.configMu.RUnlock()
+
+	w.rngMu.Lock()
+	delay := stealth.CalculateDelay(config, w.rng)
+	w.rngMu.Unlock()
+	w.pause.sleep(w.stopCtx, delay)
+}
+
+// waitTiming waits out the timing profile's delay for proxyID, returning
+// early when ctx is done or the worker stops. The delay stands still
+// while the worker is paused.
+func (w *Worker) waitTiming(ctx context.Context, proxyID string) {
+	if w.timing == nil {
+		return
+	}
+
+	w.pause.sleep(ctx, w.timing.GetDelay(proxyID))
+}
+
+// recordTiming feeds a request outcome through prx to the timing
+// profile. A cooldown it starts is put on the proxy as well: the pool
+// cannot see the profile's cooldowns, and would otherwise lease the proxy
+// to a worker that then sleeps through the cooldown holding it.
+func (w *Worker) recordTiming(prx *proxy.Proxy, status ResultStatus) {
+	if w.timing == nil {
+		return
+	}
+
+	switch status {
+	case StatusCaptcha:
+		w.timing.RecordCaptcha(prx.ID)
+	case StatusBlocked:
+		w.timing.RecordBlock(prx.ID)
+	case StatusError:
+		w.timing.RecordError(prx.ID)
+	default:
+		w.timing.RecordRequest(prx.ID)
+	}
 
-	delay := stealth.CalculateDelay(config, nil)
-	time.Sleep(delay)
+	if remaining := w.timing.GetCooldownRemaining(prx.ID); remaining > 0 {
+		prx.ExtendCooldown(remaining)
+	}
 }
 
-// SetEngine sets a custom search engine
+// SetEngine sets a custom search engine and adopts its recommended
+// cooldowns on the pool and the timing profile
 func (w *Worker) SetEngine(e engine.SearchEngine) {
 	w.engine = e
+	w.engines = nil
+
+	timing := w.timingFor(e)
+	if w.pool != nil {
+		w.pool.SetCooldowns(timing.CaptchaCooldown, timing.BlockCooldown)
+	}
+	if w.timing != nil {
+		w.timing.ApplyEngineCooldowns(timing.CaptchaCooldown, timing.BlockCooldown)
+	}
 }
 
-// SetStealthManager sets a custom stealth manager
+// SetEngines runs every task on all the given engines at once, each with
+// its own proxy, merging the URLs into one result. Proxies flagged by an
+// engine cool down for that engine's timing. One engine is the same as
+// SetEngine.
+func (w *Worker) SetEngines(engines ...engine.SearchEngine) {
+	if len(engines) == 0 {
+		return
+	}
+
+	w.SetEngine(engines[0])
+	if len(engines) > 1 {
+		w.engines = engines
+	}
+}
+
+// timingFor returns an engine's cooldowns with config overrides applied
+func (w *Worker) timingFor(e engine.SearchEngine) engine.Timing {
+	timing := e.Timing()
+	if w.config.CaptchaCooldown > 0 {
+		timing.CaptchaCooldown = w.config.CaptchaCooldown
+	}
+	if w.config.BlockCooldown > 0 {
+		timing.BlockCooldown = w.config.BlockCooldown
+	}
+	return timing
+}
+
+// OnBreaker sets a callback invoked whenever the circuit breaker changes
+// state; until is when an open breaker half-opens. It is not called when
+// the breaker is disabled.
+func (w *Worker) OnBreaker(fn func(state BreakerState, until time.Time)) {
+	if w.breaker == nil {
+		return
+	}
+	w.breaker.mu.Lock()
+	w.breaker.onChange = fn
+	w.breaker.mu.Unlock()
+}
+
+// OnPoolCooldown sets a callback invoked when every proxy is cooling down
+// and workers start waiting; until is when the first proxy recovers
+func (w *Worker) OnPoolCooldown(fn func(until time.Time)) {
+	w.onPoolCooldown = fn
+}
+
+// SetStealthManager sets a custom stealth manager, applying
+// Config.FingerprintRotation to it
 func (w *Worker) SetStealthManager(m *stealth.Manager) {
+	w.configureStealth(m)
 	w.stealth = m
 }
 
+// configureStealth applies Config.FingerprintRotation to m
+func (w *Worker) configureStealth(m *stealth.Manager) {
+	if n := w.config.FingerprintRotation; n > 0 {
+		m.SetRotationInterval(n)
+		m.SetRotateProxies(true)
+	}
+}
+
 // IsRunning returns whether the worker is running
 func (w *Worker) IsRunning() bool {
 	return w.running.Load()
@@ -497,7 +2305,7 @@ func (w *Worker) IsRunning() bool {
 
 // TaskQueueLength returns the current task queue length
 func (w *Worker) TaskQueueLength() int {
-	return len(w.tasks)
+	return w.tasks.len()
 }
 
 // ResultQueueLength returns the current result queue length
@@ -1,15 +1,23 @@
 package worker
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"dorker/worker/internal/engine"
 	"dorker/worker/internal/proxy"
+	"dorker/worker/internal/stealth"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -165,6 +173,77 @@ func TestWorkerSubmitBufferFull(t *testing.T) {
 	}
 }
 
+func TestWorkerSubmitBatch(t *testing.T) {
+	config := DefaultConfig()
+	config.Workers = 0 // No workers to process tasks
+	config.BufferSize = 2
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+
+	w := New(config, pool)
+	if _, err := w.SubmitBatch([]*Task{{ID: "0", Dork: "test"}}); err == nil {
+		t.Error("SubmitBatch should fail when not running")
+	}
+	w.running.Store(true) // Manually set running without starting workers
+
+	tasks := make([]*Task, 5)
+	for i := range tasks {
+		tasks[i] = &Task{ID: fmt.Sprintf("%d", i+1), Dork: "test"}
+	}
+
+	rejected, err := w.SubmitBatch(tasks)
+	if err != nil {
+		t.Fatalf("SubmitBatch() error = %v", err)
+	}
+	if want := []string{"3", "4", "5"}; fmt.Sprint(rejected) != fmt.Sprint(want) {
+		t.Errorf("rejected = %v, want %v", rejected, want)
+	}
+	if total := w.Stats().TasksTotal; total != 2 {
+		t.Errorf("TasksTotal = %d, want 2", total)
+	}
+}
+
+func TestWorkerSubmitWait(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+	w.config.Workers = 1
+	w.config.MaxDelay = time.Millisecond
+	w.tasks = newTaskQueue(2, 0) // Fewer slots than tasks below
+
+	if err := w.SubmitWait(&Task{ID: "0", Dork: "test"}); err == nil {
+		t.Error("SubmitWait should fail when not running")
+	}
+
+	w.Start()
+	defer w.Stop()
+
+	const n = 10
+	submitErr := make(chan error, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			if err := w.SubmitWait(&Task{ID: fmt.Sprintf("t%d", i), Dork: "inurl:admin"}); err != nil {
+				submitErr <- err
+				return
+			}
+		}
+		submitErr <- nil
+	}()
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-w.Results():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d results, want %d", i, n)
+		}
+	}
+	if err := <-submitErr; err != nil {
+		t.Fatalf("SubmitWait() error = %v", err)
+	}
+	if stats := w.Stats(); stats.TasksTotal != n || stats.TasksCompleted != n {
+		t.Errorf("Stats() total %d completed %d, want %d", stats.TasksTotal, stats.TasksCompleted, n)
+	}
+}
+
 func TestWorkerStats(t *testing.T) {
 	config := DefaultConfig()
 	pool := proxy.NewPool(proxy.DefaultPoolConfig())
@@ -187,6 +266,10 @@ func TestWorkerStats(t *testing.T) {
 	if stats.URLsFound != 0 {
 		t.Errorf("initial URLsFound = %d, want 0", stats.URLsFound)
 	}
+
+	if stats.EffectiveConcurrency != config.Workers {
+		t.Errorf("EffectiveConcurrency = %d, want %d", stats.EffectiveConcurrency, config.Workers)
+	}
 }
 
 func TestWorkerQueueLengths(t *testing.T) {
@@ -278,7 +361,10 @@ func TestWorkerCaptchaDetection(t *testing.T) {
 	defer server.Close()
 
 	// Verify CAPTCHA HTML is detected
-	resp, _ := http.Get(server.URL)
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("mock server request failed: %v", err)
+	}
 	defer resp.Body.Close()
 
 	// Read body and check
@@ -299,7 +385,10 @@ func TestWorkerBlockDetection(t *testing.T) {
 	}))
 	defer server.Close()
 
-	resp, _ := http.Get(server.URL)
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("mock server request failed: %v", err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusForbidden {
@@ -497,3 +586,2043 @@ func TestConfigValidation(t *testing.T) {
 		t.Errorf("ResultsPerPage = %d, should be between 10 and 100", config.ResultsPerPage)
 	}
 }
+
+func TestWorkerDedup(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/x\nhttps://A.Example.com/x/#top\nhttps://b.example.com/\n")
+	})
+	w.config.Dedup = true
+	w.config.MaxDelay = time.Millisecond
+
+	w.processTask(0, &Task{ID: "first", Dork: "test"})
+	first := <-w.results
+	if len(first.URLs) != 2 {
+		t.Fatalf("first result URLs = %+v, want 2 unique", first.URLs)
+	}
+	if first.URLs[1].URL != "https://b.example.com/" || first.URLs[1].Position != 2 {
+		t.Errorf("second URL = %+v, want b.example.com at position 2", first.URLs[1])
+	}
+
+	// Another task finds only URLs already emitted
+	w.processTask(0, &Task{ID: "second", Dork: "test"})
+	second := <-w.results
+	if len(second.URLs) != 0 {
+		t.Errorf("second result URLs = %+v, want none", second.URLs)
+	}
+
+	if got := w.DedupStats(); got.Total != 6 || got.Unique != 2 {
+		t.Errorf("DedupStats() = %+v, want 6 total, 2 unique", got)
+	}
+}
+
+func TestWorkerURLFilters(t *testing.T) {
+	var requests atomic.Int64
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			fmt.Fprint(rw, "https://a.example.com/x\nhttps://facebook.com/c\n")
+		case 2:
+			fmt.Fprint(rw, "https://www.facebook.com/a\nhttps://cdn.example.com/b.pdf\n")
+		default:
+			fmt.Fprint(rw, "https://b.example.com/y\nhttps://b.example.com/y.pdf\n")
+		}
+	})
+	w.config.MaxDelay = time.Millisecond
+	w.config.ExcludePatterns = []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)}
+	w.config.DenyDomains = []string{"facebook.com"}
+	w.excludeURLs = urlExcludes(w.config)
+
+	// The second page is filtered out entirely without ending the crawl
+	w.processTask(0, &Task{ID: "t1", Dork: "test", MaxPages: 3})
+	result := <-w.results
+	if len(result.URLs) != 2 || result.URLs[1].URL != "https://b.example.com/y" || result.URLs[1].Position != 2 {
+		t.Errorf("URLs = %+v, want a.example.com and b.example.com", result.URLs)
+	}
+	if result.Pages != 3 {
+		t.Errorf("Pages = %d, want 3", result.Pages)
+	}
+
+	stats := w.Stats()
+	if stats.URLsFiltered != 4 || stats.URLsFound != 2 {
+		t.Errorf("URLsFiltered = %d, URLsFound = %d, want 4 and 2", stats.URLsFiltered, stats.URLsFound)
+	}
+}
+
+func TestWorkerCollapseDomains(t *testing.T) {
+	config := DefaultConfig()
+	config.UniqueDomains = true
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+
+	w := New(config, pool)
+
+	results := []engine.SearchResult{
+		{URL: "https://a.example.com/x", Position: 1},
+		{URL: "https://example.com/y", Position: 2},
+		{URL: "https://shop.example.co.uk/login", Position: 3},
+		{URL: "https://other.co.uk/", Position: 4},
+	}
+	first := w.collapseDomains(results)
+
+	want := []string{"example.com", "example.co.uk", "other.co.uk"}
+	if len(first) != len(want) {
+		t.Fatalf("collapsed = %d results, want %d", len(first), len(want))
+	}
+	for i, r := range first {
+		if r.URL != want[i] {
+			t.Errorf("result[%d] = %q, want %q", i, r.URL, want[i])
+		}
+		if r.Position != i+1 {
+			t.Errorf("result[%d] position = %d, want %d", i, r.Position, i+1)
+		}
+	}
+
+	// Collapsing alone does not use a domain up
+	if again := w.collapseDomains(results); len(again) != len(want) {
+		t.Errorf("collapse before claiming = %+v, want %v again", again, want)
+	}
+	if claimed := w.claimDomains(first[:2]); len(claimed) != 2 {
+		t.Errorf("claimed = %+v, want %v", claimed, want[:2])
+	}
+	if claimed := w.claimDomains(first); len(claimed) != 1 || claimed[0].URL != "other.co.uk" || claimed[0].Position != 1 {
+		t.Errorf("claimed again = %+v, want only other.co.uk", claimed)
+	}
+
+	// Domains are emitted once across results
+	second := w.collapseDomains([]engine.SearchResult{
+		{URL: "https://b.example.com/z"},
+		{URL: "https://new.example.org/"},
+	})
+	if len(second) != 1 || second[0].URL != "example.org" {
+		t.Errorf("second collapse = %+v, want only example.org", second)
+	}
+	w.claimDomains(second)
+
+	if got := w.Stats().UniqueDomains; got != 4 {
+		t.Errorf("UniqueDomains = %d, want 4", got)
+	}
+}
+
+// mockEngine is a minimal SearchEngine for driving the worker in tests
+type mockEngine struct{}
+
+func (mockEngine) Name() string { return "mock" }
+
+func (mockEngine) BuildSearchURL(query string, page int, resultsPerPage int) string {
+	return fmt.Sprintf("http://search.test/search?q=%s&page=%d", url.QueryEscape(query), page)
+}
+
+func (mockEngine) ParseResults(html string) []engine.SearchResult {
+	var results []engine.SearchResult
+	for _, line := range strings.Split(html, "\n") {
+		if strings.HasPrefix(line, "http") {
+			results = append(results, engine.SearchResult{URL: line, Position: len(results) + 1})
+		}
+	}
+	return results
+}
+
+func (mockEngine) DetectCaptcha(html string) bool { return strings.Contains(html, "captcha") }
+
+func (mockEngine) DetectBlock(html string) bool { return strings.Contains(html, "blocked") }
+
+func (mockEngine) DetectNoResults(html string) bool { return false }
+
+func (mockEngine) Timing() engine.Timing { return engine.Timing{} }
+
+// newMockProxyWorker returns a worker whose only proxy is an HTTP server
+// answering every proxied request with handler
+func newMockProxyWorker(t *testing.T, handler http.HandlerFunc) *Worker {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("SplitHostPort() error = %v", err)
+	}
+
+	// No cooldown so the single proxy stays usable across retries
+	poolConfig := proxy.DefaultPoolConfig()
+	poolConfig.CooldownDuration = 0
+
+	pool := proxy.NewPool(poolConfig)
+	pool.AddProxy(&proxy.Proxy{ID: "mock", Host: host, Port: port, Type: proxy.ProxyTypeHTTP})
+
+	config := DefaultConfig()
+	config.MaxRetries = 2
+	config.RetryDelay = 10 * time.Millisecond
+	config.BlockRetryDelay = 10 * time.Millisecond
+
+	w := New(config, pool)
+	w.SetEngine(mockEngine{})
+	return w
+}
+
+func TestComputeBackoff(t *testing.T) {
+	config := DefaultConfig()
+	config.RetryDelay = time.Second
+	config.BlockRetryDelay = 10 * time.Second
+	config.MaxRetryDelay = time.Minute
+	w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+
+	for _, reason := range []ResultStatus{StatusError, StatusCaptcha, StatusBlocked} {
+		base := config.RetryDelay
+		if reason != StatusError {
+			base = config.BlockRetryDelay
+		}
+
+		// Doubling outgrows the ±20% jitter, so delays rise until the cap
+		prev := time.Duration(0)
+		for retry := 0; float64(base<<retry)*1.2 < float64(config.MaxRetryDelay); retry++ {
+			got := w.computeBackoff(retry, reason)
+			want := base << retry
+			if got < want*8/10 || got > want*12/10 {
+				t.Errorf("computeBackoff(%d, %s) = %v, want %v ±20%%", retry, reason, got, want)
+			}
+			if got <= prev {
+				t.Errorf("computeBackoff(%d, %s) = %v, want more than %v", retry, reason, got, prev)
+			}
+			prev = got
+		}
+
+		for _, retry := range []int{10, 64, 1000} {
+			got := w.computeBackoff(retry, reason)
+			if got > config.MaxRetryDelay || got < config.MaxRetryDelay*8/10 {
+				t.Errorf("computeBackoff(%d, %s) = %v, want near the %v cap", retry, reason, got, config.MaxRetryDelay)
+			}
+		}
+	}
+
+	// Network errors retry faster than blocks
+	if fast, slow := w.computeBackoff(0, StatusError), w.computeBackoff(0, StatusBlocked); fast >= slow {
+		t.Errorf("error backoff %v should be shorter than block backoff %v", fast, slow)
+	}
+}
+
+// timedEngine is a mockEngine recommending fixed cooldowns
+type timedEngine struct {
+	mockEngine
+	timing engine.Timing
+}
+
+func (e timedEngine) Timing() engine.Timing { return e.timing }
+
+func TestWorkerSetEngineTiming(t *testing.T) {
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+	pool.AddProxy(&proxy.Proxy{ID: "p1", Host: "192.168.1.1", Port: "8080", Type: proxy.ProxyTypeHTTP})
+
+	config := DefaultConfig()
+	config.BlockCooldown = time.Hour // Overrides the engine
+
+	w := New(config, pool)
+	w.SetEngine(timedEngine{timing: engine.Timing{
+		CaptchaCooldown: 10 * time.Minute,
+		BlockCooldown:   20 * time.Minute,
+	}})
+
+	pool.ReportCaptcha("p1")
+	p, _ := pool.GetByID("p1")
+	if remaining := time.Until(p.CooldownUntil); remaining < 9*time.Minute || remaining > 10*time.Minute {
+		t.Errorf("CAPTCHA cooldown = %v, want engine's 10m", remaining)
+	}
+
+	pool.ReportBlock("p1")
+	if remaining := time.Until(p.CooldownUntil); remaining < 59*time.Minute {
+		t.Errorf("block cooldown = %v, want config's 1h", remaining)
+	}
+}
+
+func TestWorkerSearchOnce(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "inurl:admin" {
+			t.Errorf("proxied query = %q, want inurl:admin", r.URL.Query().Get("q"))
+		}
+		fmt.Fprint(rw, "https://a.example.com/admin\nhttps://b.example.com/admin\n")
+	})
+
+	result, err := w.SearchOnce(context.Background(), "inurl:admin", 0)
+	if err != nil {
+		t.Fatalf("SearchOnce() error = %v", err)
+	}
+
+	if result.Status != StatusSuccess {
+		t.Errorf("Status = %v, want %v", result.Status, StatusSuccess)
+	}
+	if len(result.URLs) != 2 {
+		t.Errorf("URLs = %d, want 2", len(result.URLs))
+	}
+	if result.ProxyID != "mock" {
+		t.Errorf("ProxyID = %q, want mock", result.ProxyID)
+	}
+
+	// Results bypass the channel
+	if w.ResultQueueLength() != 0 {
+		t.Errorf("ResultQueueLength() = %d, want 0", w.ResultQueueLength())
+	}
+
+	stats := w.Stats()
+	if stats.TasksTotal != 1 || stats.TasksCompleted != 1 || stats.URLsFound != 2 {
+		t.Errorf("stats = %+v, want 1 total, 1 completed, 2 URLs", stats)
+	}
+}
+
+func TestWorkerSearchOnceRetriesCaptcha(t *testing.T) {
+	var requests int
+	var mu sync.Mutex
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		fmt.Fprint(rw, "captcha")
+	})
+
+	result, err := w.SearchOnce(context.Background(), "test", 0)
+	if err == nil {
+		t.Fatal("SearchOnce() expected error after exhausting retries")
+	}
+	if result == nil || result.Status != StatusCaptcha {
+		t.Errorf("result = %+v, want captcha status", result)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3 (1 + MaxRetries)", requests)
+	}
+	if w.Stats().TasksFailed != 1 {
+		t.Errorf("TasksFailed = %d, want 1", w.Stats().TasksFailed)
+	}
+}
+
+func TestWorkerAdaptiveConcurrency(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "captcha")
+	})
+	w.adaptive = newAdaptiveLimiter(8, 3, 0.5)
+
+	// 1 + MaxRetries CAPTCHAs fill the window
+	if _, err := w.SearchOnce(context.Background(), "test", 0); err == nil {
+		t.Fatal("SearchOnce() expected error after exhausting retries")
+	}
+	if got := w.Stats().EffectiveConcurrency; got != 4 {
+		t.Errorf("EffectiveConcurrency = %d, want 4", got)
+	}
+}
+
+func TestWorkerCircuitBreaker(t *testing.T) {
+	var captcha atomic.Bool
+	var requests atomic.Int64
+	captcha.Store(true)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if captcha.Load() {
+			fmt.Fprint(rw, "captcha")
+			return
+		}
+		fmt.Fprint(rw, "http://example.com/a")
+	})
+	w.config.Workers = 1
+	w.config.MaxRetries = 0
+	w.config.MaxDelay = time.Millisecond
+	w.breaker = newCircuitBreaker(2, 1, 300*time.Millisecond, 1)
+
+	states := make(chan BreakerState, 8)
+	w.OnBreaker(func(state BreakerState, until time.Time) {
+		states <- state
+	})
+	w.Start()
+	defer w.Stop()
+
+	// Two CAPTCHAs in a row trip the breaker
+	for _, id := range []string{"t1", "t2"} {
+		w.Submit(&Task{ID: id, Dork: id})
+		if result := <-w.Results(); result.Status != StatusCaptcha {
+			t.Fatalf("%s status = %s, want captcha", id, result.Status)
+		}
+	}
+	if state := <-states; state != BreakerOpen {
+		t.Fatalf("breaker went %s, want open", state)
+	}
+	if stats := w.Stats(); stats.BreakerState != BreakerOpen || stats.BreakerTrips != 1 {
+		t.Errorf("stats breaker = %s with %d trips, want open with 1", stats.BreakerState, stats.BreakerTrips)
+	}
+
+	// Nothing is dispatched during the cooldown
+	captcha.Store(false)
+	w.Submit(&Task{ID: "t3", Dork: "t3"})
+	time.Sleep(100 * time.Millisecond)
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests while open = %d, want 2", n)
+	}
+
+	// The probe gets through, closing the breaker
+	select {
+	case result := <-w.Results():
+		if result.Status != StatusSuccess {
+			t.Errorf("probe status = %s, want success", result.Status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no result after the cooldown")
+	}
+	for _, want := range []BreakerState{BreakerHalfOpen, BreakerClosed} {
+		if state := <-states; state != want {
+			t.Errorf("breaker went %s, want %s", state, want)
+		}
+	}
+	if state := w.Stats().BreakerState; state != BreakerClosed {
+		t.Errorf("stats breaker = %s, want closed", state)
+	}
+}
+
+func TestWorkerTimingProfile(t *testing.T) {
+	if w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig())); w.timing != nil {
+		t.Error("timing manager created without a profile")
+	}
+
+	var captcha atomic.Bool
+	captcha.Store(true)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		if captcha.Load() {
+			fmt.Fprint(rw, "captcha")
+			return
+		}
+		fmt.Fprint(rw, "https://example.com/\n")
+	})
+	w.timing = stealth.NewTimingManagerWithConfig(stealth.ProfileConfig{
+		MinDelay:        time.Millisecond,
+		MaxDelay:        time.Millisecond,
+		CaptchaCooldown: time.Millisecond,
+	})
+
+	if _, err := w.SearchOnce(context.Background(), "test", 0); err == nil {
+		t.Fatal("SearchOnce() expected error after exhausting retries")
+	}
+	if got := w.timing.GetSessionStats("mock").CaptchaCount; got != 3 {
+		t.Errorf("CaptchaCount = %d, want 3", got)
+	}
+
+	captcha.Store(false)
+	if _, err := w.SearchOnce(context.Background(), "test", 0); err != nil {
+		t.Fatalf("SearchOnce() error = %v", err)
+	}
+	if got := w.timing.GetSessionStats("mock").RequestCount; got != 1 {
+		t.Errorf("RequestCount = %d, want 1", got)
+	}
+}
+
+func TestWorkerTimingCooldownHoldsProxy(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+	w.timing = stealth.NewTimingManagerWithConfig(stealth.ProfileConfig{
+		MinDelay:      time.Millisecond,
+		MaxDelay:      time.Millisecond,
+		ErrorCooldown: time.Hour,
+	})
+
+	result, _ := w.executeOn(context.Background(), &Task{ID: "1", Dork: "test"}, mockEngine{})
+	if result.Status != StatusError {
+		t.Fatalf("Status = %s, want %s", result.Status, StatusError)
+	}
+
+	// The pool sees the profile's cooldown, so no worker leases the proxy
+	// only to sleep through it
+	prx, _ := w.pool.GetByID("mock")
+	if prx.IsAvailable() {
+		t.Error("proxy available during the profile's error cooldown")
+	}
+	if got, err := w.pool.Get(); err == nil {
+		t.Errorf("Get() = %s, want no proxy available", got.ID)
+	}
+}
+
+func TestWorkerSearchOnceCanceled(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://example.com/\n")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := w.SearchOnce(ctx, "test", 0); err != context.Canceled {
+		t.Errorf("SearchOnce() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWorkerMaxRuntime(t *testing.T) {
+	config := DefaultConfig()
+	config.Workers = 1
+	config.MaxRuntime = 50 * time.Millisecond
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+
+	w := New(config, pool)
+	w.Start()
+
+	if remaining := w.Stats().RemainingRuntime; remaining <= 0 || remaining > config.MaxRuntime {
+		t.Errorf("RemainingRuntime = %v, want within (0, %v]", remaining, config.MaxRuntime)
+	}
+
+	select {
+	case <-w.DeadlineReached():
+	case <-time.After(time.Second):
+		t.Fatal("deadline did not fire")
+	}
+
+	// Results channel closes once the worker has stopped
+	for range w.Results() {
+	}
+
+	if w.IsRunning() {
+		t.Error("worker should stop at the deadline")
+	}
+	if !w.HitDeadline() {
+		t.Error("HitDeadline() = false, want true")
+	}
+}
+
+func TestWorkerMaxRuntimeCanceledOnStop(t *testing.T) {
+	config := DefaultConfig()
+	config.Workers = 1
+	config.MaxRuntime = 30 * time.Millisecond
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+
+	w := New(config, pool)
+	w.Start()
+	w.Stop()
+
+	time.Sleep(60 * time.Millisecond)
+
+	if w.HitDeadline() {
+		t.Error("deadline fired after normal stop")
+	}
+	if w.Stats().RemainingRuntime != 0 {
+		t.Errorf("RemainingRuntime = %v after stop, want 0", w.Stats().RemainingRuntime)
+	}
+}
+
+func TestWorkerDrained(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "no links")
+	})
+	w.config.Workers = 2
+	w.Start()
+	defer w.Stop()
+
+	if w.IsDrained() {
+		t.Error("IsDrained() = true before any task was submitted")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := w.Submit(&Task{ID: fmt.Sprintf("task_%d", i), Dork: "test"}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	select {
+	case <-w.Drained():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drained() was not signalled")
+	}
+
+	if !w.IsDrained() {
+		t.Error("IsDrained() = false after all tasks finished")
+	}
+
+	// Every result is queued before the drained signal
+	if got := w.ResultQueueLength(); got != 3 {
+		t.Errorf("ResultQueueLength() = %d, want 3", got)
+	}
+}
+
+func TestWorkerMultiPage(t *testing.T) {
+	var requests int32
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		page := r.URL.Query().Get("page")
+		if page == "3" {
+			fmt.Fprint(rw, "no links")
+			return
+		}
+		fmt.Fprintf(rw, "https://a.example.com/%s\nhttps://b.example.com/%s\n", page, page)
+	})
+	w.config.BaseDelay = time.Millisecond
+	w.config.MinDelay = time.Millisecond
+	w.config.MaxDelay = time.Millisecond
+
+	tests := []struct {
+		name      string
+		maxPages  int
+		wantPages int
+		wantReqs  int32
+	}{
+		{"config default", 0, 1, 1},
+		{"stops at limit", 2, 2, 2},
+		{"stops at empty page", 8, 3, 4},
+		{"clamped", 1000, 3, 4},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&requests, 0)
+		w.processTask(0, &Task{ID: tt.name, Dork: "inurl:admin", MaxPages: tt.maxPages})
+
+		result := <-w.results
+		if result.Pages != tt.wantPages {
+			t.Errorf("%s: Pages = %d, want %d", tt.name, result.Pages, tt.wantPages)
+		}
+		if len(result.URLs) != 2*tt.wantPages {
+			t.Errorf("%s: URLs = %d, want %d", tt.name, len(result.URLs), 2*tt.wantPages)
+		}
+		if last := result.URLs[len(result.URLs)-1]; last.Position != len(result.URLs) {
+			t.Errorf("%s: last Position = %d, want %d", tt.name, last.Position, len(result.URLs))
+		}
+		if got := atomic.LoadInt32(&requests); got != tt.wantReqs {
+			t.Errorf("%s: requests = %d, want %d", tt.name, got, tt.wantReqs)
+		}
+	}
+
+	if limit := w.pageLimit(&Task{MaxPages: 1000}); limit != MaxPagesLimit {
+		t.Errorf("pageLimit() = %d, want %d", limit, MaxPagesLimit)
+	}
+}
+
+// pagedEngine is a mockEngine that sees a next page only while the page
+// says "more"
+type pagedEngine struct{ mockEngine }
+
+func (pagedEngine) HasNextPage(html string) bool { return strings.Contains(html, "more") }
+
+func TestWorkerMultiPageStops(t *testing.T) {
+	var requests int32
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch page := r.URL.Query().Get("page"); page {
+		case "0":
+			fmt.Fprintf(rw, "https://a.example.com/%s\nmore\n", page)
+		case "1":
+			fmt.Fprintf(rw, "https://a.example.com/%s\n", page)
+		default:
+			fmt.Fprint(rw, "captcha")
+		}
+	})
+	w.config.BaseDelay = time.Millisecond
+	w.config.MinDelay = time.Millisecond
+	w.config.MaxDelay = time.Millisecond
+
+	// The pager says page 1 is the last
+	w.SetEngine(pagedEngine{})
+	w.processTask(0, &Task{ID: "last", Dork: "inurl:admin", MaxPages: 5})
+
+	result := <-w.results
+	if result.Pages != 2 || len(result.URLs) != 2 {
+		t.Errorf("last page: Pages = %d, URLs = %d, want 2, 2", result.Pages, len(result.URLs))
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("last page: requests = %d, want 2", got)
+	}
+
+	// A CAPTCHA ends the crawl, keeping the pages already fetched
+	atomic.StoreInt32(&requests, 0)
+	w.SetEngine(mockEngine{})
+	w.processTask(0, &Task{ID: "captcha", Dork: "inurl:admin", MaxPages: 5})
+
+	result = <-w.results
+	if result.Status != StatusSuccess || result.Pages != 2 {
+		t.Errorf("captcha: Status = %s, Pages = %d, want success, 2", result.Status, result.Pages)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("captcha: requests = %d, want 3 (no retry of the CAPTCHA page)", got)
+	}
+}
+
+func TestWorkerMaxResultsPerDork(t *testing.T) {
+	var requests int32
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		page := r.URL.Query().Get("page")
+		fmt.Fprintf(rw, "https://a.example.com/shared\nhttps://b.example.com/%s\nhttps://c.example.com/%s\n", page, page)
+	})
+	w.config.BaseDelay = time.Millisecond
+	w.config.MinDelay = time.Millisecond
+	w.config.MaxDelay = time.Millisecond
+	w.config.MaxResultsPerDork = 4
+
+	urls := func(result *Result) string {
+		var list []string
+		for _, u := range result.URLs {
+			list = append(list, strings.TrimPrefix(u.URL, "https://"))
+		}
+		return strings.Join(list, " ")
+	}
+
+	tests := []struct {
+		name      string
+		dedup     bool
+		maxPages  int
+		wantURLs  string
+		wantPages int
+		truncated bool
+	}{
+		// Raw hits count: page 1 is cut after its first URL
+		{"raw", false, 5, "a.example.com/shared b.example.com/0 c.example.com/0 a.example.com/shared", 2, true},
+		// The repeated URL does not count, so page 1 is cut after b
+		{"dedup", true, 5, "a.example.com/shared b.example.com/0 c.example.com/0 b.example.com/1", 2, true},
+		// URLs emitted by the last task do not count either
+		{"dedup again", true, 5, "c.example.com/1 b.example.com/2 c.example.com/2 b.example.com/3", 4, true},
+		// The page limit comes first
+		{"under cap", false, 1, "a.example.com/shared b.example.com/0 c.example.com/0", 1, false},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&requests, 0)
+		w.config.Dedup = tt.dedup
+		w.processTask(0, &Task{ID: tt.name, Dork: "inurl:admin", MaxPages: tt.maxPages})
+
+		result := <-w.results
+		if got := urls(result); got != tt.wantURLs {
+			t.Errorf("%s: URLs = %s, want %s", tt.name, got, tt.wantURLs)
+		}
+		if result.Pages != tt.wantPages || result.Truncated != tt.truncated {
+			t.Errorf("%s: Pages = %d, Truncated = %v, want %d, %v", tt.name, result.Pages, result.Truncated, tt.wantPages, tt.truncated)
+		}
+		if got := atomic.LoadInt32(&requests); got != int32(tt.wantPages) {
+			t.Errorf("%s: requests = %d, want %d", tt.name, got, tt.wantPages)
+		}
+	}
+}
+
+func TestWorkerMaxResultsPerDorkUniqueDomains(t *testing.T) {
+	pages := map[string][]string{
+		"one 0": {"https://a.example.com/", "https://b.example.org/"},
+		"one 1": {"https://www.example.com/x", "https://c.example.net/", "https://d.example.io/"},
+		"two 0": {"https://d.example.io/y", "https://e.example.co/"},
+	}
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, strings.Join(pages[r.URL.Query().Get("q")+" "+r.URL.Query().Get("page")], "\n"))
+	})
+	w.config.BaseDelay = time.Millisecond
+	w.config.MinDelay = time.Millisecond
+	w.config.MaxDelay = time.Millisecond
+	w.config.UniqueDomains = true
+	w.config.MaxResultsPerDork = 3
+
+	urls := func(result *Result) string {
+		var list []string
+		for _, u := range result.URLs {
+			list = append(list, u.URL)
+		}
+		return strings.Join(list, " ")
+	}
+
+	// example.com repeats on page 2 and counts once towards the cap
+	w.processTask(0, &Task{ID: "one", Dork: "one", MaxPages: 2})
+	if result := <-w.results; urls(result) != "example.com example.org example.net" || !result.Truncated {
+		t.Errorf("first URLs = %s, truncated %v, want example.com example.org example.net, truncated", urls(result), result.Truncated)
+	}
+
+	// example.io was cut from the first dork, so the second still emits it
+	w.processTask(0, &Task{ID: "two", Dork: "two", MaxPages: 1})
+	if result := <-w.results; urls(result) != "example.io example.co" {
+		t.Errorf("second URLs = %s, want example.io example.co", urls(result))
+	}
+
+	if got := w.Stats().UniqueDomains; got != 5 {
+		t.Errorf("UniqueDomains = %d, want 5", got)
+	}
+}
+
+func TestWorkerWaitsOutPoolCooldown(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	})
+	w.config.MaxDelay = time.Millisecond
+
+	var notified int32
+	w.OnPoolCooldown(func(until time.Time) {
+		atomic.AddInt32(&notified, 1)
+	})
+
+	// Every proxy is briefly cooling down
+	prx, _ := w.pool.GetByID("mock")
+	prx.SetCooldown(100 * time.Millisecond)
+
+	start := time.Now()
+	w.processTask(0, &Task{ID: "task_1", Dork: "inurl:admin"})
+	result := <-w.results
+
+	if result.Status != StatusSuccess {
+		t.Errorf("Status = %v (%s), want %v", result.Status, result.Error, StatusSuccess)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("task finished after %v, want it to wait out the cooldown", elapsed)
+	}
+	if got := atomic.LoadInt32(&notified); got != 1 {
+		t.Errorf("cooldown notifications = %d, want 1", got)
+	}
+}
+
+func TestWorkerNoProxyToWaitFor(t *testing.T) {
+	w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+
+	result, retryable := w.execute(context.Background(), &Task{ID: "task_1", Dork: "test"})
+	if result.Status != StatusError || retryable {
+		t.Errorf("execute() = %v retryable %v, want non-retryable error", result.Status, retryable)
+	}
+}
+
+func TestWorkerRetryWithFullBuffer(t *testing.T) {
+	// Each dork gets a CAPTCHA on its first attempt
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		dork := r.URL.Query().Get("q")
+		mu.Lock()
+		attempts[dork]++
+		first := attempts[dork] == 1
+		mu.Unlock()
+
+		if first {
+			fmt.Fprint(rw, "captcha")
+			return
+		}
+		fmt.Fprintf(rw, "https://example.com/%s\n", url.PathEscape(dork))
+	})
+	w.config.Workers = 1
+	w.config.MaxDelay = time.Millisecond
+	w.tasks = newTaskQueue(2, 0)
+	w.Start()
+	defer w.Stop()
+
+	// Keep the main buffer full while the first task is retried
+	for i := 0; i < 4; i++ {
+		task := &Task{ID: fmt.Sprintf("task_%d", i), Dork: fmt.Sprintf("dork%d", i)}
+		for w.Submit(task) != nil {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	select {
+	case <-w.Drained():
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks did not finish")
+	}
+
+	stats := w.Stats()
+	if stats.TasksCompleted != 4 || stats.TasksFailed != 0 {
+		t.Errorf("completed = %d, failed = %d, want 4 and 0", stats.TasksCompleted, stats.TasksFailed)
+	}
+	if stats.RetryQueued != 0 {
+		t.Errorf("RetryQueued = %d, want 0 after draining", stats.RetryQueued)
+	}
+}
+
+func TestWorkerStickyProxy(t *testing.T) {
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+	for i := 0; i < 5; i++ {
+		pool.AddProxy(&proxy.Proxy{
+			ID:   fmt.Sprintf("proxy_%d", i),
+			Host: fmt.Sprintf("127.0.0.%d", i+1),
+			Port: "8080",
+			Type: proxy.ProxyTypeHTTP,
+		})
+	}
+
+	w := New(DefaultConfig(), pool)
+
+	first, err := w.getProxy(&Task{Dork: "inurl:admin", Sticky: true})
+	if err != nil {
+		t.Fatalf("getProxy() error = %v", err)
+	}
+
+	for page := 1; page < 20; page++ {
+		prx, err := w.getProxy(&Task{Dork: "inurl:admin", Page: page, Sticky: true})
+		if err != nil {
+			t.Fatalf("getProxy() error = %v", err)
+		}
+		if prx.ID != first.ID {
+			t.Fatalf("page %d proxy = %s, want pinned %s", page, prx.ID, first.ID)
+		}
+	}
+
+	// A pinned proxy that becomes unavailable is an error, not a switch
+	pool.ReportBlock(first.ID)
+	if _, err := w.getProxy(&Task{Dork: "inurl:admin", Page: 20, Sticky: true}); err == nil {
+		t.Error("getProxy() should fail when the pinned proxy is unavailable")
+	}
+
+	// Non-sticky tasks are unaffected
+	if _, err := w.getProxy(&Task{Dork: "inurl:admin"}); err != nil {
+		t.Errorf("non-sticky getProxy() error = %v", err)
+	}
+}
+
+func TestWorkerSessionKey(t *testing.T) {
+	pool := proxy.NewPool(proxy.DefaultPoolConfig())
+	for i := 0; i < 5; i++ {
+		pool.AddProxy(&proxy.Proxy{
+			ID:   fmt.Sprintf("proxy_%d", i),
+			Host: fmt.Sprintf("127.0.0.%d", i+1),
+			Port: "8080",
+			Type: proxy.ProxyTypeHTTP,
+		})
+	}
+
+	w := New(DefaultConfig(), pool)
+
+	first, err := w.getProxy(&Task{Dork: "inurl:admin", SessionKey: "s1"})
+	if err != nil {
+		t.Fatalf("getProxy() error = %v", err)
+	}
+	for page := 1; page < 20; page++ {
+		// Tasks of one session share the proxy whatever their dork
+		prx, err := w.getProxy(&Task{Dork: fmt.Sprintf("dork %d", page), SessionKey: "s1", Sticky: true})
+		if err != nil {
+			t.Fatalf("getProxy() error = %v", err)
+		}
+		if prx.ID != first.ID {
+			t.Fatalf("task %d proxy = %s, want pinned %s", page, prx.ID, first.ID)
+		}
+	}
+	if got := w.SessionProxies(); len(got) != 1 || got["s1"] != first.ID {
+		t.Errorf("SessionProxies() = %v, want s1 -> %s", got, first.ID)
+	}
+
+	// A pinned proxy that becomes unavailable is replaced
+	pool.ReportBlock(first.ID)
+	next, err := w.getProxy(&Task{Dork: "inurl:admin", SessionKey: "s1"})
+	if err != nil {
+		t.Fatalf("getProxy() after block error = %v", err)
+	}
+	if next.ID == first.ID {
+		t.Errorf("getProxy() after block = %s, want a new proxy", next.ID)
+	}
+	if got := w.SessionProxies()["s1"]; got != next.ID {
+		t.Errorf("session s1 -> %s, want %s", got, next.ID)
+	}
+
+	// The returned map is a copy
+	w.SessionProxies()["s1"] = "bogus"
+	if got := w.SessionProxies()["s1"]; got != next.ID {
+		t.Errorf("session s1 -> %s after editing the copy, want %s", got, next.ID)
+	}
+
+	// Tasks without a key are not tracked
+	if _, err := w.getProxy(&Task{Dork: "inurl:admin"}); err != nil {
+		t.Errorf("getProxy() without session error = %v", err)
+	}
+	if n := len(w.SessionProxies()); n != 1 {
+		t.Errorf("len(SessionProxies()) = %d, want 1", n)
+	}
+}
+
+func TestWorkerSessionKeyUnpinsOnFailure(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "bad") {
+			fmt.Fprint(rw, "captcha")
+			return
+		}
+		fmt.Fprint(rw, "http://example.com/a")
+	})
+	w.config.MaxDelay = time.Millisecond
+
+	task := &Task{ID: "t1", Dork: "good", SessionKey: "s1"}
+	if result, _ := w.executeOn(context.Background(), task, mockEngine{}); result.Status != StatusSuccess {
+		t.Fatalf("status = %s (%s), want success", result.Status, result.Error)
+	}
+	if got := w.SessionProxies()["s1"]; got != "mock" {
+		t.Fatalf("session s1 -> %q after success, want mock", got)
+	}
+
+	task = &Task{ID: "t2", Dork: "bad", SessionKey: "s1"}
+	if result, _ := w.executeOn(context.Background(), task, mockEngine{}); result.Status != StatusCaptcha {
+		t.Fatalf("status = %s, want captcha", result.Status)
+	}
+	if got, ok := w.SessionProxies()["s1"]; ok {
+		t.Errorf("session s1 -> %q after CAPTCHA, want unpinned", got)
+	}
+}
+
+func TestWorkerRateLimitRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+		wantErr    string
+	}{
+		{"seconds", "120", 2 * time.Minute, "retry after 2m0s"},
+		{"http date", time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat), 90 * time.Second, "retry after 1m"},
+		{"missing", "", 45 * time.Second, "bad status code: 429"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					rw.Header().Set("Retry-After", tt.retryAfter)
+				}
+				rw.WriteHeader(http.StatusTooManyRequests)
+			})
+			w.pool.SetCooldowns(45*time.Second, 0)
+
+			result, _ := w.executeOn(context.Background(), &Task{ID: "t1", Dork: "test"}, mockEngine{})
+			if result.Status == StatusSuccess || !strings.Contains(result.Error, tt.wantErr) {
+				t.Errorf("result = %s (%q), want error containing %q", result.Status, result.Error, tt.wantErr)
+			}
+
+			prx, _ := w.pool.GetByID("mock")
+			if remaining := time.Until(prx.CooldownUntil); remaining > tt.want || remaining < tt.want-2*time.Second {
+				t.Errorf("cooldown = %v, want about %v", remaining, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkerMaxConcurrentPerProxy(t *testing.T) {
+	var inflight, peak atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		fmt.Fprint(rw, "no links")
+	}))
+	defer server.Close()
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	poolConfig := proxy.DefaultPoolConfig()
+	poolConfig.CooldownDuration = 0
+	poolConfig.MaxConcurrentPerProxy = 1
+	pool := proxy.NewPool(poolConfig)
+	pool.AddProxy(&proxy.Proxy{ID: "mock", Host: host, Port: port, Type: proxy.ProxyTypeHTTP})
+
+	config := DefaultConfig()
+	config.Workers = 4
+	config.MaxDelay = time.Millisecond
+	w := New(config, pool)
+	w.SetEngine(mockEngine{})
+	w.Start()
+	defer w.Stop()
+
+	const tasks = 4
+	for i := 0; i < tasks; i++ {
+		w.Submit(&Task{ID: fmt.Sprintf("t_%d", i), Dork: "test", Sticky: i%2 == 0})
+	}
+	for i := 0; i < tasks; i++ {
+		select {
+		case result := <-w.Results():
+			if result.Status != StatusSuccess {
+				t.Errorf("task %s status = %s (%s), want success", result.TaskID, result.Status, result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d results arrived", i, tasks)
+		}
+	}
+
+	if n := peak.Load(); n != 1 {
+		t.Errorf("peak requests in flight = %d, want 1", n)
+	}
+	if n := pool.Snapshot("")[0].ActiveLeases; n != 0 {
+		t.Errorf("ActiveLeases = %d after all tasks, want 0", n)
+	}
+}
+
+// countingTransport is a ProxyTransport counting proxied requests
+type countingTransport struct {
+	proxied  atomic.Int64
+	requests atomic.Int64
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (t *countingTransport) ForProxy(prx *proxy.Proxy) (http.RoundTripper, error) {
+	t.proxied.Add(1)
+	proxyURL, _ := url.Parse(prx.URL())
+	return &proxiedRoundTripper{t, &http.Transport{Proxy: http.ProxyURL(proxyURL)}}, nil
+}
+
+type proxiedRoundTripper struct {
+	counter *countingTransport
+	next    *http.Transport
+}
+
+func (p *proxiedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.counter.requests.Add(1)
+	return p.next.RoundTrip(req)
+}
+
+func TestWorkerCustomTransport(t *testing.T) {
+	handler := func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "https://a.example.com/admin\n")
+	}
+
+	// A ProxyTransport routes the request through the proxy itself
+	w := newMockProxyWorker(t, handler)
+	custom := &countingTransport{}
+	w.config.Transport = custom
+
+	result, err := w.SearchOnce(context.Background(), "inurl:admin", 0)
+	if err != nil || result.Status != StatusSuccess {
+		t.Fatalf("SearchOnce() = %+v, %v, want success", result, err)
+	}
+	if custom.proxied.Load() != 1 || custom.requests.Load() != 1 {
+		t.Errorf("proxied = %d, requests = %d, want 1 and 1", custom.proxied.Load(), custom.requests.Load())
+	}
+
+	// An *http.Transport is cloned with the proxy applied on top
+	base := &http.Transport{MaxIdleConnsPerHost: 7}
+	w.config.Transport = base
+	prx, _ := w.pool.GetByID("mock")
+	rt, err := w.transportFor(prx, nil)
+	if err != nil {
+		t.Fatalf("transportFor() error = %v", err)
+	}
+	if tr, ok := rt.(*http.Transport); !ok || tr == base || tr.MaxIdleConnsPerHost != 7 || tr.Proxy == nil {
+		t.Errorf("transportFor() = %+v, want a proxied clone", rt)
+	}
+	if base.Proxy != nil {
+		t.Error("custom transport was modified")
+	}
+
+	// A plain RoundTripper cannot carry the proxy, so nothing goes out directly
+	w.config.Transport = http.NewFileTransport(http.Dir(t.TempDir()))
+	if _, err := w.transportFor(prx, nil); err == nil {
+		t.Error("transportFor() with a plain RoundTripper should fail")
+	}
+}
+
+// namedEngine is a mockEngine searching its own host
+type namedEngine struct {
+	mockEngine
+	name string
+}
+
+func (e namedEngine) Name() string { return e.name }
+
+func (e namedEngine) BuildSearchURL(query string, page int, resultsPerPage int) string {
+	return fmt.Sprintf("http://%s.test/search?q=%s&page=%d", e.name, url.QueryEscape(query), page)
+}
+
+// stubEngine is a mockEngine that recognises empty pages and soft blocks
+// the way a real engine would, without being one of the built-in engines
+type stubEngine struct{ mockEngine }
+
+func (stubEngine) DetectNoResults(html string) bool { return strings.Contains(html, "nothing found") }
+
+func (stubEngine) DetectSoftBlock(html string, query string, resultCount int) bool {
+	return resultCount == 0 && strings.Contains(html, "soft wall") && query == "inurl:admin"
+}
+
+func TestWorkerEngineInterfaces(t *testing.T) {
+	var body atomic.Value
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, body.Load())
+	})
+	w.SetEngine(stubEngine{})
+
+	tests := []struct {
+		body string
+		want ResultStatus
+	}{
+		{"https://a.example.com/admin\n", StatusSuccess},
+		{"nothing found", StatusNoResults},
+		{"", StatusSuccess}, // Empty, but not a page the engine recognises as such
+		{"soft wall\nhttps://a.example.com/admin\n", StatusSuccess},
+		{"soft wall", StatusBlocked}, // Last: the block quarantines the proxy
+	}
+	for _, tt := range tests {
+		body.Store(tt.body)
+		result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, stubEngine{})
+		if result.Status != tt.want {
+			t.Errorf("%q: Status = %s, want %s", tt.body, result.Status, tt.want)
+		}
+	}
+}
+
+func TestWorkerBuildSearchURLOptions(t *testing.T) {
+	w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+	task := &Task{Dork: "inurl:admin", TimeRange: "m", Country: "fr", Language: "fr"}
+
+	got := w.buildSearchURL(engine.NewGoogle(), task, "")
+	for _, want := range []string{"tbs=qdr%3Am", "gl=fr", "hl=fr"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("google URL = %s, want %s", got, want)
+		}
+	}
+
+	// Engines without options still build their plain URL
+	if got := w.buildSearchURL(mockEngine{}, task, ""); got != (mockEngine{}).BuildSearchURL(task.Dork, 0, w.config.ResultsPerPage) {
+		t.Errorf("mock URL = %s, want the plain URL", got)
+	}
+
+	// The proxy is the session a sticky domain is pinned to
+	g := engine.NewGoogleWithSeed(1)
+	g.SetDomains(engine.GoogleDomains(), true)
+	hostFor := func(proxyID string) string {
+		u, _ := url.Parse(w.buildSearchURL(g, task, proxyID))
+		return u.Host
+	}
+	pinned := map[string]string{"a": hostFor("a"), "b": hostFor("b"), "c": hostFor("c")}
+	for id, want := range pinned {
+		if got := hostFor(id); got != want {
+			t.Errorf("proxy %s domain = %q, want its pinned %q", id, got, want)
+		}
+	}
+}
+
+func TestWorkerValidatesDorks(t *testing.T) {
+	tests := []struct {
+		name    string
+		dork    string
+		strict  bool
+		status  ResultStatus
+		url     string
+		warning string
+	}{
+		{"clean", "inurl:admin", false, StatusSuccess, "http://search.test/search?q=inurl%3Aadmin&page=0", ""},
+		{"normalized", "INURL: admin", false, StatusSuccess, "http://search.test/search?q=inurl%3Aadmin&page=0", "joined inurl:"},
+		{"hopeless searched as written", "-site:example.com", false, StatusSuccess, "http://search.test/search?q=-site%3Aexample.com&page=0", "every term is excluded"},
+		{"hopeless rejected", "-site:example.com", true, StatusError, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.DryRun = true
+			config.StrictDorks = tt.strict
+			w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+			w.SetEngine(mockEngine{})
+			w.Start()
+			defer w.Stop()
+
+			if err := w.Submit(&Task{ID: "t1", Dork: tt.dork}); err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+
+			var result *Result
+			select {
+			case result = <-w.Results():
+			case <-time.After(2 * time.Second):
+				t.Fatal("no result")
+			}
+
+			if result.Status != tt.status || result.Dork != tt.dork {
+				t.Fatalf("result = %+v, want %s for dork %q", result, tt.status, tt.dork)
+			}
+			if tt.url != "" && (len(result.URLs) != 1 || result.URLs[0].URL != tt.url) {
+				t.Errorf("URLs = %+v, want %s", result.URLs, tt.url)
+			}
+			if tt.status == StatusError && !strings.Contains(result.Error, "every term is excluded") {
+				t.Errorf("Error = %q, want the reason", result.Error)
+			}
+
+			warnings := strings.Join(result.Warnings, "; ")
+			if tt.warning == "" && tt.status == StatusSuccess && warnings != "" {
+				t.Errorf("Warnings = %q, want none", warnings)
+			}
+			if !strings.Contains(warnings, tt.warning) {
+				t.Errorf("Warnings = %q, want %q", warnings, tt.warning)
+			}
+		})
+	}
+}
+
+func TestWorkerDryRun(t *testing.T) {
+	config := DefaultConfig()
+	config.DryRun = true
+	config.MaxPages = 2
+	w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+	w.SetEngines(namedEngine{name: "alpha"}, namedEngine{name: "beta"})
+	w.Start()
+	defer w.Stop()
+
+	if err := w.Submit(&Task{ID: "t1", Dork: "inurl:admin", Page: 1}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	var result *Result
+	select {
+	case result = <-w.Results():
+	case <-time.After(2 * time.Second):
+		t.Fatal("no result from a dry run with an empty pool")
+	}
+	if result.Status != StatusSuccess || result.ProxyID != "" || result.Pages != 2 {
+		t.Errorf("result = %+v, want success over 2 pages without a proxy", result)
+	}
+
+	want := []string{
+		"http://alpha.test/search?q=inurl%3Aadmin&page=1",
+		"http://alpha.test/search?q=inurl%3Aadmin&page=2",
+		"http://beta.test/search?q=inurl%3Aadmin&page=1",
+		"http://beta.test/search?q=inurl%3Aadmin&page=2",
+	}
+	if len(result.URLs) != len(want) {
+		t.Fatalf("URLs = %+v, want %d", result.URLs, len(want))
+	}
+	for i, u := range result.URLs {
+		if u.URL != want[i] || u.Position != i+1 {
+			t.Errorf("URLs[%d] = %s at %d, want %s at %d", i, u.URL, u.Position, want[i], i+1)
+		}
+		if name := strings.SplitN(strings.TrimPrefix(want[i], "http://"), ".", 2)[0]; len(u.Engines) != 1 || u.Engines[0] != name {
+			t.Errorf("URLs[%d] engines = %v, want [%s]", i, u.Engines, name)
+		}
+	}
+
+	// Google previews carry the domain and search parameters
+	w.SetEngine(engine.NewGoogle())
+	w.engines = nil
+	preview := w.previewURLs(&Task{Dork: "inurl:admin", Country: "de"})
+	if len(preview.URLs) != 2 || !strings.HasPrefix(preview.URLs[0].URL, "https://www.google.") || !strings.Contains(preview.URLs[0].URL, "gl=de") {
+		t.Fatalf("google preview = %+v, want google URLs with gl=de", preview.URLs)
+	}
+	if preview.URLs[0].Engines != nil {
+		t.Errorf("single-engine preview engines = %v, want none", preview.URLs[0].Engines)
+	}
+}
+
+func TestWorkerMultiEngine(t *testing.T) {
+	var captchaBeta atomic.Bool
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Host {
+		case "alpha.test":
+			fmt.Fprint(rw, "https://a.example.com/\nhttps://b.example.com/\n")
+		case "beta.test":
+			if captchaBeta.Load() {
+				fmt.Fprint(rw, "captcha")
+				return
+			}
+			fmt.Fprint(rw, "https://b.example.com/\nhttps://c.example.com/\n")
+		}
+	})
+	w.SetEngines(namedEngine{name: "alpha"}, namedEngine{name: "beta"})
+
+	result, err := w.SearchOnce(context.Background(), "inurl:admin", 0)
+	if err != nil || result.Status != StatusSuccess {
+		t.Fatalf("SearchOnce() = %+v, %v, want success", result, err)
+	}
+
+	want := map[string]string{
+		"https://a.example.com/": "alpha",
+		"https://b.example.com/": "alpha,beta",
+		"https://c.example.com/": "beta",
+	}
+	if len(result.URLs) != len(want) {
+		t.Fatalf("URLs = %+v, want %d merged URLs", result.URLs, len(want))
+	}
+	for i, u := range result.URLs {
+		if got := strings.Join(u.Engines, ","); got != want[u.URL] {
+			t.Errorf("%s engines = %q, want %q", u.URL, got, want[u.URL])
+		}
+		if u.Position != i+1 {
+			t.Errorf("%s position = %d, want %d", u.URL, u.Position, i+1)
+		}
+	}
+
+	// One engine failing still yields the other's URLs without a retry
+	captchaBeta.Store(true)
+	task := &Task{ID: "t", Dork: "inurl:admin"}
+	result, retryable := w.execute(context.Background(), task)
+	if result.Status != StatusSuccess || len(result.URLs) != 2 || retryable {
+		t.Errorf("execute() = %+v, retryable %v, want alpha's 2 URLs", result, retryable)
+	}
+
+	// A single engine is plain single-engine mode
+	w.SetEngines(mockEngine{})
+	if w.engines != nil {
+		t.Errorf("engines = %v, want nil with one engine", w.engines)
+	}
+}
+
+func TestMergeResultsAllFailed(t *testing.T) {
+	engines := []engine.SearchEngine{namedEngine{name: "alpha"}, namedEngine{name: "beta"}}
+	results := []*Result{
+		{Status: StatusCaptcha, ProxyID: "p1", StatusCode: 200},
+		{Status: StatusError, Error: "timeout", ProxyID: "p2"},
+	}
+
+	merged, retryable := mergeResults(&Task{ID: "t", Dork: "d"}, engines, results, []bool{true, false})
+	if merged.Status != StatusCaptcha || !retryable {
+		t.Errorf("merged = %+v, retryable %v, want retryable captcha", merged, retryable)
+	}
+	if merged.Error != "alpha: captcha; beta: timeout" || merged.ProxyID != "p1,p2" {
+		t.Errorf("Error = %q, ProxyID = %q", merged.Error, merged.ProxyID)
+	}
+	if merged.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want the first failure's 200", merged.StatusCode)
+	}
+}
+
+func TestWorkerResultStatusCode(t *testing.T) {
+	tests := []struct {
+		status     int
+		body       string
+		want       ResultStatus
+		statusCode int
+	}{
+		{http.StatusOK, "https://a.example.com/admin\n", StatusSuccess, 200},
+		{http.StatusOK, "blocked", StatusBlocked, 200},
+		{http.StatusServiceUnavailable, "", StatusError, 503},
+		{http.StatusTooManyRequests, "", StatusError, 429},
+	}
+	for _, tt := range tests {
+		w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+			time.Sleep(5 * time.Millisecond)
+			rw.WriteHeader(tt.status)
+			fmt.Fprint(rw, tt.body)
+		})
+
+		result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{})
+		if result.Status != tt.want || result.StatusCode != tt.statusCode {
+			t.Errorf("%d %q: result = %s %d, want %s %d", tt.status, tt.body, result.Status, result.StatusCode, tt.want, tt.statusCode)
+		}
+		if result.Latency < 5*time.Millisecond || result.Latency > result.Duration {
+			t.Errorf("%d %q: Latency = %v, want the request's time within Duration %v", tt.status, tt.body, result.Latency, result.Duration)
+		}
+	}
+
+	// No response, no status code
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		conn, _, _ := rw.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+	if result, _ := w.executeOn(context.Background(), &Task{ID: "t", Dork: "inurl:admin"}, mockEngine{}); result.StatusCode != 0 || result.Status != StatusError {
+		t.Errorf("result = %s %d, want error without a status code", result.Status, result.StatusCode)
+	}
+}
+
+func TestWorkerCancel(t *testing.T) {
+	var requests atomic.Int32
+	entered := make(chan struct{}, 10)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		entered <- struct{}{}
+		// Hang until the worker aborts the request
+		<-r.Context().Done()
+	})
+	w.config.Workers = 1
+	w.Start()
+	defer w.Stop()
+
+	for _, id := range []string{"running", "queued"} {
+		if err := w.Submit(&Task{ID: id, Dork: id}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	select {
+	case <-entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("first request never arrived")
+	}
+
+	if !w.Cancel("queued") {
+		t.Error("Cancel(queued) = false, want true")
+	}
+	if !w.Cancel("running") {
+		t.Error("Cancel(running) = false, want true")
+	}
+	if w.Cancel("unknown") {
+		t.Error("Cancel(unknown) = true, want false")
+	}
+
+	got := make(map[string]ResultStatus)
+	for len(got) < 2 {
+		select {
+		case result := <-w.Results():
+			got[result.TaskID] = result.Status
+		case <-time.After(2 * time.Second):
+			t.Fatalf("results = %v, want both tasks", got)
+		}
+	}
+	for id, status := range got {
+		if status != StatusCancelled {
+			t.Errorf("task %s status = %s, want %s", id, status, StatusCancelled)
+		}
+	}
+
+	// The cancelled task is not retried and the queued one never ran
+	time.Sleep(50 * time.Millisecond)
+	if n := requests.Load(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+
+	stats := w.Stats()
+	if stats.TasksCancelled != 2 || stats.TasksFailed != 2 {
+		t.Errorf("TasksCancelled = %d, TasksFailed = %d, want 2 and 2", stats.TasksCancelled, stats.TasksFailed)
+	}
+	if !w.IsDrained() {
+		t.Error("IsDrained() = false after cancelling every task")
+	}
+
+	// The aborted request is not held against the proxy
+	prx, _ := w.pool.GetByID("mock")
+	if prx.FailCount != 0 || prx.ActiveLeases() != 0 {
+		t.Errorf("proxy FailCount = %d, ActiveLeases() = %d, want 0 and 0", prx.FailCount, prx.ActiveLeases())
+	}
+}
+
+func TestWorkerCancelForgetsUnknownIDs(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {})
+
+	for _, id := range []string{"a", "b"} {
+		w.Cancel(id)
+	}
+	w.cancelMu.Lock()
+	w.cancelled["a"] = time.Now().Add(-2 * cancelMemory)
+	w.cancelMu.Unlock()
+
+	w.Cancel("c")
+	w.cancelMu.Lock()
+	defer w.cancelMu.Unlock()
+	if _, ok := w.cancelled["a"]; ok || len(w.cancelled) != 2 {
+		t.Errorf("cancelled = %v, want b and c only", w.cancelled)
+	}
+}
+
+func TestWorkerDrain(t *testing.T) {
+	entered := make(chan struct{}, 10)
+	release := make(chan struct{})
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		fmt.Fprint(rw, "no results")
+	})
+	w.config.Workers = 2
+	w.Start()
+
+	for _, id := range []string{"a", "b", "queued"} {
+		if err := w.Submit(&Task{ID: id, Dork: id}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-entered:
+		case <-time.After(2 * time.Second):
+			t.Fatal("requests never arrived")
+		}
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- w.Drain(context.Background()) }()
+
+	// New tasks are refused while running ones finish
+	time.Sleep(20 * time.Millisecond)
+	if err := w.Submit(&Task{ID: "late", Dork: "late"}); err == nil {
+		t.Error("Submit() during Drain should fail")
+	}
+	close(release)
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Drain() did not return")
+	}
+
+	got := make(map[string]ResultStatus)
+	for result := range w.Results() {
+		got[result.TaskID] = result.Status
+	}
+	if len(got) != 2 || got["a"] != StatusSuccess || got["b"] != StatusSuccess {
+		t.Errorf("results = %v, want a and b succeeded", got)
+	}
+
+	// Cancelling the task left queued sends after the channel closed
+	w.Cancel("queued")
+}
+
+func TestWorkerDrainTimeout(t *testing.T) {
+	entered := make(chan struct{}, 10)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-r.Context().Done()
+	})
+	w.config.Workers = 1
+	w.Start()
+
+	if err := w.Submit(&Task{ID: "stuck", Dork: "stuck"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	select {
+	case <-entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request never arrived")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.Drain(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Drain() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	var statuses []ResultStatus
+	for result := range w.Results() {
+		statuses = append(statuses, result.Status)
+	}
+	if len(statuses) != 1 || statuses[0] != StatusCancelled {
+		t.Errorf("results = %v, want one cancelled", statuses)
+	}
+}
+
+func TestWorkerStopAbortsRequest(t *testing.T) {
+	entered := make(chan struct{}, 10)
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		// Never respond; only an aborted connection ends the handler
+		<-r.Context().Done()
+	})
+	w.config.Workers = 1
+	w.config.RequestTimeout = time.Minute
+	w.Start()
+
+	if err := w.Submit(&Task{ID: "hung", Dork: "hung"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	select {
+	case <-entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request never arrived")
+	}
+
+	stopped := make(chan struct{})
+	start := time.Now()
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop() waited for the hung request")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop() took %v, want it to abort the request", elapsed)
+	}
+
+	// The aborted task is cancelled, not retried
+	var statuses []ResultStatus
+	for result := range w.Results() {
+		statuses = append(statuses, result.Status)
+	}
+	if len(statuses) != 1 || statuses[0] != StatusCancelled {
+		t.Errorf("results = %v, want one cancelled", statuses)
+	}
+
+	// Nor is the proxy penalized for it
+	prx, _ := w.pool.GetByID("mock")
+	if prx.FailCount != 0 || prx.Status != proxy.ProxyStatusAlive {
+		t.Errorf("proxy FailCount = %d, status = %s, want 0 and alive", prx.FailCount, prx.Status)
+	}
+}
+
+func TestWorkerUpdateConfig(t *testing.T) {
+	var inflight, peak atomic.Int32
+	entered := make(chan struct{}, 20)
+	release := make(chan struct{})
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		entered <- struct{}{}
+		<-release
+		fmt.Fprint(rw, "no links")
+	})
+	w.config.Workers = 1
+	w.Start()
+	defer w.Stop()
+
+	waitEntered := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			select {
+			case <-entered:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("only %d of %d requests arrived", i, n)
+			}
+		}
+	}
+	collect := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			select {
+			case <-w.Results():
+			case <-time.After(2 * time.Second):
+				t.Fatalf("only %d of %d results arrived", i, n)
+			}
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		w.Submit(&Task{ID: fmt.Sprintf("a_%d", i), Dork: "test"})
+	}
+	waitEntered(1)
+
+	update := w.config
+	update.Workers = 3
+	update.MaxRetries = 0
+	update.MaxDelay = time.Millisecond
+	w.UpdateConfig(update)
+
+	// The new workers pick up the queued tasks alongside the first
+	waitEntered(2)
+	if got := peak.Load(); got != 3 {
+		t.Errorf("peak concurrency = %d, want 3", got)
+	}
+	if w.maxRetries() != 0 {
+		t.Errorf("maxRetries() = %d, want 0", w.maxRetries())
+	}
+	close(release)
+	collect(3)
+
+	update.Workers = 1
+	w.UpdateConfig(update)
+	peak.Store(0)
+
+	for i := 0; i < 3; i++ {
+		w.Submit(&Task{ID: fmt.Sprintf("b_%d", i), Dork: "test"})
+	}
+	collect(3)
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("peak concurrency after scaling down = %d, want 1", got)
+	}
+	if stats := w.Stats(); stats.TasksTotal != 6 || stats.TasksCompleted != 6 {
+		t.Errorf("TasksTotal = %d, TasksCompleted = %d, want 6 and 6", stats.TasksTotal, stats.TasksCompleted)
+	}
+}
+
+func TestWorkerGlobalRPM(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		fmt.Fprint(rw, "no links")
+	})
+	w.config.Workers = 10
+
+	// 1200 RPM is one request every 50ms
+	update := w.config
+	update.GlobalRPM = 1200
+	w.UpdateConfig(update)
+
+	w.Start()
+	defer w.Stop()
+
+	const tasks = 10
+	for i := 0; i < tasks; i++ {
+		w.Submit(&Task{ID: fmt.Sprintf("t_%d", i), Dork: "test"})
+	}
+	for i := 0; i < tasks; i++ {
+		select {
+		case <-w.Results():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d results arrived", i, tasks)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != tasks {
+		t.Fatalf("requests = %d, want %d", len(times), tasks)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	elapsed := times[len(times)-1].Sub(times[0])
+	if rate := float64(len(times)-1) / elapsed.Seconds(); rate > 20*1.1 {
+		t.Errorf("observed rate = %.1f/s over %v, want at most 20/s", rate, elapsed)
+	}
+}
+
+func TestWorkerGlobalRPMStop(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "no links")
+	})
+	w.config.Workers = 5
+
+	// One request a minute: all but the first wait on the limiter
+	update := w.config
+	update.GlobalRPM = 1
+	w.UpdateConfig(update)
+	w.Start()
+
+	for i := 0; i < 5; i++ {
+		w.Submit(&Task{ID: fmt.Sprintf("t_%d", i), Dork: "test"})
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop() did not return while workers waited on the rate limit")
+	}
+}
+
+func TestWorkerPauseResume(t *testing.T) {
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(rw, "http://example.com/a")
+	})
+	w.config.Workers = 2
+	w.config.MaxDelay = time.Millisecond
+	w.Start()
+	defer w.Stop()
+
+	if !w.Pause() || !w.IsPaused() {
+		t.Fatal("Pause() did not pause a running worker")
+	}
+	if w.Pause() {
+		t.Error("Pause() = true on a paused worker")
+	}
+	before := w.Stats().TotalDuration
+
+	if err := w.Submit(&Task{ID: "t1", Dork: "test"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	select {
+	case result := <-w.Results():
+		t.Fatalf("result %s sent while paused", result.TaskID)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Elapsed time stands still while paused
+	if during := w.Stats().TotalDuration; during-before > 50*time.Millisecond {
+		t.Errorf("TotalDuration grew by %v while paused", during-before)
+	}
+
+	if !w.Resume() || w.IsPaused() {
+		t.Fatal("Resume() did not resume the worker")
+	}
+	select {
+	case result := <-w.Results():
+		if result.Status != StatusSuccess {
+			t.Errorf("status = %s (%s), want success", result.Status, result.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no result after Resume()")
+	}
+
+	if elapsed := w.Stats().TotalDuration; elapsed >= 300*time.Millisecond {
+		t.Errorf("TotalDuration = %v, want the 300ms pause excluded", elapsed)
+	}
+}
+
+func TestWorkerPauseHoldsRetryDelay(t *testing.T) {
+	var requests atomic.Int64
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			fmt.Fprint(rw, "captcha")
+			return
+		}
+		fmt.Fprint(rw, "http://example.com/a")
+	})
+	w.config.Workers = 1
+	w.config.MaxDelay = time.Millisecond
+	w.config.BlockRetryDelay = 200 * time.Millisecond
+	w.Start()
+	defer w.Stop()
+
+	if err := w.Submit(&Task{ID: "t1", Dork: "test"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Pausing during the retry delay holds the retry back
+	w.Pause()
+	time.Sleep(400 * time.Millisecond)
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("requests while paused = %d, want 1", n)
+	}
+
+	w.Resume()
+	select {
+	case result := <-w.Results():
+		if result.Status != StatusSuccess {
+			t.Errorf("status = %s, want success after the retry", result.Status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("retry never ran after Resume()")
+	}
+}
+
+func TestWorkerPauseHoldsDeadline(t *testing.T) {
+	w := New(DefaultConfig(), proxy.NewPool(proxy.DefaultPoolConfig()))
+	w.config.MaxRuntime = 200 * time.Millisecond
+	w.Start()
+	defer w.Stop()
+
+	w.Pause()
+	time.Sleep(400 * time.Millisecond)
+	if w.HitDeadline() {
+		t.Fatal("MaxRuntime elapsed while paused")
+	}
+
+	w.Resume()
+	select {
+	case <-w.DeadlineReached():
+	case <-time.After(2 * time.Second):
+		t.Fatal("deadline never reached after Resume()")
+	}
+}
+
+func TestWorkerOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		want    []string
+		dropped int64
+	}{
+		{"", []string{"r1", "r2"}, 1},
+		{OverflowDropNewest, []string{"r1", "r2"}, 1},
+		{OverflowDropOldest, []string{"r2", "r3"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			config := DefaultConfig()
+			config.BufferSize = 2
+			config.OverflowPolicy = tt.policy
+			w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+
+			for _, id := range []string{"r1", "r2", "r3"} {
+				w.sendResult(&Result{TaskID: id})
+			}
+
+			var got []string
+			for len(w.results) > 0 {
+				got = append(got, (<-w.results).TaskID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+			if dropped := w.Stats().ResultsDropped; dropped != tt.dropped {
+				t.Errorf("ResultsDropped = %d, want %d", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestWorkerOverflowBlock(t *testing.T) {
+	config := DefaultConfig()
+	config.BufferSize = 1
+	config.OverflowPolicy = OverflowBlock
+	w := New(config, proxy.NewPool(proxy.DefaultPoolConfig()))
+	w.Start()
+
+	w.sendResult(&Result{TaskID: "r1"})
+
+	sent := make(chan struct{})
+	go func() {
+		w.sendResult(&Result{TaskID: "r2"})
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("sendResult() returned while the buffer was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Reading makes room for the blocked result
+	if got := (<-w.results).TaskID; got != "r1" {
+		t.Errorf("first result = %s, want r1", got)
+	}
+	<-sent
+	if got := (<-w.results).TaskID; got != "r2" {
+		t.Errorf("second result = %s, want r2", got)
+	}
+
+	// Stop releases a blocked sender, which counts its result as dropped
+	w.sendResult(&Result{TaskID: "r3"})
+	go w.sendResult(&Result{TaskID: "r4"})
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop() hung on a blocked sendResult()")
+	}
+	if dropped := w.Stats().ResultsDropped; dropped != 1 {
+		t.Errorf("ResultsDropped = %d, want 1", dropped)
+	}
+}
+
+func TestWorkerRefererChain(t *testing.T) {
+	var mu sync.Mutex
+	referers := map[string]string{}
+	w := newMockProxyWorker(t, func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		referers[r.URL.Query().Get("page")] = r.Header.Get("Referer")
+		mu.Unlock()
+		fmt.Fprintf(rw, "http://example.com/%s", r.URL.Query().Get("page"))
+	})
+	w.config.MaxDelay = time.Millisecond
+
+	task := &Task{ID: "t1", Dork: "test", MaxPages: 3, Sticky: true}
+	w.processTask(0, task)
+	if result := <-w.results; result.Pages != 3 {
+		t.Fatalf("Pages = %d, want 3", result.Pages)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if referers["0"] != "" {
+		t.Errorf("page 0 Referer = %q, want none", referers["0"])
+	}
+	for page := 1; page <= 2; page++ {
+		want := mockEngine{}.BuildSearchURL("test", page-1, 0)
+		if got := referers[fmt.Sprint(page)]; got != want {
+			t.Errorf("page %d Referer = %q, want %q", page, got, want)
+		}
+	}
+}
+
+func TestWorkerFingerprintRotation(t *testing.T) {
+	// Without rotation a proxy keeps its fingerprint
+	w := New(DefaultConfig(), nil)
+	first := w.stealth.GetFingerprintForProxy("p1")
+	for i := 0; i < 5; i++ {
+		if fp := w.stealth.GetFingerprintForProxy("p1"); fp != first {
+			t.Fatalf("fingerprint changed to %s without FingerprintRotation", fp.ID)
+		}
+	}
+
+	// Every second request reassigns the mapping, including on a stealth
+	// manager set later
+	config := DefaultConfig()
+	config.FingerprintRotation = 2
+	w = New(config, nil)
+	for _, m := range []*stealth.Manager{w.stealth, stealth.NewManagerWithSeed(1)} {
+		w.SetStealthManager(m)
+		first := w.stealth.GetFingerprintForProxy("p1")
+		if fp := w.stealth.GetFingerprintForProxy("p1"); fp == first {
+			t.Errorf("fingerprint = %s after FingerprintRotation requests, want a new one", fp.ID)
+		}
+	}
+}